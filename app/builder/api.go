@@ -0,0 +1,163 @@
+package builder
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/go-xiaohei/pugo/app/helper"
+	"github.com/go-xiaohei/pugo/app/model"
+)
+
+// APIPath is directory of json api in destination
+const APIPath = "api/v1"
+
+type (
+	// APIPost is post in json api
+	APIPost struct {
+		Title   string    `json:"title"`
+		URL     string    `json:"url"`
+		Desc    string    `json:"desc,omitempty"`
+		Thumb   string    `json:"thumb,omitempty"`
+		Author  string    `json:"author,omitempty"`
+		Date    string    `json:"date"`
+		Updated string    `json:"updated"`
+		Tags    []*APITag `json:"tags,omitempty"`
+		Brief   string    `json:"brief"`
+	}
+	// APITag is tag in json api, API is url of its first json page
+	APITag struct {
+		Name  string `json:"name"`
+		URL   string `json:"url"`
+		API   string `json:"api,omitempty"`
+		Count int    `json:"count,omitempty"`
+	}
+	// APIPage is a page of posts in json api,
+	// Prev and Next are urls of sibling json pages
+	APIPage struct {
+		Page       int        `json:"page"`
+		TotalPages int        `json:"total_pages"`
+		Total      int        `json:"total"`
+		Prev       string     `json:"prev,omitempty"`
+		Next       string     `json:"next,omitempty"`
+		Posts      []*APIPost `json:"posts"`
+	}
+)
+
+// apiTags sorts tags by posts count, then by name
+type apiTags []*APITag
+
+func (t apiTags) Len() int      { return len(t) }
+func (t apiTags) Swap(i, j int) { t[i], t[j] = t[j], t[i] }
+func (t apiTags) Less(i, j int) bool {
+	if t[i].Count != t[j].Count {
+		return t[i].Count > t[j].Count
+	}
+	return t[i].Name < t[j].Name
+}
+
+// compileAPI writes json pages of all posts in /api/v1/posts/page/{n}.json,
+// posts of each tag in /api/v1/tags/{tag}/page/{n}.json and tags in /api/v1/tags.json.
+// Posts excluded by index rules or noindex are not in json api
+func compileAPI(ctx *Context) []helper.WorkerFunc {
+	build := ctx.Source.Build
+	if build == nil || !build.API {
+		return nil
+	}
+	size := build.APIPageSize
+	if size <= 0 {
+		size = build.PostPageSize
+	}
+	if size <= 0 {
+		size = 4
+	}
+	fns := compileAPIPages(ctx, "posts", ctx.indexedPosts(ctx.Source.Posts, model.IndexAPI), size)
+	var tags []*APITag
+	for _, tp := range ctx.Source.TagPosts {
+		posts := ctx.indexedPosts(tp.Posts, model.IndexAPI)
+		if len(posts) == 0 {
+			continue
+		}
+		dir := path.Join("tags", tp.Tag.Name)
+		fns = append(fns, compileAPIPages(ctx, dir, posts, size)...)
+		tags = append(tags, &APITag{
+			Name:  tp.Tag.Name,
+			URL:   ctx.apiURL(tp.Tag.URL),
+			API:   ctx.apiURL(APIPath, dir, "page/1.json"),
+			Count: len(posts),
+		})
+	}
+	sort.Sort(apiTags(tags))
+	fns = append(fns, func() error {
+		return writeAPI(ctx, path.Join(ctx.DstDir(), ctx.Source.Meta.Path, APIPath, "tags.json"), tags)
+	})
+	return fns
+}
+
+// compileAPIPages return funcs to write json pages of posts in dir
+func compileAPIPages(ctx *Context, dir string, posts []*model.Post, size int) []helper.WorkerFunc {
+	var fns []helper.WorkerFunc
+	cursor := helper.NewPagerCursor(size, len(posts))
+	for i := 1; ; i++ {
+		pager := cursor.Page(i)
+		if pager == nil {
+			break
+		}
+		page := &APIPage{
+			Page:       pager.Current,
+			TotalPages: pager.Pages,
+			Total:      len(posts),
+		}
+		if pager.Current > 1 {
+			page.Prev = ctx.apiURL(APIPath, dir, fmt.Sprintf("page/%d.json", pager.Current-1))
+		}
+		if pager.Current < pager.Pages {
+			page.Next = ctx.apiURL(APIPath, dir, fmt.Sprintf("page/%d.json", pager.Current+1))
+		}
+		current := posts[pager.Begin:pager.End]
+		file := path.Join(ctx.DstDir(), ctx.Source.Meta.Path, APIPath, dir, fmt.Sprintf("page/%d.json", pager.Current))
+		fns = append(fns, func() error {
+			for _, p := range current {
+				page.Posts = append(page.Posts, ctx.apiPost(p))
+			}
+			return writeAPI(ctx, file, page)
+		})
+	}
+	return fns
+}
+
+func (ctx *Context) apiPost(p *model.Post) *APIPost {
+	post := &APIPost{
+		Title:   p.Title,
+		URL:     ctx.apiURL(p.URL()),
+		Desc:    p.Desc,
+		Thumb:   p.Thumb,
+		Date:    p.Created().Format(time.RFC3339),
+		Updated: p.Updated().Format(time.RFC3339),
+		Brief:   string(p.Brief()),
+	}
+	if p.Author != nil {
+		post.Author = p.Author.Nick
+	}
+	for _, t := range p.Tags {
+		post.Tags = append(post.Tags, &APITag{Name: t.Name, URL: ctx.apiURL(t.URL)})
+	}
+	return post
+}
+
+// apiURL return url path in site with meta path
+func (ctx *Context) apiURL(elem ...string) string {
+	return path.Join(append([]string{"/", ctx.Source.Meta.Path}, elem...)...)
+}
+
+func writeAPI(ctx *Context, file string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	os.MkdirAll(path.Dir(file), os.ModePerm)
+	return writeChanged(ctx, file, data)
+}
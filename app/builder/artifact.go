@@ -0,0 +1,194 @@
+package builder
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/Unknwon/com"
+	"github.com/go-xiaohei/pugo/app/helper"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+const (
+	artifactDir  = "builds"
+	artifactFile = "artifact.json"
+)
+
+// Artifact is a kept output of building in cache directory,
+// it can be restored to destination by rollback
+type Artifact struct {
+	ID     string            `json:"id"`
+	Time   time.Time         `json:"time"`
+	Commit string            `json:"commit,omitempty"`
+	Files  map[string]string `json:"files"`
+
+	dir string
+}
+
+// Dir return directory of files in artifact
+func (a *Artifact) Dir() string {
+	return a.dir
+}
+
+// ArtifactsDir return directory of kept outputs of source directory
+func ArtifactsDir(srcDir string) (string, error) {
+	dir, _, err := ReadCacheSetting(srcDir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, artifactDir), nil
+}
+
+// ListArtifacts return kept outputs in dir, the newest is first
+func ListArtifacts(dir string) ([]*Artifact, error) {
+	return listArtifacts(dir, log15.Root())
+}
+
+func listArtifacts(dir string, logger log15.Logger) ([]*Artifact, error) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var list []*Artifact
+	for _, fi := range fis {
+		if !fi.IsDir() {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, fi.Name(), artifactFile))
+		if err != nil {
+			logger.Warn("Artifact|%s|%s", fi.Name(), err.Error())
+			continue
+		}
+		a := new(Artifact)
+		if err = json.Unmarshal(data, a); err != nil {
+			logger.Warn("Artifact|%s|%s", fi.Name(), err.Error())
+			continue
+		}
+		a.dir = filepath.Join(dir, fi.Name(), "files")
+		list = append(list, a)
+	}
+	sort.Sort(artifacts(list))
+	return list, nil
+}
+
+// artifacts sorts artifacts from new to old
+type artifacts []*Artifact
+
+func (a artifacts) Len() int           { return len(a) }
+func (a artifacts) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a artifacts) Less(i, j int) bool { return a[i].Time.After(a[j].Time) }
+
+// keepArtifact saves output in destination as artifact, and removes old artifacts over keep_builds.
+// Files same to last artifact are hard links to it, other files are copied,
+// so artifacts are not changed by next building that rewrites files in destination.
+func keepArtifact(ctx *Context) error {
+	if ctx.Source.Build == nil || ctx.Source.Build.KeepBuilds <= 0 || ctx.Draft || ctx.IsDryRun() {
+		return nil
+	}
+	dir := filepath.Join(ctx.CacheDir(), artifactDir)
+	list, err := listArtifacts(dir, ctx.Log())
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	a := &Artifact{
+		ID:     now.Format("20060102-150405"),
+		Time:   now,
+		Commit: gitCommit(ctx.SrcDir()),
+		Files:  make(map[string]string),
+	}
+	if com.IsDir(filepath.Join(dir, a.ID)) {
+		a.ID = now.Format("20060102-150405.000")
+	}
+	a.dir = filepath.Join(dir, a.ID, "files")
+	var last *Artifact
+	if len(list) > 0 {
+		last = list[0]
+	}
+
+	dstDir := ctx.DstDir()
+	var linked int
+	err = filepath.Walk(dstDir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			if fi.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, _ := filepath.Rel(dstDir, p)
+		rel = filepath.ToSlash(rel)
+		hash, err := helper.Md5File(p)
+		if err != nil {
+			return err
+		}
+		a.Files[rel] = hash
+		file := filepath.Join(a.dir, filepath.FromSlash(rel))
+		os.MkdirAll(filepath.Dir(file), os.ModePerm)
+		if last != nil && last.Files[rel] == hash {
+			if err = os.Link(filepath.Join(last.dir, filepath.FromSlash(rel)), file); err == nil {
+				linked++
+				return nil
+			}
+		}
+		return helper.CopyFile(p, file)
+	})
+	if err != nil {
+		os.RemoveAll(filepath.Join(dir, a.ID))
+		return err
+	}
+	data, _ := json.MarshalIndent(a, "", "  ")
+	if err = ioutil.WriteFile(filepath.Join(dir, a.ID, artifactFile), data, os.ModePerm); err != nil {
+		return err
+	}
+	ctx.Log().Info("Build|Artifact|%s|%d files, %d linked", a.ID, len(a.Files), linked)
+
+	list = append([]*Artifact{a}, list...)
+	if len(list) <= ctx.Source.Build.KeepBuilds {
+		return nil
+	}
+	for _, old := range list[ctx.Source.Build.KeepBuilds:] {
+		if err = os.RemoveAll(filepath.Dir(old.dir)); err != nil {
+			return err
+		}
+		ctx.Log().Debug("Build|Artifact|%s|removed", old.ID)
+	}
+	return nil
+}
+
+// RestoreArtifact replaces files in dstDir with files in artifact,
+// .git directory in dstDir is kept
+func RestoreArtifact(a *Artifact, dstDir string) error {
+	os.MkdirAll(dstDir, os.ModePerm)
+	fis, err := ioutil.ReadDir(dstDir)
+	if err != nil {
+		return err
+	}
+	for _, fi := range fis {
+		if fi.Name() == ".git" {
+			continue
+		}
+		if err = os.RemoveAll(filepath.Join(dstDir, fi.Name())); err != nil {
+			return err
+		}
+	}
+	for rel := range a.Files {
+		// copy instead of linking, building rewrites files in destination
+		file := filepath.Join(dstDir, filepath.FromSlash(rel))
+		os.MkdirAll(filepath.Dir(file), os.ModePerm)
+		if err = helper.CopyFile(filepath.Join(a.dir, filepath.FromSlash(rel)), file); err != nil {
+			return fmt.Errorf("restore '%s': %s", rel, err.Error())
+		}
+	}
+	return nil
+}
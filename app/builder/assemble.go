@@ -7,8 +7,6 @@ import (
 	"sort"
 	"strings"
 
-	"gopkg.in/inconshreveable/log15.v2"
-
 	"github.com/go-xiaohei/pugo/app/helper"
 	"github.com/go-xiaohei/pugo/app/model"
 	"github.com/go-xiaohei/pugo/app/sync"
@@ -24,6 +22,21 @@ func AssembleSource(ctx *Context) {
 	}
 
 	ctx.Sync = sync.NewSyncer(path.Join(ctx.DstDir(), ctx.Source.Meta.Path))
+	ctx.Sync.Logger = ctx.Logger
+	var (
+		staticSettings []*model.StaticRule
+		indexSettings  []*model.IndexRule
+	)
+	if ctx.Source.Build != nil {
+		staticSettings = ctx.Source.Build.Static
+		indexSettings = ctx.Source.Build.Index
+	}
+	if ctx.staticRules, ctx.Err = newStaticRules(staticSettings); ctx.Err != nil {
+		return
+	}
+	if ctx.indexRules, ctx.Err = newIndexRules(ctx.Source.Meta.Path, indexSettings); ctx.Err != nil {
+		return
+	}
 
 	ctx.Source.Nav.SetPrefix(ctx.Source.Meta.Path)
 	ctx.Source.Tags = make(map[string]*model.Tag)
@@ -31,6 +44,7 @@ func AssembleSource(ctx *Context) {
 	ctx.Source.PagePosts = make(map[int]*model.PagerPosts)
 
 	r, hr := newReplacer(ctx.Source.Meta.Path), newReplacerInHTML(ctx.Source.Meta.Path)
+	ctx.images = newImageMetas(ctx)
 	ctx.Source.Meta.Cover = r.Replace(ctx.Source.Meta.Cover)
 	for _, a := range ctx.Source.Authors {
 		a.Avatar = r.Replace(a.Avatar)
@@ -42,11 +56,28 @@ func AssembleSource(ctx *Context) {
 			p.SetURL(path.Join(ctx.Source.Meta.Path, p.URL()))
 		}
 		p.SetDestURL(filepath.Join(ctx.DstDir(), p.URL()))
+		p.Views = ctx.Source.Views.Get(p.URL())
+		p.Syndicated = ctx.Source.Syndicated.Get(p.URL())
+		for _, name := range p.Syndicate {
+			if ctx.Source.Syndication.Get(name) == nil {
+				ctx.Log().Warn("Assemble|Syndication|%s|'%s' is not in meta", p.SourceURL(), name)
+			}
+		}
+		if ctx.Source.Reaction.IsOK() {
+			p.Reactions = ctx.Source.Reactions.Get(p.URL(), ctx.Source.Reaction.Kinds)
+		}
 		p.SetPlaceholder(r, hr)
+		p.SetEditorial(ctx.Draft)
+		p.ReplaceContent(imageReplacer(ctx, p.SourceURL()))
 		ctx.Tree.Add(p.DestURL(), p.Title, model.TreePost, 0)
 		if p.Author == nil {
 			p.Author = ctx.Source.Authors[p.AuthorName]
 		}
+		if p.License == nil {
+			p.License = ctx.Source.License
+		} else if p.License.Holder == "" && p.Author != nil {
+			p.License.Holder = p.Author.Nick
+		}
 		for _, t := range p.Tags {
 			ctx.Source.Tags[t.Name] = t
 			if ctx.Source.TagPosts[t.Name] == nil {
@@ -58,15 +89,28 @@ func AssembleSource(ctx *Context) {
 				ctx.Source.TagPosts[t.Name].Posts = append(ctx.Source.TagPosts[t.Name].Posts, p)
 			}
 		}
+		// variants are not in tree, they are only for A/B testing
+		for _, name := range p.VariantNames() {
+			v := p.NewVariant(name)
+			v.SetPlaceholder(r, hr)
+			v.SetEditorial(ctx.Draft)
+			v.ReplaceContent(imageReplacer(ctx, p.SourceURL()))
+			p.AddVariant(v)
+		}
 	}
 
 	// fill page data
+	if ctx.Err = appendAttributionsPage(ctx); ctx.Err != nil {
+		return
+	}
 	for _, p := range ctx.Source.Pages {
 		if ctx.Source.Meta.Path != "" && ctx.Source.Meta.Path != "/" {
 			p.SetURL(path.Join(ctx.Source.Meta.Path, p.URL()))
 		}
 		p.SetDestURL(filepath.Join(ctx.DstDir(), p.URL()))
 		p.SetPlaceholder(hr)
+		p.SetEditorial(ctx.Draft)
+		p.ReplaceContent(imageReplacer(ctx, p.SourceURL()))
 		treeType := model.TreePage
 		if p.Node {
 			treeType = model.TreePageNode
@@ -77,6 +121,10 @@ func AssembleSource(ctx *Context) {
 		}
 	}
 
+	checkPermalinks(ctx)
+	checkLock(ctx)
+	ctx.Source.PopularPosts = ctx.Source.Posts.Popular()
+
 	// prepare tag posts
 	for _, tp := range ctx.Source.TagPosts {
 		sort.Sort(model.Posts(tp.Posts))
@@ -134,11 +182,15 @@ func AssembleSource(ctx *Context) {
 	ctx.Tree.Add(path.Join(ctx.DstDir(), ctx.Source.Meta.Path, "feed.xml"), "Feed", model.TreeXML, 0)
 	ctx.Tree.Add(path.Join(ctx.DstDir(), ctx.Source.Meta.Path, "sitemap.xml"), "Sitemap", model.TreeXML, 0)
 
+	if ctx.Err = checkCollisions(ctx); ctx.Err != nil {
+		return
+	}
+
 	if ctx.Err = ctx.Theme.Load(); ctx.Err != nil {
 		return
 	}
 
-	log15.Info("Assemble|Done")
+	ctx.Log().Info("Assemble|Done")
 }
 
 func newReplacer(static string) *strings.Replacer {
@@ -0,0 +1,132 @@
+package builder
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	// AssetManifestFile maps static files to fingerprinted files and md5 hashes,
+	// and lists old fingerprinted files to remove from deploy targets
+	AssetManifestFile = "assets.json"
+	assetCacheFile    = "assets.json"
+	// assetStaleDays is days to keep old fingerprinted files in stale list by default
+	assetStaleDays = 90
+	// assetStaleMax is max files in stale list, the oldest ones are dropped over it
+	assetStaleMax = 1000
+)
+
+// AssetManifest is fingerprinted files and hashes of static files of last building
+type AssetManifest struct {
+	Assets map[string]string `json:"assets"`
+	Stale  []string          `json:"stale,omitempty"`
+	// Hashes are md5 hashes of copied static files, computed in syncing
+	Hashes map[string]string `json:"hashes,omitempty"`
+}
+
+// ReadAssetManifest return asset manifest in destination directory
+func ReadAssetManifest(dstDir string) (*AssetManifest, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dstDir, AssetManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	manifest := new(AssetManifest)
+	if err = json.Unmarshal(data, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// assetStateFile return file to keep fingerprinted files of past buildings,
+// it's asset_state in build settings or in cache directory
+func assetStateFile(ctx *Context) string {
+	if b := ctx.Source.Build; b != nil && b.AssetState != "" {
+		if filepath.IsAbs(b.AssetState) {
+			return b.AssetState
+		}
+		return filepath.Join(ctx.SrcDir(), b.AssetState)
+	}
+	return filepath.Join(ctx.CacheDir(), assetCacheFile)
+}
+
+// compileAssets writes asset manifest of fingerprinted files and hashes of static files.
+// Fingerprinted files of past buildings are saved in state file with time they become old,
+// 0 for current files, so the file only changes when fingerprinted files change.
+// Old files are listed as stale until they are older than stale days, at most assetStaleMax files,
+// so deploy methods can remove them instead of keeping all of them for years.
+func compileAssets(ctx *Context) error {
+	hashes := ctx.Sync.Hashes()
+	if len(hashes) == 0 && (ctx.staticRules == nil || len(ctx.staticRules.variants) == 0) {
+		return nil
+	}
+	stateFile := assetStateFile(ctx)
+	seen := make(map[string]int64)
+	old, err := ioutil.ReadFile(stateFile)
+	if err == nil {
+		if err = json.Unmarshal(old, &seen); err != nil {
+			ctx.Log().Warn("Build|Assets|%s", err.Error())
+		}
+	}
+	days := int64(assetStaleDays)
+	if b := ctx.Source.Build; b != nil && b.StaleDays > 0 {
+		days = int64(b.StaleDays)
+	}
+
+	now := time.Now().Unix()
+	manifest := &AssetManifest{Assets: make(map[string]string), Hashes: hashes}
+	if ctx.staticRules != nil {
+		manifest.Assets = ctx.staticRules.variants
+	}
+	current := make(map[string]bool, len(manifest.Assets))
+	for _, f := range manifest.Assets {
+		current[f] = true
+		seen[f] = 0
+	}
+	for f, t := range seen {
+		if current[f] {
+			continue
+		}
+		if t == 0 {
+			seen[f] = now
+		} else if now-t > days*24*3600 {
+			delete(seen, f)
+			continue
+		}
+		manifest.Stale = append(manifest.Stale, f)
+	}
+	if len(manifest.Stale) > assetStaleMax {
+		sort.Slice(manifest.Stale, func(i, j int) bool {
+			return seen[manifest.Stale[i]] > seen[manifest.Stale[j]]
+		})
+		for _, f := range manifest.Stale[assetStaleMax:] {
+			delete(seen, f)
+		}
+		manifest.Stale = manifest.Stale[:assetStaleMax]
+	}
+	sort.Strings(manifest.Stale)
+
+	data, _ := json.MarshalIndent(seen, "", "  ")
+	if !bytes.Equal(data, old) {
+		os.MkdirAll(filepath.Dir(stateFile), os.ModePerm)
+		if err = ioutil.WriteFile(stateFile, data, os.ModePerm); err != nil {
+			return err
+		}
+	}
+	if len(manifest.Stale) > 0 {
+		ctx.Log().Info("Build|Assets|%d stale files", len(manifest.Stale))
+	}
+
+	data, _ = json.MarshalIndent(manifest, "", "  ")
+	dstFile := filepath.Join(ctx.DstDir(), AssetManifestFile)
+	if err := ioutil.WriteFile(dstFile, data, os.ModePerm); err != nil {
+		return err
+	}
+	ctx.Sync.SetSynced(dstFile)
+	ctx.Log().Debug("Build|%s", dstFile)
+	return nil
+}
@@ -1,86 +1,199 @@
 package builder
 
 import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"gopkg.in/inconshreveable/log15.v2"
+	"github.com/go-xiaohei/pugo/app/helper"
+	"github.com/go-xiaohei/pugo/app/metrics"
 )
 
+// lowMemoryGCPercent makes garbage collection more frequently in low memory mode
+const lowMemoryGCPercent = 20
+
+// lowMemory counts buildings in low memory mode.
+// Percent of garbage collection is process-wide,
+// it's set by the first building and restored after the last one
+var lowMemory struct {
+	sync.Mutex
+	count   int
+	percent int
+}
+
 type (
 	// Builder is object of Builder handlers
 	Builder struct {
-		IsBuilding bool
-		IsWatching bool
-		Counter    int
-
+		lock     sync.RWMutex
 		handlers []Handler
+		building int32
+		counter  int64
+		status   Status
+		// commitDir is source directory of last building,
+		// commit of status is resolved in it when status is requested
+		commitDir string
 	}
 	// Handler define a step in building process
 	Handler func(ctx *Context)
 )
 
+// ErrCanceled is returned when building is canceled
+var ErrCanceled = errors.New("build canceled")
+
 var (
-	b = &Builder{
-		IsBuilding: false,
-		IsWatching: false,
-		handlers: []Handler{
-			ReadSource,
-			ReadTheme,
-			AssembleSource,
-			Compile,
-			Sync,
-		},
-	}
-	b2 = &Builder{
-		IsBuilding: false,
-		IsWatching: false,
-		handlers: []Handler{
-			ReadSource,
-		},
-	}
+	buildsTotal    = metrics.NewCounter("pugo_builds_total", "Total number of builds.")
+	buildFailures  = metrics.NewCounter("pugo_build_failures_total", "Total number of failed builds.")
+	buildCanceled  = metrics.NewCounter("pugo_builds_canceled_total", "Total number of builds canceled by newer changes.")
+	buildDuration  = metrics.NewHistogram("pugo_build_duration_seconds", "Duration of builds in seconds.", []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60})
+	buildTimestamp = metrics.NewGauge("pugo_last_build_timestamp_seconds", "Unix time of last build.")
 )
 
+// NewDefault create a Builder with default building handlers
+func NewDefault() *Builder {
+	return New(ReadSource, ReadTheme, AssembleSource, Compile, Sync)
+}
+
+// New create a Builder with handlers
+func New(handlers ...Handler) *Builder {
+	return &Builder{handlers: handlers}
+}
+
 // Before add handler before building
-func Before(fn Handler) {
+func (b *Builder) Before(fn Handler) {
+	b.lock.Lock()
 	b.handlers = append([]Handler{fn}, b.handlers...)
+	b.lock.Unlock()
 }
 
 // After add handler after building
-func After(fn Handler) {
+func (b *Builder) After(fn Handler) {
+	b.lock.Lock()
 	b.handlers = append(b.handlers, fn)
+	b.lock.Unlock()
 }
 
 // Build do a process with Context.
-// the context should be prepared before building.
-func Build(ctx *Context) {
-	b.IsBuilding = true
+// It's safe to build different Contexts in parallel.
+func (b *Builder) Build(ctx *Context) {
+	if err := b.run(ctx); err != nil && err != ErrCanceled {
+		ctx.Log().Crit("Build|Fail|%s", err.Error())
+	}
+}
+
+// Try do a process with Context as Build,
+// but it returns error instead of exiting when building fails.
+func (b *Builder) Try(ctx *Context) error {
+	err := b.run(ctx)
+	if err != nil && err != ErrCanceled {
+		ctx.Log().Error("Build|Fail|%s", err.Error())
+	}
+	return err
+}
+
+func (b *Builder) run(ctx *Context) error {
+	atomic.AddInt32(&b.building, 1)
+	defer atomic.AddInt32(&b.building, -1)
+
+	b.lock.RLock()
+	handlers := make([]Handler, len(b.handlers))
+	copy(handlers, b.handlers)
+	b.lock.RUnlock()
+	ctx.builder = b
+	atomic.StoreInt64(&ctx.warnings, 0)
+	ctx.log = helper.NewWarnCountLogger(ctx.logger(), &ctx.warnings)
+
+	if ctx.IsLowMemory() {
+		defer setLowMemoryGC()()
+	}
 	t := time.Now()
-	for i, h := range b.handlers {
-		if h(ctx); ctx.Err != nil {
-			log15.Crit("Build|Fail|%s", ctx.Err.Error())
+	for i, h := range handlers {
+		runHandler(h, ctx)
+		if ctx.IsCanceled() {
+			ctx.Err = ErrCanceled
+		}
+		if ctx.Err != nil {
 			break
 		}
-		log15.Debug("-----|Step|%d|%.3fms", i+1, time.Since(t).Seconds()*1e3)
+		ctx.Log().Debug("-----|Step|%d|%.3fms", i+1, time.Since(t).Seconds()*1e3)
+	}
+	atomic.AddInt64(&b.counter, 1)
+	buildsTotal.Inc()
+	if ctx.Err == ErrCanceled {
+		buildCanceled.Inc()
+		ctx.Log().Info("Build|Cancel|%.1fms", ctx.Duration()*1e3)
+		return ctx.Err
+	}
+	status := Status{
+		Time:     time.Now(),
+		Duration: ctx.Duration() * 1e3,
+		Pages:    atomic.LoadInt64(&ctx.counter),
+		Warnings: ctx.Warnings(),
+	}
+	if ctx.Err != nil {
+		status.Error = ctx.Err.Error()
 	}
-	b.IsBuilding = false
-	b.Counter++
-	if ctx.Err == nil {
-		log15.Info("Done|%d Pages|%.1fms", ctx.counter, ctx.Duration()*1e3)
+	b.setStatus(status, ctx.SrcDir())
+
+	buildDuration.Observe(ctx.Duration())
+	buildTimestamp.Set(float64(status.Time.UnixNano()) / 1e9)
+	if ctx.Err != nil {
+		buildFailures.Inc()
+		return ctx.Err
+	}
+	peak := helper.PeakRSS()
+	ctx.Log().Info("Done|%d Pages|%.1fms|Peak %s", ctx.counter, ctx.Duration()*1e3, helper.FormatMemory(peak))
+	if ctx.IsLowMemory() && peak > ctx.MaxMemory {
+		ctx.Log().Warn("Build|Memory|Peak %s over limit %s", helper.FormatMemory(peak), helper.FormatMemory(ctx.MaxMemory))
 	}
+	return nil
+}
+
+// setLowMemoryGC makes garbage collection more frequently,
+// it returns func to restore previous percent
+func setLowMemoryGC() func() {
+	lowMemory.Lock()
+	if lowMemory.count == 0 {
+		lowMemory.percent = debug.SetGCPercent(lowMemoryGCPercent)
+	}
+	lowMemory.count++
+	lowMemory.Unlock()
+	return func() {
+		lowMemory.Lock()
+		if lowMemory.count--; lowMemory.count == 0 {
+			debug.SetGCPercent(lowMemory.percent)
+		}
+		lowMemory.Unlock()
+	}
+}
+
+// runHandler calls handler, and turns panic into error of ctx
+func runHandler(h Handler, ctx *Context) {
+	defer func() {
+		if e := recover(); e != nil {
+			ctx.Err = fmt.Errorf("panic: %v", e)
+		}
+	}()
+	h(ctx)
+}
+
+// IsBuilding return whether the builder is building now
+func (b *Builder) IsBuilding() bool {
+	return atomic.LoadInt32(&b.building) > 0
 }
 
 // Counter return the times of building process ran
-func Counter() int {
-	return b.Counter
+func (b *Builder) Counter() int {
+	return int(atomic.LoadInt64(&b.counter))
 }
 
 // Read do a process to read Source with Context.
 // It does not build any thing, just read source data.
+// Callers need to check error in ctx.Err.
 func Read(ctx *Context) {
-	for _, h := range b2.handlers {
-		if h(ctx); ctx.Err != nil {
-			log15.Crit("Read|Fail|%s", ctx.Err.Error())
-			break
-		}
+	if ReadSource(ctx); ctx.Err != nil {
+		ctx.Log().Error("Read|Fail|%s", ctx.Err.Error())
 	}
 }
@@ -1,10 +1,25 @@
 package builder
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
 	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/Unknwon/com"
 	"github.com/go-xiaohei/pugo/app/helper"
+	"github.com/go-xiaohei/pugo/app/model"
+	pugosync "github.com/go-xiaohei/pugo/app/sync"
+	"github.com/go-xiaohei/pugo/app/theme"
 	. "github.com/smartystreets/goconvey/convey"
 	"github.com/urfave/cli"
 	"gopkg.in/inconshreveable/log15.v2"
@@ -17,15 +32,63 @@ func init() {
 
 func TestBuildSimple(t *testing.T) {
 	Convey("Build Simple", t, func() {
-		ctx := NewContext(&cli.Context{}, "../../source", "../../dest", "../../source/theme/default")
+		dest := tempDest("simple")
+		defer os.RemoveAll(dest)
+		ctx := NewContext(&cli.Context{}, "../../source", dest, "../../source/theme/default")
 		ShouldBeTrue(ctx.IsValid())
 		ShouldBeNil(ctx.Err)
+		b := NewDefault()
 
 		Convey("Build All", func() {
-			Build(ctx)
+			b.Build(ctx)
 			ShouldBeNil(ctx.Err)
 		})
 
+		Convey("Output Collision", func() {
+			b.Build(ctx)
+			So(ctx.Err, ShouldBeNil)
+			So(checkCollisions(ctx), ShouldBeNil)
+
+			ctx.Source.Posts = append(ctx.Source.Posts, ctx.Source.Posts[0])
+			err := checkCollisions(ctx)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, ctx.Source.Posts[0].SourceURL())
+		})
+
+		Convey("Golden Snapshots", func() {
+			b.Build(ctx)
+			So(ctx.Err, ShouldBeNil)
+
+			golden, _ := ioutil.TempDir("", "pugo-golden")
+			defer os.RemoveAll(golden)
+			diffs, err := CompareGolden(ctx.DstDir(), golden, true)
+			So(err, ShouldBeNil)
+			diffs, err = CompareGolden(ctx.DstDir(), golden, false)
+			So(err, ShouldBeNil)
+			So(diffs, ShouldBeEmpty)
+
+			ioutil.WriteFile(golden+"/index.html", []byte("changed"), 0644)
+			ioutil.WriteFile(golden+"/old.html", []byte("old"), 0644)
+			diffs, err = CompareGolden(ctx.DstDir(), golden, false)
+			So(err, ShouldBeNil)
+			So(diffs, ShouldResemble, []string{"index.html: changed at line 1", "old.html: not built"})
+
+			// update removes stale snapshots only
+			_, err = CompareGolden(ctx.DstDir(), golden, true)
+			So(err, ShouldBeNil)
+			So(com.IsFile(golden+"/old.html"), ShouldBeFalse)
+			diffs, err = CompareGolden(ctx.DstDir(), golden, false)
+			So(err, ShouldBeNil)
+			So(diffs, ShouldBeEmpty)
+
+			// directory with other files is not updated
+			ioutil.WriteFile(golden+"/notes.txt", []byte("keep"), 0644)
+			_, err = CompareGolden(ctx.DstDir(), golden, true)
+			So(err, ShouldNotBeNil)
+			So(com.IsFile(golden+"/notes.txt"), ShouldBeTrue)
+			So(com.IsFile(golden+"/index.html"), ShouldBeTrue)
+		})
+
 		Convey("Read All", func() {
 			Read(ctx)
 			ShouldBeNil(ctx.Err)
@@ -53,3 +116,1148 @@ func TestBuildContext(t *testing.T) {
 		ShouldEqual(ctx.SrcLangDir(), "../source/lang")
 	})
 }
+
+// tempDest return a temporary destination directory, tests remove it after building
+func tempDest(name string) string {
+	dir, err := ioutil.TempDir("", "pugo-"+name)
+	if err != nil {
+		panic(err)
+	}
+	return dir
+}
+
+func TestBuildConcurrent(t *testing.T) {
+	Convey("Build Concurrent", t, func() {
+		var wg sync.WaitGroup
+		dest := tempDest("concurrent")
+		defer os.RemoveAll(dest)
+		ctxs := make([]*Context, 3)
+		b := NewDefault()
+		for i := range ctxs {
+			ctxs[i] = NewContext(&cli.Context{}, "../../source", filepath.Join(dest, fmt.Sprint(i)), "../../source/theme/default")
+			wg.Add(1)
+			go func(ctx *Context) {
+				b.Build(ctx)
+				wg.Done()
+			}(ctxs[i])
+		}
+		wg.Wait()
+		for _, ctx := range ctxs {
+			So(ctx.Err, ShouldBeNil)
+			So(ctx.markdown.Len(), ShouldBeGreaterThan, 0)
+		}
+		So(ctxs[0].markdown, ShouldNotPointTo, ctxs[1].markdown)
+		So(b.Counter(), ShouldEqual, len(ctxs))
+
+		// commit is resolved when status is requested, not in building
+		So(b.status.Commit, ShouldBeEmpty)
+		So(b.commitDir, ShouldNotBeEmpty)
+		status := b.Status()
+		So(status.Commit, ShouldEqual, gitCommit(ctxs[0].SrcDir()))
+		So(b.commitDir, ShouldBeEmpty)
+		So(status.Building, ShouldBeFalse)
+		So(status.Error, ShouldBeEmpty)
+		So(status.Pages, ShouldBeGreaterThan, 0)
+		So(status.Count, ShouldEqual, b.Counter())
+	})
+}
+
+func TestBuildCanceled(t *testing.T) {
+	Convey("Build Canceled", t, func() {
+		dest := tempDest("canceled")
+		defer os.RemoveAll(dest)
+		b := NewDefault()
+		ctx := NewContext(&cli.Context{}, "../../source", dest, "../../source/theme/default")
+		ctx.Cancel()
+		So(b.Try(ctx), ShouldEqual, ErrCanceled)
+		So(b.Status().Time.IsZero(), ShouldBeTrue)
+
+		ctx.Again()
+		So(ctx.IsCanceled(), ShouldBeFalse)
+		So(b.Try(ctx), ShouldBeNil)
+		So(b.Status().Pages, ShouldBeGreaterThan, 0)
+	})
+}
+
+func TestBuildIgnore(t *testing.T) {
+	Convey("Ignore Files", t, func() {
+		ctx := NewContext(&cli.Context{}, "../../source", "../../dest", "../../source/theme/default")
+		ctx.ignore = helper.NewIgnore([]byte("*.psd\nraw/\nwelcome.md"))
+		So(ctx.IsIgnored("../../source/media/a.psd", false), ShouldBeTrue)
+		So(ctx.IsIgnored("../../source/media/raw/a.jpg", false), ShouldBeTrue)
+		So(ctx.IsIgnored("../../source/media/a.jpg", false), ShouldBeFalse)
+		So(ctx.IsIgnored("../../other/a.psd", false), ShouldBeFalse)
+
+		files, err := walkMarkdown(ctx, ctx.SrcPostDir())
+		So(err, ShouldBeNil)
+		for _, f := range files {
+			So(f, ShouldNotEndWith, "welcome.md")
+		}
+	})
+}
+
+func TestBuildStaticRules(t *testing.T) {
+	Convey("Static Rules", t, func() {
+		dest := tempDest("static")
+		defer os.RemoveAll(dest)
+		rules := []*model.StaticRule{
+			{Glob: "css/style.css", Action: model.StaticFingerprint},
+			{Glob: "css/*.css", Action: model.StaticMinify},
+			{Glob: "js/**", Action: model.StaticExclude},
+		}
+		b := New(ReadSource, ReadTheme, func(ctx *Context) {
+			ctx.Source.Build.Static = rules
+		}, AssembleSource, Compile, Sync)
+		ctx := NewContext(&cli.Context{}, "../../source", dest, "../../source/theme/default")
+		os.Remove(filepath.Join(ctx.CacheDir(), assetCacheFile))
+		So(b.Try(ctx), ShouldBeNil)
+
+		asset := ctx.assetPath("/css/style.css")
+		So(asset, ShouldStartWith, "css/style.")
+		So(asset, ShouldHaveLength, len("css/style.12345678.css"))
+		So(com.IsFile(filepath.Join(ctx.DstDir(), asset)), ShouldBeTrue)
+		So(com.IsFile(filepath.Join(ctx.DstDir(), "css/style.css")), ShouldBeFalse)
+		So(com.IsDir(filepath.Join(ctx.DstDir(), "js")), ShouldBeFalse)
+
+		src, _ := ioutil.ReadFile("../../source/theme/default/static/css/prism.css")
+		dst, _ := ioutil.ReadFile(filepath.Join(ctx.DstDir(), "css/prism.css"))
+		So(len(dst), ShouldBeLessThan, len(src))
+
+		manifest, err := ReadAssetManifest(ctx.DstDir())
+		So(err, ShouldBeNil)
+		So(manifest.Assets["css/style.css"], ShouldEqual, asset)
+		So(manifest.Stale, ShouldBeEmpty)
+		hash, _ := helper.Md5File(filepath.Join(ctx.DstDir(), "css/prism.css"))
+		So(manifest.Hashes["css/prism.css"], ShouldEqual, hash)
+		hash, _ = helper.Md5File(filepath.Join(ctx.DstDir(), asset))
+		So(manifest.Hashes[asset], ShouldEqual, hash)
+
+		// fingerprinted file of last building is stale
+		seen := map[string]int64{"css/style.00000000.css": time.Now().Unix(), "css/style.11111111.css": 1}
+		data, _ := json.Marshal(seen)
+		So(ioutil.WriteFile(filepath.Join(ctx.CacheDir(), assetCacheFile), data, os.ModePerm), ShouldBeNil)
+		ctx = NewContext(&cli.Context{}, "../../source", dest, "../../source/theme/default")
+		So(b.Try(ctx), ShouldBeNil)
+		manifest, err = ReadAssetManifest(ctx.DstDir())
+		So(err, ShouldBeNil)
+		So(manifest.Stale, ShouldResemble, []string{"css/style.00000000.css"})
+		data, _ = ioutil.ReadFile(filepath.Join(ctx.CacheDir(), assetCacheFile))
+		seen = nil
+		So(json.Unmarshal(data, &seen), ShouldBeNil)
+		So(seen[asset], ShouldEqual, 0)
+		So(seen, ShouldNotContainKey, "css/style.11111111.css")
+
+		Convey("State File", func() {
+			state := filepath.Join(dest, "..", filepath.Base(dest)+"-assets.json")
+			defer os.Remove(state)
+			b := New(ReadSource, ReadTheme, func(ctx *Context) {
+				ctx.Source.Build.Static = rules
+				ctx.Source.Build.AssetState = state
+				ctx.Source.Build.StaleDays = 1
+			}, AssembleSource, Compile, Sync)
+
+			now := time.Now().Unix()
+			seen := map[string]int64{"css/style.00000000.css": 0, "css/style.22222222.css": now - 2*24*3600}
+			for i := 0; i < assetStaleMax+5; i++ {
+				seen[fmt.Sprintf("img/a.%08d.png", i)] = now - int64(assetStaleMax+5-i)
+			}
+			data, _ := json.Marshal(seen)
+			So(ioutil.WriteFile(state, data, os.ModePerm), ShouldBeNil)
+			ctx := NewContext(&cli.Context{}, "../../source", dest, "../../source/theme/default")
+			So(b.Try(ctx), ShouldBeNil)
+			manifest, err := ReadAssetManifest(ctx.DstDir())
+			So(err, ShouldBeNil)
+			So(manifest.Stale, ShouldHaveLength, assetStaleMax)
+			So(manifest.Stale, ShouldContain, "css/style.00000000.css")
+			So(manifest.Stale, ShouldNotContain, "css/style.22222222.css")
+			So(manifest.Stale, ShouldNotContain, "img/a.00000000.png")
+			So(manifest.Stale, ShouldContain, fmt.Sprintf("img/a.%08d.png", assetStaleMax+4))
+
+			// state file is not written again if nothing changes
+			fi, err := os.Stat(state)
+			So(err, ShouldBeNil)
+			old := fi.ModTime().Add(-time.Hour)
+			So(os.Chtimes(state, old, old), ShouldBeNil)
+			ctx = NewContext(&cli.Context{}, "../../source", dest, "../../source/theme/default")
+			So(b.Try(ctx), ShouldBeNil)
+			fi, _ = os.Stat(state)
+			So(fi.ModTime().Unix(), ShouldEqual, old.Unix())
+		})
+	})
+}
+
+func TestBuildReactions(t *testing.T) {
+	Convey("Reactions", t, func() {
+		dest := tempDest("reactions")
+		defer os.RemoveAll(dest)
+		b := New(ReadSource, ReadTheme, func(ctx *Context) {
+			ctx.Source.Reaction = &model.Reaction{Kinds: []string{"like"}}
+			ctx.Source.Reactions = make(model.PostReactions)
+			ctx.Source.Reactions.Add(ctx.Source.Posts[0].URL(), "like")
+		}, AssembleSource, Compile)
+		ctx := NewContext(&cli.Context{}, "../../source", dest, "../../source/theme/default")
+		So(b.Try(ctx), ShouldBeNil)
+
+		p := ctx.Source.Posts[0]
+		So(p.Reactions["like"], ShouldEqual, 1)
+		manifests := ReactionManifests(ctx)
+		So(manifests, ShouldHaveLength, len(ctx.Source.Posts))
+		data, err := ioutil.ReadFile(manifests[p.URL()])
+		So(err, ShouldBeNil)
+		var manifest model.ReactionManifest
+		So(json.Unmarshal(data, &manifest), ShouldBeNil)
+		So(manifest.Counts["like"], ShouldEqual, 1)
+		So(manifest.Endpoint, ShouldEqual, model.ReactionEndpoint)
+	})
+}
+
+func TestBuildSyndicated(t *testing.T) {
+	Convey("Syndicated", t, func() {
+		dir := tempDest("syndicated")
+		defer os.RemoveAll(dir)
+		ps, err := ReadSyndicated(dir)
+		So(err, ShouldBeNil)
+		So(ps, ShouldNotBeNil)
+
+		file := filepath.Join(dir, model.SyndicationDefaultFile)
+		So(ioutil.WriteFile(file, []byte(`{"/a.html":`), 0644), ShouldBeNil)
+		ps, err = ReadSyndicated(dir)
+		So(err, ShouldNotBeNil)
+		So(ps, ShouldBeNil)
+	})
+}
+
+func TestBuildForms(t *testing.T) {
+	Convey("Forms", t, func() {
+		ctx := &Context{Source: &Source{
+			Meta: &model.Meta{Path: "/blog"},
+			Mail: &model.Mail{Secret: "secret"},
+			Forms: model.FormGroup{
+				{Name: "contact", Provider: model.FormServer, Fields: []*model.FormField{{Name: "message", Type: "textarea", Label: "Message", Required: true}}},
+				{Name: "hello", Provider: model.FormFormspree, Key: "abc"},
+			},
+		}}
+		html, err := formHTML(ctx, "contact")
+		So(err, ShouldBeNil)
+		So(string(html), ShouldContainSubstring, `action="/blog/-/form/contact"`)
+		So(string(html), ShouldContainSubstring, `<input type="hidden" name="`+model.FormTokenField+`" value=""/>`)
+		So(string(html), ShouldContainSubstring, `f.elements["`+model.FormTokenField+`"].value=x.responseText`)
+		So(string(html), ShouldContainSubstring, `<textarea id="contact-message" name="message" required>`)
+		So(string(html), ShouldContainSubstring, `name="`+model.FormHoneypot+`"`)
+
+		html, err = formHTML(ctx, "hello")
+		So(err, ShouldBeNil)
+		So(string(html), ShouldContainSubstring, `action="https://formspree.io/f/abc"`)
+		So(string(html), ShouldNotContainSubstring, "<script>")
+
+		_, err = formHTML(ctx, "unknown")
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestBuildGone(t *testing.T) {
+	Convey("Gone", t, func() {
+		dest := tempDest("gone")
+		defer os.RemoveAll(dest)
+		ctx := NewContext(&cli.Context{}, "../../source", dest, "../../source/theme/default")
+		So(New(ReadSource, ReadTheme, AssembleSource, Compile).Try(ctx), ShouldBeNil)
+		removed := ctx.Source.Posts[0].URL()
+
+		ctx = NewContext(&cli.Context{}, "../../source", dest, "../../source/theme/default")
+		b := New(ReadSource, ReadTheme, func(ctx *Context) {
+			ctx.Source.Posts = ctx.Source.Posts[1:]
+			ctx.Source.Build.GoneRules = []string{model.GoneNginx}
+			// page out of sitemap is still published
+			ctx.Source.Pages[0].NoIndex = true
+		}, AssembleSource, Compile)
+		So(b.Try(ctx), ShouldBeNil)
+		So(ReadGone(ctx), ShouldContain, removed)
+		So(ReadGone(ctx), ShouldNotContain, ctx.Source.Pages[0].URL())
+		data, err := ioutil.ReadFile(filepath.Join(ctx.DstDir(), "gone.nginx.conf"))
+		So(err, ShouldBeNil)
+		So(string(data), ShouldContainSubstring, "location = "+removed+" { return 410; }")
+
+		ctx = NewContext(&cli.Context{}, "../../source", dest, "../../source/theme/default")
+		So(New(ReadSource, ReadTheme, AssembleSource, Compile, Sync).Try(ctx), ShouldBeNil)
+		So(ReadGone(ctx), ShouldNotContain, removed)
+	})
+}
+
+func TestBuildMigrate(t *testing.T) {
+	Convey("Migrate", t, func() {
+		dir, _ := ioutil.TempDir("", "pugo-migrate-")
+		defer os.RemoveAll(dir)
+		themeDir := filepath.Join(dir, "theme")
+		os.MkdirAll(themeDir, os.ModePerm)
+		tpl := filepath.Join(themeDir, "posts.html")
+		ioutil.WriteFile(tpl, []byte(`{{range .Posts}}{{.PreviewHTML}}{{end}}`), os.ModePerm)
+
+		ctx := &Context{srcDir: dir, Theme: theme.New(themeDir)}
+		applied, err := Migrate(ctx, true)
+		So(err, ShouldBeNil)
+		So(applied, ShouldHaveLength, 1)
+		lock, _ := ReadLock(dir)
+		So(lock, ShouldBeNil)
+
+		applied, err = Migrate(ctx, false)
+		So(err, ShouldBeNil)
+		So(applied, ShouldHaveLength, 1)
+		data, _ := ioutil.ReadFile(tpl)
+		So(string(data), ShouldEqual, `{{range .Posts}}{{.BriefHTML}}{{end}}`)
+		lock, err = ReadLock(dir)
+		So(err, ShouldBeNil)
+		So(lock.Schema, ShouldEqual, SchemaVersion)
+		So(lock.Theme, ShouldEqual, "theme")
+		fi, err := os.Stat(filepath.Join(dir, LockFile))
+		So(err, ShouldBeNil)
+		So(fi.Mode().Perm(), ShouldEqual, os.FileMode(0644))
+
+		applied, err = Migrate(ctx, false)
+		So(err, ShouldBeNil)
+		So(applied, ShouldBeEmpty)
+	})
+}
+
+func TestDiffDirs(t *testing.T) {
+	Convey("DiffDirs", t, func() {
+		dir, _ := ioutil.TempDir("", "pugo-diff-")
+		defer os.RemoveAll(dir)
+		oldDir, newDir := filepath.Join(dir, "old"), filepath.Join(dir, "new")
+		for file, content := range map[string]string{
+			"old/same.html":    "same",
+			"old/changed.html": "old",
+			"old/removed.html": "removed",
+			"new/same.html":    "same",
+			"new/changed.html": "new content",
+			"new/a/added.html": "added",
+		} {
+			os.MkdirAll(filepath.Dir(filepath.Join(dir, file)), os.ModePerm)
+			ioutil.WriteFile(filepath.Join(dir, file), []byte(content), os.ModePerm)
+		}
+		diff, err := DiffDirs(oldDir, newDir)
+		So(err, ShouldBeNil)
+		So(diff.Same, ShouldEqual, 1)
+		So(diff.Added, ShouldResemble, []FileDiff{{File: "a/added.html", NewSize: 5}})
+		So(diff.Removed, ShouldResemble, []FileDiff{{File: "removed.html", OldSize: 7}})
+		So(diff.Changed, ShouldResemble, []FileDiff{{File: "changed.html", OldSize: 3, NewSize: 11}})
+		So(diff.Delta(), ShouldEqual, 5-7+8)
+
+		diff, err = DiffDirs(filepath.Join(dir, "missing"), newDir)
+		So(err, ShouldBeNil)
+		So(diff.Added, ShouldHaveLength, 3)
+	})
+}
+
+func TestBuildSEO(t *testing.T) {
+	Convey("SEO", t, func() {
+		dest := tempDest("seo")
+		defer os.RemoveAll(dest)
+		ctx := NewContext(&cli.Context{}, "../../source", dest, "../../source/theme/default")
+		So(New(ReadSource, ReadTheme, AssembleSource, Compile).Try(ctx), ShouldBeNil)
+		data, err := ioutil.ReadFile(filepath.Join(ctx.CacheDir(), seoCacheFile))
+		So(err, ShouldBeNil)
+		var cached map[string]*SEOMeta
+		So(json.Unmarshal(data, &cached), ShouldBeNil)
+		So(cached, ShouldNotBeEmpty)
+
+		pages, err := SEOSnapshot(ctx.DstDir(), nil)
+		So(err, ShouldBeNil)
+		p := ctx.Source.Posts[0]
+		rel, _ := filepath.Rel(ctx.DstDir(), p.DestURL())
+		meta := pages["/"+filepath.ToSlash(rel)]
+		So(meta, ShouldNotBeNil)
+		So(meta.Title, ShouldContainSubstring, p.Title)
+		So(meta.Desc, ShouldEqual, p.Desc)
+
+		oldPages := map[string]*SEOMeta{
+			"/a.html": {Title: "A", Desc: "a page", Data: `{"@type":"Article"}`},
+			"/b.html": {Title: "B"},
+		}
+		newPages := map[string]*SEOMeta{
+			"/a.html": {Title: "A2", Data: `{"@type":"Article"}`},
+			"/c.html": {Title: "C"},
+		}
+		report := CompareSEO(oldPages, newPages)
+		So(report.Added, ShouldResemble, []string{"/c.html"})
+		So(report.Removed, ShouldResemble, []string{"/b.html"})
+		So(report.Lost, ShouldResemble, []SEOChange{{URL: "/a.html", Field: SEODesc, Old: "a page"}})
+		So(report.Changed, ShouldResemble, []SEOChange{{URL: "/a.html", Field: SEOTitle, Old: "A", New: "A2"}})
+		So(report.LostCount()[SEODesc], ShouldEqual, 1)
+	})
+}
+
+func TestBuildSandbox(t *testing.T) {
+	Convey("Sandbox", t, func() {
+		dest := tempDest("sandbox")
+		defer os.RemoveAll(dest)
+		ctx := NewContext(&cli.Context{}, "../../source", dest, "../../source/theme/default")
+		ctx.Sandbox = true
+		b := New(ReadSource, ReadTheme, func(ctx *Context) {
+			ctx.Source.Mail = &model.Mail{Password: "secret"}
+			ctx.Source.Env = map[string]string{"GA_ID": "UA-1"}
+		}, AssembleSource, Compile, Sync)
+		So(b.Try(ctx), ShouldBeNil)
+		So(ctx.Theme.Sandbox, ShouldBeTrue)
+		view := ctx.View()
+		So(view["Source"].(*Source).Mail, ShouldBeNil)
+		So(view["Source"].(*Source).Env, ShouldBeNil)
+		So(view["Env"], ShouldBeNil)
+		So(ctx.Source.Mail.Password, ShouldEqual, "secret")
+	})
+}
+
+func TestBuildSitemap(t *testing.T) {
+	Convey("Sitemap", t, func() {
+		dest := tempDest("sitemap")
+		defer os.RemoveAll(dest)
+		ctx := NewContext(&cli.Context{}, "../../source", dest, "../../source/theme/default")
+		ctx.MaxMemory = 1 << 30
+		b := New(ReadSource, ReadTheme, AssembleSource, Compile)
+		percent := debug.SetGCPercent(100)
+		So(b.Try(ctx), ShouldBeNil)
+		So(debug.SetGCPercent(percent), ShouldEqual, 100)
+		feed, err := ioutil.ReadFile(filepath.Join(ctx.DstDir(), "feed.xml"))
+		So(err, ShouldBeNil)
+		sitemapFile := filepath.Join(ctx.DstDir(), "sitemap.xml")
+		info, err := os.Stat(sitemapFile)
+		So(err, ShouldBeNil)
+
+		Convey("Unchanged", func() {
+			time.Sleep(10 * time.Millisecond)
+			ctx.Again()
+			So(b.Try(ctx), ShouldBeNil)
+			info2, err := os.Stat(sitemapFile)
+			So(err, ShouldBeNil)
+			So(info2.ModTime(), ShouldEqual, info.ModTime())
+			feed2, _ := ioutil.ReadFile(filepath.Join(ctx.DstDir(), "feed.xml"))
+			So(string(feed2), ShouldEqual, string(feed))
+		})
+
+		Convey("Guid", func() {
+			p := *ctx.Source.Posts[0]
+			data, err := encodeFeedItem(ctx, &p)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldNotContainSubstring, "<guid")
+			p.UUID = "0A0B0C0D-0000-4000-8000-000000000000"
+			data, err = encodeFeedItem(ctx, &p)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldContainSubstring, `<guid isPermaLink="false">urn:uuid:0a0b0c0d-0000-4000-8000-000000000000</guid>`)
+		})
+
+		Convey("Split", func() {
+			sitemapSplit = 3
+			defer func() { sitemapSplit = SitemapMaxURLs }()
+			ctx.Again()
+			So(b.Try(ctx), ShouldBeNil)
+			data, _ := ioutil.ReadFile(sitemapFile)
+			So(string(data), ShouldContainSubstring, "<sitemapindex")
+			So(com.IsFile(filepath.Join(ctx.DstDir(), "sitemap-1.xml")), ShouldBeTrue)
+			files, _ := filepath.Glob(filepath.Join(ctx.DstDir(), "sitemap-*.xml"))
+			var count int
+			for _, f := range files {
+				data, _ = ioutil.ReadFile(f)
+				count += strings.Count(string(data), "<url>")
+			}
+			So(count, ShouldEqual, len(sitemapURLs(ctx)))
+		})
+	})
+}
+
+func TestBuildAPI(t *testing.T) {
+	Convey("API", t, func() {
+		dest := tempDest("api")
+		defer os.RemoveAll(dest)
+		ctx := NewContext(&cli.Context{}, "../../source", dest, "../../source/theme/default")
+		b := New(ReadSource, ReadTheme, func(ctx *Context) {
+			ctx.Source.Build.API = true
+			ctx.Source.Build.APIPageSize = 1
+		}, AssembleSource, Compile)
+		So(b.Try(ctx), ShouldBeNil)
+
+		data, err := ioutil.ReadFile(filepath.Join(ctx.DstDir(), APIPath, "posts/page/1.json"))
+		So(err, ShouldBeNil)
+		page := new(APIPage)
+		So(json.Unmarshal(data, page), ShouldBeNil)
+		So(page.Page, ShouldEqual, 1)
+		So(page.Total, ShouldEqual, len(ctx.Source.Posts))
+		So(page.Posts, ShouldHaveLength, 1)
+		So(page.Posts[0].URL, ShouldEqual, ctx.Source.Posts[0].URL())
+		if page.TotalPages > 1 {
+			So(page.Next, ShouldEqual, "/"+APIPath+"/posts/page/2.json")
+		}
+
+		data, err = ioutil.ReadFile(filepath.Join(ctx.DstDir(), APIPath, "tags.json"))
+		So(err, ShouldBeNil)
+		var tags []*APITag
+		So(json.Unmarshal(data, &tags), ShouldBeNil)
+		So(tags, ShouldHaveLength, len(ctx.Source.TagPosts))
+		So(com.IsFile(filepath.Join(ctx.DstDir(), filepath.FromSlash(tags[0].API))), ShouldBeTrue)
+	})
+}
+
+func TestBuildFuture(t *testing.T) {
+	Convey("Future Posts", t, func() {
+		dir, err := ioutil.TempDir("", "pugo-future")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		meta, _ := ioutil.ReadFile("../../source/meta.toml")
+		So(ioutil.WriteFile(filepath.Join(dir, "meta.toml"), meta, 0644), ShouldBeNil)
+		os.MkdirAll(filepath.Join(dir, "page"), os.ModePerm)
+		os.MkdirAll(filepath.Join(dir, "post"), os.ModePerm)
+		for name, date := range map[string]string{"past": "2016-01-02 10:00", "future": "2099-01-02 10:00"} {
+			data := fmt.Sprintf("```toml\ntitle = \"%s\"\nslug = \"%s\"\ndate = \"%s\"\n```\n\ncontent", name, name, date)
+			So(ioutil.WriteFile(filepath.Join(dir, "post", name+".md"), []byte(data), 0644), ShouldBeNil)
+		}
+
+		ctx := NewContext(&cli.Context{}, dir, filepath.Join(dir, "dest"), "../../source/theme/default")
+		So(New(ReadSource).Try(ctx), ShouldBeNil)
+		So(ctx.Source.Posts, ShouldHaveLength, 1)
+		So(ctx.Source.Posts[0].Slug, ShouldEqual, "past")
+
+		ctx = NewContext(&cli.Context{}, dir, filepath.Join(dir, "dest"), "../../source/theme/default")
+		ctx.Draft = true
+		So(New(ReadSource).Try(ctx), ShouldBeNil)
+		So(ctx.Source.Posts, ShouldHaveLength, 2)
+	})
+}
+
+func TestBuildDryRun(t *testing.T) {
+	Convey("Dry Run", t, func() {
+		dir, err := ioutil.TempDir("", "pugo-dry")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		meta, _ := ioutil.ReadFile("../../source/meta.toml")
+		So(ioutil.WriteFile(filepath.Join(dir, "meta.toml"), meta, 0644), ShouldBeNil)
+		os.MkdirAll(filepath.Join(dir, "page"), os.ModePerm)
+		os.MkdirAll(filepath.Join(dir, "post"), os.ModePerm)
+		os.MkdirAll(filepath.Join(dir, ".cache"), os.ModePerm)
+		So(ioutil.WriteFile(filepath.Join(dir, ".cache", seoCacheFile), []byte("{}"), 0644), ShouldBeNil)
+
+		ctx := NewContext(&cli.Context{}, dir, filepath.Join(dir, "dest"), "../../source/theme/default")
+		So(ctx.DryRun(), ShouldBeNil)
+		So(ctx.IsDryRun(), ShouldBeTrue)
+		cacheDir := ctx.CacheDir()
+		So(cacheDir, ShouldNotStartWith, dir)
+		So(com.IsFile(filepath.Join(cacheDir, seoCacheFile)), ShouldBeTrue)
+
+		So(New(ReadSource, ReadTheme, AssembleSource, Compile).Try(ctx), ShouldBeNil)
+		So(com.IsFile(filepath.Join(dir, LockFile)), ShouldBeFalse)
+		data, _ := ioutil.ReadFile(filepath.Join(dir, ".cache", seoCacheFile))
+		So(string(data), ShouldEqual, "{}")
+
+		So(ctx.Close(), ShouldBeNil)
+		So(com.IsDir(cacheDir), ShouldBeFalse)
+	})
+}
+
+func TestBuildArtifact(t *testing.T) {
+	Convey("Artifact", t, func() {
+		dest := tempDest("artifact")
+		defer os.RemoveAll(dest)
+		ctx := NewContext(&cli.Context{}, "../../source", dest, "../../source/theme/default")
+		b := New(ReadSource, ReadTheme, AssembleSource, Compile)
+		So(b.Try(ctx), ShouldBeNil)
+		dir := filepath.Join(ctx.CacheDir(), artifactDir)
+		defer os.RemoveAll(dir)
+
+		ctx.Source.Build.KeepBuilds = 2
+		for i := 0; i < 3; i++ {
+			time.Sleep(5 * time.Millisecond)
+			So(keepArtifact(ctx), ShouldBeNil)
+		}
+		list, err := ListArtifacts(dir)
+		So(err, ShouldBeNil)
+		So(list, ShouldHaveLength, 2)
+		So(list[0].Time.After(list[1].Time), ShouldBeTrue)
+		So(len(list[0].Files), ShouldBeGreaterThan, 0)
+
+		for rel := range list[0].Files {
+			fi1, _ := os.Stat(filepath.Join(list[0].Dir(), rel))
+			fi2, _ := os.Stat(filepath.Join(list[1].Dir(), rel))
+			So(os.SameFile(fi1, fi2), ShouldBeTrue)
+			break
+		}
+
+		restore := filepath.Join(dest, "restore")
+		os.MkdirAll(filepath.Join(restore, "old"), os.ModePerm)
+		So(RestoreArtifact(list[1], restore), ShouldBeNil)
+		So(com.IsDir(filepath.Join(restore, "old")), ShouldBeFalse)
+		for rel := range list[1].Files {
+			So(com.IsFile(filepath.Join(restore, rel)), ShouldBeTrue)
+		}
+	})
+}
+
+func TestBuildGraph(t *testing.T) {
+	Convey("Content Graph", t, func() {
+		dir, err := ioutil.TempDir("", "pugo-graph")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		meta, _ := ioutil.ReadFile("../../source/meta.toml")
+		So(ioutil.WriteFile(filepath.Join(dir, "meta.toml"), meta, 0644), ShouldBeNil)
+		os.MkdirAll(filepath.Join(dir, "page"), os.ModePerm)
+		os.MkdirAll(filepath.Join(dir, "post"), os.ModePerm)
+		for name, content := range map[string]string{
+			"a": "[b](/b.html) [out](http://example.com/b.html)",
+			"b": "[a](a.html#top) [self](/b.html)",
+			"c": "[a](http://localhost:9899/a)",
+			"d": "no links",
+		} {
+			data := fmt.Sprintf("```toml\ntitle = \"%s\"\ndate = \"2016-01-02 10:00\"\n```\n\n%s", name, content)
+			So(ioutil.WriteFile(filepath.Join(dir, "page", name+".md"), []byte(data), 0644), ShouldBeNil)
+		}
+
+		ctx := NewContext(&cli.Context{}, dir, filepath.Join(dir, "dest"), "../../source/theme/default")
+		So(New(ReadSource, ReadTheme, AssembleSource).Try(ctx), ShouldBeNil)
+		g := LinkGraph(ctx)
+		So(g.Nodes, ShouldHaveLength, 4)
+		So(g.Nodes[0].URL, ShouldEqual, "/a.html")
+		So(g.Nodes[0].Links, ShouldResemble, []string{"/b.html"})
+		So(g.Nodes[0].Backlinks, ShouldEqual, 2)
+		So(g.Nodes[1].Links, ShouldResemble, []string{"/a.html"})
+		So(g.Orphans, ShouldResemble, []string{"/c.html", "/d.html"})
+		So(g.DeadEnds, ShouldResemble, []string{"/d.html"})
+
+		dot := string(g.DOT())
+		So(dot, ShouldContainSubstring, `"/c.html" -> "/a.html";`)
+		So(dot, ShouldContainSubstring, `"/d.html" [label="d", shape=note, color=red, style=dashed];`)
+		data, err := g.JSON()
+		So(err, ShouldBeNil)
+		So(string(data), ShouldContainSubstring, `"dead_ends"`)
+	})
+}
+
+func TestBuildSyncConflict(t *testing.T) {
+	Convey("Sync Conflict", t, func() {
+		dir, err := ioutil.TempDir("", "pugo-conflict")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		meta, _ := ioutil.ReadFile("../../source/meta.toml")
+		So(ioutil.WriteFile(filepath.Join(dir, "meta.toml"), meta, 0644), ShouldBeNil)
+		os.MkdirAll(filepath.Join(dir, "page"), os.ModePerm)
+		os.MkdirAll(filepath.Join(dir, "post"), os.ModePerm)
+		// theme has static/robots.txt too
+		So(ioutil.WriteFile(filepath.Join(dir, "page", "robots.txt"), []byte("page robots"), 0644), ShouldBeNil)
+
+		c := helper.NewLogCapture()
+		ctx := NewContext(&cli.Context{}, dir, filepath.Join(dir, "dest"), "../../source/theme/default")
+		ctx.Logger = helper.NewLogger(c)
+		So(New(ReadSource, ReadTheme, AssembleSource, Compile, Sync).Try(ctx), ShouldBeNil)
+
+		// page file wins over theme static file
+		data, err := ioutil.ReadFile(filepath.Join(ctx.DstDir(), "robots.txt"))
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, "page robots")
+
+		var conflicts []string
+		for _, r := range c.Records() {
+			if strings.HasPrefix(r.Msg, "Sync|Conflict|") {
+				So(r.Lvl, ShouldEqual, log15.LvlWarn)
+				conflicts = append(conflicts, r.Msg)
+			}
+		}
+		So(conflicts, ShouldHaveLength, 1)
+		So(conflicts[0], ShouldStartWith, "Sync|Conflict|robots.txt|use "+filepath.ToSlash(filepath.Join(dir, "page", "robots.txt")))
+	})
+}
+
+func TestBuildTagMerge(t *testing.T) {
+	Convey("Tag Merge", t, func() {
+		dir, err := ioutil.TempDir("", "pugo-tag")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		meta, _ := ioutil.ReadFile("../../source/meta.toml")
+		So(ioutil.WriteFile(filepath.Join(dir, "meta.toml"), meta, 0644), ShouldBeNil)
+		os.MkdirAll(filepath.Join(dir, "page"), os.ModePerm)
+		os.MkdirAll(filepath.Join(dir, "post"), os.ModePerm)
+		for name, tags := range map[string]string{"a": `["golang", "go"]`, "b": `["golang"]`, "c": `["web"]`} {
+			data := fmt.Sprintf("```toml\ntitle = \"%s\"\ndate = \"2016-01-02 10:00\"\ntags = %s\n```\n\ncontent", name, tags)
+			So(ioutil.WriteFile(filepath.Join(dir, "post", name+".md"), []byte(data), 0644), ShouldBeNil)
+		}
+
+		ctx := NewContext(&cli.Context{}, dir, filepath.Join(dir, "dest"), "../../source/theme/default")
+		So(New(ReadSource).Try(ctx), ShouldBeNil)
+
+		// broken post fails merging, nothing is changed
+		brokenFile := filepath.Join(dir, "post", "e.md")
+		So(ioutil.WriteFile(brokenFile, []byte("```toml\ntitle = \"e\ntags = [\"golang\"]\n```\n\ncontent"), 0644), ShouldBeNil)
+		_, err = MergeTag(ctx, "golang", "go", false)
+		So(err, ShouldNotBeNil)
+		data, _ := ioutil.ReadFile(filepath.Join(dir, "post", "a.md"))
+		So(string(data), ShouldContainSubstring, `tags = ["golang", "go"]`)
+		redirects, _ := ReadRedirects(dir)
+		So(redirects, ShouldBeEmpty)
+		So(os.Remove(brokenFile), ShouldBeNil)
+
+		// ini front-matter is rewritten in ini
+		iniFile := filepath.Join(dir, "post", "d.md")
+		So(ioutil.WriteFile(iniFile, []byte("```ini\ntitle = d\ndate = 2016-01-02 10:00\ntags = golang,web\n```\n\ncontent"), 0644), ShouldBeNil)
+
+		files, err := MergeTag(ctx, "golang", "go", false)
+		So(err, ShouldBeNil)
+		So(files, ShouldHaveLength, 3)
+		data, _ = ioutil.ReadFile(filepath.Join(dir, "post", "a.md"))
+		So(string(data), ShouldContainSubstring, `tags = ["go"]`)
+		data, _ = ioutil.ReadFile(iniFile)
+		So(string(data), ShouldStartWith, "```ini\ntitle = d\ndate = 2016-01-02 10:00\ntags = \"go,web\"\n```")
+		post, err := model.NewPostOfMarkdown(iniFile, nil)
+		So(err, ShouldBeNil)
+		So(post.TagString, ShouldResemble, []string{"go", "web"})
+
+		redirects, err = ReadRedirects(dir)
+		So(err, ShouldBeNil)
+		So(redirects, ShouldHaveLength, 1)
+		So(redirects[0].From, ShouldEqual, "/tags/golang.html")
+
+		_, err = MergeTag(ctx, "go", "go", false)
+		So(err, ShouldNotBeNil)
+
+		ctx = NewContext(&cli.Context{}, dir, filepath.Join(dir, "dest"), "../../source/theme/default")
+		So(New(ReadSource, ReadTheme, AssembleSource, Compile).Try(ctx), ShouldBeNil)
+		So(ctx.Source.TagPosts["go"].Posts, ShouldHaveLength, 3)
+		So(ctx.Source.TagPosts["golang"], ShouldBeNil)
+		data, err = ioutil.ReadFile(filepath.Join(ctx.DstDir(), "tags", "golang.html"))
+		So(err, ShouldBeNil)
+		So(string(data), ShouldContainSubstring, `url=/tags/go.html`)
+	})
+}
+
+func TestBuildImageMeta(t *testing.T) {
+	Convey("Image Sidecar", t, func() {
+		dir, err := ioutil.TempDir("", "pugo-image")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		meta, _ := ioutil.ReadFile("../../source/meta.toml")
+		So(ioutil.WriteFile(filepath.Join(dir, "meta.toml"), meta, 0644), ShouldBeNil)
+		os.MkdirAll(filepath.Join(dir, "page"), os.ModePerm)
+		os.MkdirAll(filepath.Join(dir, "post"), os.ModePerm)
+		os.MkdirAll(filepath.Join(dir, "media"), os.ModePerm)
+		for name, data := range map[string]string{
+			"media/cat.jpg":     "jpg",
+			"media/cat.jpg.yml": "alt: A cat\ncaption: Sleeping\nlicense: CC BY 4.0",
+			"media/dog.jpg":     "jpg",
+			"page/x.jpg.yml":    "alt: Out of media",
+			"post/a.md":         "```toml\ntitle = \"a\"\ndate = \"2016-01-02 10:00\"\n```\n\n![](@media/cat.jpg)\n\ntext ![](@media/dog.jpg) ![Dog](@media/dog.jpg)",
+		} {
+			So(ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644), ShouldBeNil)
+		}
+
+		ctx := NewContext(&cli.Context{}, dir, filepath.Join(dir, "dest"), "../../source/theme/default")
+		So(New(ReadSource, ReadTheme, AssembleSource, Compile, Sync).Try(ctx), ShouldBeNil)
+		content := string(ctx.Source.Posts[0].Content())
+		So(content, ShouldContainSubstring, `<figure><img src="/media/cat.jpg" alt="A cat" />`)
+		So(content, ShouldContainSubstring, `<figcaption>Sleeping <small>CC BY 4.0</small></figcaption></figure>`)
+		So(content, ShouldContainSubstring, `alt="Dog"`)
+		imageMeta := ctx.Theme.Funcs()["imageMeta"].(func(string) *model.ImageMeta)
+		So(imageMeta("@media/cat.jpg").Alt, ShouldEqual, "A cat")
+		So(imageMeta("/media/../page/x.jpg"), ShouldBeNil)
+
+		So(com.IsFile(filepath.Join(ctx.DstDir(), "media", "cat.jpg")), ShouldBeTrue)
+		So(com.IsFile(filepath.Join(ctx.DstDir(), "media", "cat.jpg.yml")), ShouldBeFalse)
+	})
+}
+
+func TestBuildLicense(t *testing.T) {
+	Convey("License", t, func() {
+		dir, err := ioutil.TempDir("", "pugo-license")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		meta, _ := ioutil.ReadFile("../../source/meta.toml")
+		So(ioutil.WriteFile(filepath.Join(dir, "meta.toml"), meta, 0644), ShouldBeNil)
+		os.MkdirAll(filepath.Join(dir, "page"), os.ModePerm)
+		os.MkdirAll(filepath.Join(dir, "post"), os.ModePerm)
+		for name, data := range map[string]string{
+			"post/a.md":            "```toml\ntitle = \"a\"\ndate = \"2016-01-02 10:00\"\n```\n\na",
+			"post/b.md":            "```toml\ntitle = \"b\"\ndate = \"2016-01-02 10:00\"\nlicense = \"CC0-1.0\"\n```\n\nb",
+			model.AttributionsFile: "[[asset]]\nname = \"Icons\"\nurl = \"https://example.com\"\nlicense = \"CC-BY-4.0\"\n",
+		} {
+			So(ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644), ShouldBeNil)
+		}
+
+		ctx := NewContext(&cli.Context{}, dir, filepath.Join(dir, "dest"), "../../source/theme/default")
+		So(New(ReadSource, ReadTheme, AssembleSource, Compile).Try(ctx), ShouldBeNil)
+		for _, p := range ctx.Source.Posts {
+			data, err := ioutil.ReadFile(p.DestURL())
+			So(err, ShouldBeNil)
+			if p.Slug == "a" {
+				So(string(data), ShouldContainSubstring, `<link rel="license" href="https://creativecommons.org/licenses/by/4.0/"/>`)
+				continue
+			}
+			So(string(data), ShouldContainSubstring, `"license":"https://creativecommons.org/publicdomain/zero/1.0/"`)
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(ctx.DstDir(), AttributionsSlug+".html"))
+		So(err, ShouldBeNil)
+		So(string(data), ShouldContainSubstring, `<a href="https://example.com">Icons</a>`)
+		So(string(data), ShouldContainSubstring, `Creative Commons Attribution 4.0`)
+	})
+}
+
+func TestBuildFontSubset(t *testing.T) {
+	Convey("Font Subset", t, func() {
+		dir, err := ioutil.TempDir("", "pugo-font")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		for name, data := range map[string]string{
+			"index.html":    `<html><style>p{}</style><p title="x">中文 &amp; <b>Go</b></p><script>var z;</script></html>`,
+			"css/style.css": `@font-face{src:url("/fonts/a.woff?v=1"),url(../fonts/a.woff),url(/fonts/a.woff2),url(/fonts/ba.woff)}`,
+		} {
+			os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), os.ModePerm)
+			So(ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644), ShouldBeNil)
+		}
+
+		chars, err := fontChars(dir)
+		So(err, ShouldBeNil)
+		for _, r := range "中文 &Go" {
+			So(chars[r], ShouldBeTrue)
+		}
+		for _, r := range "xz<{" {
+			So(chars[r], ShouldBeFalse)
+		}
+
+		ctx := NewContext(&cli.Context{}, "../../source", dir, "../../source/theme/default")
+		ctx.Sync = pugosync.NewSyncer(dir)
+		names := map[string]string{"a.woff": "a.subset.woff"}
+		So(rewriteFontRefs(ctx, fontRefsRegexp(names), names), ShouldBeNil)
+		data, _ := ioutil.ReadFile(filepath.Join(dir, "css/style.css"))
+		So(string(data), ShouldEqual, `@font-face{src:url("/fonts/a.subset.woff?v=1"),url(../fonts/a.subset.woff),url(/fonts/a.woff2),url(/fonts/ba.woff)}`)
+	})
+}
+
+func TestBuildContentReport(t *testing.T) {
+	Convey("Content Report", t, func() {
+		dir, err := ioutil.TempDir("", "pugo-report")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		meta, _ := ioutil.ReadFile("../../source/meta.toml")
+		So(ioutil.WriteFile(filepath.Join(dir, "meta.toml"), meta, 0644), ShouldBeNil)
+		os.MkdirAll(filepath.Join(dir, "page"), os.ModePerm)
+		os.MkdirAll(filepath.Join(dir, "post"), os.ModePerm)
+		long := strings.Repeat("Title ", 12)
+		for name, data := range map[string]string{
+			"post/a.md": "```toml\ntitle = \"Same\"\ndesc = \"a\"\ndate = \"2016-01-02 10:00\"\ntags = [\"go\"]\n```\n\nGo is fun. Go is fast. We like go.",
+			"post/b.md": "```toml\ntitle = \"" + long + "\"\ndate = \"2016-01-02 10:00\"\n```\n\nThe cat sat on the mat.",
+			"page/c.md": "```toml\ntitle = \"same\"\n```\n\nc",
+		} {
+			So(ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644), ShouldBeNil)
+		}
+
+		ctx := NewContext(&cli.Context{}, dir, filepath.Join(dir, "dest"), "../../source/theme/default")
+		So(New(ReadSource, ReadTheme, AssembleSource).Try(ctx), ShouldBeNil)
+		report := NewContentReport(ctx.Source)
+		So(report.Posts, ShouldHaveLength, 2)
+		So(report.DuplicateTitles["same"], ShouldHaveLength, 2)
+		for _, p := range report.Posts {
+			if p.Title == "Same" {
+				So(p.Words, ShouldEqual, 9)
+				So(p.Level, ShouldEqual, "easy")
+				So(p.Density["go"], ShouldAlmostEqual, 300.0/9, 0.01)
+				So(p.Warnings, ShouldHaveLength, 1)
+				So(p.Warnings[0], ShouldContainSubstring, "keyword 'go'")
+				continue
+			}
+			So(p.TitleLength, ShouldEqual, len(long))
+			So(p.Warnings, ShouldHaveLength, 2)
+		}
+		So(report.Warnings(), ShouldEqual, 4)
+
+		ctx.Source.Build.ContentReport = true
+		So(compileContentReport(ctx), ShouldBeNil)
+		So(com.IsFile(filepath.Join(ctx.CacheDir(), ContentReportFile)), ShouldBeTrue)
+	})
+}
+
+func TestBuildLegacy(t *testing.T) {
+	Convey("Legacy Output", t, func() {
+		dest := tempDest("legacy")
+		defer os.RemoveAll(dest)
+		html := `<head><link rel="modulepreload" href="/a.js"><script type="module" src="/a.js"></script>
+<script nomodule src="/b.js"></script></head>`
+		So(string(stripModules([]byte(html))), ShouldEqual, `<head><script nomodule src="/b.js"></script></head>`)
+
+		ctx := NewContext(&cli.Context{}, "../../source", dest, "../../source/theme/default")
+		ctx.Legacy = true
+		So(New(ReadSource, ReadTheme, AssembleSource, Compile).Try(ctx), ShouldBeNil)
+		data, err := ioutil.ReadFile(filepath.Join(ctx.DstDir(), "index.html"))
+		So(err, ShouldBeNil)
+		So(string(data), ShouldNotContainSubstring, "jquery")
+	})
+}
+
+func TestBuildInclude(t *testing.T) {
+	Convey("Include", t, func() {
+		dir, err := ioutil.TempDir("", "pugo-include")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		meta, _ := ioutil.ReadFile("../../source/meta.toml")
+		So(ioutil.WriteFile(filepath.Join(dir, "meta.toml"), meta, 0644), ShouldBeNil)
+		for _, d := range []string{"page", "post", "snippets"} {
+			os.MkdirAll(filepath.Join(dir, d), os.ModePerm)
+		}
+		for name, data := range map[string]string{
+			"post/a.md":              "```toml\ntitle = \"A\"\ndate = \"2016-01-02 10:00\"\n```\n\nhello\n\n{{% include \"snippets/disclaimer.md\" %}}",
+			"post/b.md":              "```toml\ntitle = \"B\"\ndate = \"2016-01-02 10:00\"\n```\n\n{{% include \"snippets/loop.md\" %}}",
+			"snippets/disclaimer.md": "**not** an advice",
+			"snippets/loop.md":       "{{% include \"snippets/loop.md\" %}}",
+			"page/c.md":              "```toml\ntitle = \"C\"\n```\n\n{{% include \"snippets/disclaimer.md\" %}}",
+		} {
+			So(ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644), ShouldBeNil)
+		}
+
+		ctx := NewContext(&cli.Context{}, dir, filepath.Join(dir, "dest"), "../../source/theme/default")
+		So(New(ReadSource).Try(ctx), ShouldBeNil)
+		So(ctx.Source.Posts, ShouldHaveLength, 1)
+		So(string(ctx.Source.Posts[0].Content()), ShouldContainSubstring, "<strong>not</strong> an advice")
+		So(string(ctx.Source.Pages[0].Content()), ShouldContainSubstring, "<strong>not</strong> an advice")
+		So(ctx.IncludedBy(filepath.Join(dir, "snippets/disclaimer.md")), ShouldHaveLength, 2)
+		So(ctx.IncludedBy(filepath.Join(dir, "snippets/loop.md")), ShouldBeEmpty)
+	})
+}
+
+func TestBuildDirSetting(t *testing.T) {
+	Convey("Dir Setting", t, func() {
+		dir, err := ioutil.TempDir("", "pugo-dirs")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		meta, _ := ioutil.ReadFile("../../source/meta.toml")
+
+		So(ioutil.WriteFile(filepath.Join(dir, "meta.toml"), meta, 0644), ShouldBeNil)
+		dest, theme, err := ReadDirSetting(dir)
+		So(err, ShouldBeNil)
+		So(dest, ShouldBeEmpty)
+		So(theme, ShouldBeEmpty)
+
+		meta = bytes.Replace(meta, []byte("[build]\n"), []byte("[build]\ndest = \"../public\"\ntheme = \"themes/simple\"\n"), 1)
+		So(ioutil.WriteFile(filepath.Join(dir, "meta.toml"), meta, 0644), ShouldBeNil)
+		dest, theme, err = ReadDirSetting(dir)
+		So(err, ShouldBeNil)
+		So(dest, ShouldEqual, filepath.Join(filepath.Dir(dir), "public"))
+		So(theme, ShouldEqual, filepath.Join(dir, "themes", "simple"))
+
+		_, _, err = ReadDirSetting(filepath.Join(dir, "none"))
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestBuildMissingAsset(t *testing.T) {
+	Convey("Missing Asset", t, func() {
+		dir, err := ioutil.TempDir("", "pugo-missing")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		for _, name := range []string{"a.png", "a.jpg", "a.gif"} {
+			data, err := placeholderAsset(name)
+			So(err, ShouldBeNil)
+			img, _, err := image.Decode(bytes.NewReader(data))
+			So(err, ShouldBeNil)
+			So(img.Bounds().Dx(), ShouldEqual, placeholderSize)
+		}
+		data, _ := placeholderAsset("js/app.js")
+		So(string(data), ShouldContainSubstring, "js/app.js")
+
+		newCtx := func() *Context {
+			ctx := NewContext(&cli.Context{}, "../../source", dir, "../../source/theme/default")
+			ctx.Sync = pugosync.NewSyncer(dir)
+			So(New(ReadSource, ReadTheme).Try(ctx), ShouldBeNil)
+			return ctx
+		}
+		ctx := newCtx()
+		rel, err := ctx.themeAsset("/css/style.css")
+		So(err, ShouldBeNil)
+		So(rel, ShouldEqual, "css/style.css")
+		_, err = ctx.themeAsset("img/logo.png")
+		So(err, ShouldBeNil)
+		So(ctx.MissingAssets(), ShouldResemble, []string{"img/logo.png"})
+		So(com.IsFile(filepath.Join(dir, "img/logo.png")), ShouldBeFalse)
+
+		ctx.Draft = true
+		ctx.resetMissingAssets()
+		_, err = ctx.themeAsset("img/logo.png")
+		So(err, ShouldBeNil)
+		So(com.IsFile(filepath.Join(dir, "img/logo.png")), ShouldBeTrue)
+		_, ok := ctx.Sync.SyncedFrom(filepath.Join(dir, "img/logo.png"))
+		So(ok, ShouldBeTrue)
+
+		ctx.Strict = true
+		_, err = ctx.themeAsset("img/logo.png")
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestBuildSchema(t *testing.T) {
+	Convey("Build Front-matter Schema", t, func() {
+		ctx := NewContext(&cli.Context{}, "../../source", "../../dest", "../../source/theme/default")
+		So(New(ReadSource, ReadTheme).Try(ctx), ShouldBeNil)
+		So(ctx.Theme.Load(), ShouldBeNil)
+
+		s, err := FrontMatterSchema(ctx, "post")
+		So(err, ShouldBeNil)
+		So(s.Properties["author"].Enum, ShouldContain, "pugo")
+		So(s.Properties["tags"].Items.Examples, ShouldNotBeEmpty)
+		So(s.Properties["license"].Examples, ShouldContain, "CC-BY-4.0")
+
+		s, err = FrontMatterSchema(ctx, "page")
+		So(err, ShouldBeNil)
+		So(s.Properties["template"].Examples, ShouldContain, "page.html")
+		So(s.Properties, ShouldNotContainKey, "tags")
+
+		_, err = FrontMatterSchema(ctx, "meta")
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestBuildIndexRules(t *testing.T) {
+	Convey("Build Index Rules", t, func() {
+		dest := tempDest("index")
+		defer os.RemoveAll(dest)
+		var post *model.Post
+		ctx := NewContext(&cli.Context{}, "../../source", dest, "../../source/theme/default")
+		b := New(ReadSource, ReadTheme, func(ctx *Context) {
+			post = ctx.Source.Posts[0]
+			ctx.Source.Build.API = true
+			ctx.Source.Build.Index = []*model.IndexRule{
+				{Glob: "**/" + path.Base(post.URL()), Exclude: []string{model.IndexSitemap, model.IndexFeed}, Robots: "noindex, nofollow"},
+			}
+			ctx.Source.Pages[0].NoIndex = true
+		}, AssembleSource, Compile)
+		So(b.Try(ctx), ShouldBeNil)
+		page := ctx.Source.Pages[0]
+
+		sitemap, err := ioutil.ReadFile(filepath.Join(ctx.DstDir(), "sitemap.xml"))
+		So(err, ShouldBeNil)
+		So(string(sitemap), ShouldContainSubstring, "<loc>"+ctx.Source.Meta.Root+"</loc>")
+		So(string(sitemap), ShouldNotContainSubstring, "<loc>"+ctx.Source.Meta.DomainURL(post.URL())+"</loc>")
+		So(string(sitemap), ShouldNotContainSubstring, "<loc>"+ctx.Source.Meta.DomainURL(page.URL())+"</loc>")
+
+		feed, err := ioutil.ReadFile(filepath.Join(ctx.DstDir(), "feed.xml"))
+		So(err, ShouldBeNil)
+		So(string(feed), ShouldNotContainSubstring, ctx.Source.Meta.DomainURL(post.URL()))
+
+		data, err := ioutil.ReadFile(filepath.Join(ctx.DstDir(), APIPath, "posts/page/1.json"))
+		So(err, ShouldBeNil)
+		apiPage := new(APIPage)
+		So(json.Unmarshal(data, apiPage), ShouldBeNil)
+		So(apiPage.Total, ShouldEqual, len(ctx.Source.Posts))
+
+		html, err := ioutil.ReadFile(post.DestURL())
+		So(err, ShouldBeNil)
+		So(string(html), ShouldContainSubstring, `<meta name="robots" content="noindex, nofollow"/>`)
+		html, err = ioutil.ReadFile(page.DestURL())
+		So(err, ShouldBeNil)
+		So(string(html), ShouldContainSubstring, `<meta name="robots" content="noindex"/>`)
+		html, err = ioutil.ReadFile(filepath.Join(ctx.DstDir(), "index.html"))
+		So(err, ShouldBeNil)
+		So(string(html), ShouldNotContainSubstring, `name="robots"`)
+	})
+}
+
+func TestBuildLogger(t *testing.T) {
+	Convey("Build With Logger", t, func() {
+		var (
+			captures = []*helper.LogCapture{helper.NewLogCapture(), helper.NewLogCapture()}
+			dirs     = []string{tempDest("log1"), tempDest("log2")}
+			errs     = make([]error, 2)
+			ctxs     = make([]*Context, 2)
+			wg       sync.WaitGroup
+		)
+		defer os.RemoveAll(dirs[0])
+		defer os.RemoveAll(dirs[1])
+		for i := range captures {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				ctx := NewContext(&cli.Context{}, "../../source", dirs[i], "../../source/theme/default")
+				ctx.Logger = helper.NewLogger(captures[i])
+				errs[i] = New(ReadSource, ReadTheme, AssembleSource, Compile, Sync).Try(ctx)
+				ctxs[i] = ctx
+			}(i)
+		}
+		wg.Wait()
+
+		for i, c := range captures {
+			So(errs[i], ShouldBeNil)
+			records := c.Records()
+			So(records, ShouldNotBeEmpty)
+			var own, other int
+			for _, r := range records {
+				if strings.Contains(r.Msg, dirs[i]) {
+					own++
+				}
+				if strings.Contains(r.Msg, dirs[1-i]) {
+					other++
+				}
+			}
+			So(own, ShouldBeGreaterThan, 0)
+			So(other, ShouldEqual, 0)
+			So(ctxs[i].Warnings(), ShouldEqual, c.Count(log15.LvlWarn))
+		}
+	})
+	Convey("Stop With Logger", t, func() {
+		c := helper.NewLogCapture()
+		ctx := NewContext(&cli.Context{}, "../../source/not-exist", tempDest("log3"), "../../source/theme/default")
+		defer os.RemoveAll(ctx.DstDir())
+		ctx.Logger = helper.NewLogger(c)
+		Read(ctx)
+		So(ctx.Err, ShouldNotBeNil)
+		So(ctx.Source, ShouldBeNil)
+		So(c.Count(log15.LvlError), ShouldEqual, 1)
+
+		// it returns without building once
+		WatchWith(ctx, NewWatchOptions())
+		So(c.Count(log15.LvlCrit), ShouldEqual, 1)
+	})
+}
+
+func TestBuildReadOrder(t *testing.T) {
+	Convey("Read Order", t, func() {
+		dir, err := ioutil.TempDir("", "pugo-order")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		meta, _ := ioutil.ReadFile("../../source/meta.toml")
+		So(ioutil.WriteFile(filepath.Join(dir, "meta.toml"), meta, 0644), ShouldBeNil)
+		os.MkdirAll(filepath.Join(dir, "page"), os.ModePerm)
+		os.MkdirAll(filepath.Join(dir, "post"), os.ModePerm)
+		var nodes bytes.Buffer
+		for i := 0; i < 20; i++ {
+			// posts with equal dates are sorted by title
+			date := "2016-01-02 10:00"
+			if i%3 == 0 {
+				date = fmt.Sprintf("2016-01-%02d 10:00", i+3)
+			}
+			data := fmt.Sprintf("```toml\ntitle = \"post %02d\"\ndate = \"%s\"\n```\n\ncontent", i, date)
+			So(ioutil.WriteFile(filepath.Join(dir, "post", fmt.Sprintf("p%02d.md", i)), []byte(data), 0644), ShouldBeNil)
+			data = fmt.Sprintf("```toml\ntitle = \"page %d\"\ndate = \"2016-01-02 10:00\"\n```\n\ncontent", i)
+			So(ioutil.WriteFile(filepath.Join(dir, "page", fmt.Sprintf("p%02d.md", i)), []byte(data), 0644), ShouldBeNil)
+			fmt.Fprintf(&nodes, "[\"node/n%02d\"]\ntitle = \"node %d\"\nslug = \"node/n%02d\"\nnode = true\n\n", i, i, i)
+		}
+		So(ioutil.WriteFile(filepath.Join(dir, "page.toml"), nodes.Bytes(), 0644), ShouldBeNil)
+
+		read := func(workers int) ([]string, []string) {
+			readWorkers = workers
+			defer func() { readWorkers = 0 }()
+			ctx := NewContext(&cli.Context{}, dir, filepath.Join(dir, "dest"), "../../source/theme/default")
+			ReadSource(ctx)
+			So(ctx.Err, ShouldBeNil)
+			var posts, pages []string
+			for _, p := range ctx.Source.Posts {
+				posts = append(posts, p.Title)
+			}
+			for _, p := range ctx.Source.Pages {
+				pages = append(pages, p.Title)
+			}
+			return posts, pages
+		}
+		posts, pages := read(1)
+		So(posts, ShouldHaveLength, 20)
+		So(pages, ShouldHaveLength, 40)
+		So(posts[0], ShouldEqual, "post 18")
+		So(posts[7:9], ShouldResemble, []string{"post 01", "post 02"})
+		So(pages[:2], ShouldResemble, []string{"node 0", "node 1"})
+		So(pages[20:22], ShouldResemble, []string{"page 0", "page 1"})
+		for i := 0; i < 3; i++ {
+			p1, p2 := read(8)
+			So(p1, ShouldResemble, posts)
+			So(p2, ShouldResemble, pages)
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package builder
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// checkCollisions finds outputs that are generated by more than one source,
+// one would overwrite another silently when compiling
+func checkCollisions(ctx *Context) error {
+	outputs := make(map[string][]string)
+	add := func(dest, from string) {
+		if dest == "" {
+			return
+		}
+		dest = filepath.Clean(dest)
+		outputs[dest] = append(outputs[dest], from)
+	}
+
+	for _, p := range ctx.Source.Posts {
+		add(p.DestURL(), p.SourceURL())
+		for _, v := range p.Variants() {
+			add(v.DestURL(), fmt.Sprintf("%s (variant %s)", p.SourceURL(), v.Variant))
+		}
+	}
+	for _, p := range ctx.Source.Pages {
+		if p.Node {
+			continue
+		}
+		add(p.DestURL(), p.SourceURL())
+	}
+	for name, tp := range ctx.Source.TagPosts {
+		add(tp.DestURL(), fmt.Sprintf("tag '%s'", name))
+	}
+	for i, pp := range ctx.Source.PagePosts {
+		add(pp.DestURL(), fmt.Sprintf("posts page %d", i))
+	}
+	add(ctx.Source.IndexPosts.DestURL(), "index")
+	add(ctx.Source.Archive.DestURL(), "archive")
+
+	var collisions []string
+	for dest, from := range outputs {
+		if len(from) < 2 {
+			continue
+		}
+		sort.Strings(from)
+		rel, _ := filepath.Rel(ctx.DstDir(), dest)
+		ctx.Log().Error("Assemble|Collision|%s|%s", filepath.ToSlash(rel), strings.Join(from, ", "))
+		collisions = append(collisions, fmt.Sprintf("'%s' is generated by %s", filepath.ToSlash(rel), strings.Join(from, ", ")))
+	}
+	if len(collisions) == 0 {
+		return nil
+	}
+	sort.Strings(collisions)
+	return fmt.Errorf("output collisions: %s", strings.Join(collisions, "; "))
+}
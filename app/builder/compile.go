@@ -1,19 +1,22 @@
 package builder
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
+	"runtime/debug"
 	"sync/atomic"
 	"time"
 
 	"github.com/go-xiaohei/pugo/app/helper"
 	"github.com/go-xiaohei/pugo/app/model"
 	"github.com/gorilla/feeds"
-	"gopkg.in/inconshreveable/log15.v2"
 )
 
 // Compile compile source to static files
@@ -23,65 +26,134 @@ func Compile(ctx *Context) {
 		return
 	}
 
-	// init worker in this progress
-	w := helper.NewWorker(0)
+	// content is analyzed before it's released in low memory mode
+	if ctx.Err = compileContentReport(ctx); ctx.Err != nil {
+		return
+	}
 
-	var reqs []helper.WorkerFunc
+	var reqs, listReqs []helper.WorkerFunc
 	reqs = append(reqs, compilePosts(ctx)...)
-	reqs = append(reqs, compileIndexPage(ctx))
-	reqs = append(reqs, compilePagePosts(ctx)...)
-	reqs = append(reqs, compileTagPosts(ctx)...)
 	reqs = append(reqs, compilePages(ctx)...)
-	reqs = append(reqs, compileArchive(ctx))
+	listReqs = append(listReqs, compileIndexPage(ctx))
+	listReqs = append(listReqs, compilePagePosts(ctx)...)
+	listReqs = append(listReqs, compileTagPosts(ctx)...)
+	listReqs = append(listReqs, compileArchive(ctx))
+	listReqs = append(listReqs, compileAPI(ctx)...)
 
-	for _, fn := range reqs {
-		w.AddFunc(fn)
+	if !ctx.IsLowMemory() {
+		runCompile(ctx, 0, append(reqs, listReqs...))
+		if ctx.Err = compileRSS(ctx); ctx.Err != nil {
+			ctx.Log().Info("Compile|Done")
+			return
+		}
+	} else {
+		// in low memory mode, compile one by one,
+		// write feed, then release posts content before compiling list pages
+		runCompile(ctx, 1, reqs)
+		if ctx.Err = compileRSS(ctx); ctx.Err != nil {
+			ctx.Log().Info("Compile|Done")
+			return
+		}
+		for _, p := range ctx.Source.Posts {
+			p.ReleaseContent()
+			for _, v := range p.Variants() {
+				v.ReleaseContent()
+			}
+		}
+		debug.FreeOSMemory()
+		runCompile(ctx, 1, listReqs)
 	}
-	w.RunOnce()
-	for _, err := range w.Errors() {
-		log15.Error("Build|%s", err.Error())
+	if ctx.Err = compileSitemap(ctx); ctx.Err != nil {
+		ctx.Log().Info("Compile|Done")
+		return
 	}
-
-	if ctx.Err = compileRSS(ctx); ctx.Err != nil {
-		log15.Info("Compile|Done")
+	if ctx.Err = compileGone(ctx); ctx.Err != nil {
+		ctx.Log().Info("Compile|Done")
 		return
 	}
-	if ctx.Err = compileSitemap(ctx); ctx.Err != nil {
-		log15.Info("Compile|Done")
+	if ctx.Err = compileRedirects(ctx); ctx.Err != nil {
+		ctx.Log().Info("Compile|Done")
+		return
+	}
+	if ctx.Err = compileCNAME(ctx); ctx.Err != nil {
+		ctx.Log().Info("Compile|Done")
+		return
+	}
+	if ctx.Err = compileReactions(ctx); ctx.Err != nil {
+		ctx.Log().Info("Compile|Done")
+		return
+	}
+	if ctx.Err = compileVariants(ctx); ctx.Err != nil {
+		ctx.Log().Info("Compile|Done")
+		return
+	}
+	if ctx.Err = compileVerify(ctx); ctx.Err != nil {
+		ctx.Log().Info("Compile|Done")
+		return
+	}
+	if ctx.Err = compileSEO(ctx); ctx.Err != nil {
+		ctx.Log().Info("Compile|Done")
 		return
 	}
-	log15.Info("Compile|Done")
+	ctx.Log().Info("Compile|Done")
+}
+
+// runCompile runs compile funcs in worker with size,
+// funcs are skipped after building is canceled
+func runCompile(ctx *Context, size int, reqs []helper.WorkerFunc) {
+	w := helper.NewWorker(size)
+	for _, fn := range reqs {
+		fn := fn
+		w.AddFunc(func() error {
+			if ctx.IsCanceled() {
+				return nil
+			}
+			return fn()
+		})
+	}
+	w.RunOnce()
+	for _, err := range w.Errors() {
+		ctx.Log().Error("Build|%s", err.Error())
+	}
 }
 
 func compilePosts(ctx *Context) []helper.WorkerFunc {
 	posts := ctx.Source.Posts
 	if len(posts) == 0 {
-		log15.Warn("NoPosts")
+		ctx.Log().Warn("NoPosts")
 		return nil
 	}
 	var fns []helper.WorkerFunc
 	for _, post := range posts {
-		p2 := post
-		fn := func() error {
-			viewData := ctx.View()
-			viewData["Title"] = p2.Title + " - " + ctx.Source.Meta.Title
-			viewData["Desc"] = p2.Desc
-			viewData["Post"] = p2
-			viewData["PermaKey"] = p2.Slug
-			viewData["PostType"] = model.TreePost
-			viewData["Hover"] = model.TreePost
-			viewData["URL"] = p2.URL()
-			err := compile(ctx, "post.html", viewData, p2.DestURL())
-			if err != nil {
-				err = fmt.Errorf("%s|%s", p2.SourceURL(), err.Error())
-			}
-			return err
+		fns = append(fns, compilePost(ctx, post))
+		for _, v := range post.Variants() {
+			fns = append(fns, compilePost(ctx, v))
 		}
-		fns = append(fns, fn)
 	}
 	return fns
 }
 
+func compilePost(ctx *Context, p2 *model.Post) helper.WorkerFunc {
+	return func() error {
+		viewData := ctx.View()
+		viewData["Title"] = p2.Title + " - " + ctx.Source.Meta.Title
+		viewData["Desc"] = p2.Desc
+		viewData["Post"] = p2
+		viewData["PermaKey"] = p2.Slug
+		viewData["PostType"] = model.TreePost
+		viewData["Hover"] = model.TreePost
+		viewData["URL"] = p2.URL()
+		viewData["Variant"] = p2.Variant
+		viewData["License"] = p2.License
+		viewData["Robots"] = ctx.robots(p2.URL(), p2.NoIndex)
+		err := compile(ctx, p2.VariantTemplate(), viewData, p2.DestURL())
+		if err != nil {
+			err = fmt.Errorf("%s|%s", p2.SourceURL(), err.Error())
+		}
+		return err
+	}
+}
+
 func compilePagePosts(ctx *Context) []helper.WorkerFunc {
 	var fns []helper.WorkerFunc
 	lists := ctx.Source.PagePosts
@@ -161,7 +233,7 @@ func compileTagPosts(ctx *Context) []helper.WorkerFunc {
 func compilePages(ctx *Context) []helper.WorkerFunc {
 	pages := ctx.Source.Pages
 	if len(pages) == 0 {
-		log15.Warn("MoPages")
+		ctx.Log().Warn("MoPages")
 		return nil
 	}
 	var fns []helper.WorkerFunc
@@ -179,6 +251,7 @@ func compilePages(ctx *Context) []helper.WorkerFunc {
 			viewData["PostType"] = model.TreePage
 			viewData["Hover"] = p.NavHover
 			viewData["URL"] = p.URL()
+			viewData["Robots"] = ctx.robots(p.URL(), p.NoIndex)
 			if p.Lang != "" {
 				viewData["Lang"] = p.Lang
 				if i18n, ok := ctx.Source.I18n[p.Lang]; ok {
@@ -225,128 +298,147 @@ func compile(ctx *Context, file string, viewData map[string]interface{}, destFil
 		return err
 	}
 	defer f.Close()
-	if err := ctx.Theme.Execute(f, file, viewData); err != nil {
+	if rel, err := filepath.Rel(ctx.DstDir(), destFile); err == nil {
+		viewData["Canonical"] = ctx.Source.Meta.DomainURL(canonicalPath(rel))
+	}
+	if ctx.Legacy {
+		var buf bytes.Buffer
+		if err := ctx.Theme.Execute(&buf, ctx.Theme.Variant(file, LegacyVariant), viewData); err != nil {
+			return err
+		}
+		if _, err := f.Write(stripModules(buf.Bytes())); err != nil {
+			return err
+		}
+	} else if err := ctx.Theme.Execute(f, file, viewData); err != nil {
 		return err
 	}
 	ctx.Sync.SetSynced(destFile)
-	log15.Debug("Build|%s", filepath.ToSlash(destFile))
+	ctx.Log().Debug("Build|%s", filepath.ToSlash(destFile))
 	atomic.AddInt64(&ctx.counter, 1)
 	return nil
 }
 
+// compileRSS writes feed items one by one,
+// it does not keep all items in memory as feeds.Feed.
+// Encoded items are cached, unchanged posts reuse items of last building.
 func compileRSS(ctx *Context) error {
-	// todo : should compile RSS if no posts ?
-	toDir := ctx.DstDir()
-	now := time.Now()
-	feed := &feeds.Feed{
+	posts := ctx.indexedPosts(ctx.Source.Posts, model.IndexFeed)
+	channel := &feeds.RssFeed{
 		Title:       ctx.Source.Meta.Title,
-		Link:        &feeds.Link{Href: ctx.Source.Meta.Root},
+		Link:        ctx.Source.Meta.Root,
 		Description: ctx.Source.Meta.Desc,
-		Created:     now,
+		PubDate:     latestTime(posts).Format(time.RFC1123Z),
 	}
 	if ctx.Source.Owner != nil {
-		feed.Author = &feeds.Author{
-			Name:  ctx.Source.Owner.Nick,
-			Email: ctx.Source.Owner.Email,
-		}
+		channel.ManagingEditor = fmt.Sprintf("%s (%s)", ctx.Source.Owner.Email, ctx.Source.Owner.Nick)
 	}
-	var item *feeds.Item
-	for _, p := range ctx.Source.Posts {
-		item = &feeds.Item{
-			Title:       p.Title,
-			Link:        &feeds.Link{Href: ctx.Source.Meta.DomainURL(p.URL())},
-			Description: string(p.Content()),
-			Created:     p.Created(),
-			Updated:     p.Updated(),
-		}
-		if p.Author != nil {
-			item.Author = &feeds.Author{
-				Name:  p.Author.Nick,
-				Email: p.Author.Email,
-			}
-		}
-		feed.Items = append(feed.Items, item)
+
+	// encode channel without items, then insert items before closing tags
+	var head bytes.Buffer
+	if err := xml.NewEncoder(&head).Encode(channel.FeedXml()); err != nil {
+		return err
 	}
+	closing := []byte("</channel></rss>")
+	headBytes := bytes.TrimSuffix(head.Bytes(), closing)
 
-	dstFile := path.Join(toDir, ctx.Source.Meta.Path, "feed.xml")
+	dstFile := path.Join(ctx.DstDir(), ctx.Source.Meta.Path, "feed.xml")
 	os.MkdirAll(filepath.Dir(dstFile), os.ModePerm)
 	f, err := os.OpenFile(dstFile, os.O_CREATE|os.O_TRUNC|os.O_RDWR, os.ModePerm)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
-	if err = feed.WriteRss(f); err != nil {
+	w := bufio.NewWriter(f)
+	w.WriteString(xml.Header)
+	w.Write(headBytes)
+	for _, p := range posts {
+		data, err := encodeFeedItem(ctx, p)
+		if err != nil {
+			return err
+		}
+		w.Write(data)
+	}
+	w.Write(closing)
+	if err = w.Flush(); err != nil {
 		return err
 	}
 	ctx.Sync.SetSynced(dstFile)
-	log15.Debug("Build|%s", dstFile)
+	ctx.Log().Debug("Build|%s", dstFile)
 	atomic.AddInt64(&ctx.counter, 1)
 	return nil
 }
 
-func compileSitemap(ctx *Context) error {
-	toDir := ctx.DstDir()
-	now := time.Now()
-	var buf bytes.Buffer
-	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>`)
-	buf.WriteString(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
-	buf.WriteString("<url>")
-	fmt.Fprintf(&buf, "<loc>%s</loc>", ctx.Source.Meta.Root)
-	fmt.Fprintf(&buf, "<lastmod>%s</lastmod>", now.Format(time.RFC3339))
-	buf.WriteString("<changefreq>daily</changefreq>")
-	buf.WriteString("<priority>1.0</priority>")
-	buf.WriteString("</url>")
+// compileCNAME writes domain to CNAME file in destination
+func compileCNAME(ctx *Context) error {
+	if !ctx.Source.Meta.CNAME {
+		return nil
+	}
+	dstFile := filepath.Join(ctx.DstDir(), "CNAME")
+	if err := ioutil.WriteFile(dstFile, []byte(ctx.Source.Meta.Domain+"\n"), os.ModePerm); err != nil {
+		return err
+	}
+	ctx.Sync.SetSynced(dstFile)
+	ctx.Log().Debug("Build|%s", dstFile)
+	atomic.AddInt64(&ctx.counter, 1)
+	return nil
+}
 
-	for _, p := range ctx.Source.Pages {
-		buf.WriteString("<url>")
-		fmt.Fprintf(&buf, "<loc>%s</loc>", ctx.Source.Meta.DomainURL(p.URL()))
-		fmt.Fprintf(&buf, "<lastmod>%s</lastmod>", p.Created().Format(time.RFC3339))
-		buf.WriteString("<changefreq>weekly</changefreq>")
-		buf.WriteString("<priority>0.5</priority>")
-		buf.WriteString("</url>")
+// canonicalPath return url path of compiled file,
+// index.html is trimmed as its directory
+func canonicalPath(rel string) string {
+	rel = "/" + filepath.ToSlash(rel)
+	if path.Base(rel) == "index.html" {
+		return path.Dir(rel) + "/"
 	}
+	return rel
+}
 
+// compileVariants writes variants.json as router config for A/B testing,
+// it maps each post url to urls of all its variants
+func compileVariants(ctx *Context) error {
+	routes := make(map[string][]string)
 	for _, p := range ctx.Source.Posts {
-		buf.WriteString("<url>")
-		fmt.Fprintf(&buf, "<loc>%s</loc>", ctx.Source.Meta.DomainURL(p.URL()))
-		fmt.Fprintf(&buf, "<lastmod>%s</lastmod>", p.Created().Format(time.RFC3339))
-		buf.WriteString("<changefreq>daily</changefreq>")
-		buf.WriteString("<priority>0.6</priority>")
-		buf.WriteString("</url>")
-	}
-	buf.WriteString("<url>")
-	fmt.Fprintf(&buf, "<loc>%s</loc>", ctx.Source.Meta.DomainURL("archive.html"))
-	fmt.Fprintf(&buf, "<lastmod>%s</lastmod>", now.Format(time.RFC3339))
-	buf.WriteString("<changefreq>daily</changefreq>")
-	buf.WriteString("<priority>0.6</priority>")
-	buf.WriteString("</url>")
-
-	for i := 1; i <= ctx.Source.PostPage; i++ {
-		buf.WriteString("<url>")
-		fmt.Fprintf(&buf, "<loc>%s</loc>", ctx.Source.Meta.DomainURL(fmt.Sprintf("post/%d.html", i)))
-		fmt.Fprintf(&buf, "<lastmod>%s</lastmod>", now.Format(time.RFC3339))
-		buf.WriteString("<changefreq>daily</changefreq>")
-		buf.WriteString("<priority>0.6</priority>")
-		buf.WriteString("</url>")
+		if len(p.Variants()) == 0 {
+			continue
+		}
+		urls := []string{p.URL()}
+		for _, v := range p.Variants() {
+			urls = append(urls, v.URL())
+		}
+		routes[p.URL()] = urls
 	}
-
-	for _, t := range ctx.Source.Tags {
-		buf.WriteString("<url>")
-		fmt.Fprintf(&buf, "<loc>%s</loc>", ctx.Source.Meta.DomainURL(t.URL))
-		fmt.Fprintf(&buf, "<lastmod>%s</lastmod>", now.Format(time.RFC3339))
-		buf.WriteString("<changefreq>weekly</changefreq>")
-		buf.WriteString("<priority>0.5</priority>")
-		buf.WriteString("</url>")
+	if len(routes) == 0 {
+		return nil
 	}
-
-	buf.WriteString("</urlset>")
-	dstFile := path.Join(toDir, ctx.Source.Meta.Path, "sitemap.xml")
+	data, err := json.MarshalIndent(routes, "", "  ")
+	if err != nil {
+		return err
+	}
+	dstFile := path.Join(ctx.DstDir(), ctx.Source.Meta.Path, "variants.json")
 	os.MkdirAll(path.Dir(dstFile), os.ModePerm)
-	if err := ioutil.WriteFile(dstFile, buf.Bytes(), os.ModePerm); err != nil {
+	if err = ioutil.WriteFile(dstFile, data, os.ModePerm); err != nil {
 		return err
 	}
 	ctx.Sync.SetSynced(dstFile)
-	log15.Debug("Build|%s", dstFile)
+	ctx.Log().Debug("Build|%s", dstFile)
 	atomic.AddInt64(&ctx.counter, 1)
 	return nil
 }
+
+// compileVerify writes verification files of search engines
+func compileVerify(ctx *Context) error {
+	if ctx.Source.Verify == nil {
+		return nil
+	}
+	for name, data := range ctx.Source.Verify.Files() {
+		dstFile := path.Join(ctx.DstDir(), ctx.Source.Meta.Path, name)
+		os.MkdirAll(path.Dir(dstFile), os.ModePerm)
+		if err := ioutil.WriteFile(dstFile, data, os.ModePerm); err != nil {
+			return err
+		}
+		ctx.Sync.SetSynced(dstFile)
+		ctx.Log().Debug("Build|%s", dstFile)
+		atomic.AddInt64(&ctx.counter, 1)
+	}
+	return nil
+}
@@ -3,7 +3,10 @@ package builder
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"path"
+	"path/filepath"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -38,10 +41,45 @@ type (
 		Tree *model.Tree
 		// Sync is file syncer
 		Sync *sync.Syncer
+		// Draft is draft mode, editorial annotations are rendered visibly
+		Draft bool
+		// MaxMemory is memory limit in bytes,
+		// if it's set, build in low memory mode
+		MaxMemory int64
+		// Sandbox is restricted mode for untrusted themes,
+		// theme can not read files out of its directory or secrets in meta
+		Sandbox bool
+		// Legacy is compatibility mode for old browsers,
+		// legacy variants of templates are used and module scripts are removed
+		Legacy bool
+		// Strict fails building if templates reference missing templates or static files,
+		// otherwise they are warned, and replaced by placeholders in draft mode
+		Strict bool
+		// Logger receives logs of building with this context, root logger by default.
+		// Set it to capture logs of concurrent buildings separately
+		Logger log15.Logger
 
 		time           time.Time
 		counter        int64
+		dryRun         bool
+		tmpCacheDir    string
+		canceled       int32
 		srcDir, dstDir string
+		// ignore and themeIgnore are rules in .pugoignore of source and theme directory
+		ignore, themeIgnore *helper.Ignore
+		staticRules         *staticRules
+		indexRules          *indexRules
+		images              *imageMetas
+		// includes are content files that include each file, for rebuilding after it changes
+		includes includeDeps
+		missing  missingAssets
+		// markdown caches rendered html of posts and pages, it's kept in rebuilding
+		markdown *helper.MarkdownCache
+		// builder is last Builder ran with this context, watching rebuilds with it
+		builder *Builder
+		// log counts warnings of building in warnings
+		log      log15.Logger
+		warnings int64
 	}
 )
 
@@ -53,6 +91,7 @@ func NewContext(cli *cli.Context, from, to, theme string) *Context {
 		To:        to,
 		ThemeName: theme,
 		time:      time.Now(),
+		markdown:  helper.NewMarkdownCache(""),
 	}
 	c.Tree = model.NewTree(c.DstDir())
 	// c.Sync = sync.NewSyncer(c.DstDir())
@@ -61,9 +100,10 @@ func NewContext(cli *cli.Context, from, to, theme string) *Context {
 
 // View get view data to template from Context
 func (ctx *Context) View() map[string]interface{} {
+	source := ctx.viewSource()
 	m := map[string]interface{}{
 		"Version":   vars.Version,
-		"Source":    ctx.Source,
+		"Source":    source,
 		"Nav":       ctx.Source.Nav,
 		"Meta":      ctx.Source.Meta,
 		"Title":     ctx.Source.Meta.Title + " - " + ctx.Source.Meta.Subtitle,
@@ -71,11 +111,17 @@ func (ctx *Context) View() map[string]interface{} {
 		"Comment":   ctx.Source.Comment,
 		"Owner":     ctx.Source.Owner,
 		"Analytics": ctx.Source.Analytics,
+		"Popular":   ctx.Source.PopularPosts,
+		"Verify":    ctx.Source.Verify,
+		"License":   ctx.Source.License,
 		"Tree":      ctx.Tree,
 		"Lang":      ctx.Source.Meta.Language,
 		"Hover":     "",
+		"Robots":    "",
 		"Base":      strings.TrimRight(ctx.Source.Meta.Path, "/"),
 		"Root":      strings.TrimRight(ctx.Source.Meta.Root, "/"),
+		"Legacy":    ctx.Legacy,
+		"Env":       source.Env,
 	}
 	if ctx.Source.Meta.Language == "" {
 		m["I18n"] = ctx.Source.I18n["en"]
@@ -92,6 +138,19 @@ func (ctx *Context) View() map[string]interface{} {
 	return m
 }
 
+// viewSource return source data for templates,
+// secrets and environment variables are removed in sandbox mode
+func (ctx *Context) viewSource() *Source {
+	if !ctx.Sandbox {
+		return ctx.Source
+	}
+	s := *ctx.Source
+	s.Mail = nil
+	s.Syndication = nil
+	s.Env = nil
+	return &s
+}
+
 // IsValid check context requirement, there must have values in some fields
 func (ctx *Context) IsValid() bool {
 	if ctx.From == "" || ctx.To == "" || ctx.ThemeName == "" {
@@ -100,6 +159,11 @@ func (ctx *Context) IsValid() bool {
 	return true
 }
 
+// IsLowMemory return whether building in low memory mode
+func (ctx *Context) IsLowMemory() bool {
+	return ctx.MaxMemory > 0
+}
+
 // Duration return seconds after *Context created
 func (ctx *Context) Duration() float64 {
 	return time.Since(ctx.time).Seconds()
@@ -108,7 +172,55 @@ func (ctx *Context) Duration() float64 {
 // Again reset some fields in context to rebuild
 func (ctx *Context) Again() {
 	ctx.time = time.Now()
+	ctx.Err = nil
 	atomic.StoreInt64(&ctx.counter, 0)
+	atomic.StoreInt32(&ctx.canceled, 0)
+}
+
+// Cancel cancels building with this context,
+// the building stops before next step and returns ErrCanceled
+func (ctx *Context) Cancel() {
+	atomic.StoreInt32(&ctx.canceled, 1)
+}
+
+// IsCanceled return whether building is canceled
+func (ctx *Context) IsCanceled() bool {
+	return atomic.LoadInt32(&ctx.canceled) > 0
+}
+
+// ReadIgnore reads .pugoignore in source directory, ReadSource reads it too
+func (ctx *Context) ReadIgnore() error {
+	var err error
+	ctx.ignore, err = helper.ReadIgnore(filepath.Join(ctx.srcDir, IgnoreFile))
+	return err
+}
+
+// IsIgnored return whether file in source or theme directory is ignored by .pugoignore
+func (ctx *Context) IsIgnored(file string, isDir bool) bool {
+	return ctx.ignoreFunc()(file, isDir)
+}
+
+// ignoreFunc return IsIgnored with current rules,
+// it's safe to call when context is rebuilding
+func (ctx *Context) ignoreFunc() func(file string, isDir bool) bool {
+	var (
+		srcDir, themeDir    = ctx.SrcDir(), ""
+		ignore, themeIgnore = ctx.ignore, ctx.themeIgnore
+	)
+	if ctx.Theme != nil {
+		themeDir = ctx.Theme.Dir()
+	}
+	return func(file string, isDir bool) bool {
+		if themeDir != "" {
+			if rel, err := filepath.Rel(themeDir, file); err == nil && !strings.HasPrefix(rel, "..") {
+				return themeIgnore.Match(rel, isDir)
+			}
+		}
+		if rel, err := filepath.Rel(srcDir, file); err == nil && !strings.HasPrefix(rel, "..") {
+			return ignore.Match(rel, isDir)
+		}
+		return false
+	}
 }
 
 // SrcDir get src dir
@@ -153,6 +265,101 @@ func (ctx *Context) SrcMediaDir() string {
 	return path.Join(ctx.srcDir, "media")
 }
 
+// CacheDir get cache dir in src, it's a temporary directory in dry run
+func (ctx *Context) CacheDir() string {
+	ctx.parseDir()
+	if ctx.tmpCacheDir != "" {
+		return ctx.tmpCacheDir
+	}
+	if ctx.Source != nil && ctx.Source.Build != nil && ctx.Source.Build.CacheDir != "" {
+		return path.Join(ctx.srcDir, ctx.Source.Build.CacheDir)
+	}
+	return path.Join(ctx.srcDir, ".cache")
+}
+
+// DryRun makes building not change source directory, for comparing or previewing output.
+// Cache files are copied to a temporary directory, lock file and artifacts are not written.
+// Call Close to remove the temporary directory after building
+func (ctx *Context) DryRun() error {
+	ctx.parseDir()
+	if ctx.Err != nil {
+		return ctx.Err
+	}
+	cacheDir, _, err := ReadCacheSetting(ctx.srcDir)
+	if err != nil {
+		return err
+	}
+	tmpDir, err := ioutil.TempDir("", "pugo-cache-")
+	if err != nil {
+		return err
+	}
+	// sub directories, such as markdown cache and artifacts, are not needed to compare output
+	files, _ := ioutil.ReadDir(cacheDir)
+	for _, fi := range files {
+		if fi.IsDir() {
+			continue
+		}
+		if err = helper.CopyFile(filepath.Join(cacheDir, fi.Name()), filepath.Join(tmpDir, fi.Name())); err != nil {
+			os.RemoveAll(tmpDir)
+			return err
+		}
+	}
+	ctx.dryRun, ctx.tmpCacheDir = true, tmpDir
+	return nil
+}
+
+// IsDryRun return whether building is dry run
+func (ctx *Context) IsDryRun() bool {
+	return ctx.dryRun
+}
+
+// Close removes temporary directory of dry run
+func (ctx *Context) Close() error {
+	if ctx.tmpCacheDir == "" {
+		return nil
+	}
+	return os.RemoveAll(ctx.tmpCacheDir)
+}
+
+// ReadCacheSetting return cache directory and remote url of cache in meta of source directory
+func ReadCacheSetting(srcDir string) (string, string, error) {
+	metaAll, err := ReadSecondMeta(srcDir)
+	if err != nil {
+		return "", "", err
+	}
+	cacheDir, remote := ".cache", ""
+	if metaAll.Build != nil {
+		if metaAll.Build.CacheDir != "" {
+			cacheDir = metaAll.Build.CacheDir
+		}
+		remote = metaAll.Build.CacheRemote
+	}
+	return filepath.Join(srcDir, cacheDir), remote, nil
+}
+
+// ReadDirSetting return destination and theme directories in build settings of meta in source directory,
+// they are relative to source directory and empty if not set
+func ReadDirSetting(srcDir string) (string, string, error) {
+	metaAll, err := ReadSecondMeta(srcDir)
+	if err != nil || metaAll.Build == nil {
+		return "", "", err
+	}
+	var dest, theme string
+	if metaAll.Build.Dest != "" {
+		dest = filepath.Join(srcDir, metaAll.Build.Dest)
+	}
+	if metaAll.Build.Theme != "" {
+		theme = filepath.Join(srcDir, metaAll.Build.Theme)
+	}
+	return dest, theme, nil
+}
+
+// IsSharedCache checks relative path in cache directory is shared with remote,
+// kept build outputs are not shared
+func IsSharedCache(rel string) bool {
+	return rel != artifactDir && !strings.HasPrefix(rel, artifactDir+"/")
+}
+
 // DstDir get destination directory after build once
 func (ctx *Context) DstDir() string {
 	ctx.parseDir()
@@ -180,7 +387,7 @@ func (ctx *Context) parseDir() {
 		return
 	}
 	ctx.srcDir = srcDir
-	log15.Info("Read|%s", srcDir)
+	ctx.Log().Info("Read|%s", srcDir)
 
 	if destDir, ctx.Err = toDir(ctx.To); ctx.Err != nil {
 		return
@@ -197,3 +404,24 @@ func toDir(urlString string) (string, error) {
 	}
 	return "", errors.New("Directory need schema dir://")
 }
+
+// Log return logger of the context, it's root logger if Logger is not set.
+// Warnings are counted when building.
+func (ctx *Context) Log() log15.Logger {
+	if ctx.log != nil {
+		return ctx.log
+	}
+	return ctx.logger()
+}
+
+func (ctx *Context) logger() log15.Logger {
+	if ctx.Logger == nil {
+		return log15.Root()
+	}
+	return ctx.Logger
+}
+
+// Warnings return count of warning and error logs in last building
+func (ctx *Context) Warnings() int64 {
+	return atomic.LoadInt64(&ctx.warnings)
+}
@@ -0,0 +1,126 @@
+package builder
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/Unknwon/com"
+)
+
+type (
+	// FileDiff is a file different between two directories, size is 0 if file is missing
+	FileDiff struct {
+		File    string
+		OldSize int64
+		NewSize int64
+	}
+	// DirDiff is summary of differences between two directories
+	DirDiff struct {
+		Added   []FileDiff
+		Removed []FileDiff
+		Changed []FileDiff
+		Same    int
+	}
+)
+
+// Delta return size delta of file
+func (fd FileDiff) Delta() int64 {
+	return fd.NewSize - fd.OldSize
+}
+
+// Delta return total size delta of all files
+func (dd *DirDiff) Delta() int64 {
+	var delta int64
+	for _, list := range [][]FileDiff{dd.Added, dd.Removed, dd.Changed} {
+		for _, fd := range list {
+			delta += fd.Delta()
+		}
+	}
+	return delta
+}
+
+// IsSame return whether two directories are same
+func (dd *DirDiff) IsSame() bool {
+	return len(dd.Added) == 0 && len(dd.Removed) == 0 && len(dd.Changed) == 0
+}
+
+// DiffDirs compares files in oldDir and newDir, files are compared by content.
+// Missing oldDir is treated as empty.
+func DiffDirs(oldDir, newDir string) (*DirDiff, error) {
+	newFiles, err := walkSizes(newDir)
+	if err != nil {
+		return nil, err
+	}
+	oldFiles := make(map[string]int64)
+	if com.IsDir(oldDir) {
+		if oldFiles, err = walkSizes(oldDir); err != nil {
+			return nil, err
+		}
+	}
+	diff := new(DirDiff)
+	for f, size := range newFiles {
+		oldSize, ok := oldFiles[f]
+		if !ok {
+			diff.Added = append(diff.Added, FileDiff{File: f, NewSize: size})
+			continue
+		}
+		same := oldSize == size
+		if same {
+			if same, err = sameContent(filepath.Join(oldDir, f), filepath.Join(newDir, f)); err != nil {
+				return nil, err
+			}
+		}
+		if same {
+			diff.Same++
+			continue
+		}
+		diff.Changed = append(diff.Changed, FileDiff{File: f, OldSize: oldSize, NewSize: size})
+	}
+	for f, size := range oldFiles {
+		if _, ok := newFiles[f]; !ok {
+			diff.Removed = append(diff.Removed, FileDiff{File: f, OldSize: size})
+		}
+	}
+	for _, list := range [][]FileDiff{diff.Added, diff.Removed, diff.Changed} {
+		sort.Sort(fileDiffs(list))
+	}
+	return diff, nil
+}
+
+type fileDiffs []FileDiff
+
+func (fd fileDiffs) Len() int           { return len(fd) }
+func (fd fileDiffs) Less(i, j int) bool { return fd[i].File < fd[j].File }
+func (fd fileDiffs) Swap(i, j int)      { fd[i], fd[j] = fd[j], fd[i] }
+
+// walkSizes return sizes of files in dir by relative path
+func walkSizes(dir string) (map[string]int64, error) {
+	files := make(map[string]int64)
+	err := filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, p)
+		files[filepath.ToSlash(rel)] = fi.Size()
+		return nil
+	})
+	return files, err
+}
+
+func sameContent(file1, file2 string) (bool, error) {
+	data1, err := ioutil.ReadFile(file1)
+	if err != nil {
+		return false, err
+	}
+	data2, err := ioutil.ReadFile(file2)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(data1, data2), nil
+}
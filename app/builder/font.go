@@ -0,0 +1,141 @@
+package builder
+
+import (
+	"bytes"
+	"html"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-xiaohei/pugo/app/helper"
+)
+
+var (
+	htmlHiddenRegex = regexp.MustCompile(`(?is)<(script|style)[^>]*>.*?</(script|style)>`)
+	htmlTagRegex    = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// compileFonts subsets fonts in build settings to characters in html pages,
+// and rewrites references of fonts in css and html files to subset fonts
+func compileFonts(ctx *Context) error {
+	if ctx.Source.Build == nil || len(ctx.Source.Build.Fonts) == 0 {
+		return nil
+	}
+	fonts := ctx.Source.Build.Fonts
+	chars, err := fontChars(ctx.DstDir())
+	if err != nil {
+		return err
+	}
+	ctx.Log().Debug("Build|Fonts|%d characters", len(chars))
+
+	names := make(map[string]string, len(fonts))
+	for _, f := range fonts {
+		rel := ctx.assetPath(f.File)
+		file := filepath.Join(ctx.DstDir(), rel)
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			ctx.Log().Warn("Build|Fonts|%s|%s", f.File, err.Error())
+			continue
+		}
+		keep := make(map[rune]bool, len(chars)+len(f.Chars))
+		for r := range chars {
+			keep[r] = true
+		}
+		for _, r := range f.Chars {
+			keep[r] = true
+		}
+		subset, err := helper.SubsetFont(data, keep)
+		if err != nil {
+			// such as CFF or WOFF2 fonts, the font is kept and not subset
+			ctx.Log().Warn("Build|Fonts|%s|Skip|%s", f.File, err.Error())
+			continue
+		}
+		ext := path.Ext(rel)
+		name := strings.TrimSuffix(path.Base(rel), ext) + ".subset." + helper.Md5(string(subset))[:8] + ext
+		if err = writeChanged(ctx, filepath.Join(filepath.Dir(file), name), subset); err != nil {
+			return err
+		}
+		ctx.Log().Info("Build|Fonts|%s|%d -> %d bytes", rel, len(data), len(subset))
+		names[path.Base(rel)] = name
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	return rewriteFontRefs(ctx, fontRefsRegexp(names), names)
+}
+
+// fontRefsRegexp matches font names in urls,
+// name is after slash, quote or parenthesis, and before quote, parenthesis, query or hash,
+// so "a.woff" does not match "a.woff2" or "ba.woff"
+func fontRefsRegexp(names map[string]string) *regexp.Regexp {
+	quoted := make([]string, 0, len(names))
+	for name := range names {
+		quoted = append(quoted, regexp.QuoteMeta(name))
+	}
+	sort.Strings(quoted)
+	return regexp.MustCompile(`([/("'])(` + strings.Join(quoted, "|") + `)([)"'?#\s])`)
+}
+
+// fontChars return characters in text of html pages in dir
+func fontChars(dir string) (map[rune]bool, error) {
+	chars := make(map[rune]bool)
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || path.Ext(p) != ".html" {
+			return nil
+		}
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		for _, r := range htmlText(data) {
+			if r != utf8.RuneError && r >= 0x20 {
+				chars[r] = true
+			}
+		}
+		return nil
+	})
+	return chars, err
+}
+
+// htmlText return text in html without tags, scripts and styles
+func htmlText(data []byte) string {
+	data = htmlHiddenRegex.ReplaceAll(data, nil)
+	data = htmlTagRegex.ReplaceAll(data, []byte(" "))
+	return html.UnescapeString(string(data))
+}
+
+// rewriteFontRefs replaces font names matched by re to subset names in css and html files in destination
+func rewriteFontRefs(ctx *Context, re *regexp.Regexp, names map[string]string) error {
+	return filepath.Walk(ctx.DstDir(), func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if ext := path.Ext(p); info.IsDir() || (ext != ".css" && ext != ".html") {
+			return nil
+		}
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		newData := re.ReplaceAllFunc(data, func(m []byte) []byte {
+			sub := re.FindSubmatch(m)
+			return []byte(string(sub[1]) + names[string(sub[2])] + string(sub[3]))
+		})
+		if bytes.Equal(data, newData) {
+			return nil
+		}
+		if err = ioutil.WriteFile(p, newData, os.ModePerm); err != nil {
+			return err
+		}
+		ctx.Log().Debug("Build|Fonts|%s", p)
+		return ctx.Sync.Rehash(p)
+	})
+}
@@ -0,0 +1,78 @@
+package builder
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"path"
+
+	"github.com/go-xiaohei/pugo/app/model"
+)
+
+const (
+	formspreeURL   = "https://formspree.io/f/"
+	staticFormsURL = "https://api.staticforms.xyz/submit"
+)
+
+var formTpl = template.Must(template.New("form").Parse(`<form class="pugo-form" name="{{.Form.Name}}" action="{{.Action}}" method="POST"{{if .Netlify}} data-netlify="true" netlify-honeypot="{{.Honeypot}}"{{end}}>
+{{- if .Netlify}}
+<input type="hidden" name="form-name" value="{{.Form.Name}}"/>
+{{- end}}
+{{- range .Hidden}}
+<input type="hidden" name="{{index . 0}}" value="{{index . 1}}"/>
+{{- end}}
+<p style="display:none"><label>Leave empty <input type="text" name="{{.Honeypot}}" tabindex="-1" autocomplete="off"/></label></p>
+{{- range .Form.Fields}}
+<p><label for="{{$.Form.Name}}-{{.Name}}">{{.Label}}</label>
+{{- if eq .Type "textarea"}}
+<textarea id="{{$.Form.Name}}-{{.Name}}" name="{{.Name}}"{{if .Required}} required{{end}}></textarea></p>
+{{- else}}
+<input id="{{$.Form.Name}}-{{.Name}}" type="{{.Type}}" name="{{.Name}}"{{if .Required}} required{{end}}/></p>
+{{- end}}
+{{- end}}
+<p><button type="submit">Send</button></p>
+</form>
+{{- if .Token}}
+<script>(function(f){f.addEventListener("focusin",function(){var x=new XMLHttpRequest();x.open("GET",f.action);x.setRequestHeader("Cache-Control","no-cache");x.onload=function(){if(x.status==200)f.elements[{{.Token}}].value=x.responseText};x.send()},{once:true})})(document.currentScript.previousElementSibling)</script>
+{{- end}}`))
+
+// formHTML renders form by name, the form is wired to its provider
+func formHTML(ctx *Context, name string) (template.HTML, error) {
+	f := ctx.Source.Forms.Get(name)
+	if f == nil {
+		return "", fmt.Errorf("form '%s' is not found", name)
+	}
+	data := map[string]interface{}{
+		"Form":     f,
+		"Honeypot": model.FormHoneypot,
+	}
+	var hidden [][2]string
+	switch f.Provider {
+	case model.FormFormspree:
+		data["Action"] = formspreeURL + f.Key
+		hidden = append(hidden, [2]string{"_subject", f.Subject})
+		if f.Success != "" {
+			hidden = append(hidden, [2]string{"_next", f.Success})
+		}
+	case model.FormNetlify:
+		data["Action"] = f.Success
+		data["Netlify"] = true
+	case model.FormStaticForms:
+		data["Action"] = staticFormsURL
+		hidden = append(hidden, [2]string{"accessKey", f.Key}, [2]string{"subject", f.Subject})
+		if f.Success != "" {
+			hidden = append(hidden, [2]string{"redirectTo", f.Success})
+		}
+	case model.FormServer:
+		// token expires, so it's requested from server when the form is filled
+		data["Action"] = path.Join("/", ctx.Source.Meta.Path, model.FormEndpoint, f.Name)
+		data["Token"] = model.FormTokenField
+		hidden = append(hidden, [2]string{model.FormTokenField, ""})
+	}
+	data["Hidden"] = hidden
+	var buf bytes.Buffer
+	if err := formTpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return template.HTML(buf.String()), nil
+}
@@ -0,0 +1,138 @@
+package builder
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/Unknwon/com"
+	"github.com/go-xiaohei/pugo/app/helper"
+)
+
+// CompareGolden compares html files in dstDir with golden snapshots in goldenDir,
+// it returns differences of files.
+// If update is true, golden snapshots are replaced by html files in dstDir.
+func CompareGolden(dstDir, goldenDir string, update bool) ([]string, error) {
+	built, err := walkHTML(dstDir)
+	if err != nil {
+		return nil, err
+	}
+	if update {
+		// only replace html snapshots, golden dir may be set to a directory with other files by mistake
+		golden, err := goldenSnapshots(goldenDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range golden {
+			if err = os.Remove(filepath.Join(goldenDir, f)); err != nil {
+				return nil, err
+			}
+		}
+		for _, f := range built {
+			dst := filepath.Join(goldenDir, f)
+			os.MkdirAll(filepath.Dir(dst), os.ModePerm)
+			if err = helper.CopyFile(filepath.Join(dstDir, f), dst); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	}
+
+	var golden []string
+	if com.IsDir(goldenDir) {
+		if golden, err = walkHTML(goldenDir); err != nil {
+			return nil, err
+		}
+	}
+	var (
+		diffs     []string
+		isBuilt   = make(map[string]bool)
+		hasGolden = make(map[string]bool)
+	)
+	for _, f := range golden {
+		hasGolden[f] = true
+	}
+	for _, f := range built {
+		isBuilt[f] = true
+		if !hasGolden[f] {
+			diffs = append(diffs, fmt.Sprintf("%s: no snapshot", f))
+			continue
+		}
+		data1, err := ioutil.ReadFile(filepath.Join(dstDir, f))
+		if err != nil {
+			return nil, err
+		}
+		data2, err := ioutil.ReadFile(filepath.Join(goldenDir, f))
+		if err != nil {
+			return nil, err
+		}
+		if line := diffLine(data1, data2); line > 0 {
+			diffs = append(diffs, fmt.Sprintf("%s: changed at line %d", f, line))
+		}
+	}
+	for _, f := range golden {
+		if !isBuilt[f] {
+			diffs = append(diffs, fmt.Sprintf("%s: not built", f))
+		}
+	}
+	sort.Strings(diffs)
+	return diffs, nil
+}
+
+// goldenSnapshots return html snapshots in goldenDir to be updated,
+// it fails if goldenDir contains other files
+func goldenSnapshots(goldenDir string) ([]string, error) {
+	if !com.IsExist(goldenDir) {
+		return nil, nil
+	}
+	if !com.IsDir(goldenDir) {
+		return nil, fmt.Errorf("golden snapshots '%s' is not a directory", goldenDir)
+	}
+	err := filepath.Walk(goldenDir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() && filepath.Ext(p) != ".html" {
+			return fmt.Errorf("golden snapshots '%s' contains non-html file '%s', refuse to update", goldenDir, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return walkHTML(goldenDir)
+}
+
+// walkHTML return relative paths of html files in dir
+func walkHTML(dir string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() || filepath.Ext(p) != ".html" {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, p)
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	return files, err
+}
+
+// diffLine return first different line number of two data, or 0 if they are same
+func diffLine(data1, data2 []byte) int {
+	if bytes.Equal(data1, data2) {
+		return 0
+	}
+	lines1, lines2 := bytes.Split(data1, []byte("\n")), bytes.Split(data2, []byte("\n"))
+	for i := range lines1 {
+		if i >= len(lines2) || !bytes.Equal(lines1[i], lines2[i]) {
+			return i + 1
+		}
+	}
+	return len(lines1) + 1
+}
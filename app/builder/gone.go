@@ -0,0 +1,148 @@
+package builder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+
+	"github.com/go-xiaohei/pugo/app/model"
+)
+
+const (
+	goneCacheFile = "sitemap.json"
+	// GoneFile lists url paths of removed pages, one path per line
+	GoneFile = "gone.txt"
+)
+
+// goneManifest is urls of last compiled pages and urls that are gone
+type goneManifest struct {
+	URLs []string `json:"urls"`
+	Gone []string `json:"gone"`
+}
+
+// compiledPaths return url paths of html pages compiled in this building,
+// "index.html" is trimmed as it's served by directory url.
+// Pages excluded from sitemap by index rules are still compiled, so they are not gone
+func compiledPaths(ctx *Context) ([]string, error) {
+	var paths []string
+	err := filepath.Walk(ctx.DstDir(), func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() || path.Ext(p) != ".html" {
+			return nil
+		}
+		if from, ok := ctx.Sync.SyncedFrom(p); !ok || from != "" {
+			return nil
+		}
+		rel, _ := filepath.Rel(ctx.DstDir(), p)
+		u := "/" + filepath.ToSlash(rel)
+		if path.Base(u) == "index.html" {
+			u = path.Dir(u)
+		}
+		paths = append(paths, u)
+		return nil
+	})
+	return paths, err
+}
+
+// compileGone compares compiled pages with last building,
+// urls of removed pages are written to gone.txt and gone rules,
+// so hosts can respond 410 Gone instead of 404 Not Found.
+// Gone urls are kept until they are compiled again or redirected.
+func compileGone(ctx *Context) error {
+	paths, err := compiledPaths(ctx)
+	if err != nil {
+		return err
+	}
+	cacheFile := filepath.Join(ctx.CacheDir(), goneCacheFile)
+	old := new(goneManifest)
+	if data, err := ioutil.ReadFile(cacheFile); err == nil {
+		if err = json.Unmarshal(data, old); err != nil {
+			ctx.Log().Warn("Build|Gone|%s", err.Error())
+		}
+	}
+
+	current := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		current[p] = true
+	}
+	// redirected urls are not gone
+	redirected := redirectedPaths(ctx)
+	gone := make(map[string]bool)
+	for _, p := range append(old.URLs, old.Gone...) {
+		if !current[p] && !redirected[p] {
+			gone[p] = true
+		}
+	}
+	manifest := &goneManifest{URLs: paths}
+	for p := range gone {
+		manifest.Gone = append(manifest.Gone, p)
+	}
+	sort.Strings(manifest.URLs)
+	sort.Strings(manifest.Gone)
+
+	data, _ := json.MarshalIndent(manifest, "", "  ")
+	os.MkdirAll(filepath.Dir(cacheFile), os.ModePerm)
+	if err = ioutil.WriteFile(cacheFile, data, os.ModePerm); err != nil {
+		return err
+	}
+	if len(manifest.Gone) == 0 {
+		return nil
+	}
+	ctx.Log().Info("Build|Gone|%d urls", len(manifest.Gone))
+
+	files := map[string][]byte{GoneFile: goneRules("", manifest.Gone)}
+	if ctx.Source.Build != nil {
+		for _, format := range ctx.Source.Build.GoneRules {
+			files["gone."+format+".conf"] = goneRules(format, manifest.Gone)
+		}
+	}
+	for name, data := range files {
+		dstFile := path.Join(ctx.DstDir(), ctx.Source.Meta.Path, name)
+		if err = ioutil.WriteFile(dstFile, data, os.ModePerm); err != nil {
+			return err
+		}
+		ctx.Sync.SetSynced(dstFile)
+		ctx.Log().Debug("Build|%s", dstFile)
+		atomic.AddInt64(&ctx.counter, 1)
+	}
+	return nil
+}
+
+// goneRules return gone urls in format, empty format is plain list
+func goneRules(format string, gone []string) []byte {
+	var buf bytes.Buffer
+	for _, p := range gone {
+		switch format {
+		case model.GoneNginx:
+			fmt.Fprintf(&buf, "location = %s { return 410; }\n", p)
+		case model.GoneApache:
+			fmt.Fprintf(&buf, "Redirect gone %s\n", p)
+		default:
+			buf.WriteString(p + "\n")
+		}
+	}
+	return buf.Bytes()
+}
+
+// ReadGone return url paths in gone.txt of last building
+func ReadGone(ctx *Context) []string {
+	data, err := ioutil.ReadFile(path.Join(ctx.DstDir(), ctx.Source.Meta.Path, GoneFile))
+	if err != nil {
+		return nil
+	}
+	var gone []string
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			gone = append(gone, string(line))
+		}
+	}
+	return gone
+}
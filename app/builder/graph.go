@@ -0,0 +1,170 @@
+package builder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// GraphPost is kind of post node in content graph
+	GraphPost = "post"
+	// GraphPage is kind of page node in content graph
+	GraphPage = "page"
+)
+
+var graphLinkRegex = regexp.MustCompile(`(?is)<a\s[^>]*?href="([^"]*)"`)
+
+type (
+	// GraphNode is a post or page in content graph
+	GraphNode struct {
+		URL   string   `json:"url"`
+		Title string   `json:"title"`
+		Kind  string   `json:"kind"`
+		Links []string `json:"links"`
+		// Backlinks is count of other contents linking to the node
+		Backlinks int `json:"backlinks"`
+		// Nav means the node is linked in navigation
+		Nav bool `json:"nav,omitempty"`
+	}
+	// Graph is internal links between posts and pages in content
+	Graph struct {
+		Nodes []*GraphNode `json:"nodes"`
+		// Orphans are not linked by other contents or navigation
+		Orphans []string `json:"orphans"`
+		// DeadEnds link to no other contents
+		DeadEnds []string `json:"dead_ends"`
+	}
+)
+
+// LinkGraph return graph of links in content of posts and pages,
+// it need be used after source is assembled
+func LinkGraph(ctx *Context) *Graph {
+	g := new(Graph)
+	nodes := make(map[string]*GraphNode)
+	contents := make(map[string][]byte)
+	for _, p := range ctx.Source.Posts {
+		n := &GraphNode{URL: p.URL(), Title: p.Title, Kind: GraphPost, Links: []string{}}
+		nodes[n.URL], contents[n.URL] = n, p.Content()
+		g.Nodes = append(g.Nodes, n)
+	}
+	for _, p := range ctx.Source.Pages {
+		if p.Node {
+			continue
+		}
+		n := &GraphNode{URL: p.URL(), Title: p.Title, Kind: GraphPage, Links: []string{}}
+		nodes[n.URL], contents[n.URL] = n, p.Content()
+		g.Nodes = append(g.Nodes, n)
+	}
+
+	host := ""
+	if u, err := url.Parse(ctx.Source.Meta.Root); err == nil {
+		host = u.Host
+	}
+	// find node of link, links in content may omit .html or index.html
+	find := func(from, link string) *GraphNode {
+		u, err := url.Parse(link)
+		if err != nil || (u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https") {
+			return nil
+		}
+		if u.Host != "" && u.Host != host {
+			return nil
+		}
+		p := u.Path
+		if p == "" {
+			return nil
+		}
+		if !strings.HasPrefix(p, "/") {
+			p = path.Join(path.Dir(from), p)
+		}
+		p = path.Clean(p)
+		for _, candidate := range []string{p, p + ".html", path.Join(p, "index.html")} {
+			if n := nodes[candidate]; n != nil {
+				return n
+			}
+		}
+		return nil
+	}
+
+	for _, n := range g.Nodes {
+		linked := make(map[string]bool)
+		for _, sub := range graphLinkRegex.FindAllSubmatch(contents[n.URL], -1) {
+			to := find(n.URL, string(sub[1]))
+			if to == nil || to == n || linked[to.URL] {
+				continue
+			}
+			linked[to.URL] = true
+			n.Links = append(n.Links, to.URL)
+			to.Backlinks++
+		}
+		sort.Strings(n.Links)
+	}
+	for _, nav := range ctx.Source.Nav {
+		if nav.IsRemote {
+			continue
+		}
+		if n := find("/", nav.Link); n != nil {
+			n.Nav = true
+		}
+	}
+
+	sort.Sort(graphNodes(g.Nodes))
+	for _, n := range g.Nodes {
+		if n.Backlinks == 0 && !n.Nav {
+			g.Orphans = append(g.Orphans, n.URL)
+		}
+		if len(n.Links) == 0 {
+			g.DeadEnds = append(g.DeadEnds, n.URL)
+		}
+	}
+	return g
+}
+
+type graphNodes []*GraphNode
+
+func (gn graphNodes) Len() int           { return len(gn) }
+func (gn graphNodes) Less(i, j int) bool { return gn[i].URL < gn[j].URL }
+func (gn graphNodes) Swap(i, j int)      { gn[i], gn[j] = gn[j], gn[i] }
+
+// JSON return graph in json
+func (g *Graph) JSON() ([]byte, error) {
+	return json.MarshalIndent(g, "", "  ")
+}
+
+// DOT return graph in graphviz dot language,
+// orphans are red and dead-ends are dashed
+func (g *Graph) DOT() []byte {
+	orphans := make(map[string]bool)
+	for _, u := range g.Orphans {
+		orphans[u] = true
+	}
+	var buf bytes.Buffer
+	buf.WriteString("digraph pugo {\n\trankdir=LR;\n\tnode [shape=box];\n")
+	for _, n := range g.Nodes {
+		var attrs []string
+		attrs = append(attrs, "label="+strconv.Quote(n.Title))
+		if n.Kind == GraphPage {
+			attrs = append(attrs, "shape=note")
+		}
+		if orphans[n.URL] {
+			attrs = append(attrs, "color=red")
+		}
+		if len(n.Links) == 0 {
+			attrs = append(attrs, "style=dashed")
+		}
+		fmt.Fprintf(&buf, "\t%s [%s];\n", strconv.Quote(n.URL), strings.Join(attrs, ", "))
+	}
+	for _, n := range g.Nodes {
+		for _, to := range n.Links {
+			fmt.Fprintf(&buf, "\t%s -> %s;\n", strconv.Quote(n.URL), strconv.Quote(to))
+		}
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
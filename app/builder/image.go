@@ -0,0 +1,131 @@
+package builder
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/go-xiaohei/pugo/app/model"
+)
+
+var (
+	imageTagRegex   = regexp.MustCompile(`(?is)<img\s[^>]*>`)
+	imageAttrRegex  = regexp.MustCompile(`(?is)\s(src|alt)="([^"]*)"`)
+	imageAltRegex   = regexp.MustCompile(`(?is)\salt="[^"]*"`)
+	imageFigureHTML = regexp.MustCompile(`(?is)<p>\s*(<img\s[^>]*>)\s*</p>`)
+)
+
+// imageMetas caches sidecar metadata of media images by url
+type imageMetas struct {
+	lock   sync.Mutex
+	ctx    *Context
+	metas  map[string]*model.ImageMeta
+	warned map[string]bool
+}
+
+func newImageMetas(ctx *Context) *imageMetas {
+	return &imageMetas{
+		ctx:    ctx,
+		metas:  make(map[string]*model.ImageMeta),
+		warned: make(map[string]bool),
+	}
+}
+
+// Get return metadata of image url in media directory,
+// it return nil if the image has no sidecar file
+func (im *imageMetas) Get(src string) *model.ImageMeta {
+	u, err := url.Parse(src)
+	if err != nil || u.Host != "" {
+		return nil
+	}
+	// path is cleaned, so sidecar files out of media directory are not read
+	mediaURL, urlPath := path.Join("/", im.ctx.Source.Meta.Path, "media")+"/", path.Clean("/"+u.Path)
+	if !strings.HasPrefix(urlPath, mediaURL) {
+		return nil
+	}
+	im.lock.Lock()
+	defer im.lock.Unlock()
+	if m, ok := im.metas[urlPath]; ok {
+		return m
+	}
+	file := filepath.Join(im.ctx.SrcMediaDir(), filepath.FromSlash(strings.TrimPrefix(urlPath, mediaURL)))
+	m, err := model.ReadImageMeta(file)
+	if err != nil {
+		im.ctx.Log().Warn("Assemble|Image|%s", err.Error())
+	}
+	im.metas[urlPath] = m
+	return m
+}
+
+// Apply fills alt text of images in content from sidecar files,
+// images alone in paragraph with caption or credit are rendered as figure.
+// It warns images without alt text in file.
+func (im *imageMetas) Apply(file string, content []byte) []byte {
+	content = imageFigureHTML.ReplaceAllFunc(content, func(p []byte) []byte {
+		img := imageFigureHTML.FindSubmatch(p)[1]
+		m := im.Get(imageAttr(img, "src"))
+		if m == nil || (m.Caption == "" && m.Credit() == "") {
+			return p
+		}
+		var buf bytes.Buffer
+		buf.WriteString(`<figure>`)
+		buf.Write(img)
+		buf.WriteString(`<figcaption>`)
+		buf.WriteString(html.EscapeString(m.Caption))
+		if credit := m.Credit(); credit != "" {
+			if m.Caption != "" {
+				buf.WriteString(" ")
+			}
+			if m.Link != "" {
+				fmt.Fprintf(&buf, `<small><a href="%s">%s</a></small>`, html.EscapeString(m.Link), html.EscapeString(credit))
+			} else {
+				fmt.Fprintf(&buf, `<small>%s</small>`, html.EscapeString(credit))
+			}
+		}
+		buf.WriteString(`</figcaption></figure>`)
+		return buf.Bytes()
+	})
+	return imageTagRegex.ReplaceAllFunc(content, func(img []byte) []byte {
+		src := imageAttr(img, "src")
+		if strings.TrimSpace(imageAttr(img, "alt")) != "" {
+			return img
+		}
+		m := im.Get(src)
+		if m == nil || m.Alt == "" {
+			if !im.warned[file+src] {
+				im.ctx.Log().Warn("Assemble|Image|%s|'%s' has no alt text", file, src)
+				im.warned[file+src] = true
+			}
+			return img
+		}
+		alt := ` alt="` + html.EscapeString(m.Alt) + `"`
+		if loc := imageAltRegex.FindIndex(img); loc != nil {
+			return append(append(append([]byte(nil), img[:loc[0]]...), alt...), img[loc[1]:]...)
+		}
+		i := bytes.Index(img, []byte("<img")) + len("<img")
+		return append(append(append([]byte(nil), img[:i]...), alt...), img[i:]...)
+	})
+}
+
+// imageReplacer return func to apply image metadata in content of file
+func imageReplacer(ctx *Context, file string) func([]byte) []byte {
+	return func(content []byte) []byte {
+		return ctx.images.Apply(file, content)
+	}
+}
+
+// imageAttr return unescaped value of attribute in img tag
+func imageAttr(img []byte, name string) string {
+	for _, m := range imageAttrRegex.FindAllSubmatch(img, -1) {
+		if strings.ToLower(string(m[1])) == name {
+			return html.UnescapeString(string(m[2]))
+		}
+	}
+	return ""
+}
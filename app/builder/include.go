@@ -0,0 +1,42 @@
+package builder
+
+import (
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// includeDeps maps included files to content files that include them
+type includeDeps struct {
+	sync.RWMutex
+	files map[string][]string
+}
+
+func (ctx *Context) resetIncludes() {
+	ctx.includes.Lock()
+	ctx.includes.files = make(map[string][]string)
+	ctx.includes.Unlock()
+}
+
+func (ctx *Context) addIncludes(file string, includes []string) {
+	ctx.includes.Lock()
+	defer ctx.includes.Unlock()
+	if ctx.includes.files == nil {
+		ctx.includes.files = make(map[string][]string)
+	}
+	file = filepath.ToSlash(filepath.Clean(file))
+	for _, f := range includes {
+		f = filepath.ToSlash(f)
+		ctx.includes.files[f] = append(ctx.includes.files[f], file)
+	}
+}
+
+// IncludedBy return content files that include the file directly or by other included files,
+// they are rebuilt after the file changes
+func (ctx *Context) IncludedBy(file string) []string {
+	ctx.includes.RLock()
+	defer ctx.includes.RUnlock()
+	files := append([]string(nil), ctx.includes.files[filepath.ToSlash(filepath.Clean(file))]...)
+	sort.Strings(files)
+	return files
+}
@@ -0,0 +1,87 @@
+package builder
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-xiaohei/pugo/app/helper"
+	"github.com/go-xiaohei/pugo/app/model"
+)
+
+type (
+	// indexRules decides whether posts and pages are excluded from sitemap, feed and json api
+	indexRules struct {
+		// base is path of site root, it's trimmed from urls before matching
+		base  string
+		rules []indexRule
+	}
+	indexRule struct {
+		glob    *regexp.Regexp
+		exclude map[string]bool
+		robots  string
+	}
+)
+
+func newIndexRules(base string, rules []*model.IndexRule) (*indexRules, error) {
+	ir := &indexRules{base: strings.TrimRight(base, "/")}
+	for _, r := range rules {
+		re, err := helper.GlobRegexp(r.Glob)
+		if err != nil {
+			return nil, fmt.Errorf("index rule '%s': %s", r.Glob, err.Error())
+		}
+		rule := indexRule{glob: re, exclude: make(map[string]bool), robots: r.Robots}
+		for _, e := range r.Exclude {
+			rule.exclude[e] = true
+		}
+		ir.rules = append(ir.rules, rule)
+	}
+	return ir, nil
+}
+
+// match return the first rule matching url of content, or nil
+func (ir *indexRules) match(url string) *indexRule {
+	if ir == nil {
+		return nil
+	}
+	rel := strings.TrimLeft(strings.TrimPrefix(url, ir.base), "/")
+	for i, r := range ir.rules {
+		if r.glob.MatchString(rel) {
+			return &ir.rules[i]
+		}
+	}
+	return nil
+}
+
+// isUnindexed return whether content of url is excluded from index,
+// noindex is setting in front-matter of the content
+func (ctx *Context) isUnindexed(url string, noindex bool, index string) bool {
+	if noindex {
+		return true
+	}
+	r := ctx.indexRules.match(url)
+	return r != nil && r.exclude[index]
+}
+
+// indexedPosts return posts that are not excluded from index
+func (ctx *Context) indexedPosts(posts []*model.Post, index string) []*model.Post {
+	var list []*model.Post
+	for _, p := range posts {
+		if !ctx.isUnindexed(p.URL(), p.NoIndex, index) {
+			list = append(list, p)
+		}
+	}
+	return list
+}
+
+// robots return content of robots meta tag of content,
+// it's "noindex" if noindex is set in front-matter and no rule sets robots
+func (ctx *Context) robots(url string, noindex bool) string {
+	if r := ctx.indexRules.match(url); r != nil && r.robots != "" {
+		return r.robots
+	}
+	if noindex {
+		return "noindex"
+	}
+	return ""
+}
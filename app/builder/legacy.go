@@ -0,0 +1,18 @@
+package builder
+
+import "regexp"
+
+// LegacyVariant is variant name of templates for old browsers, such as "post.legacy.html"
+const LegacyVariant = "legacy"
+
+var (
+	legacyModuleRegex  = regexp.MustCompile(`(?is)<script\s[^>]*type=["']?module["']?[^>]*>.*?</script>\s*`)
+	legacyPreloadRegex = regexp.MustCompile(`(?is)<link\s[^>]*rel=["']?modulepreload["']?[^>]*>\s*`)
+)
+
+// stripModules removes module scripts and their preload links in html,
+// old browsers do not run them but still download them
+func stripModules(data []byte) []byte {
+	data = legacyModuleRegex.ReplaceAll(data, nil)
+	return legacyPreloadRegex.ReplaceAll(data, nil)
+}
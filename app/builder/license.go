@@ -0,0 +1,69 @@
+package builder
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-xiaohei/pugo/app/model"
+)
+
+// AttributionsSlug is slug of generated page that lists third-party assets
+const AttributionsSlug = "attributions"
+
+// appendAttributionsPage generates page of assets in attributions.toml,
+// it's skipped if a page with the same slug exists
+func appendAttributionsPage(ctx *Context) error {
+	file := filepath.Join(ctx.SrcDir(), model.AttributionsFile)
+	assets, err := model.ReadAttributions(file)
+	if err != nil || len(assets) == 0 {
+		return err
+	}
+	for _, p := range ctx.Source.Pages {
+		if p.Slug == AttributionsSlug {
+			ctx.Log().Warn("Assemble|Attributions|page '%s' exists, skip generating", p.SourceURL())
+			return nil
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("```toml\n")
+	fmt.Fprintf(&buf, "title = %q\nslug = %q\n", "Attributions", AttributionsSlug)
+	buf.WriteString("```\n\n")
+	if l := ctx.Source.License; l.IsValid() {
+		fmt.Fprintf(&buf, "Content of this site is licensed under [%s](%s) unless otherwise noted.\n\n", l.Title, l.URL)
+	}
+	buf.WriteString("This site uses these third-party assets:\n\n")
+	for _, a := range assets {
+		name := markdownEscape(a.Name)
+		if a.URL != "" {
+			name = fmt.Sprintf("[%s](%s)", name, a.URL)
+		}
+		buf.WriteString("- " + name)
+		if a.Author != "" {
+			buf.WriteString(" by " + markdownEscape(a.Author))
+		}
+		if a.License != nil {
+			fmt.Fprintf(&buf, ", licensed under [%s](%s)", markdownEscape(a.License.Title), a.License.URL)
+		}
+		if len(a.Files) > 0 {
+			buf.WriteString(": `" + strings.Join(a.Files, "`, `") + "`")
+		}
+		buf.WriteString("\n")
+	}
+
+	p, err := model.NewPageOfBytes(file, AttributionsSlug, buf.Bytes(), nil)
+	if err != nil {
+		return err
+	}
+	ctx.Source.Pages = append(ctx.Source.Pages, p)
+	ctx.Log().Debug("Assemble|Attributions|%d assets", len(assets))
+	return nil
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`, "`", "\\`")
+
+func markdownEscape(s string) string {
+	return markdownEscaper.Replace(s)
+}
@@ -0,0 +1,101 @@
+package builder
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+	"github.com/Unknwon/com"
+	"github.com/go-xiaohei/pugo/app/vars"
+)
+
+const (
+	// LockFile records versions of PuGo, config schema and theme used by site
+	LockFile = "pugo.lock"
+	// SchemaVersion is version of config and content schema,
+	// it increases when a migration is added
+	SchemaVersion = 2
+)
+
+// Lock is versions in lock file
+type Lock struct {
+	PuGo         string `toml:"pugo"`
+	Schema       int    `toml:"schema"`
+	Theme        string `toml:"theme"`
+	ThemeVersion string `toml:"theme_version"`
+}
+
+// ReadLock reads lock file in srcDir,
+// it returns nil if lock file is missing
+func ReadLock(srcDir string) (*Lock, error) {
+	file := filepath.Join(srcDir, LockFile)
+	if !com.IsFile(file) {
+		return nil, nil
+	}
+	lock := new(Lock)
+	if _, err := toml.DecodeFile(file, lock); err != nil {
+		return nil, err
+	}
+	return lock, nil
+}
+
+// WriteLock writes lock file to srcDir
+func WriteLock(srcDir string, lock *Lock) error {
+	var buf bytes.Buffer
+	buf.WriteString("# versions used by this site, generated by PuGo\n")
+	if err := toml.NewEncoder(&buf).Encode(lock); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(srcDir, LockFile), buf.Bytes(), 0644)
+}
+
+// currentLock return versions of current PuGo and theme
+func currentLock(ctx *Context, schema int) *Lock {
+	lock := &Lock{
+		PuGo:   vars.Version,
+		Schema: schema,
+		Theme:  filepath.Base(ctx.Theme.Dir()),
+	}
+	if ctx.Theme.Meta != nil {
+		lock.ThemeVersion = ctx.Theme.Meta.Version
+	}
+	return lock
+}
+
+// checkLock compares versions in lock file with current PuGo and theme,
+// it warns mismatches, and writes lock file if missing and not in dry run
+func checkLock(ctx *Context) {
+	if ctx.Theme == nil {
+		return
+	}
+	lock, err := ReadLock(ctx.SrcDir())
+	if err != nil {
+		ctx.Log().Warn("Lock|%s", err.Error())
+		return
+	}
+	if lock == nil && !ctx.IsDryRun() {
+		lock = currentLock(ctx, SchemaVersion)
+		if err = WriteLock(ctx.SrcDir(), lock); err != nil {
+			ctx.Log().Warn("Lock|%s", err.Error())
+			return
+		}
+		ctx.Log().Info("Lock|%s|PuGo %s|Theme %s %s", LockFile, lock.PuGo, lock.Theme, lock.ThemeVersion)
+		return
+	}
+	if lock == nil {
+		return
+	}
+	current := currentLock(ctx, SchemaVersion)
+	if lock.Schema < current.Schema {
+		ctx.Log().Warn("Lock|Schema|%d < %d|run 'pugo migrate' to upgrade", lock.Schema, current.Schema)
+	} else if lock.Schema > current.Schema {
+		ctx.Log().Warn("Lock|Schema|%d > %d|site needs newer PuGo", lock.Schema, current.Schema)
+	}
+	if lock.PuGo != current.PuGo {
+		ctx.Log().Warn("Lock|PuGo|pinned %s, running %s", lock.PuGo, current.PuGo)
+	}
+	if lock.Theme != current.Theme || lock.ThemeVersion != current.ThemeVersion {
+		ctx.Log().Warn("Lock|Theme|pinned %s %s, using %s %s", lock.Theme, lock.ThemeVersion, current.Theme, current.ThemeVersion)
+	}
+}
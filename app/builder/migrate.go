@@ -0,0 +1,94 @@
+package builder
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Migration upgrades config and content of site to schema Version
+type Migration struct {
+	Version int
+	Desc    string
+	// Apply changes files in ctx, it return changed files
+	Apply func(ctx *Context, dryRun bool) ([]string, error)
+}
+
+// migrations are sorted by version
+var migrations = []*Migration{
+	{
+		Version: 2,
+		Desc:    "replace deprecated .PreviewHTML and .Preview with .BriefHTML and .Brief in theme templates",
+		Apply: func(ctx *Context, dryRun bool) ([]string, error) {
+			return replaceInFiles(ctx.Theme.Dir(), ".html", dryRun,
+				".PreviewHTML", ".BriefHTML",
+				".Preview}}", ".Brief}}",
+				".Preview ", ".Brief ")
+		},
+	},
+}
+
+// Migrate applies migrations after schema in lock file of source,
+// then updates lock file with current versions if not dry run.
+// Site without lock file is treated as schema 1.
+// It return applied migrations.
+func Migrate(ctx *Context, dryRun bool) ([]*Migration, error) {
+	lock, err := ReadLock(ctx.SrcDir())
+	if err != nil {
+		return nil, err
+	}
+	schema := 1
+	if lock != nil {
+		schema = lock.Schema
+	}
+	var applied []*Migration
+	for _, m := range migrations {
+		if m.Version <= schema {
+			continue
+		}
+		files, err := m.Apply(ctx, dryRun)
+		if err != nil {
+			return applied, err
+		}
+		ctx.Log().Info("Migrate|%d|%s", m.Version, m.Desc)
+		for _, f := range files {
+			ctx.Log().Info("Migrate|%d|Change|%s", m.Version, f)
+		}
+		applied = append(applied, m)
+	}
+	if dryRun {
+		return applied, nil
+	}
+	return applied, WriteLock(ctx.SrcDir(), currentLock(ctx, SchemaVersion))
+}
+
+// replaceInFiles replaces old and new string pairs in files with ext in dir
+func replaceInFiles(dir, ext string, dryRun bool, oldnew ...string) ([]string, error) {
+	var changed []string
+	err := filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() || filepath.Ext(p) != ext {
+			return nil
+		}
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		out := data
+		for i := 0; i+1 < len(oldnew); i += 2 {
+			out = bytes.Replace(out, []byte(oldnew[i]), []byte(oldnew[i+1]), -1)
+		}
+		if bytes.Equal(out, data) {
+			return nil
+		}
+		changed = append(changed, p)
+		if dryRun {
+			return nil
+		}
+		return ioutil.WriteFile(p, out, fi.Mode())
+	})
+	return changed, err
+}
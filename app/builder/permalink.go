@@ -0,0 +1,45 @@
+package builder
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+)
+
+const permalinkFile = "permalinks.json"
+
+// checkPermalinks compares permalinks of posts with last building,
+// it warns changed permalinks, that break links of old urls
+func checkPermalinks(ctx *Context) {
+	links := make(map[string]string)
+	for _, p := range ctx.Source.Posts {
+		rel, err := filepath.Rel(ctx.SrcDir(), p.SourceURL())
+		if err != nil {
+			continue
+		}
+		links[filepath.ToSlash(rel)] = p.URL()
+	}
+
+	file := filepath.Join(ctx.CacheDir(), permalinkFile)
+	old := make(map[string]string)
+	if data, err := ioutil.ReadFile(file); err == nil {
+		if err = json.Unmarshal(data, &old); err != nil {
+			ctx.Log().Warn("Assemble|Permalink|%s", err.Error())
+		}
+	}
+	for src, link := range links {
+		if oldLink, ok := old[src]; ok && oldLink != link {
+			ctx.Log().Warn("Assemble|Permalink|%s|%s -> %s", src, oldLink, link)
+		}
+	}
+	if reflect.DeepEqual(old, links) {
+		return
+	}
+	data, _ := json.MarshalIndent(links, "", "  ")
+	os.MkdirAll(filepath.Dir(file), os.ModePerm)
+	if err := ioutil.WriteFile(file, data, os.ModePerm); err != nil {
+		ctx.Log().Warn("Assemble|Permalink|%s", err.Error())
+	}
+}
@@ -0,0 +1,120 @@
+package builder
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// placeholderSize is width and height of placeholder images
+const placeholderSize = 64
+
+// missingAssets are static files that templates reference but are missing
+type missingAssets struct {
+	sync.Mutex
+	files map[string]bool
+}
+
+// themeAsset return path of static file in destination for templates.
+// Missing file is error in strict mode, and is replaced by generated placeholder in draft mode
+func (ctx *Context) themeAsset(file string) (string, error) {
+	rel := strings.TrimPrefix(path.Clean("/"+file), "/")
+	if ctx.staticSource(rel) != "" {
+		return ctx.assetPath(rel), nil
+	}
+	ctx.missing.Lock()
+	if ctx.missing.files == nil {
+		ctx.missing.files = make(map[string]bool)
+	}
+	first := !ctx.missing.files[rel]
+	ctx.missing.files[rel] = true
+	ctx.missing.Unlock()
+	if ctx.Strict {
+		return rel, fmt.Errorf("asset '%s' is missing", rel)
+	}
+	if !first {
+		return rel, nil
+	}
+	ctx.Log().Warn("Theme|Asset|%s|file is missing", rel)
+	if ctx.Draft && ctx.Sync != nil {
+		if err := writePlaceholder(ctx, rel); err != nil {
+			ctx.Log().Warn("Theme|Asset|%s|%s", rel, err.Error())
+		}
+	}
+	return rel, nil
+}
+
+// MissingAssets return static files that templates reference but are missing in last building
+func (ctx *Context) MissingAssets() []string {
+	ctx.missing.Lock()
+	defer ctx.missing.Unlock()
+	var files []string
+	for f := range ctx.missing.files {
+		files = append(files, f)
+	}
+	return files
+}
+
+func (ctx *Context) resetMissingAssets() {
+	ctx.missing.Lock()
+	ctx.missing.files = make(map[string]bool)
+	ctx.missing.Unlock()
+}
+
+// writePlaceholder writes placeholder of missing static file to destination
+func writePlaceholder(ctx *Context, rel string) error {
+	data, err := placeholderAsset(rel)
+	if err != nil {
+		return err
+	}
+	file := filepath.Join(ctx.DstDir(), filepath.FromSlash(rel))
+	os.MkdirAll(filepath.Dir(file), os.ModePerm)
+	if err = ioutil.WriteFile(file, data, os.ModePerm); err != nil {
+		return err
+	}
+	ctx.Sync.SetSynced(file)
+	ctx.Log().Info("Theme|Asset|%s|placeholder", rel)
+	return nil
+}
+
+// placeholderAsset return content of placeholder by extension of file,
+// images are gray, styles and scripts are comments, others are empty
+func placeholderAsset(rel string) ([]byte, error) {
+	note := "placeholder of missing file " + rel
+	switch strings.ToLower(path.Ext(rel)) {
+	case ".png", ".jpg", ".jpeg", ".gif", ".webp":
+		img := image.NewPaletted(image.Rect(0, 0, placeholderSize, placeholderSize), color.Palette{color.Gray{0xcc}})
+		draw.Draw(img, img.Bounds(), image.NewUniform(color.Gray{0xcc}), image.ZP, draw.Src)
+		var (
+			buf bytes.Buffer
+			err error
+		)
+		switch strings.ToLower(path.Ext(rel)) {
+		case ".jpg", ".jpeg":
+			err = jpeg.Encode(&buf, img, nil)
+		case ".gif":
+			err = gif.Encode(&buf, img, nil)
+		default:
+			// browsers sniff webp placeholder as png
+			err = png.Encode(&buf, img)
+		}
+		return buf.Bytes(), err
+	case ".svg":
+		return []byte(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d"><!-- %s --><rect width="100%%" height="100%%" fill="#ccc"/></svg>`,
+			placeholderSize, placeholderSize, note)), nil
+	case ".css", ".js":
+		return []byte("/* " + note + " */\n"), nil
+	}
+	return []byte{}, nil
+}
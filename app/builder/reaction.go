@@ -0,0 +1,78 @@
+package builder
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/Unknwon/com"
+	"github.com/go-xiaohei/pugo/app/model"
+)
+
+// ReadReactions read reactions data file in source directory,
+// it returns nil if reactions are disabled or data file is missing
+func ReadReactions(ctx *Context) (model.PostReactions, error) {
+	if !ctx.Source.Reaction.IsOK() {
+		return nil, nil
+	}
+	file := filepath.Join(ctx.SrcDir(), ctx.Source.Reaction.DataFile())
+	if !com.IsFile(file) {
+		return nil, nil
+	}
+	ctx.Log().Debug("Read|Reactions|%s", file)
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return model.NewPostReactions(f)
+}
+
+// reactionManifestFile return manifest file of post html file
+func reactionManifestFile(destURL string) string {
+	return strings.TrimSuffix(destURL, filepath.Ext(destURL)) + ".reactions.json"
+}
+
+// ReactionManifests return manifest files of posts by url
+func ReactionManifests(ctx *Context) map[string]string {
+	files := make(map[string]string)
+	if ctx.Source == nil || !ctx.Source.Reaction.IsOK() {
+		return files
+	}
+	for _, p := range ctx.Source.Posts {
+		files[p.URL()] = reactionManifestFile(p.DestURL())
+	}
+	return files
+}
+
+// compileReactions writes reactions manifest beside each post
+func compileReactions(ctx *Context) error {
+	if !ctx.Source.Reaction.IsOK() {
+		return nil
+	}
+	for _, p := range ctx.Source.Posts {
+		manifest := model.ReactionManifest{
+			URL:      p.URL(),
+			Kinds:    ctx.Source.Reaction.Kinds,
+			Counts:   p.Reactions,
+			Endpoint: path.Join("/", ctx.Source.Meta.Path, model.ReactionEndpoint),
+		}
+		data, err := json.Marshal(manifest)
+		if err != nil {
+			return err
+		}
+		dstFile := reactionManifestFile(p.DestURL())
+		os.MkdirAll(filepath.Dir(dstFile), os.ModePerm)
+		if err = ioutil.WriteFile(dstFile, data, os.ModePerm); err != nil {
+			return err
+		}
+		ctx.Sync.SetSynced(dstFile)
+		ctx.Log().Debug("Build|%s", dstFile)
+		atomic.AddInt64(&ctx.counter, 1)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package builder
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-xiaohei/pugo/app/model"
+)
+
+// RedirectsFile lists redirects in source directory, one "from to" url paths per line,
+// paths are escaped, such as "/tags/old%20name.html"
+const RedirectsFile = "redirects.txt"
+
+// Redirect moves old url path to new url path
+type Redirect struct {
+	From string
+	To   string
+}
+
+// ReadRedirects return redirects in redirects.txt of source directory
+func ReadRedirects(srcDir string) ([]*Redirect, error) {
+	f, err := os.Open(filepath.Join(srcDir, RedirectsFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	var (
+		redirects []*Redirect
+		scanner   = bufio.NewScanner(f)
+		line      int
+	)
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: need 'from to' url paths", RedirectsFile, line)
+		}
+		from, err := url.PathUnescape(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", RedirectsFile, line, err.Error())
+		}
+		redirects = append(redirects, &Redirect{From: path.Join("/", from), To: fields[1]})
+	}
+	return redirects, scanner.Err()
+}
+
+// AddRedirect appends redirect to redirects.txt of source directory,
+// existing redirects to from are replaced, and redirects to from are moved to the new url
+func AddRedirect(srcDir, from, to string) error {
+	redirects, err := ReadRedirects(srcDir)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, r := range redirects {
+		if r.From == from {
+			continue
+		}
+		if r.To == escapePath(from) {
+			r.To = to
+		}
+		fmt.Fprintf(&buf, "%s %s\n", escapePath(r.From), r.To)
+	}
+	fmt.Fprintf(&buf, "%s %s\n", escapePath(from), escapePath(to))
+	return ioutil.WriteFile(filepath.Join(srcDir, RedirectsFile), buf.Bytes(), 0644)
+}
+
+// escapePath escapes url path, full url is kept
+func escapePath(p string) string {
+	if strings.Contains(p, "://") {
+		return p
+	}
+	return (&url.URL{Path: p}).EscapedPath()
+}
+
+// compileRedirects writes redirect pages in redirects.txt, and redirect rules as gone rules.
+// The page is not written if other content is compiled to the same url.
+func compileRedirects(ctx *Context) error {
+	redirects, err := ReadRedirects(ctx.SrcDir())
+	if err != nil || len(redirects) == 0 {
+		return err
+	}
+	meta := ctx.Source.Meta
+	for _, r := range redirects {
+		to := r.To
+		if !strings.Contains(to, "://") {
+			to = path.Join(meta.Path, to)
+		}
+		dstFile := filepath.Join(ctx.DstDir(), meta.Path, filepath.FromSlash(r.From))
+		if path.Ext(dstFile) == "" {
+			dstFile = filepath.Join(dstFile, "index.html")
+		}
+		if _, ok := ctx.Sync.SyncedFrom(dstFile); ok {
+			ctx.Log().Warn("Build|Redirect|%s|page exists, not redirected", r.From)
+			continue
+		}
+		os.MkdirAll(filepath.Dir(dstFile), os.ModePerm)
+		if err = writeChanged(ctx, dstFile, redirectPage(to)); err != nil {
+			return err
+		}
+	}
+	ctx.Log().Info("Build|Redirect|%d urls", len(redirects))
+
+	if ctx.Source.Build == nil {
+		return nil
+	}
+	for _, format := range ctx.Source.Build.GoneRules {
+		dstFile := path.Join(ctx.DstDir(), meta.Path, "redirects."+format+".conf")
+		if err = writeChanged(ctx, dstFile, redirectRules(format, meta.Path, redirects)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// redirectPage return html page that redirects to link
+func redirectPage(link string) []byte {
+	u := html.EscapeString(link)
+	return []byte(fmt.Sprintf(`<!DOCTYPE html><html><head><meta charset="utf-8">`+
+		`<meta http-equiv="refresh" content="0; url=%s"><link rel="canonical" href="%s">`+
+		`<meta name="robots" content="noindex"><title>Redirecting</title></head>`+
+		`<body><a href="%s">%s</a></body></html>`, u, u, u, u))
+}
+
+// redirectRules return permanent redirects in format of gone rules
+func redirectRules(format, prefix string, redirects []*Redirect) []byte {
+	var buf bytes.Buffer
+	for _, r := range redirects {
+		from, to := path.Join("/", prefix, r.From), r.To
+		if !strings.Contains(to, "://") {
+			to = path.Join("/", prefix, to)
+		}
+		switch format {
+		case model.GoneNginx:
+			fmt.Fprintf(&buf, "location = %s { return 301 %s; }\n", from, to)
+		case model.GoneApache:
+			fmt.Fprintf(&buf, "Redirect 301 %s %s\n", from, to)
+		}
+	}
+	return buf.Bytes()
+}
+
+// redirectedPaths return url paths that are redirected
+func redirectedPaths(ctx *Context) map[string]bool {
+	redirects, _ := ReadRedirects(ctx.SrcDir())
+	paths := make(map[string]bool, len(redirects))
+	for _, r := range redirects {
+		paths[path.Join("/", ctx.Source.Meta.Path, r.From)] = true
+	}
+	return paths
+}
@@ -0,0 +1,143 @@
+package builder
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-xiaohei/pugo/app/helper"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+const (
+	// ContentReportFile is report of posts content in cache directory
+	ContentReportFile = "content-report.json"
+
+	// reportTitleMax is max length of title shown in search results
+	reportTitleMax = 60
+	// reportDensityMax is max percent of keyword in words before it looks like stuffing
+	reportDensityMax = 3.0
+	// reportTopWords is count of most frequent words in report
+	reportTopWords = 5
+)
+
+type (
+	// ContentReport is readability and SEO analysis of posts
+	ContentReport struct {
+		Posts []*PostReport `json:"posts"`
+		// DuplicateTitles are urls of posts and pages by shared title in lower case
+		DuplicateTitles map[string][]string `json:"duplicate_titles,omitempty"`
+	}
+	// PostReport is analysis of a post
+	PostReport struct {
+		URL         string             `json:"url"`
+		Title       string             `json:"title"`
+		TitleLength int                `json:"title_length"`
+		Words       int                `json:"words"`
+		Flesch      float64            `json:"flesch,omitempty"`
+		Level       string             `json:"level,omitempty"`
+		TopWords    []helper.WordCount `json:"top_words"`
+		// Density is percent of each tag in words
+		Density  map[string]float64 `json:"density,omitempty"`
+		Warnings []string           `json:"warnings,omitempty"`
+	}
+)
+
+// NewContentReport analyzes posts and pages in source
+func NewContentReport(s *Source) *ContentReport {
+	report := &ContentReport{DuplicateTitles: make(map[string][]string)}
+	titles := make(map[string][]string)
+	for _, p := range s.Posts {
+		text := htmlText(p.Content())
+		stats := helper.AnalyzeText(text)
+		pr := &PostReport{
+			URL:         p.URL(),
+			Title:       p.Title,
+			TitleLength: utf8.RuneCountInString(p.Title),
+			Words:       stats.Words,
+			Flesch:      stats.Flesch(),
+			Level:       stats.Level(),
+			TopWords:    stats.TopWords(reportTopWords),
+			Density:     make(map[string]float64),
+		}
+		if pr.TitleLength > reportTitleMax {
+			pr.Warnings = append(pr.Warnings, fmt.Sprintf("title has %d characters, more than %d", pr.TitleLength, reportTitleMax))
+		}
+		if p.Desc == "" {
+			pr.Warnings = append(pr.Warnings, "description is empty")
+		}
+		if pr.Level == "very difficult" {
+			pr.Warnings = append(pr.Warnings, fmt.Sprintf("content is very difficult to read, Flesch score %.1f", pr.Flesch))
+		}
+		for _, t := range p.Tags {
+			d := stats.Density(t.Name)
+			pr.Density[t.Name] = d
+			if d > reportDensityMax {
+				pr.Warnings = append(pr.Warnings, fmt.Sprintf("keyword '%s' is %.1f%% of words, more than %.0f%%", t.Name, d, reportDensityMax))
+			}
+		}
+		report.Posts = append(report.Posts, pr)
+		titles[reportTitleKey(p.Title)] = append(titles[reportTitleKey(p.Title)], p.URL())
+	}
+	for _, p := range s.Pages {
+		titles[reportTitleKey(p.Title)] = append(titles[reportTitleKey(p.Title)], p.URL())
+	}
+	for title, urls := range titles {
+		if len(urls) < 2 {
+			continue
+		}
+		sort.Strings(urls)
+		report.DuplicateTitles[title] = urls
+	}
+	return report
+}
+
+func reportTitleKey(title string) string {
+	return strings.ToLower(strings.TrimSpace(title))
+}
+
+// Warnings return count of warnings in posts and duplicate titles
+func (r *ContentReport) Warnings() int {
+	count := len(r.DuplicateTitles)
+	for _, p := range r.Posts {
+		count += len(p.Warnings)
+	}
+	return count
+}
+
+// Log prints warnings and summary of report to logger
+func (r *ContentReport) Log(logger log15.Logger) {
+	for _, p := range r.Posts {
+		for _, w := range p.Warnings {
+			logger.Warn("Build|Content|%s|%s", p.URL, w)
+		}
+	}
+	keys := make([]string, 0, len(r.DuplicateTitles))
+	for k := range r.DuplicateTitles {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		logger.Warn("Build|Content|Duplicate title '%s'|%s", k, strings.Join(r.DuplicateTitles[k], ", "))
+	}
+	logger.Info("Build|Content|%d posts, %d warnings", len(r.Posts), r.Warnings())
+}
+
+// compileContentReport writes content report of posts to cache directory,
+// if content_report is set in build settings
+func compileContentReport(ctx *Context) error {
+	if ctx.Source.Build == nil || !ctx.Source.Build.ContentReport {
+		return nil
+	}
+	report := NewContentReport(ctx.Source)
+	report.Log(ctx.Log())
+	data, _ := json.MarshalIndent(report, "", "  ")
+	file := filepath.Join(ctx.CacheDir(), ContentReportFile)
+	os.MkdirAll(filepath.Dir(file), os.ModePerm)
+	return ioutil.WriteFile(file, data, os.ModePerm)
+}
@@ -0,0 +1,61 @@
+package builder
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-xiaohei/pugo/app/model"
+)
+
+// FrontMatterSchema return JSON Schema of front-matter of "post" or "page",
+// authors are enumerated and existing tags, templates and languages are suggested as examples.
+// Fields declared in theme are added to meta of page.
+// It needs source and theme are read
+func FrontMatterSchema(ctx *Context, kind string) (*model.JSONSchema, error) {
+	if ctx.Source == nil {
+		return nil, fmt.Errorf("source is not read")
+	}
+	var authors []string
+	for name := range ctx.Source.Authors {
+		authors = append(authors, name)
+	}
+
+	switch kind {
+	case "post":
+		s := model.NewFrontMatterSchema(new(model.Post), "PuGo post front-matter")
+		var tags []string
+		for _, p := range ctx.Source.Posts {
+			tags = append(tags, p.TagString...)
+		}
+		s.SetEnum("tags", tags, false)
+		s.SetEnum("author", authors, true)
+		s.SetEnum("license", model.CCLicenseNames(), false)
+		s.SetEnum("syndicate", []string{model.SyndicationDevTo, model.SyndicationMedium}, true)
+		return s, nil
+	case "page":
+		s := model.NewFrontMatterSchema(new(model.Page), "PuGo page front-matter")
+		s.SetEnum("author", authors, true)
+		var hovers, langs, templates []string
+		for _, n := range ctx.Source.Nav {
+			hovers = append(hovers, n.Hover)
+		}
+		for lang := range ctx.Source.I18n {
+			langs = append(langs, lang)
+		}
+		s.SetEnum("hover", hovers, false)
+		s.SetEnum("lang", langs, false)
+		if ctx.Theme != nil {
+			for _, name := range ctx.Theme.TemplateNames() {
+				if strings.HasSuffix(name, ".html") {
+					templates = append(templates, name)
+				}
+			}
+			s.SetEnum("template", templates, false)
+			if ctx.Theme.Meta != nil {
+				s.AddFields("meta", ctx.Theme.Meta.Fields)
+			}
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("front-matter kind '%s' is not 'post' or 'page'", kind)
+}
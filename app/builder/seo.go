@@ -0,0 +1,215 @@
+package builder
+
+import (
+	"encoding/json"
+	"html"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+const (
+	seoCacheFile = "seo.json"
+
+	// SEOTitle is title field of SEO metadata
+	SEOTitle = "title"
+	// SEODesc is description field of SEO metadata
+	SEODesc = "description"
+	// SEOCanonical is canonical url field of SEO metadata
+	SEOCanonical = "canonical"
+	// SEOData is structured data field of SEO metadata
+	SEOData = "structured data"
+)
+
+var (
+	seoTitleRegex     = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	seoDescRegex      = regexp.MustCompile(`(?is)<meta\s+name="description"\s+content="([^"]*)"`)
+	seoCanonicalRegex = regexp.MustCompile(`(?is)<link\s+rel="canonical"\s+href="([^"]*)"`)
+	seoDataRegex      = regexp.MustCompile(`(?is)<script\s+type="application/ld\+json"[^>]*>(.*?)</script>`)
+
+	seoFields = []string{SEOTitle, SEODesc, SEOCanonical, SEOData}
+)
+
+type (
+	// SEOMeta is SEO metadata of a html page
+	SEOMeta struct {
+		Title     string `json:"title,omitempty"`
+		Desc      string `json:"desc,omitempty"`
+		Canonical string `json:"canonical,omitempty"`
+		Data      string `json:"data,omitempty"`
+	}
+	// SEOChange is a changed field of page's SEO metadata
+	SEOChange struct {
+		URL   string
+		Field string
+		Old   string
+		New   string
+	}
+	// SEOReport compares SEO metadata of pages between two buildings
+	SEOReport struct {
+		Added   []string
+		Removed []string
+		// Lost are fields that are empty now
+		Lost    []SEOChange
+		Changed []SEOChange
+	}
+)
+
+// Field return value of field
+func (m *SEOMeta) Field(field string) string {
+	switch field {
+	case SEOTitle:
+		return m.Title
+	case SEODesc:
+		return m.Desc
+	case SEOCanonical:
+		return m.Canonical
+	case SEOData:
+		return m.Data
+	}
+	return ""
+}
+
+// parseSEOMeta reads SEO metadata in html data
+func parseSEOMeta(data []byte) *SEOMeta {
+	m := new(SEOMeta)
+	find := func(re *regexp.Regexp) string {
+		if sub := re.FindSubmatch(data); len(sub) > 1 {
+			return strings.TrimSpace(html.UnescapeString(string(sub[1])))
+		}
+		return ""
+	}
+	m.Title = find(seoTitleRegex)
+	m.Desc = find(seoDescRegex)
+	m.Canonical = find(seoCanonicalRegex)
+	var blocks []string
+	for _, sub := range seoDataRegex.FindAllSubmatch(data, -1) {
+		blocks = append(blocks, strings.TrimSpace(string(sub[1])))
+	}
+	m.Data = strings.Join(blocks, "\n")
+	return m
+}
+
+// SEOSnapshot return SEO metadata of html files in dir by url path,
+// filter decides whether the file is read, nil filter reads all html files
+func SEOSnapshot(dir string, filter func(file string) bool) (map[string]*SEOMeta, error) {
+	pages := make(map[string]*SEOMeta)
+	err := filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() || path.Ext(p) != ".html" {
+			return nil
+		}
+		if filter != nil && !filter(p) {
+			return nil
+		}
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, p)
+		pages["/"+filepath.ToSlash(rel)] = parseSEOMeta(data)
+		return nil
+	})
+	return pages, err
+}
+
+// CompareSEO compares SEO metadata of pages in old and new snapshot
+func CompareSEO(oldPages, newPages map[string]*SEOMeta) *SEOReport {
+	report := new(SEOReport)
+	for u, m := range newPages {
+		old, ok := oldPages[u]
+		if !ok {
+			report.Added = append(report.Added, u)
+			continue
+		}
+		for _, field := range seoFields {
+			oldValue, newValue := old.Field(field), m.Field(field)
+			if oldValue == newValue {
+				continue
+			}
+			change := SEOChange{URL: u, Field: field, Old: oldValue, New: newValue}
+			if newValue == "" {
+				report.Lost = append(report.Lost, change)
+				continue
+			}
+			report.Changed = append(report.Changed, change)
+		}
+	}
+	for u := range oldPages {
+		if _, ok := newPages[u]; !ok {
+			report.Removed = append(report.Removed, u)
+		}
+	}
+	sort.Strings(report.Added)
+	sort.Strings(report.Removed)
+	sort.Sort(seoChanges(report.Lost))
+	sort.Sort(seoChanges(report.Changed))
+	return report
+}
+
+// LostCount return number of pages losing each field
+func (r *SEOReport) LostCount() map[string]int {
+	counts := make(map[string]int)
+	for _, c := range r.Lost {
+		counts[c.Field]++
+	}
+	return counts
+}
+
+// Log prints summary of report to logger, lost fields and removed pages are regressions
+func (r *SEOReport) Log(logger log15.Logger) {
+	counts := r.LostCount()
+	for _, field := range seoFields {
+		if counts[field] > 0 {
+			logger.Warn("Build|SEO|%d pages lost %s", counts[field], field)
+		}
+	}
+	if len(r.Removed) > 0 {
+		logger.Warn("Build|SEO|%d pages removed", len(r.Removed))
+	}
+	logger.Info("Build|SEO|%d added, %d removed, %d lost, %d changed",
+		len(r.Added), len(r.Removed), len(r.Lost), len(r.Changed))
+}
+
+type seoChanges []SEOChange
+
+func (sc seoChanges) Len() int { return len(sc) }
+func (sc seoChanges) Less(i, j int) bool {
+	if sc[i].URL == sc[j].URL {
+		return sc[i].Field < sc[j].Field
+	}
+	return sc[i].URL < sc[j].URL
+}
+func (sc seoChanges) Swap(i, j int) { sc[i], sc[j] = sc[j], sc[i] }
+
+// compileSEO saves SEO metadata of compiled pages,
+// and compares it with last building to warn regressions
+func compileSEO(ctx *Context) error {
+	pages, err := SEOSnapshot(ctx.DstDir(), func(file string) bool {
+		from, ok := ctx.Sync.SyncedFrom(file)
+		return ok && from == ""
+	})
+	if err != nil {
+		return err
+	}
+	cacheFile := filepath.Join(ctx.CacheDir(), seoCacheFile)
+	if data, err := ioutil.ReadFile(cacheFile); err == nil {
+		var old map[string]*SEOMeta
+		if err = json.Unmarshal(data, &old); err != nil {
+			ctx.Log().Warn("Build|SEO|%s", err.Error())
+		} else if report := CompareSEO(old, pages); len(report.Lost) > 0 || len(report.Removed) > 0 {
+			report.Log(ctx.Log())
+		}
+	}
+	data, _ := json.Marshal(pages)
+	os.MkdirAll(filepath.Dir(cacheFile), os.ModePerm)
+	return ioutil.WriteFile(cacheFile, data, os.ModePerm)
+}
@@ -0,0 +1,179 @@
+package builder
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-xiaohei/pugo/app/model"
+)
+
+const (
+	// SitemapMaxURLs is max urls in one sitemap file,
+	// more urls are split to sitemap-{n}.xml files listed in sitemap.xml as sitemap index
+	SitemapMaxURLs = 50000
+)
+
+// sitemapSplit is max urls in one sitemap file in building
+var sitemapSplit = SitemapMaxURLs
+
+// sitemapURL is url entry in sitemap
+type sitemapURL struct {
+	Loc        string
+	LastMod    time.Time
+	ChangeFreq string
+	Priority   string
+}
+
+// latestTime return latest created or updated time of posts,
+// list pages use it as last modified time, so sitemap does not change if posts don't change
+func latestTime(posts []*model.Post) time.Time {
+	var latest time.Time
+	for _, p := range posts {
+		if p.Created().After(latest) {
+			latest = p.Created()
+		}
+		if p.Updated().After(latest) {
+			latest = p.Updated()
+		}
+	}
+	if latest.IsZero() {
+		return time.Now()
+	}
+	return latest
+}
+
+// sitemapURLs return urls of index, pages, posts, archive, post lists and tags,
+// posts and pages excluded by index rules or noindex are skipped
+func sitemapURLs(ctx *Context) []sitemapURL {
+	meta := ctx.Source.Meta
+	latest := latestTime(ctx.Source.Posts)
+	urls := []sitemapURL{{meta.Root, latest, "daily", "1.0"}}
+	for _, p := range ctx.Source.Pages {
+		if ctx.isUnindexed(p.URL(), p.NoIndex, model.IndexSitemap) {
+			continue
+		}
+		urls = append(urls, sitemapURL{meta.DomainURL(p.URL()), p.Created(), "weekly", "0.5"})
+	}
+	for _, p := range ctx.indexedPosts(ctx.Source.Posts, model.IndexSitemap) {
+		urls = append(urls, sitemapURL{meta.DomainURL(p.URL()), p.Created(), "daily", "0.6"})
+	}
+	urls = append(urls, sitemapURL{meta.DomainURL("archive.html"), latest, "daily", "0.6"})
+	for i := 1; i <= ctx.Source.PostPage; i++ {
+		urls = append(urls, sitemapURL{meta.DomainURL(fmt.Sprintf("post/%d.html", i)), latest, "daily", "0.6"})
+	}
+	for _, t := range ctx.Source.Tags {
+		urls = append(urls, sitemapURL{meta.DomainURL(t.URL), latest, "weekly", "0.5"})
+	}
+	return urls
+}
+
+// compileSitemap writes sitemap.xml. If urls are more than SitemapMaxURLs,
+// they are split to sitemap-{n}.xml files, and sitemap.xml is the sitemap index.
+// Unchanged sitemap files are not rewritten, so deploying only uploads changed files.
+func compileSitemap(ctx *Context) error {
+	urls := sitemapURLs(ctx)
+	dir := path.Join(ctx.DstDir(), ctx.Source.Meta.Path)
+	os.MkdirAll(dir, os.ModePerm)
+	if len(urls) <= sitemapSplit {
+		return writeChanged(ctx, path.Join(dir, "sitemap.xml"), sitemapURLSet(urls))
+	}
+
+	var index bytes.Buffer
+	index.WriteString(`<?xml version="1.0" encoding="UTF-8"?>`)
+	index.WriteString(`<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	for i := 0; i*sitemapSplit < len(urls); i++ {
+		end := (i + 1) * sitemapSplit
+		if end > len(urls) {
+			end = len(urls)
+		}
+		chunk := urls[i*sitemapSplit : end]
+		name := fmt.Sprintf("sitemap-%d.xml", i+1)
+		if err := writeChanged(ctx, path.Join(dir, name), sitemapURLSet(chunk)); err != nil {
+			return err
+		}
+		var lastMod time.Time
+		for _, u := range chunk {
+			if u.LastMod.After(lastMod) {
+				lastMod = u.LastMod
+			}
+		}
+		index.WriteString("<sitemap>")
+		fmt.Fprintf(&index, "<loc>%s</loc>", ctx.Source.Meta.DomainURL(name))
+		fmt.Fprintf(&index, "<lastmod>%s</lastmod>", lastMod.Format(time.RFC3339))
+		index.WriteString("</sitemap>")
+	}
+	index.WriteString("</sitemapindex>")
+	ctx.Log().Info("Build|Sitemap|%d urls are split", len(urls))
+	return writeChanged(ctx, path.Join(dir, "sitemap.xml"), index.Bytes())
+}
+
+func sitemapURLSet(urls []sitemapURL) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>`)
+	buf.WriteString(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	for _, u := range urls {
+		buf.WriteString("<url>")
+		fmt.Fprintf(&buf, "<loc>%s</loc>", u.Loc)
+		fmt.Fprintf(&buf, "<lastmod>%s</lastmod>", u.LastMod.Format(time.RFC3339))
+		fmt.Fprintf(&buf, "<changefreq>%s</changefreq>", u.ChangeFreq)
+		fmt.Fprintf(&buf, "<priority>%s</priority>", u.Priority)
+		buf.WriteString("</url>")
+	}
+	buf.WriteString("</urlset>")
+	return buf.Bytes()
+}
+
+// writeChanged writes data to file if file content is different,
+// unchanged file keeps its modified time
+func writeChanged(ctx *Context, file string, data []byte) error {
+	if old, err := ioutil.ReadFile(file); err == nil && bytes.Equal(old, data) {
+		ctx.Log().Debug("Build|Unchanged|%s", file)
+	} else if err = ioutil.WriteFile(file, data, os.ModePerm); err != nil {
+		return err
+	} else {
+		ctx.Log().Debug("Build|%s", file)
+	}
+	ctx.Sync.SetSynced(file)
+	atomic.AddInt64(&ctx.counter, 1)
+	return nil
+}
+
+// rssItem is feeds.RssItem with guid attribute,
+// guid of post is urn:uuid, it's not a permalink
+type rssItem struct {
+	XMLName     xml.Name `xml:"item"`
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	Author      string   `xml:"author,omitempty"`
+	GUID        *rssGUID `xml:"guid,omitempty"`
+	PubDate     string   `xml:"pubDate,omitempty"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+// encodeFeedItem return encoded rss item of post
+func encodeFeedItem(ctx *Context, p *model.Post) ([]byte, error) {
+	item := &rssItem{
+		Title:       p.Title,
+		Link:        ctx.Source.Meta.DomainURL(p.URL()),
+		Description: string(p.Content()),
+		PubDate:     p.Created().Format(time.RFC1123Z),
+	}
+	if guid := p.GUID(); guid != "" {
+		item.GUID = &rssGUID{Value: guid}
+	}
+	if p.Author != nil {
+		item.Author = p.Author.Nick
+	}
+	return xml.Marshal(item)
+}
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/Unknwon/com"
 	"github.com/go-xiaohei/pugo/app/helper"
@@ -21,14 +22,21 @@ var (
 type (
 	// Source include all sources data
 	Source struct {
-		Meta      *model.Meta
-		Nav       model.NavGroup
-		Owner     *model.Author
-		Authors   map[string]*model.Author
-		Comment   *model.Comment
-		Analytics *model.Analytics
-		Build     *model.Build
-		I18n      map[string]*helper.I18n
+		Meta        *model.Meta
+		Nav         model.NavGroup
+		Owner       *model.Author
+		Authors     map[string]*model.Author
+		Comment     *model.Comment
+		Analytics   *model.Analytics
+		Reaction    *model.Reaction
+		Forms       model.FormGroup
+		Mail        *model.Mail
+		Syndication model.SyndicationGroup
+		Build       *model.Build
+		Verify      *model.Verify
+		License     *model.License
+		Env         map[string]string
+		I18n        map[string]*helper.I18n
 
 		Posts      model.Posts
 		PagePosts  map[int]*model.PagerPosts
@@ -38,6 +46,11 @@ type (
 		Pages      model.Pages
 		Tags       map[string]*model.Tag
 		TagPosts   map[string]*model.TagPosts
+
+		Views        model.PostViews
+		Reactions    model.PostReactions
+		Syndicated   model.PostSyndication
+		PopularPosts model.Posts
 	}
 )
 
@@ -46,13 +59,20 @@ type (
 // but meta, navigation, authors,comment and analytics are loaded.
 func NewSource(all *model.MetaAll) *Source {
 	s := &Source{
-		Meta:      all.Meta,
-		Nav:       all.NavGroup,
-		Owner:     all.AuthorGroup[0],
-		Comment:   all.Comment,
-		Analytics: all.Analytics,
-		Authors:   make(map[string]*model.Author),
-		Build:     all.Build,
+		Meta:        all.Meta,
+		Nav:         all.NavGroup,
+		Owner:       all.AuthorGroup[0],
+		Comment:     all.Comment,
+		Analytics:   all.Analytics,
+		Reaction:    all.Reaction,
+		Forms:       all.FormGroup,
+		Mail:        all.Mail,
+		Syndication: all.Syndication,
+		Authors:     make(map[string]*model.Author),
+		Build:       all.Build,
+		Verify:      all.Verify,
+		License:     all.License,
+		Env:         all.Env,
 	}
 	for _, a := range all.AuthorGroup {
 		s.Authors[a.Name] = a
@@ -60,6 +80,10 @@ func NewSource(all *model.MetaAll) *Source {
 	return s
 }
 
+// IgnoreFile is file name of ignore rules in gitignore syntax,
+// ignored files are not read, synced or watched
+const IgnoreFile = ".pugoignore"
+
 // ReadSource read source with *Context.
 // parse *Context.From and read data to *Context.Source
 func ReadSource(ctx *Context) {
@@ -70,16 +94,34 @@ func ReadSource(ctx *Context) {
 
 	// read meta
 	// then read languages,posts and pages together
-	metaAll, err := ReadSecondMeta(ctx.srcDir)
+	metaAll, err := readSecondMeta(ctx.srcDir, ctx.Log())
 	if err != nil {
 		ctx.Err = err
 		return
 	}
+	if err = metaAll.Meta.DomainMismatch(); err != nil {
+		if ctx.Strict {
+			ctx.Err = err
+			return
+		}
+		ctx.Log().Warn("Read|Meta|%s", err.Error())
+	}
 	ctx.Source = NewSource(metaAll)
+	ctx.resetIncludes()
+	if err = ctx.ReadIgnore(); err != nil {
+		ctx.Err = err
+		return
+	}
+	ctx.markdown.Rotate()
+	if ctx.Source.Build != nil && ctx.Source.Build.MarkdownCache {
+		ctx.markdown.SetDir(filepath.Join(ctx.CacheDir(), "markdown"))
+	} else {
+		ctx.markdown.SetDir("")
+	}
 
 	w := helper.NewWorker(0)
 	w.AddFunc(func() error {
-		ctx.Source.I18n = ReadLang(ctx.SrcLangDir())
+		ctx.Source.I18n = readLang(ctx.SrcLangDir(), ctx.Log())
 		return nil
 	})
 	w.AddFunc(func() error {
@@ -104,10 +146,37 @@ func ReadSource(ctx *Context) {
 		}
 		return err
 	})
+	w.AddFunc(func() error {
+		views, err := ReadViews(ctx)
+		if err != nil {
+			// views are optional, do not break building
+			ctx.Log().Warn("Read|Views|%s", err.Error())
+		}
+		ctx.Source.Views = views
+		return nil
+	})
+	w.AddFunc(func() error {
+		reactions, err := ReadReactions(ctx)
+		if err != nil {
+			// reactions are optional, do not break building
+			ctx.Log().Warn("Read|Reactions|%s", err.Error())
+		}
+		ctx.Source.Reactions = reactions
+		return nil
+	})
+	w.AddFunc(func() error {
+		syndicated, err := ReadSyndicated(ctx.SrcDir())
+		if err != nil {
+			// corrupt data file would push all posts again, stop here
+			return fmt.Errorf("Syndication|%s", err.Error())
+		}
+		ctx.Source.Syndicated = syndicated
+		return nil
+	})
 	w.RunOnce()
 	if len(w.Errors()) > 0 {
 		for _, err := range w.Errors() {
-			log15.Error("Read|%s", err.Error())
+			ctx.Log().Error("Read|%s", err.Error())
 		}
 		ctx.Err = w.Errors()[0]
 	}
@@ -115,30 +184,60 @@ func ReadSource(ctx *Context) {
 
 // ReadSecondMeta read meta file in srcDir
 func ReadSecondMeta(srcDir string) (*model.MetaAll, error) {
+	return readSecondMeta(srcDir, log15.Root())
+}
+
+func readSecondMeta(srcDir string, logger log15.Logger) (*model.MetaAll, error) {
 	var metaFile string
 	for t, f := range model.ShouldMetaFiles() {
 		metaFile = filepath.Join(srcDir, f)
 		if !com.IsFile(metaFile) {
 			continue
 		}
-		log15.Debug("Read|%s", metaFile)
+		logger.Debug("Read|%s", metaFile)
 		bytes, err := ioutil.ReadFile(metaFile)
 		if err != nil {
 			return nil, err
 		}
-		meta, err := model.NewMetaAll(bytes, t)
+		if guess := helper.GuessedEncoding(bytes); guess != "" {
+			logger.Warn("Read|%s|not UTF-8, decoded as %s", metaFile, guess)
+		}
+		if bytes, err = helper.DecodeText(bytes, ""); err != nil {
+			return nil, err
+		}
+		fileEnv, err := helper.LoadEnv(envFiles(srcDir)...)
+		if err != nil {
+			return nil, err
+		}
+		bytes, env := model.InterpolateMeta(bytes, t, fileEnv)
+		meta, err := model.NewMetaAllEnv(bytes, t, fileEnv)
 		if err != nil {
 			return nil, err
 		}
 		if meta != nil {
+			meta.Env = env
 			return meta, nil
 		}
 	}
 	return nil, errMetaFileMissing
 }
 
+// envFiles return .env files in srcDir,
+// .env.{PUGO_ENV} overrides .env, PUGO_ENV is "production" by default
+func envFiles(srcDir string) []string {
+	name := os.Getenv("PUGO_ENV")
+	if name == "" {
+		name = "production"
+	}
+	return []string{filepath.Join(srcDir, ".env"), filepath.Join(srcDir, ".env."+name)}
+}
+
 // ReadLang read languages in srcDir
 func ReadLang(srcDir string) map[string]*helper.I18n {
+	return readLang(srcDir, log15.Root())
+}
+
+func readLang(srcDir string, logger log15.Logger) map[string]*helper.I18n {
 	if !com.IsDir(srcDir) {
 		return nil
 	}
@@ -153,16 +252,19 @@ func ReadLang(srcDir string) map[string]*helper.I18n {
 		p = filepath.ToSlash(p)
 		ext := filepath.Ext(p)
 		if ext == ".toml" || ext == ".ini" {
-			log15.Debug("Read|%s", p)
+			logger.Debug("Read|%s", p)
 			b, err := ioutil.ReadFile(p)
+			if err == nil {
+				b, err = helper.DecodeText(b, "")
+			}
 			if err != nil {
-				log15.Warn("Read|Lang|%s|%v", p, err)
+				logger.Warn("Read|Lang|%s|%v", p, err)
 				return nil
 			}
 			lang := strings.TrimSuffix(filepath.Base(p), ext)
 			i18n, err := helper.NewI18n(lang, b, ext)
 			if err != nil {
-				log15.Warn("Read|Lang|%s|%v", p, err)
+				logger.Warn("Read|Lang|%s|%v", p, err)
 				return nil
 			}
 			langs[lang] = i18n
@@ -172,13 +274,16 @@ func ReadLang(srcDir string) map[string]*helper.I18n {
 	return langs
 }
 
+// readWorkers is size of workers to parse posts and pages, 0 means number of cpu
+var readWorkers = 0
+
 // ReadPosts read posts files in srcDir/post
 func ReadPosts(ctx *Context) ([]*model.Post, error) {
 	srcDir := ctx.SrcPostDir()
 	if !com.IsDir(srcDir) {
 		return nil, fmt.Errorf("posts directory '%s' is missing", srcDir)
 	}
-	log15.Info("Read|Posts|%s", srcDir)
+	ctx.Log().Info("Read|Posts|%s", srcDir)
 
 	// try load post.toml or post.ini to read total meta file
 	var (
@@ -194,37 +299,122 @@ func ReadPosts(ctx *Context) ([]*model.Post, error) {
 		if err != nil {
 			return nil, err
 		}
-		log15.Debug("Read|PostMeta|%s", file)
+		ctx.Log().Debug("Read|PostMeta|%s", file)
 		break
 	}
 
+	files, err := walkMarkdown(ctx, srcDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// parse files concurrently, keep results in files order
+	var (
+		results = make([]*model.Post, len(files))
+		w       = helper.NewWorker(readWorkers)
+	)
+	for i, f := range files {
+		i, p := i, f
+		w.AddFunc(func() error {
+			metaKey := strings.TrimPrefix(p, filepath.ToSlash(srcDir+"/"))
+			ctx.Log().Debug("Read|%s|%v", p, postMeta[metaKey] != nil)
+			data, err := readContentFile(ctx, p)
+			if err != nil {
+				ctx.Log().Warn("Read|Post|%s|%v", p, err)
+				return nil
+			}
+			post, err := model.NewPostOfBytesWithCache(p, data, postMeta[metaKey], ctx.markdown)
+			if err != nil {
+				ctx.Log().Warn("Read|Post|%s|%v", p, err)
+				return nil
+			}
+			if err = post.SetTimezone(ctx.Source.Meta.Location()); err != nil {
+				ctx.Log().Warn("Read|Post|%s|%v", p, err)
+				return nil
+			}
+			if post.Draft == true {
+				ctx.Log().Warn("Draft|%s", p)
+				return nil
+			}
+			// future posts are published by building after its date, they are visible in draft mode
+			if !ctx.Draft && post.Created().After(ctx.time) {
+				ctx.Log().Info("Future|%s|%s", p, post.Created().Format(time.RFC3339))
+				return nil
+			}
+			results[i] = post
+			return nil
+		})
+	}
+	w.RunOnce()
+
 	var posts []*model.Post
-	err = filepath.Walk(srcDir, func(p string, fi os.FileInfo, err error) error {
+	for _, post := range results {
+		if post != nil {
+			posts = append(posts, post)
+		}
+	}
+	sort.Stable(model.Posts(posts))
+	return posts, nil
+}
+
+// readContentFile read content file and decode in encoding of build settings,
+// then expands included files in content
+func readContentFile(ctx *Context, file string) ([]byte, error) {
+	data, err := decodeContentFile(ctx, file)
+	if err != nil || !helper.HasInclude(data) {
+		return data, err
+	}
+	data, includes, err := helper.Include(data, file, ctx.SrcDir(), func(f string) ([]byte, error) {
+		return decodeContentFile(ctx, f)
+	})
+	if err != nil {
+		return nil, err
+	}
+	ctx.addIncludes(file, includes)
+	return data, nil
+}
+
+func decodeContentFile(ctx *Context, file string) ([]byte, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var encoding string
+	if ctx.Source != nil && ctx.Source.Build != nil {
+		encoding = ctx.Source.Build.Encoding
+	}
+	if encoding == "" {
+		if guess := helper.GuessedEncoding(data); guess != "" {
+			ctx.Log().Warn("Read|%s|not UTF-8, decoded as %s, set encoding in [build]", file, guess)
+		}
+	}
+	return helper.DecodeText(data, encoding)
+}
+
+// walkMarkdown return all markdown files in dir that are not ignored, in walking order
+func walkMarkdown(ctx *Context, dir string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		if ctx.IsIgnored(p, fi.IsDir()) {
+			ctx.Log().Debug("Read|Ignore|%s", p)
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
 		if fi.IsDir() {
 			return nil
 		}
 		p = filepath.ToSlash(p)
 		if filepath.Ext(p) == ".md" {
-			metaKey := strings.TrimPrefix(p, filepath.ToSlash(srcDir+"/"))
-			log15.Debug("Read|%s|%v", p, postMeta[metaKey] != nil)
-			post, err := model.NewPostOfMarkdown(p, postMeta[metaKey])
-			if err != nil {
-				log15.Warn("Read|Post|%s|%v", p, err)
-				return nil
-			} else if post != nil && !post.Draft {
-				posts = append(posts, post)
-			}
-			if post.Draft == true {
-				log15.Warn("Draft|%s", p)
-			}
+			files = append(files, p)
 		}
 		return nil
 	})
-	sort.Sort(model.Posts(posts))
-	return posts, err
+	return files, err
 }
 
 // ReadPages read pages files in srcDir/page
@@ -233,7 +423,7 @@ func ReadPages(ctx *Context) ([]*model.Page, error) {
 	if !com.IsDir(srcDir) {
 		return nil, fmt.Errorf("pages directory '%s' is missing", srcDir)
 	}
-	log15.Info("Read|Pages|%s", srcDir)
+	ctx.Log().Info("Read|Pages|%s", srcDir)
 
 	var (
 		err      error
@@ -248,48 +438,70 @@ func ReadPages(ctx *Context) ([]*model.Page, error) {
 		if err != nil {
 			return nil, err
 		}
-		log15.Debug("Read|PageMeta|%s", file)
+		ctx.Log().Debug("Read|PageMeta|%s", file)
 		break
 	}
 
-	var pages []*model.Page
-	for _, page := range pageMeta {
+	// node pages in meta file are added in keys order, map order is random
+	var (
+		pages []*model.Page
+		keys  []string
+	)
+	for key, page := range pageMeta {
 		if page.Node {
-			pages = append(pages, page)
+			keys = append(keys, key)
 		}
 	}
-	err = filepath.Walk(srcDir, func(p string, fi os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if fi.IsDir() {
-			return nil
-		}
-		p = filepath.ToSlash(p)
-		if filepath.Ext(p) == ".md" {
+	sort.Strings(keys)
+	for _, key := range keys {
+		pages = append(pages, pageMeta[key])
+	}
+	files, err := walkMarkdown(ctx, srcDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// parse files concurrently, keep results in files order
+	var (
+		results = make([]*model.Page, len(files))
+		w       = helper.NewWorker(readWorkers)
+	)
+	for i, f := range files {
+		i, p := i, f
+		w.AddFunc(func() error {
 			rel, _ := filepath.Rel(srcDir, p)
 			rel = strings.TrimSuffix(rel, filepath.Ext(rel))
 			metaKey := strings.TrimPrefix(p, filepath.ToSlash(srcDir+"/"))
-			log15.Debug("Read|%s|%v", p, pageMeta[metaKey] != nil)
-			page, err := model.NewPageOfMarkdown(p, filepath.ToSlash(rel), pageMeta[metaKey])
+			ctx.Log().Debug("Read|%s|%v", p, pageMeta[metaKey] != nil)
+			data, err := readContentFile(ctx, p)
 			if err != nil {
-				log15.Warn("Read|Page|%s|%v", p, err)
+				ctx.Log().Warn("Read|Page|%s|%v", p, err)
+				return nil
+			}
+			page, err := model.NewPageOfBytesWithCache(p, filepath.ToSlash(rel), data, pageMeta[metaKey], ctx.markdown)
+			if err != nil {
+				ctx.Log().Warn("Read|Page|%s|%v", p, err)
 				return nil
-			} else if page != nil && !page.Draft {
-				pages = append(pages, page)
 			}
 			if page.Draft == true {
-				log15.Warn("Draft|%s", p)
+				ctx.Log().Warn("Draft|%s", p)
+				return nil
 			}
 			if err = page.LoadJSON(ctx.SrcDir()); err != nil {
-				log15.Warn("Read|Page|JSON|%s|%s", page.JSONFile, err.Error())
-			} else {
-				if page.JSONFile != "" {
-					log15.Debug("Read|Page|JSON|%s", page.JSONFile)
-				}
+				ctx.Log().Warn("Read|Page|JSON|%s|%s", page.JSONFile, err.Error())
+			} else if page.JSONFile != "" {
+				ctx.Log().Debug("Read|Page|JSON|%s", page.JSONFile)
 			}
+			results[i] = page
+			return nil
+		})
+	}
+	w.RunOnce()
+
+	for _, page := range results {
+		if page != nil {
+			pages = append(pages, page)
 		}
-		return nil
-	})
-	return pages, err
+	}
+	return pages, nil
 }
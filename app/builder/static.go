@@ -0,0 +1,188 @@
+package builder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/Unknwon/com"
+	"github.com/go-xiaohei/pugo/app/helper"
+	"github.com/go-xiaohei/pugo/app/model"
+)
+
+type (
+	// staticRules decides actions of static files by build settings
+	staticRules struct {
+		rules []staticRule
+		// fingerprints caches hash of source files
+		lock         sync.Mutex
+		fingerprints map[string]string
+		// variants maps relative file to its fingerprinted file
+		variants map[string]string
+	}
+	staticRule struct {
+		glob   *regexp.Regexp
+		action string
+	}
+)
+
+func newStaticRules(rules []*model.StaticRule) (*staticRules, error) {
+	sr := &staticRules{fingerprints: make(map[string]string), variants: make(map[string]string)}
+	for _, r := range rules {
+		re, err := helper.GlobRegexp(r.Glob)
+		if err != nil {
+			return nil, fmt.Errorf("static rule '%s': %s", r.Glob, err.Error())
+		}
+		sr.rules = append(sr.rules, staticRule{glob: re, action: r.Action})
+	}
+	return sr, nil
+}
+
+// action return action of the first rule matching relative file, or copy by default
+func (sr *staticRules) action(rel string) string {
+	if sr == nil {
+		return model.StaticCopy
+	}
+	rel = filepath.ToSlash(rel)
+	for _, r := range sr.rules {
+		if r.glob.MatchString(rel) {
+			return r.action
+		}
+	}
+	return model.StaticCopy
+}
+
+// fingerprint return relative file with hash of src in name,
+// such as "css/style.css" to "css/style.1a2b3c4d.css"
+func (sr *staticRules) fingerprint(rel, src string) (string, error) {
+	sr.lock.Lock()
+	hash, ok := sr.fingerprints[src]
+	sr.lock.Unlock()
+	if !ok {
+		h, err := helper.Md5File(src)
+		if err != nil {
+			return "", err
+		}
+		hash = h[:8]
+		sr.lock.Lock()
+		sr.fingerprints[src] = hash
+		sr.lock.Unlock()
+	}
+	ext := path.Ext(rel)
+	f := strings.TrimSuffix(rel, ext) + "." + hash + ext
+	sr.lock.Lock()
+	sr.variants[rel] = f
+	sr.lock.Unlock()
+	return f, nil
+}
+
+// staticTransform return sync transform function by static rules
+func staticTransform(ctx *Context) func(relFile, src string) (string, func(src, dst string) error) {
+	return func(relFile, src string) (string, func(src, dst string) error) {
+		rel := filepath.ToSlash(relFile)
+		switch ctx.staticRules.action(rel) {
+		case model.StaticExclude:
+			return "", nil
+		case model.StaticFingerprint:
+			f, err := ctx.staticRules.fingerprint(rel, src)
+			if err != nil {
+				ctx.Log().Warn("Sync|Fingerprint|%s|%s", src, err.Error())
+				return relFile, nil
+			}
+			return filepath.FromSlash(f), nil
+		case model.StaticMinify:
+			if fn := minifyFunc(rel); fn != nil {
+				return relFile, fn
+			}
+			ctx.Log().Debug("Sync|Minify|%s|unsupported, copy it", src)
+		}
+		return relFile, nil
+	}
+}
+
+// minifyFunc return function to write minified file by extension of file,
+// it returns nil if the file type is unsupported
+func minifyFunc(file string) func(src, dst string) error {
+	var minify func([]byte) ([]byte, error)
+	switch strings.ToLower(path.Ext(file)) {
+	case ".css":
+		minify = func(data []byte) ([]byte, error) {
+			return helper.MinifyCSS(data), nil
+		}
+	case ".json":
+		minify = func(data []byte) ([]byte, error) {
+			var buf bytes.Buffer
+			err := json.Compact(&buf, data)
+			return buf.Bytes(), err
+		}
+	default:
+		return nil
+	}
+	return func(src, dst string) error {
+		data, err := ioutil.ReadFile(src)
+		if err != nil {
+			return err
+		}
+		if data, err = minify(data); err != nil {
+			return fmt.Errorf("minify '%s': %s", src, err.Error())
+		}
+		return ioutil.WriteFile(dst, data, os.ModePerm)
+	}
+}
+
+// assetPath return relative path of static file in destination,
+// fingerprinted files are renamed with hash
+func (ctx *Context) assetPath(rel string) string {
+	rel = strings.TrimPrefix(path.Clean("/"+rel), "/")
+	if ctx.staticRules.action(rel) != model.StaticFingerprint {
+		return rel
+	}
+	src := ctx.staticSource(rel)
+	if src == "" {
+		ctx.Log().Warn("Theme|Asset|%s|file is missing", rel)
+		return rel
+	}
+	f, err := ctx.staticRules.fingerprint(rel, src)
+	if err != nil {
+		ctx.Log().Warn("Theme|Asset|%s|%s", rel, err.Error())
+		return rel
+	}
+	return f
+}
+
+// staticSource return source file of static file in destination,
+// it follows the same order as Sync
+func (ctx *Context) staticSource(rel string) string {
+	postPrefix, _ := filepath.Rel(ctx.SrcDir(), ctx.SrcPostDir())
+	mediaPrefix, _ := filepath.Rel(ctx.SrcDir(), ctx.SrcMediaDir())
+	dirs := [][2]string{
+		{filepath.ToSlash(postPrefix), ctx.SrcPostDir()},
+		{"", ctx.SrcPageDir()},
+		{filepath.ToSlash(mediaPrefix), ctx.SrcMediaDir()},
+	}
+	if ctx.Theme != nil {
+		dirs = append(dirs, [2]string{"", ctx.Theme.StaticDir()})
+	}
+	for _, d := range dirs {
+		name := rel
+		if d[0] != "" {
+			if !strings.HasPrefix(rel, d[0]+"/") {
+				continue
+			}
+			name = strings.TrimPrefix(rel, d[0]+"/")
+		}
+		file := filepath.Join(d[1], filepath.FromSlash(name))
+		if path.Ext(file) == ".md" || !com.IsFile(file) || ctx.IsIgnored(file, false) {
+			continue
+		}
+		return file
+	}
+	return ""
+}
@@ -0,0 +1,53 @@
+package builder
+
+import (
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Status is the status of last building
+type Status struct {
+	Building bool      `json:"building"`
+	Count    int       `json:"count"`
+	Time     time.Time `json:"time"`
+	Duration float64   `json:"duration_ms"`
+	Pages    int64     `json:"pages"`
+	Warnings int64     `json:"warnings"`
+	Error    string    `json:"error,omitempty"`
+	Commit   string    `json:"commit,omitempty"`
+}
+
+// Status return status of last building,
+// git commit is resolved once after each building when status is requested
+func (b *Builder) Status() Status {
+	b.lock.Lock()
+	if b.commitDir != "" {
+		b.status.Commit = gitCommit(b.commitDir)
+		b.commitDir = ""
+	}
+	s := b.status
+	b.lock.Unlock()
+	s.Building = b.IsBuilding()
+	s.Count = b.Counter()
+	return s
+}
+
+func (b *Builder) setStatus(s Status, srcDir string) {
+	b.lock.Lock()
+	b.status = s
+	b.commitDir = srcDir
+	b.lock.Unlock()
+}
+
+// gitCommit return commit hash of git repository in dir,
+// it returns empty string if dir is not in git repository
+func gitCommit(dir string) string {
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -8,16 +8,16 @@ import (
 	"github.com/go-xiaohei/pugo/app/sync"
 )
 
-// Sync copy assets to destination directory
+// Sync copy assets to destination directory.
+// If files conflict, compiled files are used first,
+// then files in post, page and media directories, then theme static files
 func Sync(ctx *Context) {
-	if ctx.Err = ctx.Sync.SyncDir(ctx.Theme.StaticDir(), nil); ctx.Err != nil {
-		return
-	}
-
 	opt := &sync.DirOption{
 		Filter: func(p string) bool {
-			return path.Ext(p) != ".md"
+			return path.Ext(p) != ".md" && !ctx.IsIgnored(p, false)
 		},
+		Keep:      true,
+		Transform: staticTransform(ctx),
 	}
 	var ignoreFiles []string
 
@@ -42,12 +42,39 @@ func Sync(ctx *Context) {
 		return
 	}
 
+	// sidecar files of images are metadata, not media
 	opt.Prefix, _ = filepath.Rel(ctx.SrcDir(), ctx.SrcMediaDir())
+	filter := opt.Filter
+	opt.Filter = func(p string) bool {
+		return filter(p) && !model.IsImageSidecar(p)
+	}
 	if ctx.Err = ctx.Sync.SyncDir(ctx.SrcMediaDir(), opt); ctx.Err != nil {
 		return
 	}
+	opt.Filter = filter
+
+	if ctx.Err = ctx.Sync.SyncDir(ctx.Theme.StaticDir(), &sync.DirOption{
+		Filter: func(p string) bool {
+			if ctx.Theme.Sandbox && !ctx.Theme.IsInside(p) {
+				ctx.Log().Warn("Sync|Sandbox|%s|out of theme directory, skip", p)
+				return false
+			}
+			return !ctx.IsIgnored(p, false)
+		},
+		Keep:      true,
+		Transform: staticTransform(ctx),
+	}); ctx.Err != nil {
+		return
+	}
+	if ctx.Err = compileFonts(ctx); ctx.Err != nil {
+		return
+	}
+	if ctx.Err = compileAssets(ctx); ctx.Err != nil {
+		return
+	}
 	opt.Ignore = []string{".git"}
 	if ctx.Err = ctx.Sync.Clear(opt); ctx.Err != nil {
 		return
 	}
+	ctx.Err = keepArtifact(ctx)
 }
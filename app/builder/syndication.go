@@ -0,0 +1,36 @@
+package builder
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/Unknwon/com"
+	"github.com/go-xiaohei/pugo/app/model"
+)
+
+// ReadSyndicated reads links of syndicated posts in srcDir,
+// it returns empty links if data file is missing,
+// and error if data file is corrupt
+func ReadSyndicated(srcDir string) (model.PostSyndication, error) {
+	file := filepath.Join(srcDir, model.SyndicationDefaultFile)
+	if !com.IsFile(file) {
+		return make(model.PostSyndication), nil
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return model.NewPostSyndication(bytes.NewReader(data))
+}
+
+// WriteSyndicated writes links of syndicated posts to srcDir
+func WriteSyndicated(srcDir string, ps model.PostSyndication) error {
+	data, err := json.MarshalIndent(ps, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(srcDir, model.SyndicationDefaultFile), data, os.ModePerm)
+}
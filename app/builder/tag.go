@@ -0,0 +1,114 @@
+package builder
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+
+	"github.com/go-xiaohei/pugo/app/helper"
+	"github.com/go-xiaohei/pugo/app/model"
+)
+
+// tagEdit is replaced content of post file
+type tagEdit struct {
+	file string
+	data []byte
+	mode os.FileMode
+}
+
+// MergeTag replaces tag from with tag to in front-matter of posts,
+// and redirects page of tag from to page of tag to.
+// All posts are checked before writing, so no file is changed if one post can't be replaced.
+// It return changed post files.
+func MergeTag(ctx *Context, from, to string, dryRun bool) ([]string, error) {
+	oldTag, newTag := model.NewTag(from), model.NewTag(to)
+	if oldTag.Name == "" || newTag.Name == "" || oldTag.Name == newTag.Name {
+		return nil, fmt.Errorf("need two different tags to merge")
+	}
+	var (
+		changed []string
+		edits   []tagEdit
+	)
+	err := filepath.Walk(ctx.SrcPostDir(), func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() || filepath.Ext(p) != ".md" {
+			return nil
+		}
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if !utf8.Valid(data) {
+			ctx.Log().Warn("Tag|Skip|%s|not utf-8", p)
+			return nil
+		}
+		post, err := model.NewPostOfBytes(p, data, nil)
+		if err != nil {
+			return err
+		}
+		tags, ok := mergeTags(post.TagString, oldTag.Name, newTag.Name)
+		if !ok {
+			return nil
+		}
+		out, err := model.EditFrontMatter(p, data, map[string]interface{}{"tags": tags})
+		if err != nil {
+			return fmt.Errorf("%s: %s", p, err.Error())
+		}
+		// make sure replaced content is still correct
+		text, _ := helper.DecodeText(out, "")
+		if _, err = model.NewPostOfBytes(p, text, nil); err != nil {
+			return err
+		}
+		changed = append(changed, p)
+		if !bytes.Equal(out, data) {
+			edits = append(edits, tagEdit{file: p, data: out, mode: fi.Mode()})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		return changed, nil
+	}
+	// redirect first, old urls still work if writing posts fails
+	if err = AddRedirect(ctx.SrcDir(), oldTag.URL, newTag.URL); err != nil {
+		return nil, err
+	}
+	for _, e := range edits {
+		if err = ioutil.WriteFile(e.file, e.data, e.mode); err != nil {
+			return changed, err
+		}
+	}
+	return changed, nil
+}
+
+// mergeTags replaces from with to in tags, to is not duplicated.
+// It return false if from is not in tags.
+func mergeTags(tags []string, from, to string) ([]string, bool) {
+	var (
+		merged = make([]string, 0, len(tags))
+		found  bool
+		hasTo  bool
+	)
+	for _, t := range tags {
+		t = model.NewTag(t).Name
+		if t == from {
+			found = true
+			t = to
+		}
+		if t == to {
+			if hasTo {
+				continue
+			}
+			hasTo = true
+		}
+		merged = append(merged, t)
+	}
+	return merged, found
+}
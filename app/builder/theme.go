@@ -2,12 +2,16 @@ package builder
 
 import (
 	"fmt"
+	"html/template"
 	"net/url"
 	"path"
+	"path/filepath"
+	"time"
 
 	"github.com/Unknwon/com"
+	"github.com/go-xiaohei/pugo/app/helper"
+	"github.com/go-xiaohei/pugo/app/model"
 	"github.com/go-xiaohei/pugo/app/theme"
-	"gopkg.in/inconshreveable/log15.v2"
 )
 
 // ReadTheme read *Theme to *Context
@@ -21,8 +25,21 @@ func ReadTheme(ctx *Context) {
 		ctx.Err = fmt.Errorf("theme directory '%s' is missing", dir)
 		return
 	}
-	log15.Info("Theme|%s", dir)
+	ctx.Log().Info("Theme|%s", dir)
+	var err error
+	if ctx.themeIgnore, err = helper.ReadIgnore(filepath.Join(dir, IgnoreFile)); err != nil {
+		ctx.Err = err
+		return
+	}
 	ctx.Theme = theme.New(dir)
+	ctx.Theme.Logger = ctx.Logger
+	if ctx.Sandbox {
+		ctx.Log().Info("Theme|Sandbox")
+		ctx.Theme.Sandbox = true
+	}
+	ctx.Theme.Strict = ctx.Strict
+	ctx.Theme.Placeholder = ctx.Draft
+	ctx.resetMissingAssets()
 	ctx.Theme.Func("url", func(str ...string) string {
 		if len(str) > 0 {
 			if ur, _ := url.Parse(str[0]); ur != nil {
@@ -33,10 +50,41 @@ func ReadTheme(ctx *Context) {
 		}
 		return path.Join(append([]string{ctx.Source.Meta.Path}, str...)...)
 	})
+	ctx.Theme.Func("asset", func(file string) (string, error) {
+		rel, err := ctx.themeAsset(file)
+		return path.Join(ctx.Source.Meta.Path, rel), err
+	})
+	ctx.Theme.Func("form", func(name string) (template.HTML, error) {
+		return formHTML(ctx, name)
+	})
 	ctx.Theme.Func("fullUrl", func(str ...string) string {
 		return ctx.Source.Meta.Root + path.Join(str...)
 	})
+	ctx.Theme.Func("dateFormat", func(t time.Time, layout string, lang ...string) string {
+		return ctx.locale(lang...).FormatDate(t, layout)
+	})
+	ctx.Theme.Func("numberFormat", func(v interface{}, decimals int, lang ...string) (string, error) {
+		n, err := helper.ToFloat(v)
+		if err != nil {
+			return "", err
+		}
+		return ctx.locale(lang...).FormatNumber(n, decimals), nil
+	})
+	ctx.Theme.Func("imageMeta", func(src string) *model.ImageMeta {
+		if ctx.images == nil {
+			return nil
+		}
+		return ctx.images.Get(newReplacer(ctx.Source.Meta.Path).Replace(src))
+	})
 	if err := ctx.Theme.Validate(); err != nil {
-		log15.Warn("Theme|%s|%s", dir, err.Error())
+		ctx.Log().Warn("Theme|%s|%s", dir, err.Error())
+	}
+}
+
+// locale return locale of the language, or language of site if it's empty
+func (ctx *Context) locale(lang ...string) *helper.Locale {
+	if len(lang) > 0 && lang[0] != "" {
+		return helper.GetLocale(lang[0])
 	}
+	return helper.GetLocale(ctx.Source.Meta.Language)
 }
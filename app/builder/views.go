@@ -0,0 +1,86 @@
+package builder
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Unknwon/com"
+	"github.com/go-xiaohei/pugo/app/model"
+)
+
+const (
+	viewsCacheFile   = "views.csv"
+	viewsDefaultTTL  = 60
+	viewsHTTPTimeout = 10 * time.Second
+)
+
+// ReadViews read page views data from analytics export.
+// The export can be a csv file in source directory or a http url.
+// Remote data is cached in cache directory and refreshed after ttl minutes.
+func ReadViews(ctx *Context) (model.PostViews, error) {
+	a := ctx.Source.Analytics
+	if a == nil || a.Views == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(a.Views, "http://") && !strings.HasPrefix(a.Views, "https://") {
+		file := filepath.Join(ctx.SrcDir(), a.Views)
+		ctx.Log().Debug("Read|Views|%s", file)
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		return model.NewPostViews(bytes.NewReader(data))
+	}
+
+	ttl := a.ViewsTTL
+	if ttl <= 0 {
+		ttl = viewsDefaultTTL
+	}
+	cacheFile := filepath.Join(ctx.CacheDir(), viewsCacheFile)
+	if t, err := com.FileMTime(cacheFile); err == nil && time.Since(time.Unix(t, 0)) < time.Duration(ttl)*time.Minute {
+		ctx.Log().Debug("Read|Views|Cache|%s", cacheFile)
+		data, err := ioutil.ReadFile(cacheFile)
+		if err == nil {
+			return model.NewPostViews(bytes.NewReader(data))
+		}
+	}
+
+	ctx.Log().Info("Read|Views|%s", a.Views)
+	data, err := fetchViews(a.Views)
+	if err != nil {
+		// use outdated cache if fetching fails
+		if cached, err2 := ioutil.ReadFile(cacheFile); err2 == nil {
+			ctx.Log().Warn("Read|Views|%s|use cache", err.Error())
+			return model.NewPostViews(bytes.NewReader(cached))
+		}
+		return nil, err
+	}
+	views, err := model.NewPostViews(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	os.MkdirAll(filepath.Dir(cacheFile), os.ModePerm)
+	if err = ioutil.WriteFile(cacheFile, data, os.ModePerm); err != nil {
+		ctx.Log().Warn("Read|Views|Cache|%s", err.Error())
+	}
+	return views, nil
+}
+
+func fetchViews(url string) ([]byte, error) {
+	client := &http.Client{Timeout: viewsHTTPTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("views url response %s", resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
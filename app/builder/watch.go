@@ -3,41 +3,86 @@ package builder
 import (
 	"io/ioutil"
 	"path"
+	"sync/atomic"
 	"time"
 
+	"github.com/go-xiaohei/pugo/app/helper"
+	"github.com/go-xiaohei/pugo/app/metrics"
 	"gopkg.in/fsnotify.v1"
-	"gopkg.in/inconshreveable/log15.v2"
 )
 
-var (
-	// watchingExt sets the suffix that watching to
-	watchingExt = []string{".md", ".toml", ".html", ".css", ".js", ".jpg", ".png", ".gif"}
-	// watchScheduleTime sets watching timer duration
-	watchScheduleTime int64
-)
+var watchEvents = metrics.NewCounter("pugo_watch_events_total", "Total number of file changes that trigger rebuilding.")
+
+// WatchOptions sets how to watch changes
+type WatchOptions struct {
+	// Exts sets the suffix that watching to
+	Exts []string
+	// Delay sets duration to wait after last change before rebuilding
+	Delay time.Duration
+	// Schedule rebuilds periodically without changes, it's optional
+	Schedule helper.Schedule
+}
 
-// Watch watch changes
+// NewWatchOptions return default watching options
+func NewWatchOptions() *WatchOptions {
+	return &WatchOptions{
+		Exts:  []string{".md", ".toml", ".html", ".css", ".js", ".jpg", ".png", ".gif"},
+		Delay: time.Second,
+	}
+}
+
+// Watch watch changes with default options
 func Watch(ctx *Context) {
+	WatchWith(ctx, NewWatchOptions())
+}
+
+// WatchWith watch changes with options,
+// it rebuilds with the Builder that built ctx
+func WatchWith(ctx *Context, opt *WatchOptions) {
+	if ctx.srcDir == "" || ctx.dstDir == "" || ctx.Theme == nil || ctx.builder == nil {
+		ctx.Log().Crit("Watch|Need build once then watch changes")
+		return
+	}
+
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
-		log15.Crit("Watch|Fail|%s", err.Error())
+		ctx.Log().Crit("Watch|Fail|%s", err.Error())
 		return
 	}
-	log15.Info("Watch|Start")
+	ctx.Log().Info("Watch|Start")
 
-	if ctx.srcDir == "" || ctx.dstDir == "" || ctx.Theme == nil {
-		log15.Crit("Watch|Need build once then watch changes")
-	}
+	// scheduleTime, building and isIgnored are shared by ticker and fsnotify goroutines
+	var (
+		scheduleTime int64
+		building     int32
+		isIgnored    atomic.Value
+	)
+	isIgnored.Store(ctx.ignoreFunc())
 
-	// use a ticker to trigger build
+	// use a ticker to trigger build,
+	// changes in delay are coalesced into one building
 	go func() {
+		var next time.Time
+		if opt.Schedule != nil {
+			next = opt.Schedule.Next(time.Now())
+			ctx.Log().Info("Watch|Schedule|%s", next.Format(time.RFC3339))
+		}
 		c := time.Tick(1 * time.Second)
 		for {
 			t := <-c
-			if watchScheduleTime > 0 && t.UnixNano() > watchScheduleTime {
+			if opt.Schedule != nil && !next.IsZero() && t.After(next) {
+				ctx.Log().Info("Watch|Rebuild|schedule")
+				atomic.CompareAndSwapInt64(&scheduleTime, 0, t.UnixNano())
+				next = opt.Schedule.Next(t)
+			}
+			st := atomic.LoadInt64(&scheduleTime)
+			if st > 0 && t.UnixNano() > st {
 				ctx.Again()
-				Build(ctx)
-				watchScheduleTime = 0
+				atomic.StoreInt32(&building, 1)
+				ctx.builder.Build(ctx)
+				isIgnored.Store(ctx.ignoreFunc())
+				atomic.StoreInt32(&building, 0)
+				atomic.CompareAndSwapInt64(&scheduleTime, st, 0)
 			}
 		}
 	}()
@@ -47,34 +92,55 @@ func Watch(ctx *Context) {
 		for {
 			select {
 			case event := <-watcher.Events:
+				if path.Base(event.Name) == IgnoreFile {
+					ctx.Log().Info("Watch|Rebuild|%s", event.String())
+					atomic.StoreInt64(&scheduleTime, time.Now().Add(opt.Delay).UnixNano())
+					continue
+				}
+				if isIgnored.Load().(func(string, bool) bool)(event.Name, false) {
+					continue
+				}
 				ext := path.Ext(event.Name)
-				for _, e := range watchingExt {
+				for _, e := range opt.Exts {
 					if e == ext {
 						if event.Op != fsnotify.Chmod {
-							log15.Info("Watch|Rebuild|%s", event.String())
-							watchScheduleTime = time.Now().Add(time.Second).UnixNano()
+							ctx.Log().Info("Watch|Rebuild|%s", event.String())
+							watchEvents.Inc()
+							for _, f := range ctx.IncludedBy(event.Name) {
+								ctx.Log().Info("Watch|Include|%s|%s", event.Name, f)
+							}
+							atomic.StoreInt64(&scheduleTime, time.Now().Add(opt.Delay).UnixNano())
+							// the change supersedes current building
+							if atomic.LoadInt32(&building) > 0 && !ctx.IsCanceled() {
+								ctx.Log().Info("Watch|Cancel|%s", event.Name)
+								ctx.Cancel()
+							}
 						}
 						break
 					}
 				}
 			case err := <-watcher.Errors:
-				log15.Warn("Watch|Error|%s", err.Error())
+				ctx.Log().Warn("Watch|Error|%s", err.Error())
 			}
 		}
 	}()
 
-	watchDir(watcher, ctx.srcDir)
-	watchDir(watcher, ctx.Theme.Dir())
+	watchDir(ctx, watcher, ctx.srcDir)
+	watchDir(ctx, watcher, ctx.Theme.Dir())
 
 }
 
-// watch sub directory
-func watchDir(watcher *fsnotify.Watcher, srcDir string) {
+// watch sub directory that is not ignored
+func watchDir(ctx *Context, watcher *fsnotify.Watcher, srcDir string) {
 	watcher.Add(srcDir)
 	dir, _ := ioutil.ReadDir(srcDir)
 	for _, d := range dir {
 		if d.IsDir() {
-			watchDir(watcher, path.Join(srcDir, d.Name()))
+			sub := path.Join(srcDir, d.Name())
+			if ctx.IsIgnored(sub, true) {
+				continue
+			}
+			watchDir(ctx, watcher, sub)
 		}
 	}
 }
@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPack(t *testing.T) {
+	Convey("Pack and unpack cache", t, func() {
+		dir, err := ioutil.TempDir("", "pugo-cache")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		for name, data := range map[string]string{
+			"src/markdown/a.html": "a",
+			"src/seo.json":        "{}",
+			"src/builds/1/x.html": "x",
+		} {
+			file := filepath.Join(dir, filepath.FromSlash(name))
+			os.MkdirAll(filepath.Dir(file), os.ModePerm)
+			So(ioutil.WriteFile(file, []byte(data), 0644), ShouldBeNil)
+		}
+
+		var buf bytes.Buffer
+		n, err := Pack(filepath.Join(dir, "src"), &buf, func(rel string) bool {
+			return rel != "builds"
+		})
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 2)
+
+		n, err = Unpack(filepath.Join(dir, "dst"), &buf)
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 2)
+		data, _ := ioutil.ReadFile(filepath.Join(dir, "dst", "markdown", "a.html"))
+		So(string(data), ShouldEqual, "a")
+		_, err = os.Stat(filepath.Join(dir, "dst", "builds"))
+		So(os.IsNotExist(err), ShouldBeTrue)
+	})
+}
@@ -0,0 +1,154 @@
+// Package cache shares build cache directory with remote storage,
+// so that CI machines and contributors reuse rendered data.
+package cache
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// ErrNotFound means cache archive is not in remote storage yet
+var ErrNotFound = errors.New("cache is not found in remote")
+
+// Remote stores cache archive
+type Remote interface {
+	// Get return reader of archive, or ErrNotFound
+	Get() (io.ReadCloser, error)
+	// Put uploads archive data
+	Put(data []byte) error
+	String() string
+}
+
+// NewRemote return remote by url,
+// "http://" or "https://" url is read by GET and written by PUT,
+// "s3://bucket/key" is object in Amazon S3, region is set by "?region=" or AWS_REGION.
+// Token is sent as bearer authorization to http remote
+func NewRemote(rawurl, token string) (Remote, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return &httpRemote{URL: rawurl, Token: token}, nil
+	case "s3":
+		r := &s3Remote{
+			Bucket: u.Host,
+			Key:    strings.TrimPrefix(u.Path, "/"),
+			Region: u.Query().Get("region"),
+		}
+		if r.Region == "" {
+			r.Region = os.Getenv("AWS_REGION")
+		}
+		if r.Bucket == "" || r.Key == "" {
+			return nil, fmt.Errorf("s3 remote need bucket and key, such as 's3://bucket/pugo-cache.tar.gz'")
+		}
+		if r.Region == "" {
+			return nil, fmt.Errorf("s3 remote need region by '?region=' or AWS_REGION")
+		}
+		return r, nil
+	}
+	return nil, fmt.Errorf("remote '%s' is unknown, use http(s):// or s3:// url", rawurl)
+}
+
+type httpRemote struct {
+	URL   string
+	Token string
+}
+
+func (h *httpRemote) do(method string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, h.URL, body)
+	if err != nil {
+		return nil, err
+	}
+	if h.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+h.Token)
+	}
+	return http.DefaultClient.Do(req)
+}
+
+func (h *httpRemote) Get() (io.ReadCloser, error) {
+	resp, err := h.do("GET", nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", h.URL, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+func (h *httpRemote) Put(data []byte) error {
+	resp, err := h.do("PUT", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("PUT %s: %s", h.URL, resp.Status)
+	}
+	return nil
+}
+
+func (h *httpRemote) String() string {
+	return h.URL
+}
+
+// s3Remote uses default credentials of aws,
+// such as AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY
+type s3Remote struct {
+	Bucket string
+	Key    string
+	Region string
+}
+
+func (s *s3Remote) client() *s3.S3 {
+	return s3.New(session.New(), aws.NewConfig().WithRegion(s.Region))
+}
+
+func (s *s3Remote) Get() (io.ReadCloser, error) {
+	out, err := s.client().GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.Key),
+	})
+	if err != nil {
+		if e, ok := err.(awserr.Error); ok && e.Code() == "NoSuchKey" {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return out.Body, nil
+}
+
+func (s *s3Remote) Put(data []byte) error {
+	_, err := s.client().PutObject(&s3.PutObjectInput{
+		Bucket:        aws.String(s.Bucket),
+		Key:           aws.String(s.Key),
+		Body:          bytes.NewReader(data),
+		ContentLength: aws.Int64(int64(len(data))),
+		ContentType:   aws.String("application/gzip"),
+	})
+	return err
+}
+
+func (s *s3Remote) String() string {
+	return "s3://" + s.Bucket + "/" + s.Key
+}
@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRemote(t *testing.T) {
+	Convey("Remote url", t, func() {
+		r, err := NewRemote("s3://bucket/ci/cache.tar.gz?region=us-east-1", "")
+		So(err, ShouldBeNil)
+		So(r.String(), ShouldEqual, "s3://bucket/ci/cache.tar.gz")
+		_, err = NewRemote("s3://bucket", "")
+		So(err, ShouldNotBeNil)
+		_, err = NewRemote("ftp://host/cache.tar.gz", "")
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("HTTP remote", t, func() {
+		var stored []byte
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			switch r.Method {
+			case "PUT":
+				stored, _ = ioutil.ReadAll(r.Body)
+			case "GET":
+				if stored == nil {
+					http.NotFound(w, r)
+					return
+				}
+				w.Write(stored)
+			}
+		}))
+		defer srv.Close()
+
+		r, err := NewRemote(srv.URL+"/cache.tar.gz", "token")
+		So(err, ShouldBeNil)
+		_, err = r.Get()
+		So(err, ShouldEqual, ErrNotFound)
+
+		So(r.Put([]byte("archive")), ShouldBeNil)
+		body, err := r.Get()
+		So(err, ShouldBeNil)
+		data, _ := ioutil.ReadAll(body)
+		body.Close()
+		So(string(data), ShouldEqual, "archive")
+
+		r, _ = NewRemote(srv.URL+"/cache.tar.gz", "")
+		err = r.Put([]byte("archive"))
+		So(err, ShouldNotBeNil)
+		So(strings.Contains(err.Error(), "401"), ShouldBeTrue)
+	})
+}
@@ -1,11 +1,17 @@
 package command
 
 import (
+	"fmt"
+	"io/ioutil"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
+	"time"
 
+	"github.com/Unknwon/com"
 	"github.com/go-xiaohei/pugo/app/builder"
+	"github.com/go-xiaohei/pugo/app/helper"
 	"github.com/urfave/cli"
 	"gopkg.in/inconshreveable/log15.v2"
 )
@@ -20,48 +26,196 @@ var (
 			buildDestFlag,
 			buildThemeFlag,
 			buildWatchFlag,
+			buildDraftFlag,
+			buildMaxMemoryFlag,
+			buildDiffFlag,
+			buildSandboxFlag,
+			buildLegacyFlag,
+			buildStrictFlag,
 			debugFlag,
 		},
 		Before: Before,
 		Action: func(ctx *cli.Context) error {
 			// migrate.Init()
-			build(newContext(ctx, true), false)
+			if ctx.Bool("diff") {
+				buildDiff(ctx)
+				return nil
+			}
+			build(builder.NewDefault(), newContext(ctx, true), false)
 			return nil
 		},
 	}
 )
 
 func newContext(c *cli.Context, validate bool) *builder.Context {
-	ctx := builder.NewContext(
-		c,
-		c.String("source"),
-		c.String("dest"),
-		c.String("theme"),
-	)
+	_, dest, _ := buildDirs(c)
+	return newContextTo(c, dest, validate)
+}
+
+// newContextTo create context that builds to dest
+func newContextTo(c *cli.Context, dest string, validate bool) *builder.Context {
+	source, _, theme := buildDirs(c)
+	ctx := builder.NewContext(c, source, dest, theme)
+	ctx.Draft = c.Bool("draft")
+	ctx.Sandbox = c.Bool("sandbox")
+	ctx.Legacy = c.Bool("legacy")
+	ctx.Strict = c.Bool("strict")
+	if maxMemory := c.String("max-memory"); maxMemory != "" {
+		var err error
+		if ctx.MaxMemory, err = helper.ParseMemory(maxMemory); err != nil {
+			log15.Crit("Build|%s", err.Error())
+		}
+	}
 	if validate && !ctx.IsValid() {
 		log15.Crit("Build|Must have values in 'source', 'dest' & 'theme'")
 	}
 	return ctx
 }
 
-func build(ctx *builder.Context, mustWatch bool) {
+// buildDirs return source, destination and theme directories to build.
+// Flags override dest and theme in build settings of meta,
+// theme is theme/default in source directory by default
+func buildDirs(c *cli.Context) (string, string, string) {
+	source, dest, theme := c.String("source"), c.String("dest"), c.String("theme")
+	if source == "" {
+		return source, dest, theme
+	}
+	srcDir, err := toDir(source)
+	if err != nil {
+		return source, dest, theme
+	}
+	// missing meta is reported when reading source
+	metaDest, metaTheme, _ := builder.ReadDirSetting(srcDir)
+	if metaDest != "" && !c.IsSet("dest") {
+		dest = metaDest
+	}
+	if theme == "" {
+		theme = metaTheme
+	}
+	if theme == "" {
+		theme = filepath.Join(srcDir, "theme", "default")
+	}
+	return source, dest, theme
+}
+
+// build builds ctx with b, and watches changes if it's needed
+func build(b *builder.Builder, ctx *builder.Context, mustWatch bool) {
 	// ctrl+C capture
-	signalChan := make(chan os.Signal)
+	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
 
-	builder.Build(ctx)
+	b.Build(ctx)
 
 	if ctx.Cli().Bool("watch") || mustWatch {
-		builder.Watch(ctx)
+		opt := builder.NewWatchOptions()
+		opt.Schedule = rebuildSchedule(ctx.Cli())
+		builder.WatchWith(ctx, opt)
 		<-signalChan
 		log15.Info("Watch|Close")
 	}
 }
 
-func buildHangUp(ctx *builder.Context) {
-	signalChan := make(chan os.Signal)
+// buildHangUp builds once and waits for signal,
+// it rebuilds by --rebuild-every schedule if it's set
+func buildHangUp(b *builder.Builder, ctx *builder.Context) {
+	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
-	builder.Build(ctx)
+	b.Build(ctx)
+	if schedule := rebuildSchedule(ctx.Cli()); schedule != nil {
+		go func() {
+			for {
+				next := schedule.Next(time.Now())
+				if next.IsZero() {
+					log15.Error("Build|Schedule|no next time, stop rebuilding")
+					return
+				}
+				log15.Info("Build|Schedule|%s", next.Format(time.RFC3339))
+				time.Sleep(time.Until(next))
+				ctx.Again()
+				b.Build(ctx)
+			}
+		}()
+	}
 	<-signalChan
 	log15.Info("Close")
 }
+
+// buildDiff builds to temporary directory and prints differences with current destination,
+// current destination, caches and lock file in source are not changed
+func buildDiff(c *cli.Context) {
+	_, dest, _ := buildDirs(c)
+	dstDir, err := toDir(dest)
+	if err != nil {
+		log15.Crit("Build|Diff|%s", err.Error())
+	}
+	tmpDir, err := ioutil.TempDir("", "pugo-diff-")
+	if err != nil {
+		log15.Crit("Build|Diff|%s", err.Error())
+	}
+	defer os.RemoveAll(tmpDir)
+
+	ctx := newContextTo(c, tmpDir, true)
+	if err = ctx.DryRun(); err != nil {
+		os.RemoveAll(tmpDir)
+		log15.Crit("Build|Diff|%s", err.Error())
+	}
+	defer ctx.Close()
+	builder.NewDefault().Build(ctx)
+	diff, err := builder.DiffDirs(dstDir, tmpDir)
+	if err != nil {
+		os.RemoveAll(tmpDir)
+		ctx.Close()
+		log15.Crit("Build|Diff|%s", err.Error())
+	}
+	for _, fd := range diff.Added {
+		fmt.Printf("+ %s (%s)\n", fd.File, formatDelta(fd.Delta()))
+	}
+	for _, fd := range diff.Removed {
+		fmt.Printf("- %s (%s)\n", fd.File, formatDelta(fd.Delta()))
+	}
+	for _, fd := range diff.Changed {
+		fmt.Printf("~ %s (%s)\n", fd.File, formatDelta(fd.Delta()))
+	}
+	log15.Info("Build|Diff|%d added, %d removed, %d changed, %d same, %s",
+		len(diff.Added), len(diff.Removed), len(diff.Changed), diff.Same, formatDelta(diff.Delta()))
+	if com.IsDir(dstDir) {
+		printSEODiff(dstDir, tmpDir)
+	}
+}
+
+// printSEODiff prints SEO metadata lost or changed in html pages
+func printSEODiff(oldDir, newDir string) {
+	oldPages, err := builder.SEOSnapshot(oldDir, nil)
+	if err != nil {
+		log15.Warn("Build|SEO|%s", err.Error())
+		return
+	}
+	newPages, err := builder.SEOSnapshot(newDir, nil)
+	if err != nil {
+		log15.Warn("Build|SEO|%s", err.Error())
+		return
+	}
+	report := builder.CompareSEO(oldPages, newPages)
+	for _, c := range report.Lost {
+		fmt.Printf("! %s lost %s (%q)\n", c.URL, c.Field, c.Old)
+	}
+	for _, c := range report.Changed {
+		fmt.Printf("~ %s %s %q -> %q\n", c.URL, c.Field, c.Old, c.New)
+	}
+	report.Log(log15.Root())
+}
+
+// formatDelta formats size delta in bytes with sign
+func formatDelta(delta int64) string {
+	sign := "+"
+	if delta < 0 {
+		sign, delta = "-", -delta
+	}
+	switch {
+	case delta >= 1<<20:
+		return fmt.Sprintf("%s%.1f MB", sign, float64(delta)/(1<<20))
+	case delta >= 1<<10:
+		return fmt.Sprintf("%s%.1f KB", sign, float64(delta)/(1<<10))
+	}
+	return fmt.Sprintf("%s%d B", sign, delta)
+}
@@ -0,0 +1,100 @@
+package command
+
+import (
+	"bytes"
+
+	"github.com/Unknwon/com"
+	"github.com/go-xiaohei/pugo/app/builder"
+	"github.com/go-xiaohei/pugo/app/cache"
+	"github.com/urfave/cli"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+var (
+	// Cache is command of 'cache', to share build cache with remote storage
+	Cache = cli.Command{
+		Name:  "cache",
+		Usage: "share build cache with remote storage",
+		Subcommands: []cli.Command{
+			{
+				Name:  "pull",
+				Usage: "download cache from remote to cache directory",
+				Flags: []cli.Flag{
+					buildSourceFlag,
+					cacheRemoteFlag,
+					cacheTokenFlag,
+					debugFlag,
+				},
+				Before: Before,
+				Action: cachePull,
+			},
+			{
+				Name:  "push",
+				Usage: "upload cache directory to remote",
+				Flags: []cli.Flag{
+					buildSourceFlag,
+					cacheRemoteFlag,
+					cacheTokenFlag,
+					debugFlag,
+				},
+				Before: Before,
+				Action: cachePush,
+			},
+		},
+	}
+)
+
+// cacheRemote return cache directory and remote by flags and meta
+func cacheRemote(c *cli.Context) (string, cache.Remote) {
+	dir, remoteURL, err := builder.ReadCacheSetting(c.String("source"))
+	if err != nil {
+		log15.Crit("Cache|%s", err.Error())
+	}
+	if u := c.String("remote"); u != "" {
+		remoteURL = u
+	}
+	if remoteURL == "" {
+		log15.Crit("Cache|Need remote url by --remote or 'cache_remote' in [build] of meta")
+	}
+	remote, err := cache.NewRemote(remoteURL, c.String("token"))
+	if err != nil {
+		log15.Crit("Cache|%s", err.Error())
+	}
+	return dir, remote
+}
+
+func cachePull(c *cli.Context) error {
+	dir, remote := cacheRemote(c)
+	body, err := remote.Get()
+	if err == cache.ErrNotFound {
+		log15.Warn("Cache|Pull|%s|not found, build without cache", remote)
+		return nil
+	}
+	if err != nil {
+		log15.Crit("Cache|Pull|%s", err.Error())
+	}
+	defer body.Close()
+	n, err := cache.Unpack(dir, body)
+	if err != nil {
+		log15.Crit("Cache|Pull|%s", err.Error())
+	}
+	log15.Info("Cache|Pull|%s|%d files to %s", remote, n, dir)
+	return nil
+}
+
+func cachePush(c *cli.Context) error {
+	dir, remote := cacheRemote(c)
+	if !com.IsDir(dir) {
+		log15.Crit("Cache|Push|Cache directory '%s' is missing, build first", dir)
+	}
+	var buf bytes.Buffer
+	n, err := cache.Pack(dir, &buf, builder.IsSharedCache)
+	if err != nil {
+		log15.Crit("Cache|Push|%s", err.Error())
+	}
+	if err = remote.Put(buf.Bytes()); err != nil {
+		log15.Crit("Cache|Push|%s", err.Error())
+	}
+	log15.Info("Cache|Push|%s|%d files, %d bytes", remote, n, buf.Len())
+	return nil
+}
@@ -3,6 +3,7 @@ package command
 import (
 	"github.com/go-xiaohei/pugo/app/extend/deploy"
 	"github.com/urfave/cli"
+	"gopkg.in/inconshreveable/log15.v2"
 )
 
 var (
@@ -16,8 +17,25 @@ var (
 func init() {
 	commands := deploy.Commands()
 	for k := range commands {
-		commands[k].Flags = append(commands[k].Flags, debugFlag)
-		commands[k].Before = Before
+		commands[k].Flags = append(commands[k].Flags, deployCheckDNSFlag, deployAuditRulesFlag, deployAllowSecretsFlag, debugFlag)
+		commands[k].Before = deployBefore
 	}
 	Deploy.Subcommands = commands
 }
+
+// deployBefore audits files for secrets,
+// and checks DNS of domain before deploying if need
+func deployBefore(c *cli.Context) error {
+	if err := Before(c); err != nil {
+		return err
+	}
+	if err := deploy.CheckAudit(c.String("local"), c.String("audit-rules"), c.Bool("allow-secrets")); err != nil {
+		log15.Crit("Deploy|Audit|%s", err.Error())
+	}
+	if c.Bool("check-dns") {
+		if err := deploy.CheckDNS(c.String("local")); err != nil {
+			log15.Crit("Deploy|DNS|%s", err.Error())
+		}
+	}
+	return nil
+}
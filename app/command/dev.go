@@ -0,0 +1,294 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/Unknwon/com"
+	"github.com/go-xiaohei/pugo/app/asset"
+	"github.com/go-xiaohei/pugo/app/model"
+	"github.com/urfave/cli"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+var (
+	// Dev is command of 'dev', tools for theme developers
+	Dev = cli.Command{
+		Name:  "dev",
+		Usage: "tools for theme developers",
+		Subcommands: []cli.Command{
+			{
+				Name:  "fixtures",
+				Usage: "generate synthetic content to test themes",
+				Flags: []cli.Flag{
+					devFixturesToFlag,
+					devFixturesPostsFlag,
+					debugFlag,
+				},
+				Before: Before,
+				Action: devFixtures,
+			},
+		},
+	}
+)
+
+// fixtureTags are tags of fixture posts, every post uses some of them
+var fixtureTags = []string{"go", "theme", "design", "中文", "long tag name for testing wrap", "c++", "release"}
+
+const fixtureMeta = `[meta]
+title = "PuGo Fixtures"
+subtitle = "Synthetic content for theme testing"
+keyword = "pugo,fixtures"
+desc = "Synthetic content with long and short posts, CJK, code and images"
+domain = "localhost"
+root = "http://localhost:9899/"
+cover = "@media/fixture-wide.png"
+lang = "en"
+
+[[nav]]
+link = "/"
+title = "Home"
+i18n = "home"
+hover = "home"
+
+[[nav]]
+link = "/archive"
+title = "Archive"
+i18n = "archive"
+hover = "archive"
+
+[[nav]]
+link = "/about"
+title = "About"
+i18n = "about"
+hover = "about"
+
+[[author]]
+name = "pugo"
+nick = "PuGo"
+email = "pugo@example.com"
+url = "http://example.com"
+avatar = "@media/fixture-square.png"
+bio = "The author of fixtures"
+repo = "https://github.com/go-xiaohei/pugo"
+
+[[author]]
+name = "writer"
+nick = "A Writer With A Very Long Nickname"
+email = "writer@example.com"
+bio = "The other author"
+
+[build]
+post_pagesize = 5
+`
+
+func devFixtures(c *cli.Context) error {
+	dir, err := toDir(c.String("to"))
+	if err == nil {
+		err = generateFixtures(dir, c.Int("posts"))
+	}
+	if err != nil {
+		log15.Crit("Dev|Fixtures|%s", err.Error())
+	}
+	log15.Info("Dev|Fixtures|%s", dir)
+	return nil
+}
+
+// generateFixtures writes meta, posts, pages and images to dir,
+// count sets the number of extra short posts to fill pagination and archive
+func generateFixtures(dir string, count int) error {
+	if com.IsDir(dir) {
+		if files, _ := ioutil.ReadDir(dir); len(files) > 0 {
+			return errors.New("directory '" + dir + "' is not empty")
+		}
+	}
+	files := map[string][]byte{
+		"meta.toml": []byte(fixtureMeta),
+	}
+	for _, lang := range []string{"en", "zh"} {
+		if data, err := asset.Asset("source/lang/" + lang + ".toml"); err == nil {
+			files["lang/"+lang+".toml"] = data
+		}
+	}
+
+	images := map[string][2]int{"wide": {1600, 400}, "tall": {300, 1200}, "square": {256, 256}, "tiny": {8, 8}}
+	for name, size := range images {
+		data, err := fixtureImage(size[0], size[1])
+		if err != nil {
+			return err
+		}
+		files["media/fixture-"+name+".png"] = data
+	}
+
+	date := time.Date(2016, 1, 1, 10, 0, 0, 0, time.UTC)
+	for i, fp := range fixturePosts() {
+		fp.post.Date = date.AddDate(0, 0, i*7).Format("2006-01-02 15:04:05")
+		data, err := fixtureContent(fp.post, fp.content)
+		if err != nil {
+			return err
+		}
+		files["post/"+fp.post.Slug+".md"] = data
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < count; i++ {
+		post := &model.Post{
+			Title:      fmt.Sprintf("Filler post %d", i+1),
+			Slug:       fmt.Sprintf("filler-%d", i+1),
+			Desc:       "A short post to fill pagination and archive",
+			Date:       date.AddDate(-1-i/10, -i%12, -i).Format("2006-01-02 15:04:05"),
+			AuthorName: "pugo",
+			TagString:  []string{fixtureTags[r.Intn(len(fixtureTags))]},
+		}
+		data, err := fixtureContent(post, fixtureWords(r, 40+r.Intn(80)))
+		if err != nil {
+			return err
+		}
+		files[fmt.Sprintf("post/%d/filler-%d.md", 2015-i/10, i+1)] = data
+	}
+
+	for _, fp := range fixturePages() {
+		data, err := fixtureContent(fp.page, fp.content)
+		if err != nil {
+			return err
+		}
+		files["page/"+fp.file] = data
+	}
+
+	for name, data := range files {
+		file := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(file), os.ModePerm)
+		log15.Debug("Dev|Fixtures|Write|%s", file)
+		if err := ioutil.WriteFile(file, data, os.ModePerm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type fixturePost struct {
+	post    *model.Post
+	content string
+}
+
+func fixturePosts() []fixturePost {
+	r := rand.New(rand.NewSource(2))
+	var long bytes.Buffer
+	long.WriteString(fixtureWords(r, 60) + "\n\n<!--more-->\n\n")
+	for i := 1; i <= 8; i++ {
+		fmt.Fprintf(&long, "## Section %d\n\n%s\n\n", i, fixtureWords(r, 150))
+		fmt.Fprintf(&long, "### Sub section %d.1\n\n%s\n\n", i, fixtureWords(r, 80))
+		long.WriteString("- list item one\n- list item two\n  - nested item\n- list item three\n\n")
+		long.WriteString("> " + fixtureWords(r, 30) + "\n\n")
+	}
+	long.WriteString("| Name | Value | Description |\n|------|------:|-------------|\n| a | 1 | " + fixtureWords(r, 12) + " |\n| b | 22 | short |\n\n")
+
+	var images bytes.Buffer
+	images.WriteString("Images in different sizes.\n\n<!--more-->\n\n")
+	for _, name := range []string{"wide", "tall", "square", "tiny"} {
+		fmt.Fprintf(&images, "![%s image](@media/fixture-%s.png)\n\n%s\n\n", name, name, fixtureWords(r, 20))
+	}
+	images.WriteString(`<img src="@media/fixture-wide.png" alt="raw html image">` + "\n\n")
+	images.WriteString("[![linked image](@media/fixture-square.png)](http://example.com)\n")
+
+	code := "Code blocks in several languages, and `inline code` in paragraph.\n\n<!--more-->\n\n" +
+		"```go\npackage main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hello, pugo\")\n}\n```\n\n" +
+		"```js\nconst add = (a, b) => a + b;\nconsole.log(add(1, 2));\n```\n\n" +
+		"```html\n<div class=\"post\">\n  <p>{{.Title}}</p>\n</div>\n```\n\n" +
+		"```bash\npugo build --theme=\"source/theme/default\" --dest=\"dest\" && pugo server --addr=\"0.0.0.0:9899\" --no-watch\n```\n\n" +
+		"```\nno language code block with a very long line " + strings.Repeat("abcdefghij", 20) + "\n```\n\n" +
+		"    indented code block\n    second line\n"
+
+	cjk := "这是一篇中文文章，用于测试中文排版和换行。日本語の文章もあります。한국어 문장도 있습니다。\n\n<!--more-->\n\n" +
+		"## 第一节\n\n" + strings.Repeat("中文内容测试，包括标点符号：逗号、句号。", 20) + "\n\n" +
+		"## 日本語\n\n" + strings.Repeat("これはテストです。", 30) + "\n\n" +
+		"## 한국어\n\n" + strings.Repeat("이것은 테스트입니다. ", 30) + "\n"
+
+	return []fixturePost{
+		{&model.Post{Title: "Short", Slug: "short", Desc: "A very short post", AuthorName: "pugo", TagString: []string{"go"}},
+			"Only one line."},
+		{&model.Post{Title: "A long post with many sections to test table of contents and typography", Slug: "long", Desc: fixtureWords(r, 40), AuthorName: "writer", Thumb: "@media/fixture-wide.png", TagString: []string{"design", "theme", "long tag name for testing wrap"}},
+			long.String()},
+		{&model.Post{Title: "中文、日本語、한국어", Slug: "cjk", Desc: "中日韩文字测试", AuthorName: "pugo", TagString: []string{"中文", "design"}},
+			cjk},
+		{&model.Post{Title: "Code heavy post", Slug: "code", Desc: "Code blocks in several languages", AuthorName: "pugo", TagString: []string{"go", "c++"}},
+			code},
+		{&model.Post{Title: "Image heavy post", Slug: "images", Desc: "Images in different sizes", AuthorName: "writer", Thumb: "@media/fixture-tall.png", TagString: []string{"design"}},
+			images.String()},
+		{&model.Post{Title: `Special characters: <tag> & "quotes" 'single' 🎉`, Slug: "special-chars", Desc: `Desc with <b>html</b> & "quotes"`, AuthorName: "pugo", TagString: fixtureTags},
+			"Post with every tag and special characters in title."},
+		{&model.Post{Title: "No tags, no author", Slug: "no-tags", Desc: ""},
+			"Post without tags and author."},
+	}
+}
+
+type fixturePage struct {
+	file    string
+	page    *model.Page
+	content string
+}
+
+func fixturePages() []fixturePage {
+	r := rand.New(rand.NewSource(3))
+	return []fixturePage{
+		{"about.md", &model.Page{Title: "About", Slug: "about", Desc: "About fixtures", NavHover: "about", Template: "page.html", Date: "2016-01-01 10:00:00"},
+			"This site is generated by `pugo dev fixtures`.\n\n" + fixtureWords(r, 100)},
+		{"docs/guide.md", &model.Page{Title: "Nested page", Slug: "docs/guide", Desc: "Page in sub directory", Template: "page.html", Date: "2016-01-02 10:00:00"},
+			"## Nested\n\n" + fixtureWords(r, 200)},
+		{"zh.md", &model.Page{Title: "中文页面", Slug: "zh", Desc: "中文页面", Template: "page.html", Lang: "zh", Date: "2016-01-03 10:00:00"},
+			strings.Repeat("中文页面内容。", 40)},
+	}
+}
+
+// fixtureContent encodes front-matter and appends markdown content
+func fixtureContent(v interface{}, content string) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("```toml\n")
+	if err := toml.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	buf.WriteString("```\n\n")
+	buf.WriteString(content)
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+var fixtureDict = strings.Fields("lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod tempor incididunt ut labore et dolore magna aliqua enim ad minim veniam quis nostrud exercitation ullamco laboris nisi aliquip ex ea commodo consequat")
+
+// fixtureWords return n random words in sentences
+func fixtureWords(r *rand.Rand, n int) string {
+	words := make([]string, n)
+	for i := range words {
+		words[i] = fixtureDict[r.Intn(len(fixtureDict))]
+		if i%12 == 11 {
+			words[i] += "."
+		}
+	}
+	return strings.Join(words, " ") + "."
+}
+
+// fixtureImage creates png image with gradient colors
+func fixtureImage(width, height int) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{uint8(x * 255 / width), uint8(y * 255 / height), 160, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -2,7 +2,6 @@ package command
 
 import (
 	"github.com/go-xiaohei/pugo/app/builder"
-	"github.com/go-xiaohei/pugo/app/server"
 	"github.com/go-xiaohei/pugo/app/sync"
 	"github.com/urfave/cli"
 )
@@ -23,16 +22,9 @@ var (
 )
 
 func docServ(c *cli.Context) error {
+	b := builder.NewDefault()
 	if !c.Bool("no-server") {
-		builder.After(func(ctx *builder.Context) {
-			if s == nil {
-				s = server.New(ctx.DstDir())
-				go s.Run(c.String("addr"))
-			}
-			if ctx.Source != nil && ctx.Source.Meta != nil {
-				s.SetPrefix(ctx.Source.Meta.Path)
-			}
-		})
+		b.After(serveAfterBuild(b, c.String("addr"), nil, false))
 	}
 	buildContext := newContext(c, false)
 	buildContext.From = "doc/source"
@@ -40,6 +32,6 @@ func docServ(c *cli.Context) error {
 	buildContext.ThemeName = "doc/theme"
 	buildContext.Tree.Dest = buildContext.DstDir()
 	buildContext.Sync = sync.NewSyncer(buildContext.DstDir())
-	build(buildContext, !c.Bool("no-server"))
+	build(b, buildContext, !c.Bool("no-server"))
 	return nil
 }
@@ -1,22 +1,48 @@
 package command
 
-import "github.com/urfave/cli"
+import (
+	"github.com/go-xiaohei/pugo/app/theme"
+	"github.com/urfave/cli"
+)
 
 var (
 	buildDestFlag = cli.StringFlag{
-		Name:  "dest",
-		Value: "dest",
-		Usage: "write files to destination directory",
+		Name:   "dest, destination",
+		Value:  "dest",
+		EnvVar: "PUGO_DEST",
+		Usage:  "write files to destination directory, 'dest' in build settings of meta overrides default value",
 	}
 	buildSourceFlag = cli.StringFlag{
-		Name:  "source",
-		Value: "source",
-		Usage: "read files from source directory",
+		Name:   "source",
+		Value:  "source",
+		EnvVar: "PUGO_SOURCE",
+		Usage:  "read files from source directory",
 	}
 	buildThemeFlag = cli.StringFlag{
-		Name:  "theme",
-		Value: "source/theme/default",
-		Usage: "theme to use (located in flag directory)",
+		Name:   "theme",
+		EnvVar: "PUGO_THEME",
+		Usage:  "theme directory to use, 'theme' in build settings of meta or 'theme/default' in source directory by default",
+	}
+	buildMaxMemoryFlag = cli.StringFlag{
+		Name:  "max-memory",
+		Usage: "limit memory usage such as 512M, build in low memory mode",
+	}
+	buildSandboxFlag = cli.BoolFlag{
+		Name:  "sandbox",
+		Usage: "build untrusted theme in sandbox mode, theme can not read files out of its directory",
+	}
+	buildLegacyFlag = cli.BoolFlag{
+		Name:  "legacy",
+		Usage: "build for old browsers, use legacy variants of templates and remove module scripts",
+	}
+	buildStrictFlag = cli.BoolFlag{
+		Name:   "strict",
+		EnvVar: "PUGO_STRICT",
+		Usage:  "fail building if templates reference missing templates or static files",
+	}
+	buildDiffFlag = cli.BoolFlag{
+		Name:  "diff",
+		Usage: "build to temporary directory and print differences with destination, do not replace it",
 	}
 	debugFlag = cli.BoolFlag{
 		Name:  "debug",
@@ -26,10 +52,18 @@ var (
 		Name:  "watch",
 		Usage: "watch changes and rebuild files",
 	}
+	buildDraftFlag = cli.BoolFlag{
+		Name:  "draft",
+		Usage: "build in draft mode, show editorial annotations",
+	}
 	noWatchFlag = cli.BoolFlag{
 		Name:  "no-watch",
 		Usage: "do not watch changes in server",
 	}
+	liveReloadFlag = cli.BoolFlag{
+		Name:  "live-reload",
+		Usage: "inject script in html pages to reload them after rebuilding",
+	}
 	addrFlag = cli.StringFlag{
 		Name:  "addr",
 		Value: "0.0.0.0:9899",
@@ -39,6 +73,38 @@ var (
 		Name:  "static",
 		Usage: "just serve static file, no build",
 	}
+	serveSitesFlag = cli.StringFlag{
+		Name:  "sites",
+		Usage: "publish sites in config file by webhooks",
+	}
+	rebuildEveryFlag = cli.StringFlag{
+		Name:  "rebuild-every",
+		Usage: "rebuild periodically by duration such as '1h' or cron expression such as '0 */6 * * *'",
+	}
+	rollbackToFlag = cli.StringFlag{
+		Name:  "to",
+		Usage: "id of kept build output to restore, the one before last building by default",
+	}
+	rollbackListFlag = cli.BoolFlag{
+		Name:  "list",
+		Usage: "list kept build outputs",
+	}
+	deployCheckDNSFlag = cli.BoolFlag{
+		Name:  "check-dns",
+		Usage: "check domain in CNAME file is resolved before deploying",
+	}
+	deployAuditRulesFlag = cli.StringFlag{
+		Name:  "audit-rules",
+		Usage: "toml file of extra patterns to audit before deploying, such as PII",
+	}
+	deployAllowSecretsFlag = cli.BoolFlag{
+		Name:  "allow-secrets",
+		Usage: "deploy even if audit finds secrets or sensitive contents",
+	}
+	accessLogFlag = cli.StringFlag{
+		Name:  "access-log",
+		Usage: "write request logs as json lines to file, '-' is stdout",
+	}
 	noServerDocFlag = cli.BoolFlag{
 		Name:  "no-server",
 		Usage: "close to serve doc files",
@@ -48,6 +114,123 @@ var (
 		Value: "dir://source",
 		Usage: "create new content to this directory",
 	}
+	fmtDryRunFlag = cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "print files that need formatting, do not write",
+	}
+	migrateDryRunFlag = cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "print migrations and changed files, do not write",
+	}
+	tagDryRunFlag = cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "print posts to change, do not write",
+	}
+	syndicateDryRunFlag = cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "print articles to cross-post, do not push",
+	}
+	graphFormatFlag = cli.StringFlag{
+		Name:  "format",
+		Value: "dot",
+		Usage: "format of content graph, 'dot' or 'json'",
+	}
+	graphOutputFlag = cli.StringFlag{
+		Name:  "output",
+		Usage: "write content graph to file, print it by default",
+	}
+	schemaKindFlag = cli.StringFlag{
+		Name:  "kind",
+		Value: "post",
+		Usage: "front-matter of 'post' or 'page'",
+	}
+	schemaOutputFlag = cli.StringFlag{
+		Name:  "output",
+		Usage: "write schema to file, print it by default",
+	}
+	serviceNameFlag = cli.StringFlag{
+		Name:  "name",
+		Value: "pugo",
+		Usage: "name of systemd unit or Windows task",
+	}
+	serviceUserFlag = cli.StringFlag{
+		Name:  "user",
+		Usage: "account to run server, the user running sudo or current user on Linux, 'NetworkService' on Windows by default",
+	}
+	serviceDirFlag = cli.StringFlag{
+		Name:  "dir",
+		Usage: "working directory of server, current directory by default",
+	}
+	serviceRestartFlag = cli.StringFlag{
+		Name:  "restart",
+		Value: "on-failure",
+		Usage: "restart policy, 'always' or 'on-failure', Windows only supports 'on-failure'",
+	}
+	serviceUserUnitFlag = cli.BoolFlag{
+		Name:  "user-unit",
+		Usage: "install systemd unit of current user, root is not needed",
+	}
+	servicePrintFlag = cli.BoolFlag{
+		Name:  "print",
+		Usage: "print service definition, do not install",
+	}
+	cacheRemoteFlag = cli.StringFlag{
+		Name:   "remote",
+		EnvVar: "PUGO_CACHE_REMOTE",
+		Usage:  "remote url of cache, 's3://bucket/key' or 'https://host/path', 'cache_remote' in meta by default",
+	}
+	cacheTokenFlag = cli.StringFlag{
+		Name:   "token",
+		EnvVar: "PUGO_CACHE_TOKEN",
+		Usage:  "bearer token to http remote of cache",
+	}
+	publishConfigFlag = cli.StringFlag{
+		Name:  "config",
+		Value: "publish.toml",
+		Usage: "file of builds and deploy targets",
+	}
+	testSourceFlag = cli.StringFlag{
+		Name:  "source",
+		Usage: "fixtures source directory, fixtures of 'pugo dev fixtures' are generated by default",
+	}
+	testThemeFlag = cli.StringFlag{
+		Name:   "theme",
+		Value:  "source/theme/default",
+		EnvVar: "PUGO_THEME",
+		Usage:  "theme directory to test",
+	}
+	testGoldenFlag = cli.StringFlag{
+		Name:  "golden",
+		Usage: "golden snapshots directory, default is testdata/golden in theme",
+	}
+	testUpdateFlag = cli.BoolFlag{
+		Name:  "update",
+		Usage: "update golden snapshots with rendered files",
+	}
+	devFixturesToFlag = cli.StringFlag{
+		Name:  "to",
+		Value: "dir://fixtures",
+		Usage: "generate fixtures to this directory",
+	}
+	devFixturesPostsFlag = cli.IntFlag{
+		Name:  "posts",
+		Value: 24,
+		Usage: "number of extra short posts",
+	}
+	themeRemoteFlag = cli.BoolFlag{
+		Name:  "remote",
+		Usage: "list themes in community index",
+	}
+	themeIndexFlag = cli.StringFlag{
+		Name:  "index",
+		Value: theme.DefaultIndexURL,
+		Usage: "url of community themes index",
+	}
+	themeDirFlag = cli.StringFlag{
+		Name:  "dir",
+		Value: "source/theme",
+		Usage: "directory of local themes",
+	}
 	newOnlyDocFlag = cli.BoolFlag{
 		Name:  "doc",
 		Usage: "extract documentation data",
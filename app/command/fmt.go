@@ -0,0 +1,149 @@
+package command
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Unknwon/com"
+	"github.com/go-xiaohei/pugo/app/builder"
+	"github.com/go-xiaohei/pugo/app/helper"
+	"github.com/go-xiaohei/pugo/app/model"
+	"github.com/urfave/cli"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+var (
+	// Fmt is command of 'fmt'
+	Fmt = cli.Command{
+		Name:  "fmt",
+		Usage: "normalize front-matter of posts and pages",
+		Flags: []cli.Flag{
+			buildSourceFlag,
+			fmtDryRunFlag,
+			debugFlag,
+		},
+		Before: Before,
+		Action: fmtContent,
+	}
+)
+
+func fmtContent(c *cli.Context) error {
+	ctx := newContext(c, false)
+	meta, err := builder.ReadSecondMeta(ctx.SrcDir())
+	if err != nil {
+		log15.Crit("Fmt|%s", err.Error())
+	}
+	ctx.Source = builder.NewSource(meta)
+	if err = ctx.ReadIgnore(); err != nil {
+		log15.Crit("Fmt|%s", err.Error())
+	}
+
+	var (
+		dryRun = c.Bool("dry-run")
+		count  int
+		// dates are parsed in timezone of meta, so filled dates are formatted in it
+		loc = meta.Meta.Location()
+	)
+	fmtDir := func(dir string, isPost bool) {
+		if !com.IsDir(dir) {
+			return
+		}
+		filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			// ignored files are not read in building, they can be drafts in other format
+			if ctx.IsIgnored(p, fi.IsDir()) {
+				log15.Debug("Fmt|Ignore|%s", p)
+				if fi.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if fi.IsDir() || filepath.Ext(p) != ".md" {
+				return nil
+			}
+			changed, err := fmtFile(p, fi.ModTime().In(loc), isPost, dryRun)
+			if err != nil {
+				log15.Warn("Fmt|%s|%s", p, err.Error())
+				return nil
+			}
+			if changed {
+				count++
+				if dryRun {
+					log15.Info("Fmt|Change|%s", p)
+					return nil
+				}
+				log15.Info("Fmt|Write|%s", p)
+			}
+			return nil
+		})
+	}
+	fmtDir(ctx.SrcPostDir(), true)
+	fmtDir(ctx.SrcPageDir(), false)
+	log15.Info("Fmt|Done|%d files", count)
+	return nil
+}
+
+// fmtFile formats front-matter of content file,
+// it fills uuid of post and date of post or page
+func fmtFile(file string, modTime time.Time, isPost, dryRun bool) (bool, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return false, err
+	}
+	if !utf8.Valid(data) {
+		log15.Debug("Fmt|Skip|%s|not utf-8", file)
+		return false, nil
+	}
+	var (
+		v    interface{} = new(model.Page)
+		fill             = func(meta map[string]interface{}) {
+			if _, ok := meta["date"]; !ok {
+				meta["date"] = modTime.Format("2006-01-02 15:04:05")
+			}
+		}
+	)
+	if isPost {
+		v = new(model.Post)
+		fillDate := fill
+		fill = func(meta map[string]interface{}) {
+			fillDate(meta)
+			if _, ok := meta["uuid"]; !ok {
+				if uuid, err := helper.NewUUID(); err == nil {
+					meta["uuid"] = uuid
+				}
+			}
+		}
+	}
+	out, err := model.FormatFrontMatter(file, data, v, fill)
+	if err != nil {
+		return false, err
+	}
+	if bytes.Equal(out, data) {
+		return false, nil
+	}
+
+	// make sure formatted content is still correct
+	text, _ := helper.DecodeText(out, "")
+	if isPost {
+		_, err = model.NewPostOfBytes(file, text, nil)
+	} else {
+		_, err = model.NewPageOfBytes(file, "", text, nil)
+	}
+	if err != nil {
+		return false, err
+	}
+	if dryRun {
+		return true, nil
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return false, err
+	}
+	return true, ioutil.WriteFile(file, out, info.Mode())
+}
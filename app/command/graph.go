@@ -0,0 +1,79 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/go-xiaohei/pugo/app/builder"
+	"github.com/urfave/cli"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+var (
+	// Graph is command of 'graph', to export internal links of contents
+	Graph = cli.Command{
+		Name:  "graph",
+		Usage: "export internal links of posts and pages, and find orphans and dead-ends",
+		Flags: []cli.Flag{
+			buildSourceFlag,
+			buildThemeFlag,
+			graphFormatFlag,
+			graphOutputFlag,
+			debugFlag,
+		},
+		Before: Before,
+		Action: exportGraph,
+	}
+)
+
+func exportGraph(c *cli.Context) error {
+	ctx := newContext(c, false)
+	if err := ctx.DryRun(); err != nil {
+		log15.Crit("Graph|%s", err.Error())
+	}
+	defer ctx.Close()
+	if builder.Read(ctx); ctx.Err == nil {
+		builder.ReadTheme(ctx)
+	}
+	if ctx.Err == nil {
+		builder.AssembleSource(ctx)
+	}
+	if ctx.Err != nil {
+		log15.Crit("Graph|%s", ctx.Err.Error())
+	}
+	g := builder.LinkGraph(ctx)
+
+	var (
+		data []byte
+		err  error
+	)
+	switch c.String("format") {
+	case "dot":
+		data = g.DOT()
+	case "json":
+		data, err = g.JSON()
+	default:
+		log15.Crit("Graph|Format '%s' is not 'dot' or 'json'", c.String("format"))
+	}
+	if err != nil {
+		log15.Crit("Graph|%s", err.Error())
+	}
+
+	for _, u := range g.Orphans {
+		log15.Warn("Graph|Orphan|%s", u)
+	}
+	for _, u := range g.DeadEnds {
+		log15.Warn("Graph|Dead-end|%s", u)
+	}
+	log15.Info("Graph|%d contents, %d orphans, %d dead-ends", len(g.Nodes), len(g.Orphans), len(g.DeadEnds))
+
+	if file := c.String("output"); file != "" {
+		if err = ioutil.WriteFile(file, data, 0644); err != nil {
+			log15.Crit("Graph|%s", err.Error())
+		}
+		log15.Info("Graph|Write|%s", file)
+		return nil
+	}
+	os.Stdout.Write(data)
+	return nil
+}
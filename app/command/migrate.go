@@ -0,0 +1,37 @@
+package command
+
+import (
+	"github.com/go-xiaohei/pugo/app/builder"
+	"github.com/urfave/cli"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+var (
+	// Migrate is command of 'migrate'
+	Migrate = cli.Command{
+		Name:  "migrate",
+		Usage: "upgrade config and content for current PuGo version",
+		Flags: []cli.Flag{
+			buildSourceFlag,
+			buildThemeFlag,
+			migrateDryRunFlag,
+			debugFlag,
+		},
+		Before: Before,
+		Action: migrate,
+	}
+)
+
+func migrate(c *cli.Context) error {
+	ctx := newContext(c, false)
+	builder.Read(ctx)
+	if ctx.Err != nil {
+		log15.Crit("Migrate|%s", ctx.Err.Error())
+	}
+	applied, err := builder.Migrate(ctx, c.Bool("dry-run"))
+	if err != nil {
+		log15.Crit("Migrate|%s", err.Error())
+	}
+	log15.Info("Migrate|Done|%d migrations", len(applied))
+	return nil
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/BurntSushi/toml"
 	"github.com/Unknwon/com"
 	"github.com/go-xiaohei/pugo/app/asset"
+	"github.com/go-xiaohei/pugo/app/helper"
 	"github.com/go-xiaohei/pugo/app/model"
 	"github.com/urfave/cli"
 	"gopkg.in/inconshreveable/log15.v2"
@@ -76,7 +77,12 @@ func newPost(args []string, dstDir string) error {
 		return errors.New("File Exist")
 	}
 
+	uuid, err := helper.NewUUID()
+	if err != nil {
+		return err
+	}
 	post := &model.Post{
+		UUID:      uuid,
 		Title:     strings.Join(args, " "),
 		Slug:      fileKey,
 		Desc:      strings.Join(args, " "),
@@ -0,0 +1,45 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/go-xiaohei/pugo/app/publish"
+	"github.com/urfave/cli"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+var (
+	// Publish is command of 'publish', to build variants and deploy them to targets
+	Publish = cli.Command{
+		Name:      "publish",
+		Usage:     "build variants of site and deploy them to targets in config",
+		ArgsUsage: "[target...]",
+		Flags: []cli.Flag{
+			publishConfigFlag,
+			debugFlag,
+		},
+		Before: Before,
+		Action: func(c *cli.Context) error {
+			m, err := publish.LoadMatrix(c.String("config"))
+			if err != nil {
+				log15.Crit("Publish|%s", err.Error())
+			}
+			results, err := m.Run(c.Args())
+			if err != nil {
+				log15.Crit("Publish|%s", err.Error())
+			}
+			failed := 0
+			for _, r := range results {
+				fmt.Println(r.String())
+				if r.Err != nil || r.Skipped {
+					failed++
+				}
+			}
+			if failed > 0 {
+				return cli.NewExitError(fmt.Sprintf("Publish|Fail|%d of %d builds and targets", failed, len(results)), 1)
+			}
+			log15.Info("Publish|Done|%d builds and targets", len(results))
+			return nil
+		},
+	}
+)
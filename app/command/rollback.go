@@ -0,0 +1,102 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/go-xiaohei/pugo/app/builder"
+	"github.com/go-xiaohei/pugo/app/extend/deploy"
+	"github.com/urfave/cli"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+var (
+	// Rollback is command of 'rollback', to restore kept build output
+	Rollback = cli.Command{
+		Name:  "rollback",
+		Usage: "restore previous build output, and deploy it by sub command",
+		Flags: []cli.Flag{
+			buildSourceFlag,
+			buildDestFlag,
+			rollbackToFlag,
+			rollbackListFlag,
+			debugFlag,
+		},
+		Before: Before,
+		Action: func(c *cli.Context) error {
+			if c.Bool("list") {
+				listArtifacts(c.String("source"))
+				return nil
+			}
+			_, dest, _ := buildDirs(c)
+			restoreArtifact(c.String("source"), c.String("to"), dest)
+			return nil
+		},
+	}
+)
+
+func init() {
+	// 'pugo rollback git --repo=...' restores to --local, then deploys it as 'pugo deploy git'
+	commands := deploy.Commands()
+	for k := range commands {
+		commands[k].Flags = append(commands[k].Flags, buildSourceFlag, rollbackToFlag,
+			deployCheckDNSFlag, deployAuditRulesFlag, deployAllowSecretsFlag, debugFlag)
+		commands[k].Before = func(c *cli.Context) error {
+			if err := Before(c); err != nil {
+				return err
+			}
+			restoreArtifact(c.String("source"), c.String("to"), c.String("local"))
+			return deployBefore(c)
+		}
+	}
+	Rollback.Subcommands = commands
+}
+
+func listArtifacts(source string) {
+	dir, err := builder.ArtifactsDir(source)
+	if err != nil {
+		log15.Crit("Rollback|%s", err.Error())
+	}
+	list, err := builder.ListArtifacts(dir)
+	if err != nil {
+		log15.Crit("Rollback|%s", err.Error())
+	}
+	for i, a := range list {
+		current := ""
+		if i == 0 {
+			current = " (last)"
+		}
+		fmt.Printf("%s  %s  %d files  %.7s%s\n", a.ID, a.Time.Format("2006-01-02 15:04:05"), len(a.Files), a.Commit, current)
+	}
+	if len(list) == 0 {
+		log15.Warn("Rollback|No build output is kept, set 'keep_builds' in [build] of meta")
+	}
+}
+
+// restoreArtifact restores kept build output of id to dest,
+// empty id is the one before last building
+func restoreArtifact(source, id, dest string) {
+	dir, err := builder.ArtifactsDir(source)
+	if err != nil {
+		log15.Crit("Rollback|%s", err.Error())
+	}
+	list, err := builder.ListArtifacts(dir)
+	if err != nil {
+		log15.Crit("Rollback|%s", err.Error())
+	}
+	var target *builder.Artifact
+	if id == "" && len(list) > 1 {
+		target = list[1]
+	}
+	for _, a := range list {
+		if id != "" && a.ID == id {
+			target = a
+		}
+	}
+	if target == nil {
+		log15.Crit("Rollback|Build output '%s' is not found, see 'pugo rollback --list'", id)
+	}
+	if err = builder.RestoreArtifact(target, dest); err != nil {
+		log15.Crit("Rollback|%s", err.Error())
+	}
+	log15.Info("Rollback|%s|%d files to %s", target.ID, len(target.Files), dest)
+}
@@ -0,0 +1,58 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/go-xiaohei/pugo/app/builder"
+	"github.com/urfave/cli"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+var (
+	// Schema is command of 'schema', to export JSON Schema of front-matter for editors
+	Schema = cli.Command{
+		Name:  "schema",
+		Usage: "export JSON Schema of front-matter for autocompletion and validation in editors",
+		Flags: []cli.Flag{
+			buildSourceFlag,
+			buildThemeFlag,
+			schemaKindFlag,
+			schemaOutputFlag,
+			debugFlag,
+		},
+		Before: Before,
+		Action: exportSchema,
+	}
+)
+
+func exportSchema(c *cli.Context) error {
+	ctx := newContext(c, false)
+	if builder.Read(ctx); ctx.Err == nil {
+		builder.ReadTheme(ctx)
+	}
+	if ctx.Err == nil {
+		ctx.Err = ctx.Theme.Load()
+	}
+	if ctx.Err != nil {
+		log15.Crit("Schema|%s", ctx.Err.Error())
+	}
+	s, err := builder.FrontMatterSchema(ctx, c.String("kind"))
+	if err != nil {
+		log15.Crit("Schema|%s", err.Error())
+	}
+	data, err := s.JSON()
+	if err != nil {
+		log15.Crit("Schema|%s", err.Error())
+	}
+
+	if file := c.String("output"); file != "" {
+		if err = ioutil.WriteFile(file, data, 0644); err != nil {
+			log15.Crit("Schema|%s", err.Error())
+		}
+		log15.Info("Schema|Write|%s", file)
+		return nil
+	}
+	os.Stdout.Write(data)
+	return nil
+}
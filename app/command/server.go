@@ -1,13 +1,19 @@
 package command
 
 import (
+	"io"
 	"net/http"
+	"os"
+	"path/filepath"
 	// pprof to profile
 	_ "net/http/pprof"
 	"time"
 
 	"github.com/Unknwon/com"
 	"github.com/go-xiaohei/pugo/app/builder"
+	"github.com/go-xiaohei/pugo/app/helper"
+	"github.com/go-xiaohei/pugo/app/metrics"
+	"github.com/go-xiaohei/pugo/app/publish"
 	"github.com/go-xiaohei/pugo/app/server"
 	"github.com/urfave/cli"
 	"gopkg.in/inconshreveable/log15.v2"
@@ -22,10 +28,17 @@ var (
 			buildSourceFlag,
 			buildDestFlag,
 			buildThemeFlag,
+			buildDraftFlag,
 			addrFlag,
 			serveStaticFlag,
+			serveSitesFlag,
+			rebuildEveryFlag,
+			accessLogFlag,
+			buildMaxMemoryFlag,
+			buildSandboxFlag,
 			debugFlag,
 			noWatchFlag,
+			liveReloadFlag,
 			cli.BoolFlag{
 				Name: "profile",
 			},
@@ -33,14 +46,18 @@ var (
 		Before: Before,
 		Action: serv,
 	}
-
-	s *server.Server
 )
 
 func serv(c *cli.Context) error {
+	if c.String("sites") != "" {
+		servSites(c.String("sites"), c.String("addr"), rebuildSchedule(c))
+		return nil
+	}
 	if c.Bool("static") {
 		ctx := newContext(c, false)
-		builder.Read(ctx)
+		if builder.Read(ctx); ctx.Err != nil {
+			log15.Crit("Server|Read|%s", ctx.Err.Error())
+		}
 
 		dstDir := ctx.DstDir()
 		if !com.IsDir(dstDir) {
@@ -49,32 +66,127 @@ func serv(c *cli.Context) error {
 		log15.Info("Server|Static|%s", dstDir)
 		s := server.New(dstDir)
 		s.SetPrefix(ctx.Source.Meta.Path)
+		s.SetAccessLog(openAccessLog(c.String("access-log")))
 		s.Run(c.String("addr"))
 		return nil
 	}
-	builder.After(func(ctx *builder.Context) {
-		if s == nil {
-			s = server.New(ctx.DstDir())
-			go s.Run(c.String("addr"))
-		}
-		if ctx.Source != nil && ctx.Source.Meta != nil {
-			s.SetPrefix(ctx.Source.Meta.Path)
-		}
-	})
+	b := builder.NewDefault()
+	b.After(serveAfterBuild(b, c.String("addr"), openAccessLog(c.String("access-log")), c.Bool("live-reload")))
 
 	if c.Bool("profile") {
 		go http.ListenAndServe("localhost:6060", nil)
 		for {
-			build(newContext(c, false), false)
+			build(b, newContext(c, false), false)
 			time.Sleep(time.Second)
 		}
 	} else {
 		if c.Bool("no-watch") {
-			buildHangUp(newContext(c, true))
+			buildHangUp(b, newContext(c, true))
 			return nil
 		}
-		build(newContext(c, true), true)
+		build(b, newContext(c, true), true)
 	}
 
 	return nil
 }
+
+// servSites publishes sites in file by webhooks,
+// each site is built once when starting, and published again by schedule
+func servSites(file, addr string, schedule helper.Schedule) {
+	sites, err := publish.LoadSites(file)
+	if err != nil {
+		log15.Crit("Publish|Sites|%s", err.Error())
+	}
+	for _, s := range sites {
+		s.Start()
+		s.Trigger()
+	}
+	if schedule != nil {
+		go func() {
+			for {
+				next := schedule.Next(time.Now())
+				if next.IsZero() {
+					log15.Error("Publish|Schedule|no next time, stop rebuilding")
+					return
+				}
+				log15.Info("Publish|Schedule|%s", next.Format(time.RFC3339))
+				time.Sleep(time.Until(next))
+				for _, s := range sites {
+					s.Trigger()
+				}
+			}
+		}()
+	}
+	publish.NewServer(sites).Run(addr)
+}
+
+// rebuildSchedule return schedule in --rebuild-every flag, nil if it's empty
+func rebuildSchedule(c *cli.Context) helper.Schedule {
+	if c.String("rebuild-every") == "" {
+		return nil
+	}
+	schedule, err := helper.ParseSchedule(c.String("rebuild-every"))
+	if err != nil {
+		log15.Crit("Server|Schedule|%s", err.Error())
+	}
+	return schedule
+}
+
+// serveAfterBuild return a handler that starts server on addr after first building,
+// and updates prefix, reactions, forms and gone urls after every building.
+// If liveReload, browsers are notified to patch or reload pages in server.ReloadPath after building.
+// The server shows building status of b in server.StatusPath and metrics in server.MetricsPath,
+// accepts reactions of posts in server.ReactionPath if reactions are enabled,
+// and forwards submissions of forms in server.FormPath by email.
+func serveAfterBuild(b *builder.Builder, addr string, accessLog io.Writer, liveReload bool) builder.Handler {
+	var (
+		s         *server.Server
+		reactions = server.NewReactions()
+		forms     = server.NewForms()
+		reload    *server.Reload
+	)
+	if liveReload {
+		reload = server.NewReload()
+	}
+	return func(ctx *builder.Context) {
+		if s == nil {
+			s = server.New(ctx.DstDir())
+			s.SetStatus(func() interface{} {
+				return b.Status()
+			})
+			s.SetMetrics(metrics.Handler())
+			s.SetAccessLog(accessLog)
+			s.SetReactions(reactions)
+			s.SetForms(forms)
+			if reload != nil {
+				s.SetReload(reload)
+			}
+			go s.Run(addr)
+		}
+		if ctx.Source != nil && ctx.Source.Meta != nil {
+			s.SetPrefix(ctx.Source.Meta.Path)
+			r := ctx.Source.Reaction
+			reactions.Update(filepath.Join(ctx.SrcDir(), r.DataFile()), r, ctx.Source.Reactions, builder.ReactionManifests(ctx))
+			forms.Update(ctx.Source.Forms, ctx.Source.Mail)
+			s.SetGone(builder.ReadGone(ctx))
+		}
+		if reload != nil {
+			reload.Notify(ctx.DstDir())
+		}
+	}
+}
+
+// openAccessLog opens file to write request logs, '-' is stdout
+func openAccessLog(file string) io.Writer {
+	if file == "" {
+		return nil
+	}
+	if file == "-" {
+		return os.Stdout
+	}
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		log15.Crit("Server|AccessLog|%s", err.Error())
+	}
+	return f
+}
@@ -0,0 +1,123 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/go-xiaohei/pugo/app/service"
+	"github.com/urfave/cli"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+var (
+	// serviceServerFlags are flags of 'server' that are passed to server in service
+	serviceServerFlags = []cli.Flag{
+		buildSourceFlag,
+		buildDestFlag,
+		buildThemeFlag,
+		addrFlag,
+		serveSitesFlag,
+		rebuildEveryFlag,
+		accessLogFlag,
+	}
+
+	// Service is command of 'service', to run server as system service
+	Service = cli.Command{
+		Name:  "service",
+		Usage: "run server as systemd unit, or Task Scheduler task started at boot on Windows",
+		Subcommands: []cli.Command{
+			{
+				Name:  "install",
+				Usage: "install and start server as service, Windows installs a Task Scheduler task instead of a Windows service",
+				Flags: append([]cli.Flag{
+					serviceNameFlag,
+					serviceUserFlag,
+					serviceDirFlag,
+					serviceRestartFlag,
+					serviceUserUnitFlag,
+					servicePrintFlag,
+					debugFlag,
+				}, serviceServerFlags...),
+				Before: Before,
+				Action: serviceInstall,
+			},
+			{
+				Name:  "uninstall",
+				Usage: "stop and remove service",
+				Flags: []cli.Flag{
+					serviceNameFlag,
+					serviceUserUnitFlag,
+					debugFlag,
+				},
+				Before: Before,
+				Action: serviceUninstall,
+			},
+		},
+	}
+)
+
+// serviceConfig return service settings by flags,
+// server flags that are set are passed to server
+func serviceConfig(c *cli.Context) *service.Config {
+	args := []string{"server"}
+	for _, f := range serviceServerFlags {
+		name := flagName(f)
+		if c.IsSet(name) || name == "addr" {
+			args = append(args, fmt.Sprintf("--%s=%s", name, c.String(name)))
+		}
+	}
+	return &service.Config{
+		Name:     c.String("name"),
+		Args:     args,
+		User:     c.String("user"),
+		Dir:      c.String("dir"),
+		Restart:  c.String("restart"),
+		UserUnit: c.Bool("user-unit"),
+	}
+}
+
+// flagName return the first name of flag, such as "dest" of "dest, destination"
+func flagName(f cli.Flag) string {
+	name := f.GetName()
+	for i, r := range name {
+		if r == ',' {
+			return name[:i]
+		}
+	}
+	return name
+}
+
+func serviceInstall(c *cli.Context) error {
+	cfg := serviceConfig(c)
+	if c.Bool("print") {
+		var (
+			data []byte
+			err  error
+		)
+		if runtime.GOOS == "windows" {
+			data, err = service.WindowsTask(cfg)
+		} else {
+			data, err = service.Systemd(cfg)
+		}
+		if err != nil {
+			log15.Crit("Service|%s", err.Error())
+		}
+		os.Stdout.Write(data)
+		return nil
+	}
+	if err := service.Install(cfg); err != nil {
+		log15.Crit("Service|Install|%s", err.Error())
+	}
+	log15.Info("Service|Install|%s|%s", cfg.Name, cfg.User)
+	return nil
+}
+
+func serviceUninstall(c *cli.Context) error {
+	cfg := &service.Config{Name: c.String("name"), UserUnit: c.Bool("user-unit")}
+	if err := service.Uninstall(cfg); err != nil {
+		log15.Crit("Service|Uninstall|%s", err.Error())
+	}
+	log15.Info("Service|Uninstall|%s", cfg.Name)
+	return nil
+}
@@ -0,0 +1,40 @@
+package command
+
+import (
+	"github.com/go-xiaohei/pugo/app/builder"
+	"github.com/go-xiaohei/pugo/app/extend/syndicate"
+	"github.com/urfave/cli"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+var (
+	// Syndicate is command of 'syndicate', to cross-post after deploying
+	Syndicate = cli.Command{
+		Name:  "syndicate",
+		Usage: "cross-post articles to dev.to or Medium after deploying",
+		Flags: []cli.Flag{
+			buildSourceFlag,
+			buildThemeFlag,
+			syndicateDryRunFlag,
+			debugFlag,
+		},
+		Before: Before,
+		Action: syndicatePosts,
+	}
+)
+
+func syndicatePosts(c *cli.Context) error {
+	ctx := newContext(c, false)
+	if builder.Read(ctx); ctx.Err == nil {
+		builder.AssembleSource(ctx)
+	}
+	if ctx.Err != nil {
+		log15.Crit("Syndicate|%s", ctx.Err.Error())
+	}
+	count, err := syndicate.Push(ctx, c.Bool("dry-run"))
+	if err != nil {
+		log15.Crit("Syndicate|%s", err.Error())
+	}
+	log15.Info("Syndicate|Done|%d articles", count)
+	return nil
+}
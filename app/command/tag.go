@@ -0,0 +1,60 @@
+package command
+
+import (
+	"github.com/go-xiaohei/pugo/app/builder"
+	"github.com/urfave/cli"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+var (
+	// Tag is command of 'tag', to maintain tags of posts
+	Tag = cli.Command{
+		Name:  "tag",
+		Usage: "maintain tags of posts",
+		Subcommands: []cli.Command{
+			{
+				Name:      "merge",
+				Usage:     "replace old tag with new tag in posts, and redirect old tag page",
+				ArgsUsage: "<old> <new>",
+				Flags: []cli.Flag{
+					buildSourceFlag,
+					tagDryRunFlag,
+					debugFlag,
+				},
+				Before: Before,
+				Action: tagMerge,
+			},
+		},
+	}
+)
+
+func tagMerge(c *cli.Context) error {
+	if c.NArg() != 2 {
+		log15.Crit("Tag|Merge|Need old and new tag, such as 'pugo tag merge golang go'")
+	}
+	ctx := newContext(c, false)
+	meta, err := builder.ReadSecondMeta(ctx.SrcDir())
+	if err != nil {
+		log15.Crit("Tag|%s", err.Error())
+	}
+	ctx.Source = builder.NewSource(meta)
+
+	from, to := c.Args().Get(0), c.Args().Get(1)
+	dryRun := c.Bool("dry-run")
+	files, err := builder.MergeTag(ctx, from, to, dryRun)
+	if err != nil {
+		log15.Crit("Tag|Merge|%s", err.Error())
+	}
+	for _, f := range files {
+		if dryRun {
+			log15.Info("Tag|Merge|Change|%s", f)
+			continue
+		}
+		log15.Info("Tag|Merge|Write|%s", f)
+	}
+	if !dryRun {
+		log15.Info("Tag|Merge|Redirect|'%s' to '%s' in %s", from, to, builder.RedirectsFile)
+	}
+	log15.Info("Tag|Merge|Done|%d posts, build to update tag pages and feeds", len(files))
+	return nil
+}
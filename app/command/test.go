@@ -0,0 +1,83 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/go-xiaohei/pugo/app/builder"
+	"github.com/urfave/cli"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+var (
+	// Test is command of 'test'
+	Test = cli.Command{
+		Name:  "test",
+		Usage: "render fixtures with theme and compare with golden snapshots",
+		Flags: []cli.Flag{
+			testSourceFlag,
+			testThemeFlag,
+			testGoldenFlag,
+			testUpdateFlag,
+			debugFlag,
+		},
+		Before: Before,
+		Action: testTheme,
+	}
+)
+
+func testTheme(c *cli.Context) error {
+	tmpDir, err := ioutil.TempDir("", "pugo-test")
+	if err != nil {
+		log15.Crit("Test|%s", err.Error())
+	}
+	// log15.Crit exits, remove temporary directory before it
+	crit := func(format string, args ...interface{}) {
+		os.RemoveAll(tmpDir)
+		log15.Crit(format, args...)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// site source is not rendered, so snapshots do not change with new posts
+	source := c.String("source")
+	if source == "" {
+		source = filepath.Join(tmpDir, "fixtures")
+		if err = generateFixtures(source, devFixturesPostsFlag.Value); err != nil {
+			crit("Test|Fixtures|%s", err.Error())
+		}
+	}
+	ctx := builder.NewContext(c, source, filepath.Join(tmpDir, "dest"), c.String("theme"))
+	if !ctx.IsValid() {
+		crit("Test|Must have values in 'source' & 'theme'")
+	}
+	if err = ctx.DryRun(); err != nil {
+		crit("Test|%s", err.Error())
+	}
+	defer ctx.Close()
+	builder.NewDefault().Build(ctx)
+	if ctx.Err != nil {
+		crit("Test|Build|%s", ctx.Err.Error())
+	}
+
+	goldenDir := c.String("golden")
+	if goldenDir == "" {
+		goldenDir = filepath.Join(ctx.Theme.Dir(), "testdata", "golden")
+	}
+	diffs, err := builder.CompareGolden(ctx.DstDir(), goldenDir, c.Bool("update"))
+	if err != nil {
+		crit("Test|%s", err.Error())
+	}
+	if c.Bool("update") {
+		log15.Info("Test|Update|%s", goldenDir)
+		return nil
+	}
+	for _, d := range diffs {
+		log15.Error("Test|%s", d)
+	}
+	if len(diffs) > 0 {
+		crit("Test|Fail|%d files", len(diffs))
+	}
+	log15.Info("Test|Pass|%s", goldenDir)
+	return nil
+}
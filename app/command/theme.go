@@ -0,0 +1,150 @@
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Unknwon/com"
+	"github.com/go-xiaohei/pugo/app/builder"
+	"github.com/go-xiaohei/pugo/app/theme"
+	"github.com/urfave/cli"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+var (
+	// Theme is command of 'theme', to find and preview themes
+	Theme = cli.Command{
+		Name:  "theme",
+		Usage: "list and preview themes",
+		Subcommands: []cli.Command{
+			{
+				Name:  "list",
+				Usage: "list local themes, or themes in community index",
+				Flags: []cli.Flag{
+					themeRemoteFlag,
+					themeIndexFlag,
+					themeDirFlag,
+					debugFlag,
+				},
+				Before: Before,
+				Action: themeList,
+			},
+			{
+				Name:      "preview",
+				Usage:     "build local content with a theme and serve it",
+				ArgsUsage: "<name>",
+				Flags: []cli.Flag{
+					buildSourceFlag,
+					themeIndexFlag,
+					themeDirFlag,
+					addrFlag,
+					liveReloadFlag,
+					buildSandboxFlag,
+					debugFlag,
+				},
+				Before: Before,
+				Action: themePreview,
+			},
+		},
+	}
+)
+
+func themeList(c *cli.Context) error {
+	if c.Bool("remote") {
+		index, err := theme.FetchIndex(c.String("index"))
+		if err != nil {
+			log15.Crit("Theme|Index|%s", err.Error())
+		}
+		for _, item := range index {
+			line := fmt.Sprintf("%-20s %-10s %s", item.Name, item.Version, item.Desc)
+			if len(item.Tags) > 0 {
+				line += " [" + strings.Join(item.Tags, ",") + "]"
+			}
+			if !item.IsCompatible() {
+				line += " (need PuGo " + item.MinVersion + ")"
+			}
+			fmt.Println(line)
+		}
+		log15.Info("Theme|Index|%d themes", len(index))
+		return nil
+	}
+	dir, err := toDir(c.String("dir"))
+	if err != nil {
+		log15.Crit("Theme|%s", err.Error())
+	}
+	themes, err := theme.ListLocal(dir)
+	if err != nil {
+		log15.Crit("Theme|%s", err.Error())
+	}
+	for _, t := range themes {
+		name, desc := filepath.Base(t.Dir), ""
+		if t.Meta != nil {
+			desc = t.Meta.Desc
+		}
+		fmt.Printf("%-20s %s\n", name, desc)
+	}
+	return nil
+}
+
+// themePreview builds source with a theme to temporary directory and serves it,
+// the theme is a local theme in flag directory, or downloaded from community index.
+// Temporary files are removed after preview.
+func themePreview(c *cli.Context) error {
+	name := c.Args().First()
+	if name == "" {
+		log15.Crit("Theme|Preview|need theme name")
+	}
+	tmpDir, err := ioutil.TempDir("", "pugo-preview-")
+	if err != nil {
+		log15.Crit("Theme|Preview|%s", err.Error())
+	}
+	defer os.RemoveAll(tmpDir)
+
+	themeDir, err := previewTheme(name, c.String("dir"), c.String("index"), filepath.Join(tmpDir, "theme"))
+	if err != nil {
+		os.RemoveAll(tmpDir)
+		log15.Crit("Theme|Preview|%s", err.Error())
+	}
+	log15.Info("Theme|Preview|%s", themeDir)
+
+	b := builder.NewDefault()
+	b.After(serveAfterBuild(b, c.String("addr"), nil, c.Bool("live-reload")))
+	ctx := builder.NewContext(c, c.String("source"), filepath.Join(tmpDir, "dest"), themeDir)
+	// downloaded themes are untrusted, always in sandbox mode
+	ctx.Sandbox = c.Bool("sandbox") || strings.HasPrefix(themeDir, tmpDir)
+	if err = ctx.DryRun(); err != nil {
+		os.RemoveAll(tmpDir)
+		log15.Crit("Theme|Preview|%s", err.Error())
+	}
+	defer ctx.Close()
+	build(b, ctx, true)
+	return nil
+}
+
+// previewTheme return directory of theme to preview,
+// remote theme is downloaded to tmpDir
+func previewTheme(name, localDir, indexURL, tmpDir string) (string, error) {
+	if dir, err := toDir(localDir); err == nil {
+		if local := filepath.Join(dir, name); com.IsDir(local) {
+			return local, nil
+		}
+	}
+	index, err := theme.FetchIndex(indexURL)
+	if err != nil {
+		return "", err
+	}
+	item := index.Get(name)
+	if item == nil {
+		return "", fmt.Errorf("theme '%s' is not found", name)
+	}
+	if !item.IsCompatible() {
+		log15.Warn("Theme|Preview|%s|need PuGo %s", item.Name, item.MinVersion)
+	}
+	if err = item.Install(tmpDir); err != nil {
+		return "", err
+	}
+	return tmpDir, nil
+}
@@ -0,0 +1,111 @@
+package deploy
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Unknwon/com"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+// assetManifestFile is asset manifest written in building, same as builder.AssetManifestFile
+const assetManifestFile = "assets.json"
+
+// staleAssets return old fingerprinted files listed in assets.json in local directory.
+// They are not referenced by the site any more, so deploy methods remove them from targets.
+// Files existing in local directory are never stale.
+func staleAssets(local string, logger log15.Logger) []string {
+	data, err := ioutil.ReadFile(filepath.Join(local, assetManifestFile))
+	if err != nil {
+		return nil
+	}
+	var manifest struct {
+		Stale []string `json:"stale"`
+	}
+	if err = json.Unmarshal(data, &manifest); err != nil {
+		logger.Warn("Deploy|Assets|%s", err.Error())
+		return nil
+	}
+	var files []string
+	for _, f := range manifest.Stale {
+		f = strings.TrimPrefix(path.Clean("/"+f), "/")
+		if f == "" || com.IsFile(filepath.Join(local, filepath.FromSlash(f))) {
+			continue
+		}
+		files = append(files, f)
+	}
+	return files
+}
+
+// assetHashes return md5 hashes of static files in asset manifest data
+func assetHashes(data []byte) map[string]string {
+	var manifest struct {
+		Hashes map[string]string `json:"hashes"`
+	}
+	if json.Unmarshal(data, &manifest) != nil {
+		return nil
+	}
+	return manifest.Hashes
+}
+
+// readRemoteManifest reads asset manifest of last deploying from target
+func readRemoteManifest(r io.ReadCloser) []byte {
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// uploadFiles return relative files in local directory to upload.
+// Static files with same hashes in local asset manifest and remote, the manifest in target of last deploying,
+// are not changed, so they are skipped. The manifest is the last file,
+// so target has new hashes only after other files are uploaded.
+func uploadFiles(local string, remote []byte, logger log15.Logger) ([]string, error) {
+	var (
+		files    []string
+		skipped  int
+		manifest bool
+		hashes   map[string]string
+		old      = assetHashes(remote)
+	)
+	if data, err := ioutil.ReadFile(filepath.Join(local, assetManifestFile)); err == nil {
+		hashes = assetHashes(data)
+	}
+	err := filepath.Walk(local, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			return nil
+		}
+		rel, _ := filepath.Rel(local, p)
+		rel = filepath.ToSlash(rel)
+		if rel == assetManifestFile {
+			manifest = true
+			return nil
+		}
+		if h := hashes[rel]; h != "" && old[rel] == h {
+			skipped++
+			return nil
+		}
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if manifest {
+		files = append(files, assetManifestFile)
+	}
+	if skipped > 0 {
+		logger.Info("Deploy|Skip|%d unchanged files", skipped)
+	}
+	return files, nil
+}
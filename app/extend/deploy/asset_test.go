@@ -0,0 +1,59 @@
+package deploy
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+func TestUploadFiles(t *testing.T) {
+	Convey("Upload Files", t, func() {
+		dir, err := ioutil.TempDir("", "pugo-upload")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		files := map[string]string{
+			"index.html":              "<p>hello</p>",
+			"css/style.1a2b3c4d.css":  "body{}",
+			"css/prism.css":           "pre{}",
+			"img/logo.png":            "png",
+			assetManifestFile:         `{"assets":{},"hashes":{"css/style.1a2b3c4d.css":"a","css/prism.css":"b","img/logo.png":"c"}}`,
+			"zz/after-manifest.html":  "z",
+			"assets/inside-dir.json":  "{}",
+			"aaa-before-manifest.txt": "a",
+		}
+		for name, data := range files {
+			p := filepath.Join(dir, filepath.FromSlash(name))
+			So(os.MkdirAll(filepath.Dir(p), os.ModePerm), ShouldBeNil)
+			So(ioutil.WriteFile(p, []byte(data), 0644), ShouldBeNil)
+		}
+		logger := log15.New()
+		logger.SetHandler(log15.DiscardHandler())
+
+		// first deploying uploads all files, manifest is the last one
+		list, err := uploadFiles(dir, nil, logger)
+		So(err, ShouldBeNil)
+		So(list, ShouldHaveLength, len(files))
+		So(list[len(list)-1], ShouldEqual, assetManifestFile)
+
+		// unchanged static files are skipped, changed and unknown files are uploaded
+		remote := []byte(`{"hashes":{"css/style.1a2b3c4d.css":"a","css/prism.css":"old","index.html":"x"}}`)
+		list, err = uploadFiles(dir, remote, logger)
+		So(err, ShouldBeNil)
+		So(list, ShouldNotContain, "css/style.1a2b3c4d.css")
+		So(list, ShouldContain, "css/prism.css")
+		So(list, ShouldContain, "img/logo.png")
+		So(list, ShouldContain, "index.html")
+		So(list, ShouldHaveLength, len(files)-1)
+		So(list[len(list)-1], ShouldEqual, assetManifestFile)
+
+		// broken remote manifest skips nothing
+		list, err = uploadFiles(dir, []byte("{"), logger)
+		So(err, ShouldBeNil)
+		So(list, ShouldHaveLength, len(files))
+	})
+}
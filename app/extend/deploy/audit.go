@@ -0,0 +1,186 @@
+package deploy
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+const auditMaxFileSize = 5 << 20
+
+type (
+	// AuditRule is named pattern of content that should not be deployed
+	AuditRule struct {
+		Name    string `toml:"name"`
+		Pattern string `toml:"pattern"`
+
+		regex *regexp.Regexp
+	}
+	// AuditRules is rules to audit files, matches of Allow patterns are not reported
+	AuditRules struct {
+		Rules []*AuditRule `toml:"rule"`
+		Allow []string     `toml:"allow"`
+
+		allow []*regexp.Regexp
+	}
+	// AuditFinding is suspicious content in file
+	AuditFinding struct {
+		File  string
+		Line  int
+		Rule  string
+		Match string
+	}
+)
+
+// auditSecretRules are always used to find accidental secrets
+var auditSecretRules = []*AuditRule{
+	{Name: "private key", Pattern: `-----BEGIN (RSA |DSA |EC |OPENSSH |PGP |ENCRYPTED )?PRIVATE KEY( BLOCK)?-----`},
+	{Name: "aws access key", Pattern: `\b(AKIA|ASIA)[0-9A-Z]{16}\b`},
+	{Name: "github token", Pattern: `\bgh[pousr]_[0-9A-Za-z]{36,}\b`},
+	{Name: "slack token", Pattern: `\bxox[abprs]-[0-9A-Za-z-]{10,}\b`},
+	{Name: "google api key", Pattern: `\bAIza[0-9A-Za-z_-]{35}\b`},
+	{Name: "stripe secret key", Pattern: `\b[sr]k_live_[0-9A-Za-z]{20,}\b`},
+}
+
+func init() {
+	for _, r := range auditSecretRules {
+		r.regex = regexp.MustCompile(r.Pattern)
+	}
+}
+
+// ReadAuditRules reads extra rules from toml file, such as patterns of PII
+func ReadAuditRules(file string) (*AuditRules, error) {
+	rules := new(AuditRules)
+	if file == "" {
+		return rules, nil
+	}
+	if _, err := toml.DecodeFile(file, rules); err != nil {
+		return nil, err
+	}
+	var err error
+	for _, r := range rules.Rules {
+		if r.Name == "" {
+			r.Name = r.Pattern
+		}
+		if r.regex, err = regexp.Compile(r.Pattern); err != nil {
+			return nil, fmt.Errorf("audit rule '%s': %s", r.Name, err.Error())
+		}
+	}
+	for _, a := range rules.Allow {
+		regex, err := regexp.Compile(a)
+		if err != nil {
+			return nil, fmt.Errorf("audit allow '%s': %s", a, err.Error())
+		}
+		rules.allow = append(rules.allow, regex)
+	}
+	return rules, nil
+}
+
+func (rules *AuditRules) isAllowed(match []byte) bool {
+	for _, a := range rules.allow {
+		if a.Match(match) {
+			return true
+		}
+	}
+	return false
+}
+
+// Audit finds secrets and matches of rules in text files of local directory
+func Audit(local string, rules *AuditRules) ([]AuditFinding, error) {
+	all := append(append([]*AuditRule{}, auditSecretRules...), rules.Rules...)
+	var findings []AuditFinding
+	err := filepath.Walk(local, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			if fi.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if fi.Size() > auditMaxFileSize {
+			return nil
+		}
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if isBinary(data) {
+			return nil
+		}
+		rel, _ := filepath.Rel(local, p)
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(nil, auditMaxFileSize)
+		for line := 1; scanner.Scan(); line++ {
+			for _, r := range all {
+				for _, match := range r.regex.FindAll(scanner.Bytes(), -1) {
+					if rules.isAllowed(match) {
+						continue
+					}
+					findings = append(findings, AuditFinding{
+						File:  filepath.ToSlash(rel),
+						Line:  line,
+						Rule:  r.Name,
+						Match: maskSecret(string(match)),
+					})
+				}
+			}
+		}
+		return scanner.Err()
+	})
+	return findings, err
+}
+
+// CheckAudit audits local directory before deploying,
+// it returns error if anything is found, unless allowed explicitly
+func CheckAudit(local, rulesFile string, allow bool) error {
+	return CheckAuditLog(local, rulesFile, allow, log15.Root())
+}
+
+// CheckAuditLog is CheckAudit that logs findings to logger
+func CheckAuditLog(local, rulesFile string, allow bool, logger log15.Logger) error {
+	rules, err := ReadAuditRules(rulesFile)
+	if err != nil {
+		return err
+	}
+	findings, err := Audit(local, rules)
+	if err != nil {
+		return err
+	}
+	for _, f := range findings {
+		logger.Warn("Deploy|Audit|%s:%d|%s|%s", f.File, f.Line, f.Rule, f.Match)
+	}
+	if len(findings) == 0 {
+		logger.Debug("Deploy|Audit|%s|OK", local)
+		return nil
+	}
+	if allow {
+		logger.Warn("Deploy|Audit|%d findings are allowed", len(findings))
+		return nil
+	}
+	return fmt.Errorf("audit found %d secrets or sensitive contents in '%s'", len(findings), local)
+}
+
+// isBinary return whether data looks like binary, NUL byte in the beginning
+func isBinary(data []byte) bool {
+	if len(data) > 512 {
+		data = data[:512]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
+
+// maskSecret keeps first 4 characters of secret
+func maskSecret(s string) string {
+	if len(s) <= 4 {
+		return "****"
+	}
+	return s[:4] + "****"
+}
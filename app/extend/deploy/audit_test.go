@@ -0,0 +1,121 @@
+package deploy
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAudit(t *testing.T) {
+	Convey("Audit Patterns", t, func() {
+		// secrets are built from parts, so this file is not flagged by scanners
+		alnum := func(n int) string {
+			return strings.Repeat("aB3", n)[:n]
+		}
+		upper := func(n int) string {
+			return strings.Repeat("Q7Z", n)[:n]
+		}
+		cases := []struct {
+			text string
+			rule string
+		}{
+			{"-----BEGIN " + "PRIVATE KEY-----", "private key"},
+			{"-----BEGIN RSA " + "PRIVATE KEY-----", "private key"},
+			{"-----BEGIN OPENSSH " + "PRIVATE KEY-----", "private key"},
+			{"key: AKIA" + upper(16), "aws access key"},
+			{"ASIA" + upper(16) + " ", "aws access key"},
+			{"token=gh" + "p_" + alnum(36), "github token"},
+			{"gh" + "s_" + alnum(40), "github token"},
+			{"xox" + "b-" + alnum(24), "slack token"},
+			{"AIza" + alnum(35), "google api key"},
+			{"sk_" + "live_" + alnum(24), "stripe secret key"},
+			{"rk_" + "live_" + alnum(20), "stripe secret key"},
+
+			// not secrets
+			{"-----BEGIN PUBLIC KEY-----", ""},
+			{"-----BEGIN CERTIFICATE-----", ""},
+			{"AKIA" + upper(10), ""},
+			{"XAKIA" + upper(16), ""},
+			{"AKIA" + upper(16) + "X", ""},
+			{"gh" + "p_" + alnum(20), ""},
+			{"gh" + "x_" + alnum(36), ""},
+			{"xox" + "b-short", ""},
+			{"AIza" + alnum(20), ""},
+			{"sk_" + "test_" + alnum(24), ""},
+			{"sk_" + "live_" + alnum(10), ""},
+			{"plain text about AKIA and ghp_ prefixes", ""},
+		}
+		for _, c := range cases {
+			var found []string
+			for _, r := range auditSecretRules {
+				if r.regex.MatchString(c.text) {
+					found = append(found, r.Name)
+				}
+			}
+			if c.rule == "" {
+				So(found, ShouldBeEmpty)
+			} else {
+				So(found, ShouldResemble, []string{c.rule})
+			}
+		}
+	})
+
+	Convey("Audit Directory", t, func() {
+		dir, err := ioutil.TempDir("", "pugo-audit")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		secret := "AKIA" + strings.Repeat("Q", 16)
+		files := map[string]string{
+			"index.html":        "<p>hello</p>\n<!-- " + secret + " -->\n",
+			"about.html":        "<p>mail: someone@example.com</p>\n",
+			"allowed.html":      "AKIA" + strings.Repeat("X", 16) + "\n",
+			".git/config":       secret + "\n",
+			"img/logo.png":      "\x00PNG" + secret,
+			"post/clean.html":   "<p>nothing here</p>\n",
+			"post/private.html": "line\nline\n-----BEGIN EC " + "PRIVATE KEY-----\n",
+		}
+		for name, data := range files {
+			p := filepath.Join(dir, name)
+			So(os.MkdirAll(filepath.Dir(p), os.ModePerm), ShouldBeNil)
+			So(ioutil.WriteFile(p, []byte(data), 0644), ShouldBeNil)
+		}
+		rulesFile := filepath.Join(dir, "..", filepath.Base(dir)+".toml")
+		So(ioutil.WriteFile(rulesFile, []byte(`allow = ["AKIAX+"]
+[[rule]]
+name = "email"
+pattern = '[a-z]+@example\.com'
+`), 0644), ShouldBeNil)
+		defer os.Remove(rulesFile)
+
+		rules, err := ReadAuditRules(rulesFile)
+		So(err, ShouldBeNil)
+		findings, err := Audit(dir, rules)
+		So(err, ShouldBeNil)
+		So(findings, ShouldHaveLength, 3)
+		got := make(map[string]AuditFinding)
+		for _, f := range findings {
+			got[f.File] = f
+		}
+		So(got["index.html"].Line, ShouldEqual, 2)
+		So(got["index.html"].Rule, ShouldEqual, "aws access key")
+		So(got["index.html"].Match, ShouldEqual, "AKIA****")
+		So(got["about.html"].Rule, ShouldEqual, "email")
+		So(got["post/private.html"].Line, ShouldEqual, 3)
+
+		So(CheckAudit(dir, rulesFile, false), ShouldNotBeNil)
+		So(CheckAudit(dir, rulesFile, true), ShouldBeNil)
+
+		_, err = ReadAuditRules(filepath.Join(dir, "missing.toml"))
+		So(err, ShouldNotBeNil)
+		So(ioutil.WriteFile(rulesFile, []byte(`[[rule]]
+pattern = "("
+`), 0644), ShouldBeNil)
+		_, err = ReadAuditRules(rulesFile)
+		So(err, ShouldNotBeNil)
+	})
+}
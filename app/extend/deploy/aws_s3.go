@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"os"
 	"path/filepath"
 	"time"
 
@@ -19,6 +18,8 @@ import (
 )
 
 type AwsS3 struct {
+	logging
+
 	Local     string
 	AccessKey string
 	SecretKey string
@@ -44,11 +45,12 @@ func (a *AwsS3) Command() cli.Command {
 				log15.Error("AWS|Fail|%s", err.Error())
 				return
 			}
-			if err = a2.Do(); err != nil {
+			if err = Do(a2); err != nil {
 				log15.Error("AWS|Fail|%s", err.Error())
 				return
 			}
 			log15.Info("AWS|Finish|%s", time.Since(t))
+			printSitemapSubmit(ctx.String("local"))
 		},
 	}
 }
@@ -87,27 +89,31 @@ func (a *AwsS3) Do() error {
 	cfg := aws.NewConfig().WithRegion(a.Region).WithCredentials(creds)
 	s3client := s3.New(session.New(), cfg)
 
-	log15.Info("AWS|Bucket|%s", a.Region)
-
-	err = filepath.Walk(a.Local, func(path string, info os.FileInfo, err error) error {
-		if info.IsDir() {
-			return nil
-		}
-		rel, _ := filepath.Rel(a.Local, path)
-		rel = filepath.ToSlash(rel)
+	a.log().Info("AWS|Bucket|%s", a.Region)
 
-		fileData, err := ioutil.ReadFile(path)
+	var remote []byte
+	if out, err := s3client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(a.Bucket),
+		Key:    aws.String(assetManifestFile),
+	}); err == nil {
+		remote = readRemoteManifest(out.Body)
+	}
+	files, err := uploadFiles(a.Local, remote, a.log())
+	if err != nil {
+		return err
+	}
+	for _, rel := range files {
+		fileData, err := ioutil.ReadFile(filepath.Join(a.Local, filepath.FromSlash(rel)))
 		if err != nil {
 			return err
 		}
-		size := info.Size()
 		fileType := http.DetectContentType(fileData)
 		params := &s3.PutObjectInput{
 			Bucket:        aws.String(a.Bucket), // required
 			Key:           aws.String(rel),      // required
 			ACL:           aws.String("public-read"),
 			Body:          bytes.NewReader(fileData),
-			ContentLength: aws.Int64(size),
+			ContentLength: aws.Int64(int64(len(fileData))),
 			ContentType:   aws.String(fileType),
 			Metadata: map[string]*string{
 				"Key": aws.String(filepath.Base(rel)), //required
@@ -116,8 +122,18 @@ func (a *AwsS3) Do() error {
 		if _, err = s3client.PutObject(params); err != nil {
 			return err
 		}
-		log15.Info("AWS|Upload|%s", rel)
-		return nil
-	})
-	return err
+		a.log().Info("AWS|Upload|%s", rel)
+	}
+	for _, f := range staleAssets(a.Local, a.log()) {
+		params := &s3.DeleteObjectInput{
+			Bucket: aws.String(a.Bucket),
+			Key:    aws.String(f),
+		}
+		if _, err = s3client.DeleteObject(params); err != nil {
+			a.log().Warn("AWS|Del|%s|%s", f, err.Error())
+			continue
+		}
+		a.log().Info("AWS|Del|%s", f)
+	}
+	return nil
 }
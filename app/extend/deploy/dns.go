@@ -0,0 +1,32 @@
+package deploy
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+// CheckDNS checks that the domain in CNAME file of local directory is resolved
+func CheckDNS(local string) error {
+	data, err := ioutil.ReadFile(filepath.Join(local, "CNAME"))
+	if err != nil {
+		return fmt.Errorf("read CNAME: %s", err.Error())
+	}
+	domain := strings.TrimSpace(string(data))
+	if domain == "" {
+		return fmt.Errorf("CNAME file in '%s' is empty", local)
+	}
+	addrs, err := net.LookupHost(domain)
+	if err != nil {
+		return fmt.Errorf("domain '%s' is not resolved: %s", domain, err.Error())
+	}
+	if cname, err := net.LookupCNAME(domain); err == nil && strings.TrimSuffix(cname, ".") != domain {
+		log15.Info("Deploy|DNS|%s|CNAME %s", domain, cname)
+	}
+	log15.Info("Deploy|DNS|%s|%s", domain, strings.Join(addrs, ","))
+	return nil
+}
@@ -16,6 +16,8 @@ import (
 
 // Ftp is ftp deployment
 type Ftp struct {
+	logging
+
 	Local     string
 	Host      string
 	User      string
@@ -41,11 +43,12 @@ func (f *Ftp) Command() cli.Command {
 				log15.Error("Ftp|Fail|%s", err.Error())
 				return
 			}
-			if err = newFtp.Do(); err != nil {
+			if err = Do(newFtp); err != nil {
 				log15.Error("Ftp|Fail|%s", err.Error())
 				return
 			}
 			log15.Info("Ftp|Finish")
+			printSitemapSubmit(ctx.String("local"))
 		},
 	}
 }
@@ -83,7 +86,7 @@ func (f *Ftp) Do() error {
 	if err != nil {
 		return err
 	}
-	log15.Info("FTP|%s|Connect", f.Host)
+	f.log().Info("FTP|%s|Connect", f.Host)
 	defer client.Quit()
 	if f.User != "" {
 		if err = client.Login(f.User, f.Password); err != nil {
@@ -92,7 +95,7 @@ func (f *Ftp) Do() error {
 	}
 
 	// change to UTF-8 mode
-	log15.Debug("FTP|%s|UTF-8", f.Host)
+	f.log().Debug("FTP|%s|UTF-8", f.Host)
 	if _, _, err = client.Exec(ftp.StatusCommandOK, "OPTS UTF8 ON"); err != nil {
 		if !strings.Contains(err.Error(), "No need to") { // sometimes show 202, no need to set UTF8 mode because always on
 			return fmt.Errorf("OPTS UTF8 ON:%s", err.Error())
@@ -110,34 +113,47 @@ func (f *Ftp) Do() error {
 		return err
 	}
 
-	log15.Debug("FTP|UploadAll")
-	return ftpUploadAll(client, f.Local)
-}
-
-// upload files without checking diff status
-func ftpUploadAll(client *ftp.ServerConn, local string) error {
-	return filepath.Walk(local, func(path string, info os.FileInfo, err error) error {
-		if info.IsDir() {
-			return nil
+	var remote []byte
+	if r, err := client.Retr(assetManifestFile); err == nil {
+		remote = readRemoteManifest(r)
+	}
+	files, err := uploadFiles(f.Local, remote, f.log())
+	if err != nil {
+		return err
+	}
+	f.log().Debug("FTP|Upload|%d files", len(files))
+	if err = ftpUpload(client, f.Local, files, f.log()); err != nil {
+		return err
+	}
+	for _, file := range staleAssets(f.Local, f.log()) {
+		if err = client.Delete(file); err != nil {
+			f.log().Warn("FTP|Del|%s|%s", file, err.Error())
+			continue
 		}
+		f.log().Debug("FTP|Del|%s", file)
+	}
+	return nil
+}
 
-		rel, _ := filepath.Rel(local, path)
-		rel = filepath.ToSlash(rel)
-
-		makeFtpDir(client, getRecursiveDirs(filepath.Dir(rel)))
+// upload relative files in local directory
+func ftpUpload(client *ftp.ServerConn, local string, files []string, logger log15.Logger) error {
+	for _, rel := range files {
+		makeFtpDir(client, getRecursiveDirs(path.Dir(rel)))
 
 		// upload file
-		f, err := os.Open(path)
+		p := filepath.Join(local, filepath.FromSlash(rel))
+		f, err := os.Open(p)
 		if err != nil {
 			return err
 		}
-		defer f.Close()
-		if err = client.Stor(rel, f); err != nil {
+		err = client.Stor(rel, f)
+		f.Close()
+		if err != nil {
 			return err
 		}
-		log15.Debug("FTP|Stor|%s", path)
-		return nil
-	})
+		logger.Debug("FTP|Stor|%s", p)
+	}
+	return nil
 }
 
 // get dirs and subdirs
@@ -1,20 +1,23 @@
 package deploy
 
 import (
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/Unknwon/com"
+	"github.com/go-xiaohei/pugo/app/helper"
 	"github.com/urfave/cli"
 	"gopkg.in/inconshreveable/log15.v2"
 )
 
 // Git is deployment of git repository
 type Git struct {
+	logging
+
 	Repo    string
 	Message string
 	Local   string
@@ -38,11 +41,12 @@ func (g *Git) Command() cli.Command {
 				log15.Error("Git|Fail|%s", err.Error())
 				return
 			}
-			if err = g2.Do(); err != nil {
+			if err = Do(g2); err != nil {
 				log15.Error("Git|Fail|%s", err.Error())
 				return
 			}
 			log15.Info("Git|Finish")
+			printSitemapSubmit(ctx.String("local"))
 		},
 	}
 }
@@ -74,7 +78,7 @@ func (g *Git) Create(ctx *cli.Context) (Method, error) {
 
 // Do do git deploy action with built Context
 func (g *Git) Do() error {
-	log15.Debug("Git|Overwrite")
+	g.log().Debug("Git|Overwrite")
 	err := filepath.Walk(g.Local, func(path string, info os.FileInfo, err error) error {
 		if info.IsDir() {
 			return nil
@@ -86,42 +90,42 @@ func (g *Git) Do() error {
 
 		// overwrite file in repo
 		os.MkdirAll(filepath.Dir(toFile), os.ModePerm)
-		fileBytes, err := ioutil.ReadFile(path)
-		if err != nil {
-			return err
-		}
-		if err = ioutil.WriteFile(toFile, fileBytes, os.ModePerm); err != nil {
-			return err
-		}
-		return nil
+		return helper.CopyFile(path, toFile)
 	})
 	if err != nil {
 		return err
 	}
-	log15.Debug("Git|git add -A")
+	for _, f := range staleAssets(g.Local, g.log()) {
+		toFile := filepath.Join(g.Repo, filepath.FromSlash(f))
+		if com.IsFile(toFile) {
+			g.log().Debug("Git|Del|%s", f)
+			os.Remove(toFile)
+		}
+	}
+	g.log().Debug("Git|git add -A")
 	if err = runGitExec(g.Repo, []string{"add", "-A"}); err != nil {
 		return err
 	}
-	log15.Debug("Git|git commit -m")
+	g.log().Debug("Git|git commit -m")
 	message := strings.Replace(g.Message, "{t}", time.Now().Format(time.RFC1123), 1)
 	if err = runGitExec(g.Repo, []string{"commit", "-m", message}); err != nil {
 		return err
 	}
-	log15.Debug("Git|git push -f")
+	g.log().Debug("Git|git push -f")
 	if err = runGitExec(g.Repo, []string{"push", "-f"}); err != nil {
 		return err
 	}
 	return nil
 	/*
 		// git add -A
-		log15.Debug("Git|git add -A")
+		g.log().Debug("Git|git add -A")
 		_, errOut, err := com.ExecCmdDir(g.gitRepo, "git", "add", "-A")
 		if err = returnGetError(errOut, err); err != nil {
 			return err
 		}
 
 		// git commit -m "message"
-		log15.Debug("Git|git commit -m")
+		g.log().Debug("Git|git commit -m")
 		message := strings.Replace(g.gitMessage, "{t}", time.Now().Format(time.RFC1123), 1)
 		_, errOut, err = com.ExecCmdDir(g.gitRepo, "git", "commit", "-m", message)
 		if err = returnGetError(errOut, err); err != nil {
@@ -129,7 +133,7 @@ func (g *Git) Do() error {
 		}
 
 		// git push
-		log15.Debug("Git|git push -f")
+		g.log().Debug("Git|git push -f")
 		_, errOut, err = com.ExecCmdDir(g.gitRepo, "git", "push", "-f")
 		if err = returnGetError(errOut, err); err != nil {
 			return err
@@ -145,7 +149,7 @@ func runGitExec(dir string, commands []string) error {
 
 func returnGetError(errOut string, err error) error {
 	if errOut != "" && strings.Contains(errOut, "fatal:") {
-		return fmt.Errorf(errOut)
+		return errors.New(errOut)
 	}
 	if err != nil {
 		return err
@@ -0,0 +1,34 @@
+package deploy
+
+import "gopkg.in/inconshreveable/log15.v2"
+
+// LoggerSetter is implemented by deploy methods that log to logger of caller
+type LoggerSetter interface {
+	SetLogger(logger log15.Logger)
+}
+
+// logging is embedded in deploy methods, they log to root logger if logger is not set
+type logging struct {
+	logger log15.Logger
+}
+
+// SetLogger sets logger of deploy method
+func (l *logging) SetLogger(logger log15.Logger) {
+	l.logger = logger
+}
+
+func (l *logging) log() log15.Logger {
+	if l.logger == nil {
+		return log15.Root()
+	}
+	return l.logger
+}
+
+// DoLog runs deploy method with logger, so logs of concurrent deployments are separated.
+// Methods that don't implement LoggerSetter log to root logger
+func DoLog(m Method, logger log15.Logger) error {
+	if s, ok := m.(LoggerSetter); ok && logger != nil {
+		s.SetLogger(logger)
+	}
+	return Do(m)
+}
@@ -1,9 +1,19 @@
 package deploy
 
-import "github.com/urfave/cli"
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/go-xiaohei/pugo/app/metrics"
+	"github.com/urfave/cli"
+)
 
 var (
 	methods = make(map[string]Method)
+
+	deployFailures = metrics.NewCounter("pugo_deploy_failures_total", "Total number of failed deployments.")
 )
 
 func init() {
@@ -26,6 +36,15 @@ func Register(ms ...Method) {
 	}
 }
 
+// Do runs deploy method and counts failures
+func Do(m Method) error {
+	err := m.Do()
+	if err != nil {
+		deployFailures.Inc()
+	}
+	return err
+}
+
 // Commands get commands of all deploy methods
 func Commands() []cli.Command {
 	commands := make([]cli.Command, len(methods))
@@ -36,3 +55,29 @@ func Commands() []cli.Command {
 	}
 	return commands
 }
+
+// Create creates deploy method by command name, such as "git" or "sftp",
+// options are values of command flags, such as {"local": "dest", "repo": "pages"}
+func Create(name string, options map[string]string) (Method, error) {
+	for _, m := range methods {
+		cmd := m.Command()
+		if cmd.Name != name {
+			continue
+		}
+		set := flag.NewFlagSet(name, flag.ContinueOnError)
+		set.SetOutput(ioutil.Discard)
+		for _, f := range cmd.Flags {
+			f.Apply(set)
+		}
+		args := make([]string, 0, len(options))
+		for k, v := range options {
+			args = append(args, "--"+k+"="+v)
+		}
+		sort.Strings(args)
+		if err := set.Parse(args); err != nil {
+			return nil, err
+		}
+		return m.Create(cli.NewContext(nil, set, nil))
+	}
+	return nil, fmt.Errorf("unknown deploy method '%s'", name)
+}
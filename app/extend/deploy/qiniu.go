@@ -2,8 +2,9 @@ package deploy
 
 import (
 	"fmt"
-	"os"
+	"net/http"
 	"path/filepath"
+	"strings"
 
 	"github.com/Unknwon/com"
 	"github.com/urfave/cli"
@@ -13,10 +14,14 @@ import (
 )
 
 type Qiniu struct {
+	logging
+
 	Local     string
 	AccessKey string
 	SecretKey string
 	Bucket    string
+	// Domain is domain of bucket to read asset manifest of last deploying
+	Domain string
 	// MaxAge int64
 }
 
@@ -29,6 +34,7 @@ func (q *Qiniu) Command() cli.Command {
 			cli.StringFlag{Name: "ak", Usage: "accesss key"},
 			cli.StringFlag{Name: "sk", Usage: "secret key"},
 			cli.StringFlag{Name: "bucket", Usage: "storage bucket name"},
+			cli.StringFlag{Name: "domain", Usage: "bucket domain to skip unchanged files, such as 'cdn.example.com'"},
 		},
 		Action: func(ctx *cli.Context) {
 			q2, err := q.Create(ctx)
@@ -36,11 +42,12 @@ func (q *Qiniu) Command() cli.Command {
 				log15.Error("Qiniu|Fail|%s", err.Error())
 				return
 			}
-			if err = q2.Do(); err != nil {
+			if err = Do(q2); err != nil {
 				log15.Error("Qiniu|Fail|%s", err.Error())
 				return
 			}
 			log15.Info("Qiniu|Finish")
+			printSitemapSubmit(ctx.String("local"))
 		},
 	}
 }
@@ -55,6 +62,7 @@ func (q *Qiniu) Create(ctx *cli.Context) (Method, error) {
 		AccessKey: ctx.String("ak"),
 		SecretKey: ctx.String("sk"),
 		Bucket:    ctx.String("bucket"),
+		Domain:    ctx.String("domain"),
 	}
 	if !com.IsDir(q2.Local) {
 		return nil, fmt.Errorf("directory '%s' is not existed", q2.Local)
@@ -72,22 +80,45 @@ func (q *Qiniu) Do() error {
 	kodo.SetMac(q.AccessKey, q.SecretKey)
 	client := kodo.New(0, nil)
 	bucket := client.Bucket(q.Bucket)
-	log15.Info("Qiniu|Bucket|%s", q.Bucket)
-	err := filepath.Walk(q.Local, func(path string, info os.FileInfo, err error) error {
-		if info.IsDir() {
-			return nil
-		}
-		rel, _ := filepath.Rel(q.Local, path)
-		rel = filepath.ToSlash(rel)
-
-		ctx := context.Background()
-
+	q.log().Info("Qiniu|Bucket|%s", q.Bucket)
+	files, err := uploadFiles(q.Local, q.remoteManifest(), q.log())
+	if err != nil {
+		return err
+	}
+	for _, rel := range files {
 		var ret interface{}
-		if err = bucket.PutFile(ctx, ret, rel, path, nil); err != nil {
+		if err = bucket.PutFile(context.Background(), ret, rel, filepath.Join(q.Local, filepath.FromSlash(rel)), nil); err != nil {
 			return err
 		}
-		log15.Debug("Qiniu|Upload|%s", rel)
+		q.log().Debug("Qiniu|Upload|%s", rel)
+	}
+	for _, f := range staleAssets(q.Local, q.log()) {
+		if err = bucket.Delete(context.Background(), f); err != nil {
+			q.log().Warn("Qiniu|Del|%s|%s", f, err.Error())
+			continue
+		}
+		q.log().Debug("Qiniu|Del|%s", f)
+	}
+	return nil
+}
+
+// remoteManifest return asset manifest of last deploying by bucket domain, nil if domain is empty
+func (q *Qiniu) remoteManifest() []byte {
+	if q.Domain == "" {
+		return nil
+	}
+	u := strings.TrimSuffix(q.Domain, "/") + "/" + assetManifestFile
+	if !strings.Contains(u, "://") {
+		u = "http://" + u
+	}
+	resp, err := http.Get(u)
+	if err != nil {
+		q.log().Warn("Qiniu|Assets|%s", err.Error())
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
 		return nil
-	})
-	return err
+	}
+	return readRemoteManifest(resp.Body)
 }
@@ -2,13 +2,13 @@ package deploy
 
 import (
 	"fmt"
-	"io"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
 
 	"github.com/Unknwon/com"
+	"github.com/go-xiaohei/pugo/app/helper"
 	"github.com/pkg/sftp"
 	"github.com/urfave/cli"
 	"golang.org/x/crypto/ssh"
@@ -16,6 +16,8 @@ import (
 )
 
 type Sftp struct {
+	logging
+
 	Host      string
 	User      string
 	Password  string
@@ -44,11 +46,12 @@ func (s *Sftp) Command() cli.Command {
 				log15.Error("SFTP|Fail|%s", err.Error())
 				return
 			}
-			if err = s2.Do(); err != nil {
+			if err = Do(s2); err != nil {
 				log15.Error("SFTP|Fail|%s", err.Error())
 				return
 			}
 			log15.Info("SFTP|Finish")
+			printSitemapSubmit(ctx.String("local"))
 		},
 	}
 }
@@ -86,10 +89,28 @@ func (s *Sftp) Do() error {
 	}
 	defer s.sftpClient.Close()
 	defer s.sshClient.Close()
-	log15.Debug("SFTP|%s|Connect", s.Host)
+	s.log().Debug("SFTP|%s|Connect", s.Host)
 	makeSftpDir(s.sftpClient, getRecursiveDirs(s.Directory))
-	log15.Debug("SFTP|UploadAll")
-	return s.UploadAll(s.Local)
+	var remote []byte
+	if r, err := s.sftpClient.Open(path.Join(s.Directory, assetManifestFile)); err == nil {
+		remote = readRemoteManifest(r)
+	}
+	files, err := uploadFiles(s.Local, remote, s.log())
+	if err != nil {
+		return err
+	}
+	s.log().Debug("SFTP|Upload|%d files", len(files))
+	if err = s.Upload(s.Local, files); err != nil {
+		return err
+	}
+	for _, f := range staleAssets(s.Local, s.log()) {
+		if err := s.sftpClient.Remove(path.Join(s.Directory, f)); err != nil {
+			s.log().Warn("SFTP|Del|%s|%s", f, err.Error())
+			continue
+		}
+		s.log().Debug("SFTP|Del|%s", f)
+	}
+	return nil
 }
 
 func (s *Sftp) connect() error {
@@ -108,31 +129,33 @@ func (s *Sftp) connect() error {
 	return err
 }
 
-// upload files without checking diff status
-func (s *Sftp) UploadAll(local string) error {
-	return filepath.Walk(local, func(p string, info os.FileInfo, err error) error {
-		rel, _ := filepath.Rel(local, p)
-		rel = filepath.ToSlash(rel)
-
-		makeSftpDir(s.sftpClient, getRecursiveDirs(filepath.Dir(rel)))
-
-		// upload file
-		f, err := os.Open(p)
-		if err != nil {
+// Upload uploads relative files in local directory
+func (s *Sftp) Upload(local string, files []string) error {
+	for _, rel := range files {
+		makeSftpDir(s.sftpClient, getRecursiveDirs(path.Dir(rel)))
+		if err := s.upload(filepath.Join(local, filepath.FromSlash(rel)), path.Join(s.Directory, rel)); err != nil {
 			return err
 		}
-		defer f.Close()
-		f2, err := s.sftpClient.Create(path.Join(s.Directory, rel))
-		if err != nil {
-			return err
-		}
-		defer f2.Close()
-		if _, err = io.Copy(f2, f); err != nil {
-			return err
-		}
-		log15.Debug("SFTP|Stor|%s", p)
-		return nil
-	})
+	}
+	return nil
+}
+
+func (s *Sftp) upload(p, remote string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	f2, err := s.sftpClient.Create(remote)
+	if err != nil {
+		return err
+	}
+	defer f2.Close()
+	if _, err = helper.Copy(f2, f); err != nil {
+		return err
+	}
+	s.log().Debug("SFTP|Stor|%s", p)
+	return nil
 }
 
 // make sftp directories
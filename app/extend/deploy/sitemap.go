@@ -0,0 +1,61 @@
+package deploy
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+var (
+	sitemapLocRegex = regexp.MustCompile(`<loc>([^<]+)</loc>`)
+	// ping urls of search engines are retired, sitemaps are submitted in webmaster tools,
+	// these pages are opened with the site
+	sitemapSubmitURLs = []string{
+		"https://search.google.com/search-console/sitemaps?resource_id=",
+		"https://www.bing.com/webmasters/sitemaps?siteUrl=",
+	}
+)
+
+// printSitemapSubmit prints sitemap url and pages to submit it in webmaster tools after deployment
+func printSitemapSubmit(local string) {
+	sitemap, links := sitemapSubmit(local)
+	if sitemap == "" {
+		return
+	}
+	log15.Info("Sitemap|%s", sitemap)
+	for _, link := range links {
+		log15.Info("Sitemap|Submit|%s", link)
+	}
+}
+
+// sitemapSubmit return sitemap url and pages to submit it for the site in local directory.
+// The site root is the directory of first location in sitemap.xml
+func sitemapSubmit(local string) (string, []string) {
+	data, err := ioutil.ReadFile(filepath.Join(local, "sitemap.xml"))
+	if err != nil {
+		return "", nil
+	}
+	m := sitemapLocRegex.FindSubmatch(data)
+	if len(m) < 2 {
+		return "", nil
+	}
+	loc := strings.TrimSpace(string(m[1]))
+	root := strings.TrimSuffix(loc, "/") + "/"
+	if bytes.Contains(data, []byte("<sitemapindex")) {
+		// first location is a split sitemap file in the same directory
+		root = loc[:strings.LastIndex(loc, "/")+1]
+	}
+	if u, err := url.Parse(root); err != nil || u.Host == "" {
+		return "", nil
+	}
+	links := make([]string, len(sitemapSubmitURLs))
+	for i, u := range sitemapSubmitURLs {
+		links[i] = u + url.QueryEscape(root)
+	}
+	return root + "sitemap.xml", links
+}
@@ -0,0 +1,39 @@
+package deploy
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSitemapSubmit(t *testing.T) {
+	Convey("Sitemap Submit", t, func() {
+		dir, err := ioutil.TempDir("", "pugo-sitemap")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		file := filepath.Join(dir, "sitemap.xml")
+
+		sitemap, links := sitemapSubmit(dir)
+		So(sitemap, ShouldBeEmpty)
+		So(links, ShouldBeEmpty)
+
+		So(ioutil.WriteFile(file, []byte(`<urlset><url><loc>https://example.com/blog</loc></url><url><loc>https://example.com/blog/a.html</loc></url></urlset>`), 0644), ShouldBeNil)
+		sitemap, links = sitemapSubmit(dir)
+		So(sitemap, ShouldEqual, "https://example.com/blog/sitemap.xml")
+		So(links, ShouldResemble, []string{
+			"https://search.google.com/search-console/sitemaps?resource_id=https%3A%2F%2Fexample.com%2Fblog%2F",
+			"https://www.bing.com/webmasters/sitemaps?siteUrl=https%3A%2F%2Fexample.com%2Fblog%2F",
+		})
+
+		So(ioutil.WriteFile(file, []byte(`<sitemapindex><sitemap><loc>https://example.com/sitemap-1.xml</loc></sitemap></sitemapindex>`), 0644), ShouldBeNil)
+		sitemap, _ = sitemapSubmit(dir)
+		So(sitemap, ShouldEqual, "https://example.com/sitemap.xml")
+
+		So(ioutil.WriteFile(file, []byte(`<urlset><url><loc>/relative</loc></url></urlset>`), 0644), ShouldBeNil)
+		sitemap, _ = sitemapSubmit(dir)
+		So(sitemap, ShouldBeEmpty)
+	})
+}
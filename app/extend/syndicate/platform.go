@@ -0,0 +1,124 @@
+package syndicate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+var (
+	devToAPI  = "https://dev.to/api"
+	mediumAPI = "https://api.medium.com/v1"
+)
+
+// DevTo pushes articles to dev.to
+type DevTo struct {
+	Token string
+}
+
+// Push implements Platform
+func (d *DevTo) Push(a *Article) (string, error) {
+	tags := a.Tags
+	// dev.to allows 4 tags at most
+	if len(tags) > 4 {
+		tags = tags[:4]
+	}
+	body := map[string]interface{}{
+		"article": map[string]interface{}{
+			"title":         a.Title,
+			"description":   a.Desc,
+			"body_markdown": a.Markdown,
+			"canonical_url": a.Canonical,
+			"published":     a.Publish,
+			"tags":          tags,
+		},
+	}
+	var resp struct {
+		URL string `json:"url"`
+	}
+	if err := apiRequest("POST", devToAPI+"/articles", map[string]string{"api-key": d.Token}, body, &resp); err != nil {
+		return "", err
+	}
+	return resp.URL, nil
+}
+
+// Medium pushes articles to Medium
+type Medium struct {
+	Token string
+}
+
+// Push implements Platform
+func (m *Medium) Push(a *Article) (string, error) {
+	header := map[string]string{"Authorization": "Bearer " + m.Token}
+	var me struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := apiRequest("GET", mediumAPI+"/me", header, nil, &me); err != nil {
+		return "", err
+	}
+	status := "draft"
+	if a.Publish {
+		status = "public"
+	}
+	tags := a.Tags
+	// Medium allows 5 tags at most
+	if len(tags) > 5 {
+		tags = tags[:5]
+	}
+	body := map[string]interface{}{
+		"title":         a.Title,
+		"contentFormat": "markdown",
+		"content":       "# " + a.Title + "\n\n" + a.Markdown,
+		"canonicalUrl":  a.Canonical,
+		"tags":          tags,
+		"publishStatus": status,
+	}
+	var resp struct {
+		Data struct {
+			URL string `json:"url"`
+		} `json:"data"`
+	}
+	if err := apiRequest("POST", mediumAPI+"/users/"+me.Data.ID+"/posts", header, body, &resp); err != nil {
+		return "", err
+	}
+	return resp.Data.URL, nil
+}
+
+// apiRequest sends json request and decodes json response to v
+func apiRequest(method, url string, header map[string]string, body, v interface{}) error {
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, url, &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	for k, val := range header {
+		req.Header.Set(k, val)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		if len(data) > 200 {
+			data = data[:200]
+		}
+		return fmt.Errorf("%s %s: %s %s", method, url, resp.Status, bytes.TrimSpace(data))
+	}
+	return json.Unmarshal(data, v)
+}
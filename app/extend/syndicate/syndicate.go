@@ -0,0 +1,112 @@
+// Package syndicate cross-posts articles to other platforms,
+// with canonical url to the original post
+package syndicate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-xiaohei/pugo/app/builder"
+	"github.com/go-xiaohei/pugo/app/model"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// Article is a post to cross-post
+type Article struct {
+	Title     string
+	Desc      string
+	Markdown  string
+	Canonical string
+	Tags      []string
+	Publish   bool
+}
+
+// Platform pushes article and returns link of it
+type Platform interface {
+	Push(a *Article) (string, error)
+}
+
+// NewPlatform creates platform of syndication settings
+func NewPlatform(s *model.Syndication) (Platform, error) {
+	switch s.Name {
+	case model.SyndicationDevTo:
+		return &DevTo{Token: s.Token}, nil
+	case model.SyndicationMedium:
+		return &Medium{Token: s.Token}, nil
+	}
+	return nil, fmt.Errorf("syndication '%s' is unknown", s.Name)
+}
+
+var reMarkdownLink = regexp.MustCompile(`(\]\()(/[^/)\s][^)\s]*)`)
+
+// NewArticle creates article of post, media placeholders and relative links are full urls
+func NewArticle(meta *model.Meta, p *model.Post, publish bool) *Article {
+	body := bytes.Replace(p.Bytes, []byte("<!--more-->"), nil, -1)
+	body = bytes.Replace(body, []byte("@media"), []byte(meta.DomainURL("media")), -1)
+	body = reMarkdownLink.ReplaceAllFunc(body, func(m []byte) []byte {
+		sub := reMarkdownLink.FindSubmatch(m)
+		return append(sub[1], meta.DomainURL(string(sub[2]))...)
+	})
+	return &Article{
+		Title:     p.Title,
+		Desc:      p.Desc,
+		Markdown:  strings.TrimSpace(string(body)),
+		Canonical: meta.DomainURL(p.URL()),
+		Tags:      p.TagString,
+		Publish:   publish,
+	}
+}
+
+// Push cross-posts posts that are marked to syndicate and not pushed yet,
+// links of pushed articles are saved in syndication data file of source directory.
+// It continues when one article fails, and returns the first error.
+func Push(ctx *builder.Context, dryRun bool) (int, error) {
+	var (
+		count    int
+		firstErr error
+		src      = ctx.Source
+	)
+	if src.Syndicated == nil {
+		return 0, errors.New("syndication data is not loaded")
+	}
+	for _, p := range src.Posts {
+		for _, name := range p.Syndicate {
+			if src.Syndicated.Get(p.URL())[name] != "" {
+				continue
+			}
+			s := src.Syndication.Get(name)
+			if s == nil {
+				continue
+			}
+			if dryRun {
+				log15.Info("Syndicate|%s|%s|Push", name, p.URL())
+				count++
+				continue
+			}
+			platform, err := NewPlatform(s)
+			if err == nil {
+				var link string
+				if link, err = platform.Push(NewArticle(src.Meta, p, s.Publish)); err == nil {
+					log15.Info("Syndicate|%s|%s|%s", name, p.URL(), link)
+					src.Syndicated.Set(p.URL(), name, link)
+					count++
+					err = builder.WriteSyndicated(ctx.SrcDir(), src.Syndicated)
+				}
+			}
+			if err != nil {
+				log15.Error("Syndicate|%s|%s|%s", name, p.URL(), err.Error())
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+		}
+	}
+	return count, firstErr
+}
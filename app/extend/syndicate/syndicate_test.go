@@ -0,0 +1,56 @@
+package syndicate
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-xiaohei/pugo/app/builder"
+	"github.com/go-xiaohei/pugo/app/model"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSyndicate(t *testing.T) {
+	Convey("NewArticle", t, func() {
+		all, err := model.NewMetaAll([]byte("[meta]\ntitle = \"t\"\nroot = \"https://example.com/blog/\"\n[[author]]\nname = \"a\"\n"), model.FormatTOML)
+		So(err, ShouldBeNil)
+		p := &model.Post{Title: "Hello", TagString: []string{"go"}}
+		p.Bytes = []byte("brief\n<!--more-->\n![img](@media/a.png) [about](/about.html) [cdn](//cdn.example.com/x.js)")
+		p.SetURL("/blog/2016/hello.html")
+		a := NewArticle(all.Meta, p, true)
+		So(a.Canonical, ShouldEqual, "https://example.com/blog/2016/hello.html")
+		So(a.Markdown, ShouldEqual, "brief\n\n![img](https://example.com/blog/media/a.png) [about](https://example.com/blog/about.html) [cdn](//cdn.example.com/x.js)")
+	})
+
+	Convey("DevTo", t, func() {
+		var body map[string]map[string]interface{}
+		ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("api-key") != "token" || r.URL.Path != "/articles" {
+				http.Error(rw, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			rw.WriteHeader(http.StatusCreated)
+			rw.Write([]byte(`{"url":"https://dev.to/a/hello"}`))
+		}))
+		defer ts.Close()
+		devToAPI = ts.URL
+
+		a := &Article{Title: "Hello", Canonical: "https://example.com/hello.html", Tags: []string{"a", "b", "c", "d", "e"}}
+		link, err := (&DevTo{Token: "token"}).Push(a)
+		So(err, ShouldBeNil)
+		So(link, ShouldEqual, "https://dev.to/a/hello")
+		So(body["article"]["canonical_url"], ShouldEqual, a.Canonical)
+		So(body["article"]["tags"], ShouldHaveLength, 4)
+
+		_, err = (&DevTo{Token: "wrong"}).Push(a)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Push", t, func() {
+		ctx := &builder.Context{Source: &builder.Source{}}
+		_, err := Push(ctx, true)
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,88 @@
+package helper
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"os"
+	"sync"
+)
+
+const copyBufferSize = 32 * 1024
+
+var copyBufferPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, copyBufferSize)
+		return &b
+	},
+}
+
+// Copy copies from src to dst with a pooled buffer,
+// instead of allocating new buffer in each io.Copy
+func Copy(dst io.Writer, src io.Reader) (int64, error) {
+	buf := copyBufferPool.Get().(*[]byte)
+	defer copyBufferPool.Put(buf)
+	return io.CopyBuffer(dst, src, *buf)
+}
+
+// CopyFile copies local file src to dst, keeps file mode and modified time.
+// Copying between local files uses kernel fast path (sendfile or copy_file_range) if possible
+func CopyFile(src, dst string) error {
+	si, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	sr, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer sr.Close()
+
+	dw, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, si.Mode())
+	if err != nil {
+		return err
+	}
+	// *os.File implements io.ReaderFrom with fast path for file to file copy
+	if _, err = dw.ReadFrom(sr); err != nil {
+		dw.Close()
+		return err
+	}
+	if err = dw.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(dst, si.Mode()); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, si.ModTime(), si.ModTime())
+}
+
+// CopyFileHash copies local file src to dst as CopyFile,
+// and returns md5 hash of content computed during copying
+func CopyFileHash(src, dst string) (string, error) {
+	si, err := os.Stat(src)
+	if err != nil {
+		return "", err
+	}
+	sr, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer sr.Close()
+
+	dw, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, si.Mode())
+	if err != nil {
+		return "", err
+	}
+	h := md5.New()
+	if _, err = Copy(io.MultiWriter(dw, h), sr); err != nil {
+		dw.Close()
+		return "", err
+	}
+	if err = dw.Close(); err != nil {
+		return "", err
+	}
+	if err = os.Chmod(dst, si.Mode()); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), os.Chtimes(dst, si.ModTime(), si.ModTime())
+}
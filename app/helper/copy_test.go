@@ -0,0 +1,50 @@
+package helper
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCopy(t *testing.T) {
+	Convey("Copy", t, func() {
+		data := bytes.Repeat([]byte("pugo"), copyBufferSize)
+		var buf bytes.Buffer
+		n, err := Copy(&buf, bytes.NewReader(data))
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, len(data))
+		So(buf.Bytes(), ShouldResemble, data)
+
+		Convey("CopyFile", func() {
+			dir, err := ioutil.TempDir("", "pugo-copy")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(dir)
+
+			dst := filepath.Join(dir, "md5.go")
+			So(CopyFile("md5.go", dst), ShouldBeNil)
+			h1, _ := Md5File("md5.go")
+			h2, _ := Md5File(dst)
+			So(h2, ShouldEqual, h1)
+
+			si, _ := os.Stat("md5.go")
+			di, _ := os.Stat(dst)
+			So(di.ModTime().Unix(), ShouldEqual, si.ModTime().Unix())
+
+			So(CopyFile("not-exist.go", dst), ShouldNotBeNil)
+
+			dst = filepath.Join(dir, "copy.go")
+			hash, err := CopyFileHash("copy.go", dst)
+			So(err, ShouldBeNil)
+			h1, _ = Md5File("copy.go")
+			h2, _ = Md5File(dst)
+			So(hash, ShouldEqual, h1)
+			So(h2, ShouldEqual, h1)
+			_, err = CopyFileHash("not-exist.go", dst)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,73 @@
+package helper
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	editorialTodo       = regexp.MustCompile(`(?is)<!--\s*todo:(.*?)-->`)
+	editorialNote       = regexp.MustCompile(`(?s)<p>\s*{{%\s*draft-note\s*%}}.*?{{%\s*/draft-note\s*%}}\s*</p>|{{%\s*draft-note\s*%}}.*?{{%\s*/draft-note\s*%}}`)
+	editorialNoteMarker = regexp.MustCompile(`(<p>\s*)?{{%\s*/?draft-note\s*%}}(\s*</p>)?`)
+	editorialEmptyPara  = regexp.MustCompile(`<p>\s*</p>\n*`)
+	editorialCode       = regexp.MustCompile(`(?s)<code[^>]*>.*?</code>`)
+)
+
+// Editorial handles editorial annotations in rendered html content.
+// Annotations are <!-- todo: ... --> comments
+// and {{% draft-note %}} ... {{% /draft-note %}} blocks.
+// If visible is true, they are converted to visible elements with class "editorial",
+// otherwise they are stripped from content.
+// Annotations in <code> blocks are kept as they are.
+func Editorial(data []byte, visible bool) []byte {
+	var (
+		buf    bytes.Buffer
+		cursor int
+	)
+	for _, loc := range editorialCode.FindAllIndex(data, -1) {
+		buf.Write(editorial(data[cursor:loc[0]], visible))
+		buf.Write(data[loc[0]:loc[1]])
+		cursor = loc[1]
+	}
+	buf.Write(editorial(data[cursor:], visible))
+	return buf.Bytes()
+}
+
+func editorial(data []byte, visible bool) []byte {
+	if !visible {
+		data = editorialTodo.ReplaceAll(data, nil)
+		data = editorialNote.ReplaceAll(data, nil)
+		data = editorialNoteMarker.ReplaceAll(data, nil)
+		return editorialEmptyPara.ReplaceAll(data, nil)
+	}
+	data = editorialTodo.ReplaceAllFunc(data, func(raw []byte) []byte {
+		text := editorialTodo.FindSubmatch(raw)[1]
+		return []byte(`<span class="editorial editorial-todo">TODO: ` +
+			html.EscapeString(strings.TrimSpace(string(text))) + `</span>`)
+	})
+	return editorialNoteMarker.ReplaceAllFunc(data, func(raw []byte) []byte {
+		str := string(raw)
+		isBlock := strings.HasPrefix(str, "<p>") && strings.HasSuffix(str, "</p>")
+		isClose := strings.Contains(str, "/draft-note")
+		if isBlock {
+			if isClose {
+				return []byte("</div>")
+			}
+			return []byte(`<div class="editorial editorial-note">`)
+		}
+		// keep <p> or </p> that belongs to outer paragraph
+		var prefix, suffix string
+		if strings.HasPrefix(str, "<p>") {
+			prefix = "<p>"
+		}
+		if strings.HasSuffix(str, "</p>") {
+			suffix = "</p>"
+		}
+		if isClose {
+			return []byte(prefix + "</span>" + suffix)
+		}
+		return []byte(prefix + `<span class="editorial editorial-note">` + suffix)
+	})
+}
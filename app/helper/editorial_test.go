@@ -0,0 +1,34 @@
+package helper
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEditorial(t *testing.T) {
+	Convey("Editorial", t, func() {
+		html := Markdown([]byte("hello <!-- todo: fix <this> --> world\n\n" +
+			"{{% draft-note %}}\nnote here\n{{% /draft-note %}}\n\n" +
+			"para\n\n{{% draft-note %}}\n\nblock note\n\n{{% /draft-note %}}\n\n" +
+			"```\n{{% draft-note %}}code{{% /draft-note %}}\n```\n"))
+
+		Convey("Strip", func() {
+			res := string(Editorial(html, false))
+			So(res, ShouldNotContainSubstring, "todo")
+			So(res, ShouldNotContainSubstring, "note here")
+			So(res, ShouldNotContainSubstring, "block note")
+			So(res, ShouldContainSubstring, "<p>hello  world</p>")
+			So(res, ShouldContainSubstring, "<p>para</p>")
+			So(res, ShouldContainSubstring, "{{% draft-note %}}code{{% /draft-note %}}")
+		})
+
+		Convey("Visible", func() {
+			res := string(Editorial(html, true))
+			So(res, ShouldContainSubstring, `<span class="editorial editorial-todo">TODO: fix &lt;this&gt;</span>`)
+			So(res, ShouldContainSubstring, "<p><span class=\"editorial editorial-note\">\nnote here\n</span></p>")
+			So(res, ShouldContainSubstring, "<div class=\"editorial editorial-note\">\n\n<p>block note</p>\n\n</div>")
+			So(res, ShouldContainSubstring, "{{% draft-note %}}code{{% /draft-note %}}")
+		})
+	})
+}
@@ -0,0 +1,103 @@
+package helper
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var (
+	bomUTF8    = []byte{0xef, 0xbb, 0xbf}
+	bomUTF16LE = []byte{0xff, 0xfe}
+	bomUTF16BE = []byte{0xfe, 0xff}
+
+	// windows1252 maps 0x80-0x9f bytes, others are same as latin1
+	windows1252 = [32]rune{
+		0x20ac, 0xfffd, 0x201a, 0x0192, 0x201e, 0x2026, 0x2020, 0x2021,
+		0x02c6, 0x2030, 0x0160, 0x2039, 0x0152, 0xfffd, 0x017d, 0xfffd,
+		0xfffd, 0x2018, 0x2019, 0x201c, 0x201d, 0x2022, 0x2013, 0x2014,
+		0x02dc, 0x2122, 0x0161, 0x203a, 0x0153, 0xfffd, 0x017e, 0x0178,
+	}
+)
+
+// FallbackEncoding decodes data without BOM that is not valid UTF-8, if encoding is not set
+const FallbackEncoding = "windows-1252"
+
+// GuessedEncoding return FallbackEncoding if DecodeText guesses encoding of data,
+// data has no BOM and is not valid UTF-8. Callers should warn about it
+func GuessedEncoding(data []byte) string {
+	if bytes.HasPrefix(data, bomUTF8) || bytes.HasPrefix(data, bomUTF16LE) || bytes.HasPrefix(data, bomUTF16BE) || utf8.Valid(data) {
+		return ""
+	}
+	return FallbackEncoding
+}
+
+// DecodeText converts text data in encoding to UTF-8 with "\n" line endings.
+// Supported encodings are utf-8, utf-16le, utf-16be, latin1 (iso-8859-1) and windows-1252.
+// If encoding is empty, it detects by BOM, and uses FallbackEncoding for invalid UTF-8 data.
+func DecodeText(data []byte, encoding string) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		data, encoding = data[len(bomUTF8):], "utf-8"
+	case bytes.HasPrefix(data, bomUTF16LE):
+		data, encoding = data[len(bomUTF16LE):], "utf-16le"
+	case bytes.HasPrefix(data, bomUTF16BE):
+		data, encoding = data[len(bomUTF16BE):], "utf-16be"
+	}
+	encoding = strings.ToLower(strings.Replace(encoding, "_", "-", -1))
+	if encoding == "" {
+		encoding = "utf-8"
+		if !utf8.Valid(data) {
+			encoding = FallbackEncoding
+		}
+	}
+	switch encoding {
+	case "utf-8", "utf8":
+	case "utf-16le", "utf-16":
+		data = decodeUTF16(data, false)
+	case "utf-16be":
+		data = decodeUTF16(data, true)
+	case "latin1", "iso-8859-1":
+		data = decodeSingleByte(data, false)
+	case "windows-1252", "cp1252":
+		data = decodeSingleByte(data, true)
+	default:
+		return nil, fmt.Errorf("unsupported encoding '%s'", encoding)
+	}
+	return normalizeLineEnding(data), nil
+}
+
+func decodeUTF16(data []byte, bigEndian bool) []byte {
+	u := make([]uint16, len(data)/2)
+	for i := range u {
+		if bigEndian {
+			u[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		} else {
+			u[i] = uint16(data[2*i+1])<<8 | uint16(data[2*i])
+		}
+	}
+	return []byte(string(utf16.Decode(u)))
+}
+
+func decodeSingleByte(data []byte, isWindows bool) []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, len(data)))
+	for _, b := range data {
+		if isWindows && b >= 0x80 && b < 0xa0 {
+			buf.WriteRune(windows1252[b-0x80])
+			continue
+		}
+		buf.WriteRune(rune(b))
+	}
+	return buf.Bytes()
+}
+
+// normalizeLineEnding converts "\r\n" and "\r" to "\n"
+func normalizeLineEnding(data []byte) []byte {
+	if bytes.IndexByte(data, '\r') < 0 {
+		return data
+	}
+	data = bytes.Replace(data, []byte("\r\n"), []byte("\n"), -1)
+	return bytes.Replace(data, []byte("\r"), []byte("\n"), -1)
+}
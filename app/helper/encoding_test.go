@@ -0,0 +1,42 @@
+package helper
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDecodeText(t *testing.T) {
+	Convey("DecodeText", t, func() {
+		data, err := DecodeText([]byte("\xef\xbb\xbfline1\r\nline2\rline3\n"), "")
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, "line1\nline2\nline3\n")
+
+		data, err = DecodeText([]byte{0xff, 0xfe, 'a', 0, 0x2d, 0x4e}, "")
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, "a中")
+
+		data, err = DecodeText([]byte{0xfe, 0xff, 0, 'a', 0x4e, 0x2d}, "")
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, "a中")
+
+		data, err = DecodeText([]byte("caf\xe9 \x93quote\x94"), "")
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, "café “quote”")
+		So(GuessedEncoding([]byte("caf\xe9")), ShouldEqual, FallbackEncoding)
+		So(GuessedEncoding([]byte("\xef\xbb\xbfcafé")), ShouldBeEmpty)
+		So(GuessedEncoding([]byte{0xff, 0xfe, 'a', 0}), ShouldBeEmpty)
+		So(GuessedEncoding([]byte("中文")), ShouldBeEmpty)
+
+		data, err = DecodeText([]byte("caf\xe9 \x93"), "latin1")
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, "café \u0093")
+
+		data, err = DecodeText([]byte("中文"), "UTF-8")
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, "中文")
+
+		_, err = DecodeText([]byte("abc"), "gbk")
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,76 @@
+package helper
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ParseEnv parses KEY=VALUE lines of .env file.
+// Empty lines and # comments are skipped, "export " prefix is allowed,
+// values can be quoted by " or ', escapes are unquoted in double quotes
+func ParseEnv(data []byte) (map[string]string, error) {
+	env := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		text = strings.TrimPrefix(text, "export ")
+		i := strings.Index(text, "=")
+		if i < 1 {
+			return nil, fmt.Errorf("line %d: need KEY=VALUE", line)
+		}
+		key, value := strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:])
+		switch {
+		case strings.HasPrefix(value, `"`):
+			v, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", line, err.Error())
+			}
+			value = v
+		case strings.HasPrefix(value, "'"):
+			if len(value) < 2 || !strings.HasSuffix(value, "'") {
+				return nil, fmt.Errorf("line %d: quote is not closed", line)
+			}
+			value = value[1 : len(value)-1]
+		default:
+			if j := strings.Index(value, " #"); j >= 0 {
+				value = strings.TrimSpace(value[:j])
+			}
+		}
+		env[key] = value
+	}
+	return env, scanner.Err()
+}
+
+// LoadEnv reads .env files in order, variables in later files override earlier ones.
+// Missing files are skipped. Variables are returned and not set to process environment,
+// so sites in one process do not read variables of each other
+func LoadEnv(files ...string) (map[string]string, error) {
+	env := make(map[string]string)
+	for _, f := range files {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		fileEnv, err := ParseEnv(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", f, err.Error())
+		}
+		for k, v := range fileEnv {
+			env[k] = v
+		}
+	}
+	return env, nil
+}
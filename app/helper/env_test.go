@@ -0,0 +1,36 @@
+package helper
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEnv(t *testing.T) {
+	Convey("Parse", t, func() {
+		env, err := ParseEnv([]byte("# comment\n\nA=1\nexport B = two words # note\nC=\"x\\ny\"\nD='$E #'\n"))
+		So(err, ShouldBeNil)
+		So(env, ShouldResemble, map[string]string{"A": "1", "B": "two words", "C": "x\ny", "D": "$E #"})
+
+		_, err = ParseEnv([]byte("A"))
+		So(err, ShouldNotBeNil)
+		_, err = ParseEnv([]byte("A='x"))
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Load", t, func() {
+		dir, err := ioutil.TempDir("", "pugo-env")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		So(ioutil.WriteFile(filepath.Join(dir, ".env"), []byte("PUGO_TEST_A=a\nPUGO_TEST_B=b\nPUGO_TEST_C=c"), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join(dir, ".env.production"), []byte("PUGO_TEST_B=production"), 0644), ShouldBeNil)
+		env, err := LoadEnv(filepath.Join(dir, ".env"), filepath.Join(dir, ".env.production"), filepath.Join(dir, ".env.none"))
+		So(err, ShouldBeNil)
+		So(env, ShouldResemble, map[string]string{"PUGO_TEST_A": "a", "PUGO_TEST_B": "production", "PUGO_TEST_C": "c"})
+		_, ok := os.LookupEnv("PUGO_TEST_A")
+		So(ok, ShouldBeFalse)
+	})
+}
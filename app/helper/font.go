@@ -0,0 +1,527 @@
+package helper
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+)
+
+var (
+	errFontFormat    = errors.New("font is not TrueType or WOFF")
+	errFontCFF       = errors.New("font with CFF outlines is not supported, use TrueType outlines")
+	errFontCollect   = errors.New("font collection is not supported")
+	errFontWOFF2     = errors.New("WOFF2 font is not supported, use TrueType or WOFF")
+	errFontTruncated = errors.New("font data is truncated")
+)
+
+// fontTables are tables of sfnt font by tag
+type fontTables map[string][]byte
+
+// SubsetFont return TrueType or WOFF font data only with glyphs of chars.
+// Glyph ids are kept, so positioning tables are still valid,
+// glyphs not used are emptied and cmap only maps chars in font.
+// Substitution tables are removed, they may map chars to emptied glyphs.
+func SubsetFont(data []byte, chars map[rune]bool) ([]byte, error) {
+	if len(data) < 12 {
+		return nil, errFontTruncated
+	}
+	var (
+		tables  fontTables
+		version uint32
+		err     error
+		woff    bool
+	)
+	switch string(data[:4]) {
+	case "wOFF":
+		woff = true
+		version, tables, err = readWOFF(data)
+	case "wOF2":
+		return nil, errFontWOFF2
+	case "ttcf":
+		return nil, errFontCollect
+	case "OTTO":
+		return nil, errFontCFF
+	case "\x00\x01\x00\x00", "true":
+		version, tables, err = readSfnt(data)
+	default:
+		return nil, errFontFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := tables["CFF "]; ok {
+		return nil, errFontCFF
+	}
+	for _, tag := range []string{"head", "maxp", "loca", "glyf", "cmap"} {
+		if tables[tag] == nil {
+			return nil, fmt.Errorf("font has no '%s' table", tag)
+		}
+	}
+	if err = subsetTables(tables, chars); err != nil {
+		return nil, err
+	}
+	if woff {
+		return writeWOFF(version, tables)
+	}
+	return writeSfnt(version, tables), nil
+}
+
+func readSfnt(data []byte) (uint32, fontTables, error) {
+	version := binary.BigEndian.Uint32(data)
+	num := int(binary.BigEndian.Uint16(data[4:]))
+	if len(data) < 12+num*16 {
+		return 0, nil, errFontTruncated
+	}
+	tables := make(fontTables, num)
+	for i := 0; i < num; i++ {
+		rec := data[12+i*16:]
+		offset, length := binary.BigEndian.Uint32(rec[8:]), binary.BigEndian.Uint32(rec[12:])
+		if uint64(offset)+uint64(length) > uint64(len(data)) {
+			return 0, nil, errFontTruncated
+		}
+		tables[string(rec[:4])] = data[offset : offset+length]
+	}
+	return version, tables, nil
+}
+
+func readWOFF(data []byte) (uint32, fontTables, error) {
+	if len(data) < 44 {
+		return 0, nil, errFontTruncated
+	}
+	version := binary.BigEndian.Uint32(data[4:])
+	num := int(binary.BigEndian.Uint16(data[12:]))
+	if len(data) < 44+num*20 {
+		return 0, nil, errFontTruncated
+	}
+	tables := make(fontTables, num)
+	for i := 0; i < num; i++ {
+		rec := data[44+i*20:]
+		offset, compLength := binary.BigEndian.Uint32(rec[4:]), binary.BigEndian.Uint32(rec[8:])
+		origLength := binary.BigEndian.Uint32(rec[12:])
+		if uint64(offset)+uint64(compLength) > uint64(len(data)) {
+			return 0, nil, errFontTruncated
+		}
+		table := data[offset : offset+compLength]
+		if compLength < origLength {
+			r, err := zlib.NewReader(bytes.NewReader(table))
+			if err != nil {
+				return 0, nil, err
+			}
+			if table, err = ioutil.ReadAll(r); err != nil {
+				return 0, nil, err
+			}
+		}
+		tables[string(rec[:4])] = table
+	}
+	return version, tables, nil
+}
+
+// subsetTables empties glyphs not used by chars and rewrites cmap
+func subsetTables(tables fontTables, chars map[rune]bool) error {
+	head, maxp := tables["head"], tables["maxp"]
+	if len(head) < 54 || len(maxp) < 6 {
+		return errFontTruncated
+	}
+	numGlyphs := int(binary.BigEndian.Uint16(maxp[4:]))
+	longLoca := binary.BigEndian.Uint16(head[50:]) == 1
+	loca, glyf := tables["loca"], tables["glyf"]
+	glyphOffset := func(i int) (int, error) {
+		var v int
+		if longLoca {
+			if len(loca) < (i+1)*4 {
+				return 0, errFontTruncated
+			}
+			v = int(binary.BigEndian.Uint32(loca[i*4:]))
+		} else {
+			if len(loca) < (i+1)*2 {
+				return 0, errFontTruncated
+			}
+			v = int(binary.BigEndian.Uint16(loca[i*2:])) * 2
+		}
+		if v > len(glyf) {
+			return 0, errFontTruncated
+		}
+		return v, nil
+	}
+	glyphs := make([][]byte, numGlyphs)
+	for i := 0; i < numGlyphs; i++ {
+		begin, err := glyphOffset(i)
+		if err != nil {
+			return err
+		}
+		end, err := glyphOffset(i + 1)
+		if err != nil {
+			return err
+		}
+		if end > begin {
+			glyphs[i] = glyf[begin:end]
+		}
+	}
+
+	cmap, err := readCmap(tables["cmap"])
+	if err != nil {
+		return err
+	}
+	used := map[int]bool{0: true}
+	mapping := make(map[rune]int)
+	for r := range chars {
+		if gid, ok := cmap[r]; ok && gid < numGlyphs {
+			mapping[r] = gid
+			used[gid] = true
+		}
+	}
+	// components of composite glyphs are used too
+	queue := make([]int, 0, len(used))
+	for gid := range used {
+		queue = append(queue, gid)
+	}
+	for len(queue) > 0 {
+		gid := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		for _, c := range glyphComponents(glyphs[gid]) {
+			if c < numGlyphs && !used[c] {
+				used[c] = true
+				queue = append(queue, c)
+			}
+		}
+	}
+
+	var newGlyf bytes.Buffer
+	newLoca := make([]byte, (numGlyphs+1)*4)
+	for i := 0; i < numGlyphs; i++ {
+		binary.BigEndian.PutUint32(newLoca[i*4:], uint32(newGlyf.Len()))
+		if !used[i] {
+			continue
+		}
+		newGlyf.Write(glyphs[i])
+		for newGlyf.Len()%4 != 0 {
+			newGlyf.WriteByte(0)
+		}
+	}
+	binary.BigEndian.PutUint32(newLoca[numGlyphs*4:], uint32(newGlyf.Len()))
+	tables["glyf"], tables["loca"] = newGlyf.Bytes(), newLoca
+
+	newHead := append([]byte(nil), head...)
+	binary.BigEndian.PutUint16(newHead[50:], 1)
+	tables["head"] = newHead
+	tables["cmap"] = writeCmap(mapping)
+
+	// glyph names are not needed in web fonts
+	if post := tables["post"]; len(post) >= 32 && binary.BigEndian.Uint32(post) == 0x00020000 {
+		newPost := append([]byte(nil), post[:32]...)
+		binary.BigEndian.PutUint32(newPost, 0x00030000)
+		tables["post"] = newPost
+	}
+	// signature is invalid after subsetting
+	delete(tables, "DSIG")
+	// substitutions such as ligatures and alternates map to glyphs out of cmap,
+	// they are emptied and render blank, so shape with glyphs in cmap only
+	for _, tag := range []string{"GSUB", "morx", "mort"} {
+		delete(tables, tag)
+	}
+	return nil
+}
+
+// glyphComponents return glyph ids of components in composite glyph
+func glyphComponents(glyph []byte) []int {
+	if len(glyph) < 10 || int16(binary.BigEndian.Uint16(glyph)) >= 0 {
+		return nil
+	}
+	var components []int
+	for p := 10; p+4 <= len(glyph); {
+		flags := binary.BigEndian.Uint16(glyph[p:])
+		components = append(components, int(binary.BigEndian.Uint16(glyph[p+2:])))
+		p += 4
+		if flags&0x0001 != 0 {
+			p += 4
+		} else {
+			p += 2
+		}
+		switch {
+		case flags&0x0008 != 0:
+			p += 2
+		case flags&0x0040 != 0:
+			p += 4
+		case flags&0x0080 != 0:
+			p += 8
+		}
+		if flags&0x0020 == 0 {
+			break
+		}
+	}
+	return components
+}
+
+// readCmap return glyph ids of chars in unicode cmap subtable,
+// format 12 subtable is used first, then format 4
+func readCmap(data []byte) (map[rune]int, error) {
+	if len(data) < 4 {
+		return nil, errFontTruncated
+	}
+	num := int(binary.BigEndian.Uint16(data[2:]))
+	if len(data) < 4+num*8 {
+		return nil, errFontTruncated
+	}
+	var format4, format12 []byte
+	for i := 0; i < num; i++ {
+		rec := data[4+i*8:]
+		platform, encoding := binary.BigEndian.Uint16(rec), binary.BigEndian.Uint16(rec[2:])
+		offset := int(binary.BigEndian.Uint32(rec[4:]))
+		if offset+2 > len(data) {
+			return nil, errFontTruncated
+		}
+		unicode := platform == 0 || (platform == 3 && (encoding == 1 || encoding == 10))
+		if !unicode {
+			continue
+		}
+		switch binary.BigEndian.Uint16(data[offset:]) {
+		case 4:
+			format4 = data[offset:]
+		case 12:
+			format12 = data[offset:]
+		}
+	}
+	cmap := make(map[rune]int)
+	if format12 != nil {
+		if len(format12) < 16 {
+			return nil, errFontTruncated
+		}
+		groups := int(binary.BigEndian.Uint32(format12[12:]))
+		if len(format12) < 16+groups*12 {
+			return nil, errFontTruncated
+		}
+		for i := 0; i < groups; i++ {
+			g := format12[16+i*12:]
+			start, end := binary.BigEndian.Uint32(g), binary.BigEndian.Uint32(g[4:])
+			gid := binary.BigEndian.Uint32(g[8:])
+			for c := start; c <= end && c <= 0x10FFFF; c++ {
+				cmap[rune(c)] = int(gid + c - start)
+			}
+		}
+		return cmap, nil
+	}
+	if format4 == nil {
+		return nil, errors.New("font has no unicode cmap")
+	}
+	if len(format4) < 14 {
+		return nil, errFontTruncated
+	}
+	segs := int(binary.BigEndian.Uint16(format4[6:])) / 2
+	if len(format4) < 16+segs*8 {
+		return nil, errFontTruncated
+	}
+	ends, starts := format4[14:], format4[16+segs*2:]
+	deltas, rangeOffsets := format4[16+segs*4:], format4[16+segs*6:]
+	for i := 0; i < segs; i++ {
+		end, start := int(binary.BigEndian.Uint16(ends[i*2:])), int(binary.BigEndian.Uint16(starts[i*2:]))
+		delta, rangeOffset := int(binary.BigEndian.Uint16(deltas[i*2:])), int(binary.BigEndian.Uint16(rangeOffsets[i*2:]))
+		for c := start; c <= end && c != 0xFFFF; c++ {
+			gid := 0
+			if rangeOffset == 0 {
+				gid = (c + delta) & 0xFFFF
+			} else {
+				p := 16 + segs*6 + i*2 + rangeOffset + (c-start)*2
+				if p+2 > len(format4) {
+					continue
+				}
+				if gid = int(binary.BigEndian.Uint16(format4[p:])); gid != 0 {
+					gid = (gid + delta) & 0xFFFF
+				}
+			}
+			if gid != 0 {
+				cmap[rune(c)] = gid
+			}
+		}
+	}
+	return cmap, nil
+}
+
+// cmapGroup is chars from start to end mapped to glyphs from gid
+type cmapGroup struct {
+	start, end rune
+	gid        int
+}
+
+// writeCmap return cmap table with format 4 subtable for BMP chars,
+// and format 12 subtable for all chars
+func writeCmap(mapping map[rune]int) []byte {
+	chars := make([]int, 0, len(mapping))
+	for r := range mapping {
+		chars = append(chars, int(r))
+	}
+	sort.Ints(chars)
+	var groups, bmp []cmapGroup
+	for _, c := range chars {
+		r, gid := rune(c), mapping[rune(c)]
+		if n := len(groups); n > 0 && groups[n-1].end+1 == r && groups[n-1].gid+int(r-groups[n-1].start) == gid {
+			groups[n-1].end = r
+		} else {
+			groups = append(groups, cmapGroup{r, r, gid})
+		}
+	}
+	for _, g := range groups {
+		if g.start >= 0xFFFF {
+			break
+		}
+		if g.end >= 0xFFFF {
+			g.end = 0xFFFE
+		}
+		bmp = append(bmp, g)
+	}
+
+	var f12 bytes.Buffer
+	write := func(buf *bytes.Buffer, v ...interface{}) {
+		for _, x := range v {
+			binary.Write(buf, binary.BigEndian, x)
+		}
+	}
+	write(&f12, uint16(12), uint16(0), uint32(16+len(groups)*12), uint32(0), uint32(len(groups)))
+	for _, g := range groups {
+		write(&f12, uint32(g.start), uint32(g.end), uint32(g.gid))
+	}
+
+	// format 4 needs a last segment for 0xFFFF, and its length must fit in uint16
+	segs := len(bmp) + 1
+	var f4 bytes.Buffer
+	if 16+segs*8 <= 0xFFFF {
+		entrySelector := 0
+		for 1<<uint(entrySelector+1) <= segs {
+			entrySelector++
+		}
+		searchRange := 2 << uint(entrySelector)
+		write(&f4, uint16(4), uint16(16+segs*8), uint16(0), uint16(segs*2),
+			uint16(searchRange), uint16(entrySelector), uint16(segs*2-searchRange))
+		for _, g := range bmp {
+			write(&f4, uint16(g.end))
+		}
+		write(&f4, uint16(0xFFFF), uint16(0))
+		for _, g := range bmp {
+			write(&f4, uint16(g.start))
+		}
+		write(&f4, uint16(0xFFFF))
+		for _, g := range bmp {
+			write(&f4, uint16(g.gid-int(g.start)))
+		}
+		write(&f4, uint16(1))
+		for i := 0; i < segs; i++ {
+			write(&f4, uint16(0))
+		}
+	}
+
+	var buf bytes.Buffer
+	if f4.Len() > 0 {
+		write(&buf, uint16(0), uint16(2))
+		write(&buf, uint16(3), uint16(1), uint32(4+16))
+		write(&buf, uint16(3), uint16(10), uint32(4+16+f4.Len()))
+		buf.Write(f4.Bytes())
+	} else {
+		write(&buf, uint16(0), uint16(1))
+		write(&buf, uint16(3), uint16(10), uint32(4+8))
+	}
+	buf.Write(f12.Bytes())
+	return buf.Bytes()
+}
+
+func fontChecksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		var v [4]byte
+		copy(v[:], data[i:])
+		sum += binary.BigEndian.Uint32(v[:])
+	}
+	return sum
+}
+
+// sortedTags return table tags in order
+func (t fontTables) sortedTags() []string {
+	tags := make([]string, 0, len(t))
+	for tag := range t {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+func writeSfnt(version uint32, tables fontTables) []byte {
+	tags := tables.sortedTags()
+	num := len(tags)
+	entrySelector := 0
+	for 1<<uint(entrySelector+1) <= num {
+		entrySelector++
+	}
+	searchRange := (1 << uint(entrySelector)) * 16
+
+	// checksum of head is computed with zero checkSumAdjustment
+	head := append([]byte(nil), tables["head"]...)
+	binary.BigEndian.PutUint32(head[8:], 0)
+	tables["head"] = head
+
+	out := make([]byte, 12+num*16)
+	binary.BigEndian.PutUint32(out, version)
+	binary.BigEndian.PutUint16(out[4:], uint16(num))
+	binary.BigEndian.PutUint16(out[6:], uint16(searchRange))
+	binary.BigEndian.PutUint16(out[8:], uint16(entrySelector))
+	binary.BigEndian.PutUint16(out[10:], uint16(num*16-searchRange))
+	headOffset := 0
+	for i, tag := range tags {
+		data := tables[tag]
+		rec := out[12+i*16:]
+		copy(rec, tag)
+		binary.BigEndian.PutUint32(rec[4:], fontChecksum(data))
+		binary.BigEndian.PutUint32(rec[8:], uint32(len(out)))
+		binary.BigEndian.PutUint32(rec[12:], uint32(len(data)))
+		if tag == "head" {
+			headOffset = len(out)
+		}
+		out = append(out, data...)
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+	}
+	binary.BigEndian.PutUint32(out[headOffset+8:], 0xB1B0AFBA-fontChecksum(out))
+	return out
+}
+
+func writeWOFF(version uint32, tables fontTables) ([]byte, error) {
+	// checksums and head adjustment are the same as sfnt font
+	sfnt := writeSfnt(version, tables)
+	_, tables, err := readSfnt(sfnt)
+	if err != nil {
+		return nil, err
+	}
+	tags := tables.sortedTags()
+	num := len(tags)
+	out := make([]byte, 44+num*20)
+	copy(out, "wOFF")
+	binary.BigEndian.PutUint32(out[4:], version)
+	binary.BigEndian.PutUint16(out[12:], uint16(num))
+	binary.BigEndian.PutUint32(out[16:], uint32(len(sfnt)))
+	binary.BigEndian.PutUint16(out[20:], 1)
+	for i, tag := range tags {
+		data := tables[tag]
+		stored := data
+		var buf bytes.Buffer
+		w := zlib.NewWriter(&buf)
+		w.Write(data)
+		w.Close()
+		if buf.Len() < len(data) {
+			stored = buf.Bytes()
+		}
+		rec := out[44+i*20:]
+		copy(rec, tag)
+		binary.BigEndian.PutUint32(rec[4:], uint32(len(out)))
+		binary.BigEndian.PutUint32(rec[8:], uint32(len(stored)))
+		binary.BigEndian.PutUint32(rec[12:], uint32(len(data)))
+		binary.BigEndian.PutUint32(rec[16:], fontChecksum(data))
+		out = append(out, stored...)
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+	}
+	binary.BigEndian.PutUint32(out[8:], uint32(len(out)))
+	return out, nil
+}
@@ -0,0 +1,105 @@
+package helper
+
+import (
+	"encoding/binary"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// testFont return TrueType font with glyphs of 'A', 'B', and composite 'C' of 'A'
+func testFont() []byte {
+	simple := func(b byte) []byte {
+		return []byte{0, 1, 0, 0, 0, 0, 0, b, 0, b, 0, 0}
+	}
+	glyphs := [][]byte{
+		simple(1),
+		simple(2),
+		simple(3),
+		{0xFF, 0xFF, 0, 0, 0, 0, 0, 4, 0, 4, 0, 0, 0, 1, 0, 0},
+	}
+	tables := fontTables{
+		"head": make([]byte, 54),
+		"maxp": []byte{0, 0, 0x50, 0, 0, byte(len(glyphs))},
+		"post": make([]byte, 40),
+		"DSIG": make([]byte, 8),
+		"GSUB": make([]byte, 12),
+		"cmap": writeCmap(map[rune]int{'A': 1, 'B': 2, 'C': 3}),
+	}
+	binary.BigEndian.PutUint32(tables["post"], 0x00020000)
+	var glyf []byte
+	loca := make([]byte, (len(glyphs)+1)*2)
+	for i, g := range glyphs {
+		binary.BigEndian.PutUint16(loca[i*2:], uint16(len(glyf)/2))
+		glyf = append(glyf, g...)
+	}
+	binary.BigEndian.PutUint16(loca[len(glyphs)*2:], uint16(len(glyf)/2))
+	tables["glyf"], tables["loca"] = glyf, loca
+	return writeSfnt(0x00010000, tables)
+}
+
+func TestSubsetFont(t *testing.T) {
+	Convey("Subset TrueType font", t, func() {
+		data, err := SubsetFont(testFont(), map[rune]bool{'C': true, '中': true})
+		So(err, ShouldBeNil)
+		So(fontChecksum(data), ShouldEqual, 0xB1B0AFBA)
+
+		_, tables, err := readSfnt(data)
+		So(err, ShouldBeNil)
+		So(tables["DSIG"], ShouldBeNil)
+		So(tables["GSUB"], ShouldBeNil)
+		So(binary.BigEndian.Uint32(tables["post"]), ShouldEqual, 0x00030000)
+		So(binary.BigEndian.Uint16(tables["head"][50:]), ShouldEqual, 1)
+
+		cmap, err := readCmap(tables["cmap"])
+		So(err, ShouldBeNil)
+		So(cmap, ShouldResemble, map[rune]int{'C': 3})
+
+		// glyph 0 and component 'A' of 'C' are kept, 'B' is emptied
+		loca := tables["loca"]
+		size := func(gid int) uint32 {
+			return binary.BigEndian.Uint32(loca[(gid+1)*4:]) - binary.BigEndian.Uint32(loca[gid*4:])
+		}
+		So(size(0), ShouldEqual, 12)
+		So(size(1), ShouldEqual, 12)
+		So(size(2), ShouldEqual, 0)
+		So(size(3), ShouldEqual, 16)
+		So(glyphComponents(tables["glyf"][24:40]), ShouldResemble, []int{1})
+	})
+
+	Convey("Subset WOFF font", t, func() {
+		woff, err := writeWOFF(0x00010000, func() fontTables {
+			_, tables, _ := readSfnt(testFont())
+			return tables
+		}())
+		So(err, ShouldBeNil)
+		data, err := SubsetFont(woff, map[rune]bool{'B': true})
+		So(err, ShouldBeNil)
+		So(string(data[:4]), ShouldEqual, "wOFF")
+		So(binary.BigEndian.Uint32(data[8:]), ShouldEqual, len(data))
+
+		_, tables, err := readWOFF(data)
+		So(err, ShouldBeNil)
+		cmap, err := readCmap(tables["cmap"])
+		So(err, ShouldBeNil)
+		So(cmap, ShouldResemble, map[rune]int{'B': 2})
+	})
+
+	Convey("Read format 4 cmap", t, func() {
+		full := writeCmap(map[rune]int{'a': 5, 'b': 6, 'z': 9})
+		// keep only record of format 4 subtable
+		cmap := append([]byte{0, 0, 0, 1, 0, 3, 0, 1, 0, 0, 0, 12}, full[20:]...)
+		m, err := readCmap(cmap)
+		So(err, ShouldBeNil)
+		So(m, ShouldResemble, map[rune]int{'a': 5, 'b': 6, 'z': 9})
+	})
+
+	Convey("Unsupported font", t, func() {
+		_, err := SubsetFont([]byte("OTTO00000000"), nil)
+		So(err, ShouldEqual, errFontCFF)
+		_, err = SubsetFont([]byte("wOF200000000"), nil)
+		So(err, ShouldEqual, errFontWOFF2)
+		_, err = SubsetFont([]byte("abcd00000000"), nil)
+		So(err, ShouldEqual, errFontFormat)
+	})
+}
@@ -0,0 +1,153 @@
+package helper
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+type (
+	// Ignore matches paths with rules in gitignore syntax
+	Ignore struct {
+		rules []ignoreRule
+	}
+	ignoreRule struct {
+		regexp  *regexp.Regexp
+		negate  bool
+		dirOnly bool
+	}
+)
+
+// NewIgnore parses rules in gitignore syntax
+func NewIgnore(data []byte) *Ignore {
+	ig := new(Ignore)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var rule ignoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		// pattern with slash matches from root, otherwise matches name in any directory
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		expr := globToRegexp(line)
+		if !anchored {
+			expr = "(.*/)?" + expr
+		}
+		re, err := regexp.Compile("^" + expr + "$")
+		if err != nil {
+			continue
+		}
+		rule.regexp = re
+		ig.rules = append(ig.rules, rule)
+	}
+	return ig
+}
+
+// ReadIgnore reads rules from file, it returns empty rules if file is not existed
+func ReadIgnore(file string) (*Ignore, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return new(Ignore), nil
+		}
+		return nil, err
+	}
+	return NewIgnore(data), nil
+}
+
+// Match return whether the relative path is ignored,
+// files in ignored directory are ignored too
+func (ig *Ignore) Match(rel string, isDir bool) bool {
+	if ig == nil || len(ig.rules) == 0 {
+		return false
+	}
+	rel = strings.Trim(path.Clean(strings.Replace(rel, `\`, "/", -1)), "/")
+	if rel == "." || rel == "" {
+		return false
+	}
+	for i := 0; i < len(rel); i++ {
+		if rel[i] == '/' && ig.match(rel[:i], true) {
+			return true
+		}
+	}
+	return ig.match(rel, isDir)
+}
+
+func (ig *Ignore) match(rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range ig.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.regexp.MatchString(rel) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// GlobRegexp return regular expression to match whole path with glob pattern,
+// "**" matches any directories, "*" and "?" do not match slash
+func GlobRegexp(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("^" + globToRegexp(strings.TrimPrefix(pattern, "/")) + "$")
+}
+
+// globToRegexp converts glob pattern to regular expression,
+// "**" matches any directories, "*" and "?" do not match slash
+func globToRegexp(pattern string) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			buf.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "/**") && i+3 == len(pattern):
+			buf.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			buf.WriteString(".*")
+			i++
+		case c == '*':
+			buf.WriteString("[^/]*")
+		case c == '?':
+			buf.WriteString("[^/]")
+		case c == '[':
+			if j := strings.IndexByte(pattern[i:], ']'); j > 1 {
+				class := pattern[i+1 : i+j]
+				if strings.HasPrefix(class, "!") {
+					class = "^" + class[1:]
+				}
+				buf.WriteString("[" + class + "]")
+				i += j
+				continue
+			}
+			buf.WriteString(`\[`)
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			buf.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		default:
+			buf.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return buf.String()
+}
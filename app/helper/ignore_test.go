@@ -0,0 +1,53 @@
+package helper
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestIgnore(t *testing.T) {
+	Convey("Ignore", t, func() {
+		ig := NewIgnore([]byte(`
+# editor backups
+*~
+*.swp
+*.psd
+/drafts/
+raw/
+media/**/*.tiff
+!keep.psd
+\#hash
+`))
+		cases := []struct {
+			path    string
+			isDir   bool
+			ignored bool
+		}{
+			{"post/a.md~", false, true},
+			{".a.md.swp", false, true},
+			{"media/design/logo.psd", false, true},
+			{"media/design/keep.psd", false, false},
+			{"drafts", true, true},
+			{"drafts/a.md", false, true},
+			{"post/drafts/a.md", false, false},
+			{"media/raw/photo.jpg", false, true},
+			{"media/raw", false, false},
+			{"media/2016/a/photo.tiff", false, true},
+			{"media/photo.tiff", false, true},
+			{"photo.tiff", false, false},
+			{"#hash", false, true},
+			{"post/a.md", false, false},
+		}
+		for _, c := range cases {
+			So(ig.Match(c.path, c.isDir), ShouldEqual, c.ignored)
+		}
+
+		var empty *Ignore
+		So(empty.Match("a.psd", false), ShouldBeFalse)
+
+		ig, err := ReadIgnore("not-exist")
+		So(err, ShouldBeNil)
+		So(ig.Match("a.psd", false), ShouldBeFalse)
+	})
+}
@@ -0,0 +1,68 @@
+package helper
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var includeRegex = regexp.MustCompile(`{{%\s*include\s+"([^"]+)"\s*%}}`)
+
+// HasInclude checks content has {{% include "file" %}} directives
+func HasInclude(data []byte) bool {
+	return includeRegex.Match(data)
+}
+
+// Include replaces {{% include "file" %}} directives in content of file
+// by contents that read from files relative to dir, included files can include others.
+// It return expanded content and all included files, in order of first use.
+// Including a file out of dir or a file that is including itself is error.
+func Include(data []byte, file, dir string, read func(file string) ([]byte, error)) ([]byte, []string, error) {
+	var (
+		included []string
+		seen     = make(map[string]bool)
+	)
+	var expand func(data []byte, stack []string) ([]byte, error)
+	expand = func(data []byte, stack []string) ([]byte, error) {
+		var (
+			buf    bytes.Buffer
+			cursor int
+		)
+		for _, loc := range includeRegex.FindAllSubmatchIndex(data, -1) {
+			buf.Write(data[cursor:loc[0]])
+			cursor = loc[1]
+
+			name := string(data[loc[2]:loc[3]])
+			f := filepath.Join(dir, filepath.FromSlash(name))
+			if rel, err := filepath.Rel(dir, f); err != nil || strings.HasPrefix(rel, "..") {
+				return nil, fmt.Errorf("include '%s' is out of directory '%s'", name, dir)
+			}
+			for i, s := range stack {
+				if s == f {
+					return nil, fmt.Errorf("include cycle: %s", strings.Join(append(stack[i:], f), " -> "))
+				}
+			}
+			content, err := read(f)
+			if err != nil {
+				return nil, fmt.Errorf("include '%s': %s", name, err.Error())
+			}
+			if !seen[f] {
+				seen[f] = true
+				included = append(included, f)
+			}
+			if content, err = expand(bytes.Trim(content, "\n"), append(stack, f)); err != nil {
+				return nil, err
+			}
+			buf.Write(content)
+		}
+		buf.Write(data[cursor:])
+		return buf.Bytes(), nil
+	}
+	data, err := expand(data, []string{filepath.Clean(file)})
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, included, nil
+}
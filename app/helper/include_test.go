@@ -0,0 +1,47 @@
+package helper
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestInclude(t *testing.T) {
+	Convey("Include", t, func() {
+		files := map[string]string{
+			"snippets/a.md":    "A {{% include \"snippets/b.md\" %}}\n",
+			"snippets/b.md":    "B",
+			"snippets/self.md": "{{% include \"snippets/loop.md\" %}}",
+			"snippets/loop.md": "{{% include \"snippets/self.md\" %}}",
+		}
+		read := func(f string) ([]byte, error) {
+			rel, _ := filepath.Rel("src", f)
+			if data, ok := files[filepath.ToSlash(rel)]; ok {
+				return []byte(data), nil
+			}
+			return nil, fmt.Errorf("not found")
+		}
+
+		So(HasInclude([]byte("no include")), ShouldBeFalse)
+		data, included, err := Include([]byte(`x {{% include "snippets/a.md" %}} {{%include "snippets/b.md"%}}`), "src/post/p.md", "src", read)
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, "x A B B")
+		So(included, ShouldResemble, []string{filepath.Join("src", "snippets/a.md"), filepath.Join("src", "snippets/b.md")})
+
+		_, _, err = Include([]byte(`{{% include "snippets/self.md" %}}`), "src/post/p.md", "src", read)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "include cycle")
+
+		_, _, err = Include([]byte(`{{% include "post/p.md" %}}`), "src/post/p.md", "src", func(string) ([]byte, error) {
+			return []byte(`{{% include "post/p.md" %}}`), nil
+		})
+		So(err, ShouldNotBeNil)
+
+		_, _, err = Include([]byte(`{{% include "../secret" %}}`), "src/post/p.md", "src", read)
+		So(err, ShouldNotBeNil)
+		_, _, err = Include([]byte(`{{% include "snippets/none.md" %}}`), "src/post/p.md", "src", read)
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,202 @@
+package helper
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Locale is names of months and weekdays and number separators of a language
+type Locale struct {
+	Months      [12]string
+	ShortMonths [12]string
+	Days        [7]string // from Sunday
+	ShortDays   [7]string
+	Decimal     string
+	Group       string
+}
+
+var (
+	cjkMonths = [12]string{"1月", "2月", "3月", "4月", "5月", "6月", "7月", "8月", "9月", "10月", "11月", "12月"}
+
+	locales = map[string]*Locale{
+		"en": {
+			Months:      [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+			ShortMonths: [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+			Days:        [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+			ShortDays:   [7]string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"},
+			Decimal:     ".",
+			Group:       ",",
+		},
+		"zh": {
+			Months:      [12]string{"一月", "二月", "三月", "四月", "五月", "六月", "七月", "八月", "九月", "十月", "十一月", "十二月"},
+			ShortMonths: cjkMonths,
+			Days:        [7]string{"星期日", "星期一", "星期二", "星期三", "星期四", "星期五", "星期六"},
+			ShortDays:   [7]string{"周日", "周一", "周二", "周三", "周四", "周五", "周六"},
+			Decimal:     ".",
+			Group:       ",",
+		},
+		"zh-tw": {
+			Months:      [12]string{"一月", "二月", "三月", "四月", "五月", "六月", "七月", "八月", "九月", "十月", "十一月", "十二月"},
+			ShortMonths: cjkMonths,
+			Days:        [7]string{"星期日", "星期一", "星期二", "星期三", "星期四", "星期五", "星期六"},
+			ShortDays:   [7]string{"週日", "週一", "週二", "週三", "週四", "週五", "週六"},
+			Decimal:     ".",
+			Group:       ",",
+		},
+		"ja": {
+			Months:      cjkMonths,
+			ShortMonths: cjkMonths,
+			Days:        [7]string{"日曜日", "月曜日", "火曜日", "水曜日", "木曜日", "金曜日", "土曜日"},
+			ShortDays:   [7]string{"日", "月", "火", "水", "木", "金", "土"},
+			Decimal:     ".",
+			Group:       ",",
+		},
+		"ko": {
+			Months:      [12]string{"1월", "2월", "3월", "4월", "5월", "6월", "7월", "8월", "9월", "10월", "11월", "12월"},
+			ShortMonths: [12]string{"1월", "2월", "3월", "4월", "5월", "6월", "7월", "8월", "9월", "10월", "11월", "12월"},
+			Days:        [7]string{"일요일", "월요일", "화요일", "수요일", "목요일", "금요일", "토요일"},
+			ShortDays:   [7]string{"일", "월", "화", "수", "목", "금", "토"},
+			Decimal:     ".",
+			Group:       ",",
+		},
+		"de": {
+			Months:      [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+			ShortMonths: [12]string{"Jan.", "Feb.", "März", "Apr.", "Mai", "Juni", "Juli", "Aug.", "Sept.", "Okt.", "Nov.", "Dez."},
+			Days:        [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+			ShortDays:   [7]string{"So.", "Mo.", "Di.", "Mi.", "Do.", "Fr.", "Sa."},
+			Decimal:     ",",
+			Group:       ".",
+		},
+		"fr": {
+			Months:      [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+			ShortMonths: [12]string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+			Days:        [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+			ShortDays:   [7]string{"dim.", "lun.", "mar.", "mer.", "jeu.", "ven.", "sam."},
+			Decimal:     ",",
+			Group:       " ",
+		},
+		"es": {
+			Months:      [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+			ShortMonths: [12]string{"ene.", "feb.", "mar.", "abr.", "may.", "jun.", "jul.", "ago.", "sept.", "oct.", "nov.", "dic."},
+			Days:        [7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+			ShortDays:   [7]string{"dom.", "lun.", "mar.", "mié.", "jue.", "vie.", "sáb."},
+			Decimal:     ",",
+			Group:       ".",
+		},
+		"pt": {
+			Months:      [12]string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+			ShortMonths: [12]string{"jan.", "fev.", "mar.", "abr.", "mai.", "jun.", "jul.", "ago.", "set.", "out.", "nov.", "dez."},
+			Days:        [7]string{"domingo", "segunda-feira", "terça-feira", "quarta-feira", "quinta-feira", "sexta-feira", "sábado"},
+			ShortDays:   [7]string{"dom.", "seg.", "ter.", "qua.", "qui.", "sex.", "sáb."},
+			Decimal:     ",",
+			Group:       ".",
+		},
+		"ru": {
+			Months:      [12]string{"января", "февраля", "марта", "апреля", "мая", "июня", "июля", "августа", "сентября", "октября", "ноября", "декабря"},
+			ShortMonths: [12]string{"янв.", "февр.", "мар.", "апр.", "мая", "июн.", "июл.", "авг.", "сент.", "окт.", "нояб.", "дек."},
+			Days:        [7]string{"воскресенье", "понедельник", "вторник", "среда", "четверг", "пятница", "суббота"},
+			ShortDays:   [7]string{"вс", "пн", "вт", "ср", "чт", "пт", "сб"},
+			Decimal:     ",",
+			Group:       " ",
+		},
+	}
+)
+
+// GetLocale return locale of language, such as "en-US" or "zh",
+// it returns English locale if the language is unknown
+func GetLocale(lang string) *Locale {
+	for _, code := range LangCode(lang) {
+		if l := locales[strings.ToLower(code)]; l != nil {
+			return l
+		}
+	}
+	return locales["en"]
+}
+
+// FormatDate formats time by Go layout, such as "January 2, 2006",
+// month and weekday names in layout are printed in the language
+func (l *Locale) FormatDate(t time.Time, layout string) string {
+	var buf bytes.Buffer
+	for layout != "" {
+		name, token := "", ""
+		switch {
+		case strings.HasPrefix(layout, "January"):
+			name, token = l.Months[t.Month()-1], "January"
+		case strings.HasPrefix(layout, "Jan"):
+			name, token = l.ShortMonths[t.Month()-1], "Jan"
+		case strings.HasPrefix(layout, "Monday"):
+			name, token = l.Days[t.Weekday()], "Monday"
+		case strings.HasPrefix(layout, "Mon"):
+			name, token = l.ShortDays[t.Weekday()], "Mon"
+		}
+		if token != "" {
+			buf.WriteString(name)
+			layout = layout[len(token):]
+			continue
+		}
+		// format text until next name token by Go layout
+		next := len(layout)
+		for _, tk := range []string{"Jan", "Mon"} {
+			if i := strings.Index(layout[1:], tk); i >= 0 && i+1 < next {
+				next = i + 1
+			}
+		}
+		buf.WriteString(t.Format(layout[:next]))
+		layout = layout[next:]
+	}
+	return buf.String()
+}
+
+// FormatNumber formats number with decimals digits,
+// digits are grouped by thousands with separators of the language
+func (l *Locale) FormatNumber(n float64, decimals int) string {
+	if decimals < 0 {
+		decimals = 0
+	}
+	str := strconv.FormatFloat(math.Abs(n), 'f', decimals, 64)
+	integer, fraction := str, ""
+	if i := strings.IndexByte(str, '.'); i >= 0 {
+		integer, fraction = str[:i], str[i+1:]
+	}
+	var buf bytes.Buffer
+	if n < 0 && strings.Trim(str, "0.") != "" {
+		buf.WriteString("-")
+	}
+	for i, c := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			buf.WriteString(l.Group)
+		}
+		buf.WriteRune(c)
+	}
+	if fraction != "" {
+		buf.WriteString(l.Decimal + fraction)
+	}
+	return buf.String()
+}
+
+// ToFloat converts number value to float64
+func ToFloat(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	case int32:
+		return float64(n), nil
+	case uint:
+		return float64(n), nil
+	case uint64:
+		return float64(n), nil
+	case float32:
+		return float64(n), nil
+	case float64:
+		return n, nil
+	case string:
+		return strconv.ParseFloat(n, 64)
+	}
+	return 0, fmt.Errorf("'%v' is not a number", v)
+}
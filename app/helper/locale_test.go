@@ -0,0 +1,33 @@
+package helper
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLocale(t *testing.T) {
+	Convey("Locale", t, func() {
+		So(GetLocale("de-DE"), ShouldEqual, locales["de"])
+		So(GetLocale("zh-TW"), ShouldEqual, locales["zh-tw"])
+		So(GetLocale("unknown"), ShouldEqual, locales["en"])
+
+		tm := time.Date(2016, 3, 7, 15, 4, 0, 0, time.UTC)
+		So(GetLocale("en").FormatDate(tm, "Monday, January 2, 2006"), ShouldEqual, "Monday, March 7, 2016")
+		So(GetLocale("de").FormatDate(tm, "Mon 2. January 2006 15:04"), ShouldEqual, "Mo. 7. März 2016 15:04")
+		So(GetLocale("fr").FormatDate(tm, "2 Jan 2006"), ShouldEqual, "7 mars 2016")
+		So(GetLocale("zh").FormatDate(tm, "2006年1月2日 Monday"), ShouldEqual, "2016年3月7日 星期一")
+
+		So(GetLocale("en").FormatNumber(1234567.891, 2), ShouldEqual, "1,234,567.89")
+		So(GetLocale("de").FormatNumber(-1234.5, 1), ShouldEqual, "-1.234,5")
+		So(GetLocale("fr").FormatNumber(999, 0), ShouldEqual, "999")
+		So(GetLocale("en").FormatNumber(-0.001, 2), ShouldEqual, "0.00")
+
+		n, err := ToFloat("12.5")
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 12.5)
+		_, err = ToFloat(true)
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -58,3 +58,4 @@ func cleanLogCtx(ctx []interface{}) []interface{} {
 	}
 	return ctx
 }
+
@@ -0,0 +1,105 @@
+package helper
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+// NewLogger return logger that only logs to h, handler of root logger is not used,
+// so concurrent buildings can log to their own handlers
+func NewLogger(h log15.Handler) log15.Logger {
+	l := log15.New()
+	l.SetHandler(h)
+	return l
+}
+
+type (
+	// warnCountLogger counts warning, error and critical logs, then logs them by Logger
+	warnCountLogger struct {
+		log15.Logger
+		count *int64
+	}
+	// LogRecord is a captured log record, Msg is formatted with its arguments
+	LogRecord struct {
+		Time time.Time
+		Lvl  log15.Lvl
+		Msg  string
+	}
+	// LogCapture is log15.Handler that keeps records in memory,
+	// it's safe for concurrent use
+	LogCapture struct {
+		lock    sync.Mutex
+		records []*LogRecord
+	}
+)
+
+// NewWarnCountLogger return logger that logs by base,
+// and counts warning, error and critical logs in count
+func NewWarnCountLogger(base log15.Logger, count *int64) log15.Logger {
+	return &warnCountLogger{Logger: base, count: count}
+}
+
+// New returns child logger that counts in same count
+func (l *warnCountLogger) New(ctx ...interface{}) log15.Logger {
+	return NewWarnCountLogger(l.Logger.New(ctx...), l.count)
+}
+
+// Warn counts and logs a message at warning level
+func (l *warnCountLogger) Warn(msg string, ctx ...interface{}) {
+	atomic.AddInt64(l.count, 1)
+	l.Logger.Warn(msg, ctx...)
+}
+
+// Error counts and logs a message at error level
+func (l *warnCountLogger) Error(msg string, ctx ...interface{}) {
+	atomic.AddInt64(l.count, 1)
+	l.Logger.Error(msg, ctx...)
+}
+
+// Crit counts and logs a message at critical level
+func (l *warnCountLogger) Crit(msg string, ctx ...interface{}) {
+	atomic.AddInt64(l.count, 1)
+	l.Logger.Crit(msg, ctx...)
+}
+
+// NewLogCapture return empty log capture
+func NewLogCapture() *LogCapture {
+	return new(LogCapture)
+}
+
+// Log implements log15.Handler
+func (c *LogCapture) Log(r *log15.Record) error {
+	record := &LogRecord{
+		Time: r.Time,
+		Lvl:  r.Lvl,
+		Msg:  fmt.Sprintf(r.Msg, cleanLogCtx(r.Ctx)...),
+	}
+	c.lock.Lock()
+	c.records = append(c.records, record)
+	c.lock.Unlock()
+	return nil
+}
+
+// Records return captured records in order
+func (c *LogCapture) Records() []*LogRecord {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]*LogRecord(nil), c.records...)
+}
+
+// Count return the number of captured records at lvl or more severe
+func (c *LogCapture) Count(lvl log15.Lvl) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	n := 0
+	for _, r := range c.records {
+		if r.Lvl <= lvl {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,52 @@
+package helper
+
+import (
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+func TestLogCapture(t *testing.T) {
+	Convey("Log Capture", t, func() {
+		c1, c2 := NewLogCapture(), NewLogCapture()
+		l1, l2 := NewLogger(c1), NewLogger(c2)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(2)
+			go func(i int) {
+				defer wg.Done()
+				l1.Info("Build|%s|%d", "a", i)
+			}(i)
+			go func(i int) {
+				defer wg.Done()
+				l2.Warn("Build|%s|%d", "b", i)
+			}(i)
+		}
+		wg.Wait()
+
+		So(c1.Records(), ShouldHaveLength, 50)
+		So(c2.Records(), ShouldHaveLength, 50)
+		for _, r := range c1.Records() {
+			So(r.Msg, ShouldStartWith, "Build|a|")
+		}
+		So(c1.Count(log15.LvlWarn), ShouldEqual, 0)
+		So(c2.Count(log15.LvlWarn), ShouldEqual, 50)
+	})
+}
+
+func TestWarnCountLogger(t *testing.T) {
+	Convey("Warn Count Logger", t, func() {
+		var count int64
+		c := NewLogCapture()
+		l := NewWarnCountLogger(NewLogger(c), &count)
+		l.Info("ABC|%s", "info")
+		l.Warn("ABC|%s", "warn")
+		l.New("key", "value").Error("ABC|%s", "error")
+
+		So(count, ShouldEqual, 2)
+		So(c.Records(), ShouldHaveLength, 3)
+	})
+}
@@ -26,7 +26,8 @@ func (mr *markdownRender) BlockCode(out *bytes.Buffer, text []byte, lang string)
 	out.Write(bytes.Replace(tmp.Bytes(), tab, spaces, -1))
 }
 
-// Markdown converts markdown bytes to html bytes
+// Markdown converts markdown bytes to html bytes,
+// use MarkdownCache to skip rendering unchanged content
 func Markdown(raw []byte) []byte {
 	htmlFlags := 0 |
 		blackfriday.HTML_USE_XHTML |
@@ -0,0 +1,111 @@
+package helper
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// markdownCacheVersion changes when markdown renderer changes,
+// it makes old cache files invalid
+const markdownCacheVersion = "1"
+
+// MarkdownCache caches rendered markdown html by hash of raw content,
+// in memory and optionally in files of a directory.
+// Html in memory is kept for one building, see Rotate.
+type MarkdownCache struct {
+	dir  string
+	lock sync.RWMutex
+	data map[string][]byte
+	// last is html rendered in last building
+	last map[string][]byte
+}
+
+// NewMarkdownCache creates MarkdownCache.
+// If dir is not empty, rendered html is saved in dir too
+func NewMarkdownCache(dir string) *MarkdownCache {
+	return &MarkdownCache{
+		dir:  dir,
+		data: make(map[string][]byte),
+	}
+}
+
+// Rotate starts a new building, html not used since last Rotate is dropped,
+// so memory is bounded by content of one building
+func (mc *MarkdownCache) Rotate() {
+	mc.lock.Lock()
+	mc.last, mc.data = mc.data, make(map[string][]byte, len(mc.data))
+	mc.lock.Unlock()
+}
+
+// SetDir sets directory to save rendered markdown html.
+// If dir is empty, rendered html is only cached in memory
+func (mc *MarkdownCache) SetDir(dir string) {
+	mc.lock.Lock()
+	mc.dir = dir
+	mc.lock.Unlock()
+}
+
+// Render renders markdown bytes or returns cached html,
+// nil cache renders without caching
+func (mc *MarkdownCache) Render(raw []byte) []byte {
+	if mc == nil {
+		return Markdown(raw)
+	}
+	key := mc.key(raw)
+	mc.lock.RLock()
+	html, ok := mc.data[key]
+	if !ok {
+		html, ok = mc.last[key]
+	}
+	dir := mc.dir
+	mc.lock.RUnlock()
+	if ok {
+		mc.set(key, html)
+		return copyBytes(html)
+	}
+	var file string
+	if dir != "" {
+		file = filepath.Join(dir, key[:2], key+".html")
+		if html, err := ioutil.ReadFile(file); err == nil {
+			mc.set(key, html)
+			return copyBytes(html)
+		}
+	}
+	html = Markdown(raw)
+	mc.set(key, html)
+	if file != "" {
+		os.MkdirAll(filepath.Dir(file), os.ModePerm)
+		ioutil.WriteFile(file, html, os.ModePerm)
+	}
+	return copyBytes(html)
+}
+
+// Len returns count of cached items used since last Rotate
+func (mc *MarkdownCache) Len() int {
+	mc.lock.RLock()
+	defer mc.lock.RUnlock()
+	return len(mc.data)
+}
+
+func (mc *MarkdownCache) set(key string, html []byte) {
+	mc.lock.Lock()
+	mc.data[key] = html
+	mc.lock.Unlock()
+}
+
+func (mc *MarkdownCache) key(raw []byte) string {
+	h := md5.New()
+	h.Write([]byte(markdownCacheVersion))
+	h.Write(raw)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func copyBytes(data []byte) []byte {
+	b := make([]byte, len(data))
+	copy(b, data)
+	return b
+}
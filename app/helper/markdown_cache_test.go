@@ -0,0 +1,55 @@
+package helper
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMarkdownCache(t *testing.T) {
+	Convey("MarkdownCache", t, func() {
+		raw := []byte("#h1\n\ncontent")
+		mc := NewMarkdownCache("")
+		html := mc.Render(raw)
+		So(string(html), ShouldEqual, string(Markdown(raw)))
+		So(mc.Len(), ShouldEqual, 1)
+
+		html[0] = 'x'
+		So(string(mc.Render(raw)), ShouldEqual, string(Markdown(raw)))
+		So(mc.Len(), ShouldEqual, 1)
+
+		mc.Rotate()
+		So(mc.Len(), ShouldEqual, 0)
+		So(string(mc.Render(raw)), ShouldEqual, string(Markdown(raw)))
+		So(mc.Len(), ShouldEqual, 1)
+		// not used in last building
+		mc.Rotate()
+		mc.Rotate()
+		So(mc.last, ShouldBeEmpty)
+
+		var nilCache *MarkdownCache
+		So(string(nilCache.Render(raw)), ShouldEqual, string(Markdown(raw)))
+
+		Convey("CacheDir", func() {
+			dir, err := ioutil.TempDir("", "pugo-markdown")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(dir)
+
+			mc := NewMarkdownCache(dir)
+			mc.Render(raw)
+			key := mc.key(raw)
+			file := filepath.Join(dir, key[:2], key+".html")
+			data, err := ioutil.ReadFile(file)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, string(Markdown(raw)))
+
+			// read from file in new cache
+			ioutil.WriteFile(file, []byte("cached"), os.ModePerm)
+			mc2 := NewMarkdownCache(dir)
+			So(string(mc2.Render(raw)), ShouldEqual, "cached")
+		})
+	})
+}
@@ -0,0 +1,50 @@
+package helper
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var memoryUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"G", 1 << 30},
+	{"M", 1 << 20},
+	{"K", 1 << 10},
+	{"B", 1},
+}
+
+// ParseMemory parses memory size string to bytes,
+// such as 512M, 1G, 2048K or 1024
+func ParseMemory(str string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(str))
+	s = strings.TrimSuffix(s, "B")
+	if s == "" {
+		return 0, fmt.Errorf("memory size '%s' is invalid", str)
+	}
+	unit := int64(1)
+	for _, u := range memoryUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			unit = u.size
+			s = strings.TrimSuffix(s, u.suffix)
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("memory size '%s' is invalid", str)
+	}
+	return int64(n * float64(unit)), nil
+}
+
+// FormatMemory formats bytes size to readable string
+func FormatMemory(size int64) string {
+	for _, u := range memoryUnits {
+		if size >= u.size && u.size > 1 {
+			return fmt.Sprintf("%.1f%s", float64(size)/float64(u.size), u.suffix)
+		}
+	}
+	return fmt.Sprintf("%dB", size)
+}
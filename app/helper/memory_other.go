@@ -0,0 +1,9 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package helper
+
+// PeakRSS returns 0 because peak resident set size is not supported
+func PeakRSS() int64 {
+	return 0
+}
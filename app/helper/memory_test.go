@@ -0,0 +1,34 @@
+package helper
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMemory(t *testing.T) {
+	Convey("ParseMemory", t, func() {
+		for str, size := range map[string]int64{
+			"512M":  512 << 20,
+			"512mb": 512 << 20,
+			"1G":    1 << 30,
+			"1.5g":  3 << 29,
+			"2048K": 2 << 20,
+			"1024":  1024,
+		} {
+			n, err := ParseMemory(str)
+			So(err, ShouldBeNil)
+			So(n, ShouldEqual, size)
+		}
+		_, err := ParseMemory("abc")
+		So(err, ShouldNotBeNil)
+		_, err = ParseMemory("")
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("FormatMemory", t, func() {
+		So(FormatMemory(512<<20), ShouldEqual, "512.0M")
+		So(FormatMemory(3<<29), ShouldEqual, "1.5G")
+		So(FormatMemory(100), ShouldEqual, "100B")
+	})
+}
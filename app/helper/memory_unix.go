@@ -0,0 +1,21 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package helper
+
+import (
+	"runtime"
+	"syscall"
+)
+
+// PeakRSS returns peak resident set size of current process in bytes
+func PeakRSS() int64 {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0
+	}
+	if runtime.GOOS == "darwin" {
+		return int64(usage.Maxrss) // bytes in darwin
+	}
+	return int64(usage.Maxrss) * 1024
+}
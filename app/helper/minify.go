@@ -0,0 +1,63 @@
+package helper
+
+import "bytes"
+
+// MinifyCSS removes comments and needless whitespace in css,
+// strings are kept as they are
+func MinifyCSS(data []byte) []byte {
+	var (
+		buf   bytes.Buffer
+		space bool
+	)
+	isTrim := func(c byte) bool {
+		return c == '{' || c == '}' || c == ';' || c == ',' || c == '>'
+	}
+	lastByte := func() byte {
+		if buf.Len() == 0 {
+			return 0
+		}
+		return buf.Bytes()[buf.Len()-1]
+	}
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				i = len(data)
+			} else {
+				i += end + 3
+			}
+			continue
+		case c == '"' || c == '\'':
+			if space && !isTrim(lastByte()) && buf.Len() > 0 {
+				buf.WriteByte(' ')
+			}
+			space = false
+			j := i + 1
+			for ; j < len(data) && data[j] != c; j++ {
+				if data[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(data) {
+				j = len(data) - 1
+			}
+			buf.Write(data[i : j+1])
+			i = j
+			continue
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			space = true
+			continue
+		}
+		if c == '}' && lastByte() == ';' {
+			buf.Truncate(buf.Len() - 1)
+		}
+		if space && buf.Len() > 0 && !isTrim(c) && !isTrim(lastByte()) {
+			buf.WriteByte(' ')
+		}
+		space = false
+		buf.WriteByte(c)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,23 @@
+package helper
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMinifyCSS(t *testing.T) {
+	Convey("Minify CSS", t, func() {
+		css := `/* comment */
+body  ,  p > a {
+    color : red;
+    content: "a  /* b */  c";
+    width: calc(100% - 10px);
+}
+
+a :hover { margin : 0 auto ; }
+`
+		So(string(MinifyCSS([]byte(css))), ShouldEqual, `body,p>a{color : red;content: "a  /* b */  c";width: calc(100% - 10px)}a :hover{margin : 0 auto}`)
+		So(string(MinifyCSS([]byte("a{content:'x"))), ShouldEqual, "a{content:'x")
+	})
+}
@@ -0,0 +1,187 @@
+package helper
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// TextStats is counts of words, sentences and syllables in plain text,
+// each CJK character is counted as a word
+type TextStats struct {
+	Words     int
+	Sentences int
+	Syllables int
+	// CJK is count of CJK characters in Words
+	CJK int
+
+	counts map[string]int
+}
+
+// WordCount is a word and its count in text
+type WordCount struct {
+	Word  string `json:"word"`
+	Count int    `json:"count"`
+}
+
+// stopWords are common english words skipped in top words
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "but": true,
+	"by": true, "can": true, "do": true, "for": true, "from": true, "has": true, "have": true, "if": true,
+	"in": true, "is": true, "it": true, "its": true, "not": true, "of": true, "on": true, "or": true,
+	"so": true, "that": true, "the": true, "then": true, "this": true, "to": true, "was": true, "we": true,
+	"will": true, "with": true, "you": true, "your": true, "i": true, "they": true, "there": true,
+}
+
+// AnalyzeText counts words, sentences and syllables in text
+func AnalyzeText(text string) *TextStats {
+	s := &TextStats{counts: make(map[string]int)}
+	var (
+		word      []rune
+		inSegment bool
+	)
+	endWord := func() {
+		if len(word) == 0 {
+			return
+		}
+		w := strings.ToLower(string(word))
+		s.Words++
+		s.Syllables += syllables(w)
+		s.counts[w]++
+		word = word[:0]
+		inSegment = true
+	}
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			endWord()
+			s.Words++
+			s.CJK++
+			s.Syllables++
+			s.counts[string(r)]++
+			inSegment = true
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || (r == '\'' && len(word) > 0):
+			word = append(word, r)
+		default:
+			endWord()
+			if isSentenceEnd(r) && inSegment {
+				s.Sentences++
+				inSegment = false
+			}
+		}
+	}
+	endWord()
+	if inSegment {
+		s.Sentences++
+	}
+	return s
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+func isSentenceEnd(r rune) bool {
+	switch r {
+	case '.', '!', '?', '。', '！', '？', '\n':
+		return true
+	}
+	return false
+}
+
+// syllables estimates syllables of english word by vowel groups
+func syllables(word string) int {
+	count, prevVowel := 0, false
+	for _, r := range word {
+		vowel := strings.ContainsRune("aeiouy", r)
+		if vowel && !prevVowel {
+			count++
+		}
+		prevVowel = vowel
+	}
+	if strings.HasSuffix(word, "e") && !strings.HasSuffix(word, "le") && count > 1 {
+		count--
+	}
+	if count == 0 {
+		count = 1
+	}
+	return count
+}
+
+// HasScore checks text has words and is not mostly CJK,
+// Flesch score only works for english text
+func (s *TextStats) HasScore() bool {
+	return s.Words > 0 && s.Sentences > 0 && s.CJK*2 <= s.Words
+}
+
+// Flesch return Flesch reading ease score, higher is easier,
+// it's 0 if text has no score
+func (s *TextStats) Flesch() float64 {
+	if !s.HasScore() {
+		return 0
+	}
+	return 206.835 - 1.015*float64(s.Words)/float64(s.Sentences) - 84.6*float64(s.Syllables)/float64(s.Words)
+}
+
+// Level return readable level of Flesch score,
+// it's empty if text has no score
+func (s *TextStats) Level() string {
+	if !s.HasScore() {
+		return ""
+	}
+	switch score := s.Flesch(); {
+	case score >= 80:
+		return "easy"
+	case score >= 60:
+		return "standard"
+	case score >= 30:
+		return "difficult"
+	}
+	return "very difficult"
+}
+
+// Density return percent of keyword in words,
+// keyword of many words is counted by its least frequent word
+func (s *TextStats) Density(keyword string) float64 {
+	if s.Words == 0 {
+		return 0
+	}
+	k := AnalyzeText(keyword)
+	if k.Words == 0 {
+		return 0
+	}
+	least := -1
+	for w := range k.counts {
+		if c := s.counts[w]; least < 0 || c < least {
+			least = c
+		}
+	}
+	return float64(least*k.Words) * 100 / float64(s.Words)
+}
+
+// TopWords return n most frequent words except stop words and numbers
+func (s *TextStats) TopWords(n int) []WordCount {
+	list := make(wordCounts, 0, len(s.counts))
+	for w, c := range s.counts {
+		if stopWords[w] || strings.IndexFunc(w, unicode.IsLetter) < 0 {
+			continue
+		}
+		list = append(list, WordCount{w, c})
+	}
+	sort.Sort(list)
+	if len(list) > n {
+		list = list[:n]
+	}
+	return list
+}
+
+type wordCounts []WordCount
+
+func (wc wordCounts) Len() int { return len(wc) }
+func (wc wordCounts) Less(i, j int) bool {
+	if wc[i].Count == wc[j].Count {
+		return wc[i].Word < wc[j].Word
+	}
+	return wc[i].Count > wc[j].Count
+}
+func (wc wordCounts) Swap(i, j int) { wc[i], wc[j] = wc[j], wc[i] }
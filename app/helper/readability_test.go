@@ -0,0 +1,37 @@
+package helper
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReadability(t *testing.T) {
+	Convey("Analyze english text", t, func() {
+		s := AnalyzeText("The cat sat on the mat. The cat is happy! Go cat, go?")
+		So(s.Words, ShouldEqual, 13)
+		So(s.Sentences, ShouldEqual, 3)
+		So(s.CJK, ShouldEqual, 0)
+		So(s.Flesch(), ShouldBeGreaterThan, 80)
+		So(s.Level(), ShouldEqual, "easy")
+		So(s.Density("cat"), ShouldAlmostEqual, 300.0/13, 0.01)
+		So(s.Density("happy cat"), ShouldAlmostEqual, 200.0/13, 0.01)
+		So(s.Density("dog"), ShouldEqual, 0)
+		So(s.TopWords(2), ShouldResemble, []WordCount{{"cat", 3}, {"go", 2}})
+
+		s = AnalyzeText("Internationalization considerations necessitate comprehensive organizational documentation.")
+		So(s.Level(), ShouldEqual, "very difficult")
+		So(syllables("little"), ShouldEqual, 2)
+		So(syllables("make"), ShouldEqual, 1)
+	})
+
+	Convey("Analyze CJK text", t, func() {
+		s := AnalyzeText("静态网站。使用 PuGo 生成！")
+		So(s.Words, ShouldEqual, 9)
+		So(s.CJK, ShouldEqual, 8)
+		So(s.Sentences, ShouldEqual, 2)
+		So(s.HasScore(), ShouldBeFalse)
+		So(s.Level(), ShouldBeEmpty)
+		So(s.Density("网站"), ShouldAlmostEqual, 200.0/9, 0.01)
+	})
+}
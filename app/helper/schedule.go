@@ -0,0 +1,145 @@
+package helper
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule return next time to run after t
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+// ParseSchedule parses duration such as "30m" to run periodically,
+// or cron expression "minute hour day month weekday" such as "0 */6 * * *",
+// "@hourly", "@daily" and "@weekly" are shortcuts of cron expressions
+func ParseSchedule(s string) (Schedule, error) {
+	s = strings.TrimSpace(s)
+	if d, err := time.ParseDuration(s); err == nil {
+		if d < time.Minute {
+			return nil, fmt.Errorf("schedule '%s' is shorter than one minute", s)
+		}
+		return everySchedule(d), nil
+	}
+	switch s {
+	case "@hourly":
+		s = "0 * * * *"
+	case "@daily":
+		s = "0 0 * * *"
+	case "@weekly":
+		s = "0 0 * * 0"
+	}
+	fields := strings.Fields(s)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule '%s' is not duration or cron expression", s)
+	}
+	c := new(cronSchedule)
+	ranges := [][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	sets := []*[]bool{&c.minute, &c.hour, &c.day, &c.month, &c.weekday}
+	for i, f := range fields {
+		set, err := parseCronField(f, ranges[i][0], ranges[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("schedule '%s': %s", s, err.Error())
+		}
+		*sets[i] = set
+	}
+	// 7 is sunday too
+	if c.weekday[7] {
+		c.weekday[0] = true
+	}
+	c.anyDay = fields[2] == "*"
+	c.anyWeekday = fields[4] == "*"
+	// such as "0 0 31 2 *", it never matches
+	if c.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("schedule '%s' never matches", s)
+	}
+	return c, nil
+}
+
+type everySchedule time.Duration
+
+func (e everySchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// cronSchedule matches minutes, hours, days, months and weekdays
+type cronSchedule struct {
+	minute, hour, day, month, weekday []bool
+	anyDay, anyWeekday                bool
+}
+
+// Next return first matched minute after t, in time zone of t,
+// it returns zero time if nothing matches in 5 years
+func (c *cronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// a valid expression matches in 5 years, such as Feb 29
+	for limit := t.AddDate(5, 0, 0); t.Before(limit); {
+		if !c.month[t.Month()] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.hour[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.minute[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// matchDay matches day or weekday, if both are restricted, either one matches as cron
+func (c *cronSchedule) matchDay(t time.Time) bool {
+	day, weekday := c.day[t.Day()], c.weekday[t.Weekday()]
+	if c.anyDay || c.anyWeekday {
+		return day && weekday
+	}
+	return day || weekday
+}
+
+// parseCronField parses field of values, ranges and steps, such as "1,5", "9-17" and "*/15"
+func parseCronField(field string, min, max int) ([]bool, error) {
+	set := make([]bool, max+1)
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("step '%s' is invalid", part)
+			}
+			step, part = n, part[:i]
+		}
+		begin, end := min, max
+		if part != "*" {
+			var err error
+			bounds := strings.SplitN(part, "-", 2)
+			if begin, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("value '%s' is invalid", part)
+			}
+			end = begin
+			if len(bounds) == 2 {
+				if end, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("value '%s' is invalid", part)
+				}
+			} else if step > 1 {
+				end = max
+			}
+		}
+		if begin < min || end > max || begin > end {
+			return nil, fmt.Errorf("value '%s' is out of range %d-%d", part, min, max)
+		}
+		for i := begin; i <= end; i += step {
+			set[i] = true
+		}
+	}
+	return set, nil
+}
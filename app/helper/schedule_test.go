@@ -0,0 +1,48 @@
+package helper
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSchedule(t *testing.T) {
+	Convey("Schedule", t, func() {
+		now := time.Date(2017, 3, 10, 10, 20, 30, 0, time.UTC)
+
+		Convey("Duration", func() {
+			s, err := ParseSchedule("30m")
+			So(err, ShouldBeNil)
+			So(s.Next(now), ShouldResemble, now.Add(30*time.Minute))
+
+			_, err = ParseSchedule("10s")
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Cron", func() {
+			cases := map[string]time.Time{
+				"*/15 * * * *":  time.Date(2017, 3, 10, 10, 30, 0, 0, time.UTC),
+				"0 */6 * * *":   time.Date(2017, 3, 10, 12, 0, 0, 0, time.UTC),
+				"@daily":        time.Date(2017, 3, 11, 0, 0, 0, 0, time.UTC),
+				"@weekly":       time.Date(2017, 3, 12, 0, 0, 0, 0, time.UTC),
+				"30 9 1 * *":    time.Date(2017, 4, 1, 9, 30, 0, 0, time.UTC),
+				"0 8 * * 1-5":   time.Date(2017, 3, 13, 8, 0, 0, 0, time.UTC),
+				"0 0 29 2 *":    time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC),
+				"5,25 10 * * *": time.Date(2017, 3, 10, 10, 25, 0, 0, time.UTC),
+			}
+			for expr, next := range cases {
+				s, err := ParseSchedule(expr)
+				So(err, ShouldBeNil)
+				So(s.Next(now), ShouldResemble, next)
+			}
+		})
+
+		Convey("Invalid", func() {
+			for _, expr := range []string{"", "* * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *", "0 0 31 2 *", "0 0 30 2 *"} {
+				_, err := ParseSchedule(expr)
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+}
@@ -0,0 +1,19 @@
+package helper
+
+import (
+	"regexp"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNewUUID(t *testing.T) {
+	Convey("NewUUID", t, func() {
+		id, err := NewUUID()
+		So(err, ShouldBeNil)
+		So(regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(id), ShouldBeTrue)
+
+		id2, _ := NewUUID()
+		So(id2, ShouldNotEqual, id)
+	})
+}
@@ -0,0 +1,236 @@
+// Package metrics provides counters, gauges and histograms,
+// and exposes them in Prometheus text format.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// Metric is a value that can be exposed
+type Metric interface {
+	// Name return metric name
+	Name() string
+	// Write writes metric in Prometheus text format
+	Write(w io.Writer)
+}
+
+// Registry keeps metrics by name
+type Registry struct {
+	lock    sync.RWMutex
+	metrics map[string]Metric
+}
+
+// DefaultRegistry keeps metrics of building, deploying and watching,
+// they are registered once when package is initialized
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry creates empty registry
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]Metric)}
+}
+
+// Register registers metric, it panics if the name is registered
+func (r *Registry) Register(m Metric) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.metrics[m.Name()]; ok {
+		panic("metrics: duplicate metric " + m.Name())
+	}
+	r.metrics[m.Name()] = m
+}
+
+// WriteAll writes all registered metrics sorted by name
+func (r *Registry) WriteAll(w io.Writer) {
+	r.lock.RLock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	r.lock.RUnlock()
+	sort.Strings(names)
+	for _, name := range names {
+		r.lock.RLock()
+		m := r.metrics[name]
+		r.lock.RUnlock()
+		m.Write(w)
+	}
+}
+
+// Handler return http handler to show all registered metrics
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var buf bytes.Buffer
+		r.WriteAll(&buf)
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf.Bytes())
+	})
+}
+
+// NewCounter creates and registers counter
+func (r *Registry) NewCounter(name, help string) *Counter {
+	c := &Counter{name: name, help: help}
+	r.Register(c)
+	return c
+}
+
+// NewGauge creates and registers gauge
+func (r *Registry) NewGauge(name, help string) *Gauge {
+	g := &Gauge{name: name, help: help}
+	r.Register(g)
+	return g
+}
+
+// NewHistogram creates and registers histogram with upper bounds of buckets
+func (r *Registry) NewHistogram(name, help string, buckets []float64) *Histogram {
+	h := &Histogram{
+		name:    name,
+		help:    help,
+		buckets: buckets,
+		counts:  make([]int64, len(buckets)),
+	}
+	r.Register(h)
+	return h
+}
+
+// Register registers metric in DefaultRegistry
+func Register(m Metric) {
+	DefaultRegistry.Register(m)
+}
+
+// WriteAll writes all metrics in DefaultRegistry
+func WriteAll(w io.Writer) {
+	DefaultRegistry.WriteAll(w)
+}
+
+// Handler return http handler to show all metrics in DefaultRegistry
+func Handler() http.Handler {
+	return DefaultRegistry.Handler()
+}
+
+// NewCounter creates counter in DefaultRegistry
+func NewCounter(name, help string) *Counter {
+	return DefaultRegistry.NewCounter(name, help)
+}
+
+// NewGauge creates gauge in DefaultRegistry
+func NewGauge(name, help string) *Gauge {
+	return DefaultRegistry.NewGauge(name, help)
+}
+
+// NewHistogram creates histogram in DefaultRegistry
+func NewHistogram(name, help string, buckets []float64) *Histogram {
+	return DefaultRegistry.NewHistogram(name, help, buckets)
+}
+
+// Counter is a value that only increases
+type Counter struct {
+	name, help string
+	value      int64
+}
+
+// Inc increases counter by 1
+func (c *Counter) Inc() {
+	atomic.AddInt64(&c.value, 1)
+}
+
+// Value return counter value
+func (c *Counter) Value() int64 {
+	return atomic.LoadInt64(&c.value)
+}
+
+// Name return counter name
+func (c *Counter) Name() string {
+	return c.name
+}
+
+// Write writes counter
+func (c *Counter) Write(w io.Writer) {
+	writeHeader(w, c.name, c.help, "counter")
+	fmt.Fprintf(w, "%s %d\n", c.name, c.Value())
+}
+
+// Gauge is a value that can go up and down
+type Gauge struct {
+	name, help string
+	bits       uint64
+}
+
+// Set sets gauge value
+func (g *Gauge) Set(v float64) {
+	atomic.StoreUint64(&g.bits, math.Float64bits(v))
+}
+
+// Value return gauge value
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&g.bits))
+}
+
+// Name return gauge name
+func (g *Gauge) Name() string {
+	return g.name
+}
+
+// Write writes gauge
+func (g *Gauge) Write(w io.Writer) {
+	writeHeader(w, g.name, g.help, "gauge")
+	fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(g.Value()))
+}
+
+// Histogram counts observed values in buckets
+type Histogram struct {
+	name, help string
+	buckets    []float64
+	lock       sync.Mutex
+	counts     []int64
+	count      int64
+	sum        float64
+}
+
+// Observe adds value to histogram
+func (h *Histogram) Observe(v float64) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for i, b := range h.buckets {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// Name return histogram name
+func (h *Histogram) Name() string {
+	return h.name
+}
+
+// Write writes histogram with cumulative buckets
+func (h *Histogram) Write(w io.Writer) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	writeHeader(w, h.name, h.help, "histogram")
+	for i, b := range h.buckets {
+		fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", h.name, formatFloat(b), h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", h.name, h.count)
+	fmt.Fprintf(w, "%s_sum %s\n", h.name, formatFloat(h.sum))
+	fmt.Fprintf(w, "%s_count %d\n", h.name, h.count)
+}
+
+func writeHeader(w io.Writer, name, help, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMetrics(t *testing.T) {
+	Convey("Metrics", t, func() {
+		r := NewRegistry()
+		c := r.NewCounter("test_total", "test counter")
+		c.Inc()
+		c.Inc()
+		g := r.NewGauge("test_gauge", "test gauge")
+		g.Set(1.5)
+		h := r.NewHistogram("test_seconds", "test histogram", []float64{0.5, 1})
+		h.Observe(0.2)
+		h.Observe(0.8)
+		h.Observe(3)
+
+		var buf bytes.Buffer
+		r.WriteAll(&buf)
+		So(buf.String(), ShouldContainSubstring, "# TYPE test_total counter\ntest_total 2\n")
+		So(buf.String(), ShouldContainSubstring, "test_gauge 1.5\n")
+		So(buf.String(), ShouldContainSubstring, "test_seconds_bucket{le=\"0.5\"} 1\n")
+		So(buf.String(), ShouldContainSubstring, "test_seconds_bucket{le=\"1\"} 2\n")
+		So(buf.String(), ShouldContainSubstring, "test_seconds_bucket{le=\"+Inf\"} 3\n")
+		So(buf.String(), ShouldContainSubstring, "test_seconds_sum 4\n")
+		So(buf.String(), ShouldContainSubstring, "test_seconds_count 3\n")
+
+		So(func() { r.NewCounter("test_total", "") }, ShouldPanic)
+
+		w := httptest.NewRecorder()
+		r.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/-/metrics", nil))
+		So(w.Code, ShouldEqual, 200)
+		So(w.Body.String(), ShouldEqual, buf.String())
+
+		// metrics of building are in default registry
+		buf.Reset()
+		WriteAll(&buf)
+		So(buf.String(), ShouldNotContainSubstring, "test_total")
+	})
+}
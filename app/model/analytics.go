@@ -1,8 +1,95 @@
 package model
 
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
 // Analytics save unique values for web analytics service
 type Analytics struct {
 	Google string `toml:"google" ini:"google"`
 	Baidu  string `toml:"baidu" ini:"baidu"`
 	Cnzz   string `toml:"cnzz" ini:"cnzz"`
+
+	// Views is csv file or http url of exported page views,
+	// such as Plausible or Google Analytics top pages export
+	Views string `toml:"views" ini:"views"`
+	// ViewsTTL is minutes to keep downloaded views data in cache
+	ViewsTTL int `toml:"views_ttl" ini:"views_ttl"`
+}
+
+var (
+	errViewsColumnMissing = errors.New("views csv need page and views columns")
+
+	viewsPageColumns  = []string{"page", "name", "page path", "pagepath", "path", "url"}
+	viewsCountColumns = []string{"pageviews", "page views", "views", "visitors", "users"}
+)
+
+// PostViews is page views count by url
+type PostViews map[string]int64
+
+// NewPostViews parse csv data to PostViews.
+// It finds page and views columns by header row
+func NewPostViews(r io.Reader) (PostViews, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errViewsColumnMissing
+	}
+	pageIdx := findColumn(records[0], viewsPageColumns)
+	countIdx := findColumn(records[0], viewsCountColumns)
+	if pageIdx < 0 || countIdx < 0 {
+		return nil, errViewsColumnMissing
+	}
+	views := make(PostViews)
+	for _, record := range records[1:] {
+		if len(record) <= pageIdx || len(record) <= countIdx {
+			continue
+		}
+		count, err := strconv.ParseInt(strings.Replace(strings.TrimSpace(record[countIdx]), ",", "", -1), 10, 64)
+		if err != nil {
+			continue
+		}
+		views[viewsKey(record[pageIdx])] += count
+	}
+	return views, nil
+}
+
+// Get get views count of url
+func (pv PostViews) Get(url string) int64 {
+	return pv[viewsKey(url)]
+}
+
+// find column index by preferred names in order
+func findColumn(header []string, names []string) int {
+	for _, name := range names {
+		for i, h := range header {
+			if strings.ToLower(strings.TrimSpace(h)) == name {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// viewsKey make /a/b.html, /a/b/ and /a/b as same key
+func viewsKey(url string) string {
+	url = strings.TrimSpace(url)
+	if i := strings.IndexAny(url, "?#"); i >= 0 {
+		url = url[:i]
+	}
+	url = strings.TrimSuffix(url, "/")
+	url = strings.TrimSuffix(url, ".html")
+	url = strings.TrimSuffix(url, "/index")
+	if !strings.HasPrefix(url, "/") {
+		url = "/" + url
+	}
+	return url
 }
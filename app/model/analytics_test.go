@@ -0,0 +1,46 @@
+package model
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPostViews(t *testing.T) {
+	Convey("PostViews", t, func() {
+		Convey("Plausible", func() {
+			data := "name,visitors,pageviews,bounce_rate\n" +
+				"/2016/3/25/welcome.html,10,\"1,200\",50\n" +
+				"/about/,3,5,20\n" +
+				"/2016/3/25/welcome?ref=abc,1,3,0\n"
+			views, err := NewPostViews(strings.NewReader(data))
+			So(err, ShouldBeNil)
+			So(views.Get("/2016/3/25/welcome.html"), ShouldEqual, 1203)
+			So(views.Get("/about.html"), ShouldEqual, 5)
+			So(views.Get("/unknown.html"), ShouldEqual, 0)
+		})
+
+		Convey("GoogleAnalytics", func() {
+			data := "Page,Pageviews,Unique Pageviews\n/index.html,30,20\n"
+			views, err := NewPostViews(strings.NewReader(data))
+			So(err, ShouldBeNil)
+			So(views.Get("/"), ShouldEqual, 30)
+		})
+
+		Convey("WrongColumns", func() {
+			_, err := NewPostViews(strings.NewReader("a,b\n1,2\n"))
+			So(err, ShouldEqual, errViewsColumnMissing)
+		})
+	})
+
+	Convey("PopularPosts", t, func() {
+		ps := Posts(preparePosts())
+		ps[1].Views = 20
+		ps[2].Views = 100
+		popular := ps.Popular()
+		So(popular, ShouldHaveLength, 2)
+		So(popular[0].Title, ShouldEqual, "123")
+		So(popular[1].Title, ShouldEqual, "xyz")
+	})
+}
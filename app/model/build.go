@@ -1,5 +1,7 @@
 package model
 
+import "fmt"
+
 // Build is settings for builder in meta file
 type Build struct {
 	DisablePost bool `toml:"disable_post" ini:"disable_post"`
@@ -9,5 +11,140 @@ type Build struct {
 	PageDir      string `toml:"page_dir" ini:"page_dir"`
 	LangDir      string `toml:"lang_dir" ini:"lang_dir"`
 	MediaDir     string `toml:"media_dir" ini:"media_dir"`
+	CacheDir     string `toml:"cache_dir" ini:"cache_dir"`
 	PostPageSize int    `toml:"post_pagesize" ini:"post_pagesize"`
+
+	// Dest and Theme are destination and theme directories relative to source directory,
+	// flags of command override them
+	Dest  string `toml:"dest" ini:"dest"`
+	Theme string `toml:"theme" ini:"theme"`
+
+	// CacheRemote is url to share cache directory by 'pugo cache push' and 'pugo cache pull',
+	// "s3://bucket/key" or "https://host/path"
+	CacheRemote string `toml:"cache_remote" ini:"cache_remote"`
+
+	// Env is names of environment variables, also read from .env files,
+	// that are interpolated as ${NAME} in meta file and exposed to templates
+	Env []string `toml:"env" ini:"env"`
+
+	// MarkdownCache saves rendered markdown html in cache directory
+	MarkdownCache bool `toml:"markdown_cache" ini:"markdown_cache"`
+
+	// Encoding is encoding of content files, such as windows-1252.
+	// If empty, it's detected by BOM or treated as UTF-8
+	Encoding string `toml:"encoding" ini:"encoding"`
+
+	// GoneRules is formats of rules to serve 410 Gone for removed urls and 301 for redirects, "nginx" or "apache"
+	GoneRules []string `toml:"gone_rules" ini:"gone_rules"`
+
+	// API writes paginated json of posts and tags in /api/v1/
+	API bool `toml:"api" ini:"api"`
+	// APIPageSize is posts in each json page, it's post_pagesize by default
+	APIPageSize int `toml:"api_pagesize" ini:"api_pagesize"`
+
+	// ContentReport writes readability and SEO analysis of posts in cache directory
+	ContentReport bool `toml:"content_report" ini:"content_report"`
+
+	// KeepBuilds is number of build outputs kept in cache directory for rollback
+	KeepBuilds int `toml:"keep_builds" ini:"keep_builds"`
+
+	// Static is rules of static files, the first matched rule is used
+	Static []*StaticRule `toml:"static" ini:"-"`
+	// AssetState is file to keep old fingerprinted files, relative to source directory.
+	// It's in cache directory by default, set it to commit the file if cache is not kept in CI
+	AssetState string `toml:"asset_state" ini:"asset_state"`
+	// StaleDays is days to list old fingerprinted files as stale, 90 by default
+	StaleDays int `toml:"stale_days" ini:"stale_days"`
+
+	// Fonts are web fonts subset to characters used in site
+	Fonts []*FontSubset `toml:"font" ini:"-"`
+
+	// Index is rules to exclude posts and pages from sitemap, feed and json api,
+	// the first matched rule is used
+	Index []*IndexRule `toml:"index" ini:"-"`
+}
+
+// formats of gone rules
+const (
+	GoneNginx  = "nginx"
+	GoneApache = "apache"
+)
+
+// actions of static files
+const (
+	StaticCopy        = "copy"
+	StaticMinify      = "minify"
+	StaticFingerprint = "fingerprint"
+	StaticExclude     = "exclude"
+)
+
+// StaticRule sets action of static files matched by glob.
+// Glob is relative path in destination, such as "css/**" or "**/*.psd"
+type StaticRule struct {
+	Glob   string `toml:"glob"`
+	Action string `toml:"action"`
+}
+
+// indexes that index rules exclude contents from
+const (
+	IndexSitemap = "sitemap"
+	IndexFeed    = "feed"
+	IndexAPI     = "api"
+)
+
+// IndexRule excludes posts and pages matched by glob from indexes.
+// Glob is url path relative to site root, such as "landing/**".
+// Exclude is indexes to exclude from, all indexes by default.
+// Robots is content of robots meta tag in matched pages, such as "noindex, nofollow"
+type IndexRule struct {
+	Glob    string   `toml:"glob"`
+	Exclude []string `toml:"exclude"`
+	Robots  string   `toml:"robots"`
+}
+
+// FontSubset sets web font to subset after building.
+// File is relative path in destination, such as "fonts/noto-sans-sc.woff",
+// Chars are characters always kept besides characters in html pages
+type FontSubset struct {
+	File  string `toml:"file"`
+	Chars string `toml:"chars"`
+}
+
+func (b *Build) normalize() error {
+	for _, r := range b.GoneRules {
+		if r != GoneNginx && r != GoneApache {
+			return fmt.Errorf("gone rules format '%s' is unknown", r)
+		}
+	}
+	for _, r := range b.Static {
+		if r.Glob == "" {
+			return fmt.Errorf("static rule need glob")
+		}
+		switch r.Action {
+		case StaticCopy, StaticMinify, StaticFingerprint, StaticExclude:
+		case "":
+			r.Action = StaticCopy
+		default:
+			return fmt.Errorf("static rule '%s' has unknown action '%s'", r.Glob, r.Action)
+		}
+	}
+	for _, r := range b.Index {
+		if r.Glob == "" {
+			return fmt.Errorf("index rule need glob")
+		}
+		if len(r.Exclude) == 0 {
+			r.Exclude = []string{IndexSitemap, IndexFeed, IndexAPI}
+		}
+		for _, e := range r.Exclude {
+			if e != IndexSitemap && e != IndexFeed && e != IndexAPI {
+				return fmt.Errorf("index rule '%s' excludes unknown index '%s'", r.Glob, e)
+			}
+		}
+	}
+	for _, f := range b.Fonts {
+		if f.File == "" {
+			return fmt.Errorf("font need file")
+		}
+	}
+	return nil
 }
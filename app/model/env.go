@@ -0,0 +1,85 @@
+package model
+
+import (
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/ini.v1"
+)
+
+var (
+	envRefRegex     = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+	envTOMLReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+// InterpolateMeta replaces ${NAME} in meta data by environment variables
+// that are listed in env of build settings, others are kept as they are.
+// Variables are read from process environment, then fileEnv, such as variables in .env files.
+// It return replaced data and values of listed variables, unset variables are empty
+func InterpolateMeta(data []byte, format FormatType, fileEnv map[string]string) ([]byte, map[string]string) {
+	names := metaEnvNames(data, format)
+	if len(names) == 0 {
+		return data, nil
+	}
+	env := make(map[string]string, len(names))
+	for _, name := range names {
+		env[name] = getEnv(name, fileEnv)
+	}
+	data = envRefRegex.ReplaceAllFunc(data, func(ref []byte) []byte {
+		value, ok := env[string(ref[2:len(ref)-1])]
+		if !ok {
+			return ref
+		}
+		if format == FormatTOML {
+			value = envTOMLReplacer.Replace(value)
+		}
+		return []byte(value)
+	})
+	return data, env
+}
+
+// metaEnvNames reads env of build settings only,
+// so it works before values are interpolated
+func metaEnvNames(data []byte, format FormatType) []string {
+	switch format {
+	case FormatTOML:
+		var meta struct {
+			Build struct {
+				Env []string `toml:"env"`
+			} `toml:"build"`
+		}
+		if _, err := toml.Decode(string(data), &meta); err == nil {
+			return meta.Build.Env
+		}
+	case FormatINI:
+		if iniObj, err := ini.Load(data); err == nil {
+			return iniObj.Section("build").Key("env").Strings(",")
+		}
+	}
+	return nil
+}
+
+// getEnv return variable in process environment, or in fileEnv if it's not set
+func getEnv(name string, fileEnv map[string]string) string {
+	if value, ok := os.LookupEnv(name); ok {
+		return value
+	}
+	return fileEnv[name]
+}
+
+// expandSecrets replaces ${NAME} in secrets of mail and syndication,
+// they can read all variables of process environment and fileEnv
+func (ma *MetaAll) expandSecrets(fileEnv map[string]string) {
+	mapping := func(name string) string {
+		return getEnv(name, fileEnv)
+	}
+	if ma.Mail != nil {
+		ma.Mail.Password = os.Expand(ma.Mail.Password, mapping)
+		ma.Mail.Secret = os.Expand(ma.Mail.Secret, mapping)
+	}
+	for _, s := range ma.Syndication {
+		s.Token = os.Expand(s.Token, mapping)
+	}
+}
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestInterpolateMeta(t *testing.T) {
+	Convey("InterpolateMeta", t, func() {
+		os.Setenv("PUGO_TEST_GA", `UA-"1"`)
+		defer os.Unsetenv("PUGO_TEST_GA")
+
+		data, env := InterpolateMeta([]byte("[analytics]\ngoogle = \"${PUGO_TEST_GA}\"\n[mail]\npassword = \"${SMTP_PASSWORD}\"\n[build]\nenv = [\"PUGO_TEST_GA\", \"PUGO_TEST_NONE\"]\n"), FormatTOML, nil)
+		So(string(data), ShouldContainSubstring, `google = "UA-\"1\""`)
+		So(string(data), ShouldContainSubstring, `password = "${SMTP_PASSWORD}"`)
+		So(env, ShouldResemble, map[string]string{"PUGO_TEST_GA": `UA-"1"`, "PUGO_TEST_NONE": ""})
+
+		data, env = InterpolateMeta([]byte("[analytics]\ngoogle = ${PUGO_TEST_GA}\n[build]\nenv = PUGO_TEST_GA\n"), FormatINI, nil)
+		So(string(data), ShouldContainSubstring, `google = UA-"1"`)
+		So(env["PUGO_TEST_GA"], ShouldEqual, `UA-"1"`)
+
+		data, env = InterpolateMeta([]byte("[analytics]\ngoogle = \"${PUGO_TEST_GA}\"\n"), FormatTOML, nil)
+		So(string(data), ShouldContainSubstring, "${PUGO_TEST_GA}")
+		So(env, ShouldBeNil)
+
+		fileEnv := map[string]string{"PUGO_TEST_GA": "file", "PUGO_TEST_ID": "id", "SMTP_PASSWORD": "secret"}
+		data, env = InterpolateMeta([]byte("[build]\nenv = [\"PUGO_TEST_GA\", \"PUGO_TEST_ID\"]\n"), FormatTOML, fileEnv)
+		So(env, ShouldResemble, map[string]string{"PUGO_TEST_GA": `UA-"1"`, "PUGO_TEST_ID": "id"})
+
+		meta, err := NewMetaAllEnv([]byte("[meta]\ntitle = \"t\"\nroot = \"https://example.com/\"\n[[author]]\nname = \"a\"\n[mail]\npassword = \"${SMTP_PASSWORD}\"\n"), FormatTOML, fileEnv)
+		So(err, ShouldBeNil)
+		So(meta.Mail.Password, ShouldEqual, "secret")
+		_, ok := os.LookupEnv("SMTP_PASSWORD")
+		So(ok, ShouldBeFalse)
+	})
+}
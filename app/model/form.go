@@ -0,0 +1,163 @@
+package model
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// providers of forms
+const (
+	FormFormspree   = "formspree"
+	FormNetlify     = "netlify"
+	FormStaticForms = "staticforms"
+	FormServer      = "server"
+)
+
+const (
+	// FormEndpoint is url path prefix to submit forms in serve mode, followed by form name
+	FormEndpoint = "/-/form/"
+	// FormHoneypot is hidden field name, submissions with its value are spam
+	FormHoneypot = "_gotcha"
+	// FormTokenField is hidden field name of form token in serve mode
+	FormTokenField = "_token"
+	// FormTokenTTL is expiry of form token, page gets new token from server when it's filled
+	FormTokenTTL = 2 * time.Hour
+)
+
+type (
+	// Form is a contact form, it's handled by third-party provider or 'pugo server'
+	Form struct {
+		Name     string `toml:"name" ini:"name"`
+		Provider string `toml:"provider" ini:"provider"`
+		// Key is form id of Formspree or access key of StaticForms
+		Key string `toml:"key" ini:"key"`
+		// To is email to receive submissions in serve mode
+		To      string `toml:"to" ini:"to"`
+		Subject string `toml:"subject" ini:"subject"`
+		// Success is url to redirect after submitting
+		Success string       `toml:"success" ini:"success"`
+		Fields  []*FormField `toml:"field" ini:"-"`
+	}
+	// FormField is input field of form
+	FormField struct {
+		Name     string `toml:"name"`
+		Type     string `toml:"type"`
+		Label    string `toml:"label"`
+		Required bool   `toml:"required"`
+	}
+	// FormGroup is group of forms
+	FormGroup []*Form
+	// Mail is SMTP settings to send form submissions in serve mode
+	Mail struct {
+		Host     string `toml:"host" ini:"host"`
+		Port     int    `toml:"port" ini:"port"`
+		User     string `toml:"user" ini:"user"`
+		Password string `toml:"password" ini:"password"`
+		From     string `toml:"from" ini:"from"`
+		// Secret signs form tokens
+		Secret string `toml:"secret" ini:"secret"`
+	}
+)
+
+// defaultFormFields is used when form has no fields
+func defaultFormFields() []*FormField {
+	return []*FormField{
+		{Name: "name", Type: "text", Label: "Name", Required: true},
+		{Name: "email", Type: "email", Label: "Email", Required: true},
+		{Name: "message", Type: "textarea", Label: "Message", Required: true},
+	}
+}
+
+// Get return form by name
+func (fg FormGroup) Get(name string) *Form {
+	for _, f := range fg {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func (fg FormGroup) normalize(mail *Mail) error {
+	names := make(map[string]bool)
+	for _, f := range fg {
+		if f.Name == "" {
+			return fmt.Errorf("form need name")
+		}
+		if names[f.Name] {
+			return fmt.Errorf("form '%s' is duplicated", f.Name)
+		}
+		names[f.Name] = true
+		switch f.Provider {
+		case FormFormspree, FormStaticForms:
+			if f.Key == "" {
+				return fmt.Errorf("form '%s' need key of %s", f.Name, f.Provider)
+			}
+		case FormNetlify:
+		case FormServer:
+			if f.To == "" {
+				return fmt.Errorf("form '%s' need email to receive submissions", f.Name)
+			}
+			if mail == nil || mail.Host == "" || mail.From == "" || mail.Secret == "" {
+				return fmt.Errorf("form '%s' need mail host, from and secret", f.Name)
+			}
+		default:
+			return fmt.Errorf("form '%s' has unknown provider '%s'", f.Name, f.Provider)
+		}
+		if len(f.Fields) == 0 {
+			f.Fields = defaultFormFields()
+		}
+		for _, field := range f.Fields {
+			if field.Name == "" || field.Name == FormHoneypot || field.Name == FormTokenField {
+				return fmt.Errorf("form '%s' has invalid field name '%s'", f.Name, field.Name)
+			}
+			if field.Type == "" {
+				field.Type = "text"
+			}
+			if field.Label == "" {
+				field.Label = field.Name
+			}
+		}
+	}
+	return nil
+}
+
+func (m *Mail) normalize() {
+	if m.Port == 0 {
+		m.Port = 587
+	}
+}
+
+// Token return token of form issued at time t and signed by secret,
+// it expires after FormTokenTTL
+func (f *Form) Token(secret string, t time.Time) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return ts + "." + f.sign(secret, ts)
+}
+
+func (f *Form) sign(secret, ts string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(f.Name + "|" + ts))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// IsToken return whether token is signed by secret and not expired at time now
+func (f *Form) IsToken(token, secret string, now time.Time) bool {
+	i := strings.Index(token, ".")
+	if i < 0 {
+		return false
+	}
+	unix, err := strconv.ParseInt(token[:i], 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age < -time.Minute || age > FormTokenTTL {
+		return false
+	}
+	return hmac.Equal([]byte(token[i+1:]), []byte(f.sign(secret, token[:i])))
+}
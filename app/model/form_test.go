@@ -0,0 +1,55 @@
+package model
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestForms(t *testing.T) {
+	Convey("Normalize", t, func() {
+		forms := FormGroup{{Name: "contact", Provider: FormNetlify}}
+		So(forms.normalize(nil), ShouldBeNil)
+		So(forms[0].Fields, ShouldHaveLength, 3)
+		So(forms.Get("contact"), ShouldEqual, forms[0])
+		So(forms.Get("unknown"), ShouldBeNil)
+
+		So(FormGroup{{Name: "a", Provider: FormFormspree}}.normalize(nil), ShouldNotBeNil)
+		So(FormGroup{{Name: "a", Provider: "unknown"}}.normalize(nil), ShouldNotBeNil)
+		So(FormGroup{{Name: "a", Provider: FormNetlify}, {Name: "a", Provider: FormNetlify}}.normalize(nil), ShouldNotBeNil)
+		So(FormGroup{{Name: "a", Provider: FormServer, To: "me@example.com"}}.normalize(nil), ShouldNotBeNil)
+
+		forms = FormGroup{{Name: "a", Provider: FormServer, To: "me@example.com", Fields: []*FormField{{Name: "body"}}}}
+		So(forms.normalize(&Mail{Host: "smtp.example.com", From: "pugo@example.com", Secret: "s"}), ShouldBeNil)
+		So(forms[0].Fields[0].Type, ShouldEqual, "text")
+		So(forms[0].Fields[0].Label, ShouldEqual, "body")
+	})
+
+	Convey("Mail", t, func() {
+		os.Setenv("PUGO_TEST_SMTP", "password")
+		defer os.Unsetenv("PUGO_TEST_SMTP")
+		ma := &MetaAll{Mail: &Mail{Password: "${PUGO_TEST_SMTP}", Secret: "${PUGO_TEST_SECRET}"}}
+		ma.expandSecrets(map[string]string{"PUGO_TEST_SMTP": "file", "PUGO_TEST_SECRET": "secret"})
+		m := ma.Mail
+		m.normalize()
+		So(m.Password, ShouldEqual, "password")
+		So(m.Secret, ShouldEqual, "secret")
+		So(m.Port, ShouldEqual, 587)
+	})
+
+	Convey("Token", t, func() {
+		f := &Form{Name: "contact"}
+		now := time.Now()
+		token := f.Token("secret", now)
+		So(f.IsToken(token, "secret", now), ShouldBeTrue)
+		So(f.IsToken(token, "secret", now.Add(FormTokenTTL/2)), ShouldBeTrue)
+		So(f.IsToken(token, "secret", now.Add(FormTokenTTL+time.Second)), ShouldBeFalse)
+		So(f.IsToken(token, "secret", now.Add(-time.Hour)), ShouldBeFalse)
+		So(f.IsToken(token, "other", now), ShouldBeFalse)
+		So((&Form{Name: "other"}).IsToken(token, "secret", now), ShouldBeFalse)
+		So(f.IsToken("1"+token, "secret", now), ShouldBeFalse)
+		So(f.IsToken("abc", "secret", now), ShouldBeFalse)
+	})
+}
@@ -1,14 +1,579 @@
 package model
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/BurntSushi/toml"
+	"github.com/go-xiaohei/pugo/app/helper"
 	"gopkg.in/ini.v1"
 )
 
+var (
+	errFrontMatterBlock   = errors.New("need front-matter block and markdown block")
+	errFrontMatterUnknown = errors.New("front-matter block is unrecognized")
+	errFrontMatterNotTOML = errors.New("front-matter block is not toml")
+
+	tomlErrorLine = regexp.MustCompile(`^Near line (\d+)`)
+)
+
+// FrontMatterError is error of parsing front-matter block,
+// Line is line number in the file
+type FrontMatterError struct {
+	File string
+	Line int
+	Err  error
+}
+
+// Error implements error
+func (fe *FrontMatterError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", fe.File, fe.Line, fe.Err.Error())
+}
+
+// frontMatter is front-matter block and markdown block in content file
+type frontMatter struct {
+	File   string
+	Format FormatType
+	Meta   []byte
+	Body   []byte
+	// Line is line number of block beginning in the file,
+	// Meta starts with the rest of this line
+	Line int
+	// Begin and End are offsets of the whole block in data
+	Begin, End int
+}
+
+// parseFrontMatter splits content data to front-matter block and markdown block
+func parseFrontMatter(file string, data []byte) (*frontMatter, error) {
+	dataSlice := bytes.SplitN(data, postBlockSeparator, 3)
+	if len(dataSlice) != 3 {
+		return nil, &FrontMatterError{File: file, Line: 1, Err: errFrontMatterBlock}
+	}
+	blockLine := bytes.Count(dataSlice[0], []byte("\n")) + 1
+	idx := getFirstBreakByte(dataSlice[1])
+	if idx == 0 {
+		return nil, &FrontMatterError{File: file, Line: blockLine, Err: errFrontMatterBlock}
+	}
+	formatType := detectFormat(string(dataSlice[1][:idx]))
+	if formatType == 0 {
+		return nil, &FrontMatterError{File: file, Line: blockLine, Err: errFrontMatterUnknown}
+	}
+	begin := len(dataSlice[0])
+	return &frontMatter{
+		File:   file,
+		Format: formatType,
+		Meta:   dataSlice[1][idx:],
+		Body:   dataSlice[2],
+		Line:   blockLine,
+		Begin:  begin,
+		End:    begin + len(dataSlice[1]) + 2*len(postBlockSeparator),
+	}, nil
+}
+
+// DecodeTOML decodes toml meta data to v
+func (fm *frontMatter) DecodeTOML(v interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fm.error(fmt.Errorf("%v", r))
+		}
+	}()
+	if err = checkTOMLStrings(fm.Meta); err != nil {
+		return fm.error(err)
+	}
+	if err = toml.Unmarshal(fm.Meta, v); err != nil {
+		return fm.error(err)
+	}
+	return nil
+}
+
+// DecodeINI decodes ini meta data to ini object
+func (fm *frontMatter) DecodeINI() (iniObj *ini.File, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			iniObj, err = nil, fm.error(fmt.Errorf("%v", r))
+		}
+	}()
+	if iniObj, err = ini.Load(fm.Meta); err != nil {
+		return nil, fm.error(err)
+	}
+	return iniObj, nil
+}
+
+// checkTOMLStrings checks unclosed multi-line strings,
+// toml parser never stops when reading them
+func checkTOMLStrings(data []byte) error {
+	var (
+		quote []byte
+		line  int
+	)
+	for i := 0; i < len(data); i++ {
+		if quote == nil {
+			if bytes.HasPrefix(data[i:], []byte(`"""`)) || bytes.HasPrefix(data[i:], []byte(`'''`)) {
+				quote = data[i : i+3]
+				line = bytes.Count(data[:i], []byte("\n")) + 1
+				i += 2
+			}
+			continue
+		}
+		if data[i] == '\\' && quote[0] == '"' {
+			i++
+			continue
+		}
+		if bytes.HasPrefix(data[i:], quote) {
+			quote = nil
+			i += 2
+		}
+	}
+	if quote != nil {
+		return fmt.Errorf("Near line %d: unclosed multi-line string", line)
+	}
+	return nil
+}
+
+// error wraps err with line number in file
+// replace return data with front-matter block replaced by meta in format,
+// lines of meta end with line ending of the block, so CRLF files keep CRLF
+func (fm *frontMatter) replace(data []byte, format string, meta []byte) []byte {
+	eol := []byte("\n")
+	if bytes.Contains(data[fm.Begin:fm.End], []byte("\r\n")) {
+		eol = []byte("\r\n")
+		meta = bytes.Replace(bytes.Replace(meta, []byte("\r\n"), []byte("\n"), -1), []byte("\n"), eol, -1)
+	}
+	var buf bytes.Buffer
+	buf.Write(data[:fm.Begin])
+	buf.Write(postBlockSeparator)
+	buf.WriteString(format)
+	buf.Write(eol)
+	buf.Write(meta)
+	buf.Write(postBlockSeparator)
+	buf.Write(data[fm.End:])
+	return buf.Bytes()
+}
+
+func (fm *frontMatter) error(err error) error {
+	return &FrontMatterError{
+		File: fm.File,
+		Line: fm.Line + errorLine(fm.Meta, err) - 1,
+		Err:  err,
+	}
+}
+
+// errorLine finds line number of err in data,
+// it returns 1 if not found
+func errorLine(data []byte, err error) int {
+	msg := strings.TrimSpace(err.Error())
+	if m := tomlErrorLine.FindStringSubmatch(msg); len(m) == 2 {
+		if line, _ := strconv.Atoi(m[1]); line > 0 {
+			return line
+		}
+	}
+	// ini errors end with the wrong line
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 && strings.HasSuffix(msg, string(line)) {
+			return i + 1
+		}
+	}
+	return 1
+}
+
+// FormatFrontMatter rewrites toml front-matter block in content data.
+// Keys are ordered as fields of v, such as *Post or *Page, other keys are sorted after them.
+// Lines of keys and comments above them are kept, tables are kept at the end.
+// fill can set default values of keys. Bytes out of front-matter block are kept as they are.
+func FormatFrontMatter(file string, data []byte, v interface{}, fill func(map[string]interface{})) ([]byte, error) {
+	fm, err := parseFrontMatter(file, data)
+	if err != nil {
+		return nil, err
+	}
+	if fm.Format != FormatTOML {
+		return nil, errFrontMatterNotTOML
+	}
+	meta := make(map[string]interface{})
+	if err = fm.DecodeTOML(&meta); err != nil {
+		return nil, err
+	}
+	entries, tables := splitTOMLEntries(bytes.TrimLeft(fm.Meta, "\r\n"))
+	if fill != nil {
+		filled := make(map[string]interface{})
+		for k, v := range meta {
+			filled[k] = v
+		}
+		fill(filled)
+		for k, v := range filled {
+			if _, ok := meta[k]; ok {
+				continue
+			}
+			var buf bytes.Buffer
+			if err = toml.NewEncoder(&buf).Encode(map[string]interface{}{k: v}); err != nil {
+				return nil, fm.error(err)
+			}
+			entries[k] = buf.Bytes()
+		}
+	}
+
+	keys := make(map[string]interface{}, len(entries))
+	for k := range entries {
+		keys[k] = nil
+	}
+	var out bytes.Buffer
+	for _, k := range frontMatterKeys(v, keys) {
+		out.Write(entries[k])
+	}
+	out.Write(tables)
+	return fm.replace(data, "toml", out.Bytes()), nil
+}
+
+// ReplaceFrontMatter replaces values of top-level keys in toml front-matter block,
+// keys not in front-matter are appended. Other lines are kept as they are.
+func ReplaceFrontMatter(file string, data []byte, values map[string]interface{}) ([]byte, error) {
+	fm, err := parseFrontMatter(file, data)
+	if err != nil {
+		return nil, err
+	}
+	if fm.Format != FormatTOML {
+		return nil, errFrontMatterNotTOML
+	}
+	encode := func(k string) ([]byte, error) {
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(map[string]interface{}{k: values[k]}); err != nil {
+			return nil, fm.error(err)
+		}
+		return buf.Bytes(), nil
+	}
+
+	var (
+		out      bytes.Buffer
+		lines    = bytes.SplitAfter(bytes.TrimLeft(fm.Meta, "\r\n"), []byte("\n"))
+		replaced = make(map[string]bool)
+		key      string
+		value    []byte
+		inTable  bool
+	)
+	for _, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		// rest lines of multi-line value, they are skipped if the value is replaced
+		if key != "" {
+			if _, ok := values[key]; !ok {
+				out.Write(ensureLineEnd(line, line))
+			}
+			if value = append(value, line...); tomlValueComplete(value) {
+				key = ""
+			}
+			continue
+		}
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) > 0 && trimmed[0] == '[' && !inTable {
+			inTable = true
+			// new keys are top-level, write them before tables
+			for _, k := range sortedKeys(values, replaced) {
+				b, err := encode(k)
+				if err != nil {
+					return nil, err
+				}
+				out.Write(b)
+				replaced[k] = true
+			}
+		}
+		idx := bytes.IndexByte(line, '=')
+		if inTable || idx < 0 || trimmed[0] == '#' {
+			out.Write(ensureLineEnd(line, line))
+			continue
+		}
+		k := strings.Trim(strings.TrimSpace(string(line[:idx])), `"'`)
+		if value = append([]byte(nil), line[idx+1:]...); !tomlValueComplete(value) {
+			key = k
+		}
+		if _, ok := values[k]; !ok {
+			out.Write(ensureLineEnd(line, line))
+			continue
+		}
+		b, err := encode(k)
+		if err != nil {
+			return nil, err
+		}
+		out.Write(b)
+		replaced[k] = true
+	}
+	for _, k := range sortedKeys(values, replaced) {
+		b, err := encode(k)
+		if err != nil {
+			return nil, err
+		}
+		out.Write(b)
+	}
+
+	return fm.replace(data, "toml", out.Bytes()), nil
+}
+
+// EditFrontMatter replaces values of top-level keys in front-matter block and keeps its format,
+// toml block is edited by ReplaceFrontMatter, ini block by ReplaceINIFrontMatter
+func EditFrontMatter(file string, data []byte, values map[string]interface{}) ([]byte, error) {
+	fm, err := parseFrontMatter(file, data)
+	if err != nil {
+		return nil, err
+	}
+	if fm.Format == FormatINI {
+		return ReplaceINIFrontMatter(file, data, values)
+	}
+	return ReplaceFrontMatter(file, data, values)
+}
+
+// ReplaceINIFrontMatter replaces values of keys in default section of ini front-matter block,
+// keys not in the section are appended to it. Other lines are kept as they are.
+func ReplaceINIFrontMatter(file string, data []byte, values map[string]interface{}) ([]byte, error) {
+	fm, err := parseFrontMatter(file, data)
+	if err != nil {
+		return nil, err
+	}
+	if fm.Format != FormatINI {
+		return nil, fm.error(errors.New("front-matter block is not ini"))
+	}
+	var (
+		out       bytes.Buffer
+		lines     = bytes.SplitAfter(bytes.TrimLeft(fm.Meta, "\r\n"), []byte("\n"))
+		replaced  = make(map[string]bool)
+		inSection bool
+		quote     string
+		skip      bool
+	)
+	appendKeys := func() {
+		for _, k := range sortedKeys(values, replaced) {
+			out.WriteString(k + " = " + iniValue(values[k]) + "\n")
+			replaced[k] = true
+		}
+	}
+	for _, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		// rest lines of multi-line value, they are skipped if the value is replaced
+		if quote != "" {
+			if !skip {
+				out.Write(ensureLineEnd(line, line))
+			}
+			if bytes.Contains(line, []byte(quote)) {
+				quote = ""
+			}
+			continue
+		}
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) > 0 && trimmed[0] == '[' && !inSection {
+			inSection = true
+			appendKeys()
+		}
+		idx := bytes.IndexByte(line, '=')
+		if inSection || idx < 0 || trimmed[0] == '#' || trimmed[0] == ';' {
+			out.Write(ensureLineEnd(line, line))
+			continue
+		}
+		k := strings.Trim(strings.TrimSpace(string(line[:idx])), "`\"")
+		_, skip = values[k]
+		quote = iniValueQuote(line[idx+1:])
+		if !skip {
+			out.Write(ensureLineEnd(line, line))
+			continue
+		}
+		out.WriteString(k + " = " + iniValue(values[k]) + "\n")
+		replaced[k] = true
+	}
+	appendKeys()
+
+	return fm.replace(data, "ini", out.Bytes()), nil
+}
+
+// iniValue return quoted ini value of v, lists are joined by comma as tags
+func iniValue(v interface{}) string {
+	var str string
+	switch val := v.(type) {
+	case string:
+		str = val
+	case []string:
+		str = strings.Join(val, ",")
+	default:
+		return fmt.Sprint(val)
+	}
+	// comment chars are cut from "quoted" values, so they are quoted by backticks
+	if !strings.ContainsAny(str, "\"#;\n") {
+		return `"` + str + `"`
+	}
+	if !strings.Contains(str, "`") {
+		return "`" + str + "`"
+	}
+	return `"""` + str + `"""`
+}
+
+// iniValueQuote return closing quote of multi-line ini value, or empty string if value is in one line
+func iniValueQuote(value []byte) string {
+	value = bytes.TrimSpace(value)
+	for _, q := range []string{`"""`, "`"} {
+		if bytes.HasPrefix(value, []byte(q)) && !bytes.Contains(value[len(q):], []byte(q)) {
+			return q
+		}
+	}
+	return ""
+}
+
+// ReplaceBody replaces markdown block after front-matter block in content data
+func ReplaceBody(file string, data, body []byte) ([]byte, error) {
+	fm, err := parseFrontMatter(file, data)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.Write(data[:fm.End])
+	buf.WriteString("\n\n")
+	buf.Write(bytes.Trim(body, "\n"))
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+// sortedKeys return sorted keys of values that are not in except
+func sortedKeys(values map[string]interface{}, except map[string]bool) []string {
+	var keys []string
+	for k := range values {
+		if !except[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// splitTOMLEntries splits toml data to lines of top-level keys and lines of tables.
+// Comments and blank lines belong to the key below them.
+func splitTOMLEntries(data []byte) (map[string][]byte, []byte) {
+	var (
+		entries = make(map[string][]byte)
+		lines   = bytes.SplitAfter(data, []byte("\n"))
+		pending []byte
+		key     string
+		value   []byte
+	)
+	for i, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		if key != "" {
+			value = append(value, line...)
+			if tomlValueComplete(value) {
+				entries[key] = append(pending, ensureLineEnd(line, value)...)
+				pending, key = nil, ""
+			}
+			continue
+		}
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) == 0 || trimmed[0] == '#' {
+			pending = append(pending, line...)
+			continue
+		}
+		if trimmed[0] == '[' {
+			return entries, append(pending, bytes.Join(lines[i:], nil)...)
+		}
+		idx := bytes.IndexByte(line, '=')
+		if idx < 0 {
+			pending = append(pending, line...)
+			continue
+		}
+		key = strings.Trim(strings.TrimSpace(string(line[:idx])), `"'`)
+		value = append([]byte(nil), line[idx+1:]...)
+		if tomlValueComplete(value) {
+			entries[key] = append(pending, ensureLineEnd(line, line)...)
+			pending, key = nil, ""
+			continue
+		}
+		pending = append(pending, line[:idx+1]...)
+	}
+	if key != "" {
+		entries[key] = append(pending, value...)
+		pending = nil
+	}
+	return entries, pending
+}
+
+// ensureLineEnd return a copy of data, ends with "\n"
+func ensureLineEnd(line, data []byte) []byte {
+	data = append([]byte(nil), data...)
+	if bytes.HasSuffix(line, []byte("\n")) {
+		return data
+	}
+	return append(data, '\n')
+}
+
+// tomlValueComplete checks brackets and strings of toml value are closed
+func tomlValueComplete(value []byte) bool {
+	var (
+		depth int
+		quote string
+	)
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if quote != "" {
+			if c == '\\' && quote[0] == '"' {
+				i++
+				continue
+			}
+			if bytes.HasPrefix(value[i:], []byte(quote)) {
+				i += len(quote) - 1
+				quote = ""
+			}
+			continue
+		}
+		switch c {
+		case '"', '\'':
+			quote = string(c)
+			if bytes.HasPrefix(value[i:], bytes.Repeat([]byte{c}, 3)) {
+				quote = strings.Repeat(quote, 3)
+				i += 2
+			}
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+		case '#':
+			for i < len(value) && value[i] != '\n' {
+				i++
+			}
+		}
+	}
+	return depth <= 0 && quote == ""
+}
+
+// frontMatterKeys return keys in meta, ordered as toml fields of v
+func frontMatterKeys(v interface{}, meta map[string]interface{}) []string {
+	var (
+		keys []string
+		used = make(map[string]bool)
+	)
+	t := reflect.Indirect(reflect.ValueOf(v)).Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("toml"), ",")[0]
+		if _, ok := meta[name]; !ok || used[name] {
+			continue
+		}
+		keys = append(keys, name)
+		used[name] = true
+	}
+	var others []string
+	for k := range meta {
+		if !used[k] {
+			others = append(others, k)
+		}
+	}
+	sort.Strings(others)
+	return append(keys, others...)
+}
+
 // NewPostsFrontMatter parse post meta file to create post data
 func NewPostsFrontMatter(file string, t FormatType) (map[string]*Post, error) {
 	metas := make(map[string]*Post)
@@ -18,6 +583,9 @@ func NewPostsFrontMatter(file string, t FormatType) (map[string]*Post, error) {
 		if err != nil {
 			return nil, err
 		}
+		if data, err = helper.DecodeText(data, ""); err != nil {
+			return nil, err
+		}
 		if err = toml.Unmarshal(data, &metas); err != nil {
 			return nil, err
 		}
@@ -52,6 +620,9 @@ func NewPagesFrontMatter(file string, t FormatType) (map[string]*Page, error) {
 		if err != nil {
 			return nil, err
 		}
+		if data, err = helper.DecodeText(data, ""); err != nil {
+			return nil, err
+		}
 		if err = toml.Unmarshal(data, &metas); err != nil {
 			return nil, err
 		}
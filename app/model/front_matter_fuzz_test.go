@@ -0,0 +1,22 @@
+//go:build go1.18
+// +build go1.18
+
+package model
+
+import "testing"
+
+func FuzzNewPostOfBytes(f *testing.F) {
+	f.Add([]byte("```toml\ntitle = \"a\"\ndate = \"2016-03-25 12:20\"\n```\n\ncontent"))
+	f.Add([]byte("```ini\ntitle = a\ndate = 2016-03-25 12:20\n```\n\ncontent"))
+	f.Add([]byte("```toml\n[variant.b]\ntitle = \"b\"\n```\n\n{{% variant \"b\" %}}b{{% /variant %}}"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		post, err := NewPostOfBytes("fuzz.md", data, nil)
+		if err == nil && post == nil {
+			t.Fatal("nil post without error")
+		}
+		if fe, ok := err.(*FrontMatterError); ok && fe.Line < 1 {
+			t.Fatalf("wrong line number %d", fe.Line)
+		}
+		NewPageOfBytes("fuzz.md", "fuzz", data, nil)
+	})
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"strings"
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
@@ -21,3 +22,174 @@ func TestFrontMatter(t *testing.T) {
 		So(err, ShouldNotBeNil)
 	})
 }
+
+func TestFrontMatterMalformed(t *testing.T) {
+	Convey("MalformedFrontMatter", t, func() {
+		inputs := []string{
+			"",
+			"```",
+			"``````",
+			"```\n```\n",
+			"```toml```",
+			"```toml\n```\n",
+			"```toml\ntitle = \n```\n",
+			"```toml\n[[[\n```\n",
+			"```toml\n[variant]\n0=\"\"\"```",
+			"```toml\ntitle = '''abc\n```\n",
+			"```toml\ntitle = \"\xff\xfe\"\n```\n",
+			"```ini\n[abc\n```\n",
+			"```ini\n\"abc = 1\n```\n",
+			"```yaml\ntitle: a\n```\n",
+		}
+		for _, input := range inputs {
+			So(func() {
+				NewPostOfBytes("fuzz.md", []byte(input), nil)
+				NewPageOfBytes("fuzz.md", "fuzz", []byte(input), nil)
+			}, ShouldNotPanic)
+		}
+	})
+
+	Convey("FrontMatterErrorLine", t, func() {
+		data := "\n\n```toml\ntitle = \"a\"\ndate = \ntags = []\n```\n\ncontent"
+		_, err := NewPostOfBytes("a.md", []byte(data), nil)
+		So(err, ShouldNotBeNil)
+		fe, ok := err.(*FrontMatterError)
+		So(ok, ShouldBeTrue)
+		So(fe.Line, ShouldEqual, 5)
+		So(fe.Error(), ShouldStartWith, "a.md:5:")
+
+		data = "```ini\ntitle = a\n[abc\n```\n\ncontent"
+		_, err = NewPostOfBytes("b.md", []byte(data), nil)
+		So(err, ShouldNotBeNil)
+		So(err.(*FrontMatterError).Line, ShouldEqual, 3)
+
+		data = "\n```yaml\ntitle: a\n```\n\ncontent"
+		_, err = NewPageOfBytes("c.md", "c", []byte(data), nil)
+		So(err, ShouldNotBeNil)
+		So(err.(*FrontMatterError).Line, ShouldEqual, 2)
+	})
+}
+
+func TestFormatFrontMatter(t *testing.T) {
+	Convey("FormatFrontMatter", t, func() {
+		data := "```toml\n" +
+			"# tags of post\n" +
+			"tags = [\n  \"a\",\n  \"b\",\n]\n" +
+			"extra = 1\n" +
+			"title = \"Title\"\n" +
+			"\n[variant.b]\ntitle = \"B\"\n" +
+			"```\r\n\r\nbody ```code```\r\n"
+		out, err := FormatFrontMatter("a.md", []byte(data), new(Post), func(meta map[string]interface{}) {
+			if _, ok := meta["uuid"]; !ok {
+				meta["uuid"] = "uuid"
+			}
+		})
+		So(err, ShouldBeNil)
+		So(string(out), ShouldEqual, "```toml\n"+
+			"title = \"Title\"\n"+
+			"uuid = \"uuid\"\n"+
+			"# tags of post\n"+
+			"tags = [\n  \"a\",\n  \"b\",\n]\n"+
+			"extra = 1\n"+
+			"\n[variant.b]\ntitle = \"B\"\n"+
+			"```\r\n\r\nbody ```code```\r\n")
+
+		out2, err := FormatFrontMatter("a.md", out, new(Post), nil)
+		So(err, ShouldBeNil)
+		So(string(out2), ShouldEqual, string(out))
+
+		p, err := NewPostOfBytes("a.md", []byte(strings.Replace(string(out), "\r", "", -1)), nil)
+		So(err, ShouldBeNil)
+		So(p.UUID, ShouldEqual, "uuid")
+		So(p.TagString, ShouldResemble, []string{"a", "b"})
+		So(p.VariantMeta["b"].Title, ShouldEqual, "B")
+
+		_, err = FormatFrontMatter("a.ini", []byte("```ini\ntitle = a\n```\nbody"), new(Post), nil)
+		So(err, ShouldNotBeNil)
+
+		Convey("CRLF", func() {
+			crlf := "```toml\r\ntags = [\r\n  \"a\",\r\n]\r\ntitle = \"Title\"\r\n```\r\n\r\nbody\r\n"
+			out, err := FormatFrontMatter("a.md", []byte(crlf), new(Post), func(meta map[string]interface{}) {
+				meta["uuid"] = "uuid"
+			})
+			So(err, ShouldBeNil)
+			So(string(out), ShouldEqual, "```toml\r\ntitle = \"Title\"\r\nuuid = \"uuid\"\r\ntags = [\r\n  \"a\",\r\n]\r\n```\r\n\r\nbody\r\n")
+
+			out, err = EditFrontMatter("a.md", []byte(crlf), map[string]interface{}{"tags": []string{"b"}, "draft": true})
+			So(err, ShouldBeNil)
+			So(strings.Count(string(out), "\n"), ShouldEqual, strings.Count(string(out), "\r\n"))
+
+			out, err = EditFrontMatter("a.md", []byte("```ini\r\ntitle = a\r\n```\r\nbody"), map[string]interface{}{"title": "b"})
+			So(err, ShouldBeNil)
+			So(string(out), ShouldEqual, "```ini\r\ntitle = \"b\"\r\n```\r\nbody")
+		})
+	})
+}
+
+func TestReplaceFrontMatter(t *testing.T) {
+	Convey("ReplaceFrontMatter", t, func() {
+		data := "```toml\n" +
+			"title = \"Title\"\n" +
+			"# tags of post\n" +
+			"tags = [\n  \"a\",\n  \"b\",\n]\n" +
+			"desc = \"\"\"\nx = 1\n\"\"\"\n" +
+			"\n[variant.b]\ntags = [\"c\"]\n" +
+			"```\n\nbody\n"
+		out, err := ReplaceFrontMatter("a.md", []byte(data), map[string]interface{}{
+			"tags": []string{"a", "c"},
+			"slug": "s",
+		})
+		So(err, ShouldBeNil)
+		So(string(out), ShouldEqual, "```toml\n"+
+			"title = \"Title\"\n"+
+			"# tags of post\n"+
+			"tags = [\"a\", \"c\"]\n"+
+			"desc = \"\"\"\nx = 1\n\"\"\"\n"+
+			"\n"+
+			"slug = \"s\"\n"+
+			"[variant.b]\ntags = [\"c\"]\n"+
+			"```\n\nbody\n")
+
+		p, err := NewPostOfBytes("a.md", out, nil)
+		So(err, ShouldBeNil)
+		So(p.TagString, ShouldResemble, []string{"a", "c"})
+		So(p.Slug, ShouldEqual, "s")
+
+		_, err = ReplaceFrontMatter("a.ini", []byte("```ini\ntitle = a\n```\nbody"), nil)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("ReplaceINIFrontMatter", t, func() {
+		data := "```ini\n" +
+			"; title of post\n" +
+			"title = \"Title\"\n" +
+			"desc = `multi\nline`\n" +
+			"tags = \"a,b\"\n" +
+			"[variant]\ntitle = \"V\"\n" +
+			"```\n\nbody\n"
+		out, err := EditFrontMatter("a.md", []byte(data), map[string]interface{}{
+			"desc":  "a \"b\"",
+			"tags":  []string{"a", "c"},
+			"draft": true,
+		})
+		So(err, ShouldBeNil)
+		So(string(out), ShouldEqual, "```ini\n"+
+			"; title of post\n"+
+			"title = \"Title\"\n"+
+			"desc = `a \"b\"`\n"+
+			"tags = \"a,c\"\n"+
+			"draft = true\n"+
+			"[variant]\ntitle = \"V\"\n"+
+			"```\n\nbody\n")
+
+		p, err := NewPostOfBytes("a.md", out, nil)
+		So(err, ShouldBeNil)
+		So(p.Desc, ShouldEqual, "a \"b\"")
+		So(p.TagString, ShouldResemble, []string{"a", "c"})
+		So(p.Draft, ShouldBeTrue)
+
+		out, err = ReplaceBody("a.md", out, []byte("\nnew body\n\n"))
+		So(err, ShouldBeNil)
+		So(string(out), ShouldEndWith, "```\n\nnew body\n")
+	})
+}
@@ -0,0 +1,134 @@
+package model
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ImageMeta is metadata of image in sidecar file,
+// such as "cat.jpg.yml" or "cat.yml" next to "cat.jpg"
+type ImageMeta struct {
+	Alt     string
+	Caption string
+	License string
+	Author  string
+	Link    string
+}
+
+// ImageSidecarExts are extensions of image sidecar files
+var ImageSidecarExts = []string{".yml", ".yaml"}
+
+// ReadImageMeta reads sidecar file of image file,
+// it return nil if the image has no sidecar file
+func ReadImageMeta(file string) (*ImageMeta, error) {
+	base := strings.TrimSuffix(file, filepath.Ext(file))
+	for _, name := range []string{file, base} {
+		for _, ext := range ImageSidecarExts {
+			data, err := ioutil.ReadFile(name + ext)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return nil, err
+			}
+			m, err := NewImageMeta(data)
+			if err != nil {
+				return nil, fmt.Errorf("%s%s: %s", name, ext, err.Error())
+			}
+			return m, nil
+		}
+	}
+	return nil, nil
+}
+
+// NewImageMeta parses sidecar data of "key: value" lines,
+// it's plain yaml mapping, values can be quoted.
+// Values must be in one line, block scalars and indented lines are errors.
+func NewImageMeta(data []byte) (*ImageMeta, error) {
+	m := new(ImageMeta)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || text == "---" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if raw := scanner.Text(); raw[0] == ' ' || raw[0] == '\t' {
+			return nil, fmt.Errorf("line %d: indented line is not supported, write value in one line", line)
+		}
+		idx := strings.Index(text, ":")
+		if idx < 0 {
+			return nil, fmt.Errorf("line %d: need 'key: value'", line)
+		}
+		key, value := strings.TrimSpace(text[:idx]), strings.TrimSpace(text[idx+1:])
+		if strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">") {
+			return nil, fmt.Errorf("line %d: block scalar is not supported, write value in one line", line)
+		}
+		if strings.HasPrefix(value, `"`) {
+			v, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", line, err.Error())
+			}
+			value = v
+		} else if strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") && len(value) > 1 {
+			value = strings.Replace(value[1:len(value)-1], "''", "'", -1)
+		} else if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+		switch strings.ToLower(key) {
+		case "alt":
+			m.Alt = value
+		case "caption":
+			m.Caption = value
+		case "license":
+			m.License = value
+		case "author":
+			m.Author = value
+		case "link":
+			m.Link = value
+		}
+	}
+	return m, scanner.Err()
+}
+
+// Credit return license and author of image, joined by " / "
+func (m *ImageMeta) Credit() string {
+	var parts []string
+	if m.Author != "" {
+		parts = append(parts, m.Author)
+	}
+	if m.License != "" {
+		parts = append(parts, m.License)
+	}
+	return strings.Join(parts, " / ")
+}
+
+// IsImageSidecar checks file is sidecar of other file in the same directory
+func IsImageSidecar(file string) bool {
+	ext := filepath.Ext(file)
+	isSidecar := false
+	for _, e := range ImageSidecarExts {
+		if ext == e {
+			isSidecar = true
+		}
+	}
+	if !isSidecar {
+		return false
+	}
+	base := strings.TrimSuffix(file, ext)
+	if fi, err := os.Stat(base); err == nil && !fi.IsDir() {
+		return true
+	}
+	matches, _ := filepath.Glob(base + ".*")
+	for _, m := range matches {
+		if m != file && !IsImageSidecar(m) {
+			return true
+		}
+	}
+	return false
+}
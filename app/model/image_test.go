@@ -0,0 +1,63 @@
+package model
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestImageMeta(t *testing.T) {
+	Convey("ImageMeta", t, func() {
+		m, err := NewImageMeta([]byte("---\n# sidecar\nalt: A cat\ncaption: \"Cat: on \\\"sofa\\\"\"\nlicense: 'CC BY 4.0' \nauthor: pugo # photographer\nunknown: x\n"))
+		So(err, ShouldBeNil)
+		So(m.Alt, ShouldEqual, "A cat")
+		So(m.Caption, ShouldEqual, `Cat: on "sofa"`)
+		So(m.License, ShouldEqual, "CC BY 4.0")
+		So(m.Author, ShouldEqual, "pugo")
+		So(m.Credit(), ShouldEqual, "pugo / CC BY 4.0")
+
+		_, err = NewImageMeta([]byte("alt"))
+		So(err, ShouldNotBeNil)
+
+		// values are in one line
+		for _, data := range []string{
+			"alt: |\n  A cat\n",
+			"caption: >-\n  Afternoon\n  nap\n",
+			"alt: A cat\n  sleeping on the sofa\n",
+			"alt: a\n\tcaption: b\n",
+		} {
+			_, err = NewImageMeta([]byte(data))
+			So(err, ShouldNotBeNil)
+		}
+
+		Convey("Sidecar File", func() {
+			dir, err := ioutil.TempDir("", "pugo-image")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(dir)
+			for name, data := range map[string]string{
+				"a.jpg": "", "a.jpg.yml": "alt: a",
+				"b.png": "", "b.yaml": "alt: b",
+				"c.png": "", "config.yml": "",
+			} {
+				So(ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644), ShouldBeNil)
+			}
+			m, err := ReadImageMeta(filepath.Join(dir, "a.jpg"))
+			So(err, ShouldBeNil)
+			So(m.Alt, ShouldEqual, "a")
+			m, err = ReadImageMeta(filepath.Join(dir, "b.png"))
+			So(err, ShouldBeNil)
+			So(m.Alt, ShouldEqual, "b")
+			m, err = ReadImageMeta(filepath.Join(dir, "c.png"))
+			So(err, ShouldBeNil)
+			So(m, ShouldBeNil)
+
+			So(IsImageSidecar(filepath.Join(dir, "a.jpg.yml")), ShouldBeTrue)
+			So(IsImageSidecar(filepath.Join(dir, "b.yaml")), ShouldBeTrue)
+			So(IsImageSidecar(filepath.Join(dir, "config.yml")), ShouldBeFalse)
+			So(IsImageSidecar(filepath.Join(dir, "a.jpg")), ShouldBeFalse)
+		})
+	})
+}
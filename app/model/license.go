@@ -0,0 +1,183 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// AttributionsFile lists third-party assets used in site, in source directory
+const AttributionsFile = "attributions.toml"
+
+type (
+	// License is content license of site or post
+	License struct {
+		// Name is SPDX id such as "CC-BY-4.0", or any name with URL
+		Name string `toml:"name" ini:"name"`
+		// Title is readable name, it's filled for Creative Commons licenses
+		Title string `toml:"title" ini:"title"`
+		// URL is link to license text, it's filled for Creative Commons licenses
+		URL string `toml:"url" ini:"url"`
+		// Holder is copyright holder, site owner by default
+		Holder string `toml:"holder" ini:"holder"`
+	}
+	// Attribution is a third-party asset used in site
+	Attribution struct {
+		Name    string   `toml:"name"`
+		Author  string   `toml:"author"`
+		URL     string   `toml:"url"`
+		License *License `toml:"-"`
+		// LicenseName is name of license, the same as License.Name
+		LicenseName string   `toml:"license"`
+		LicenseURL  string   `toml:"license_url"`
+		Files       []string `toml:"files"`
+	}
+)
+
+var (
+	// ccLicenses are titles of Creative Commons licenses by SPDX id
+	ccLicenses = map[string]string{
+		"CC0-1.0":         "CC0 1.0 Universal",
+		"CC-BY-4.0":       "Creative Commons Attribution 4.0",
+		"CC-BY-SA-4.0":    "Creative Commons Attribution-ShareAlike 4.0",
+		"CC-BY-ND-4.0":    "Creative Commons Attribution-NoDerivatives 4.0",
+		"CC-BY-NC-4.0":    "Creative Commons Attribution-NonCommercial 4.0",
+		"CC-BY-NC-SA-4.0": "Creative Commons Attribution-NonCommercial-ShareAlike 4.0",
+		"CC-BY-NC-ND-4.0": "Creative Commons Attribution-NonCommercial-NoDerivatives 4.0",
+	}
+
+	errLicenseURL = errors.New("license need url if it's not Creative Commons license, such as 'CC-BY-4.0'")
+)
+
+// CCLicenseNames return SPDX ids of Creative Commons licenses
+func CCLicenseNames() []string {
+	var names []string
+	for id := range ccLicenses {
+		names = append(names, id)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewLicense return license by name,
+// Creative Commons licenses are filled with title and url
+func NewLicense(name string) (*License, error) {
+	l := &License{Name: name}
+	return l, l.normalize()
+}
+
+func (l *License) normalize() error {
+	for id, title := range ccLicenses {
+		if !strings.EqualFold(id, l.Name) {
+			continue
+		}
+		l.Name = id
+		if l.Title == "" {
+			l.Title = title
+		}
+		if l.URL == "" {
+			l.URL = ccLicenseURL(id)
+		}
+		return nil
+	}
+	if l.Name == "" {
+		return nil
+	}
+	if l.Title == "" {
+		l.Title = l.Name
+	}
+	if l.URL == "" {
+		return fmt.Errorf("%s: %s", l.Name, errLicenseURL)
+	}
+	return nil
+}
+
+// ccLicenseURL return deed url of Creative Commons license id
+func ccLicenseURL(id string) string {
+	if id == "CC0-1.0" {
+		return "https://creativecommons.org/publicdomain/zero/1.0/"
+	}
+	parts := strings.Split(strings.ToLower(id), "-")
+	return fmt.Sprintf("https://creativecommons.org/licenses/%s/%s/",
+		strings.Join(parts[1:len(parts)-1], "-"), parts[len(parts)-1])
+}
+
+// IsValid checks license has name
+func (l *License) IsValid() bool {
+	return l != nil && l.Name != ""
+}
+
+// LinkTag return <link rel="license"> tag in head
+func (l *License) LinkTag() template.HTML {
+	if !l.IsValid() {
+		return ""
+	}
+	return template.HTML(fmt.Sprintf(`<link rel="license" href="%s"/>`, template.HTMLEscapeString(l.URL)))
+}
+
+// HTML return link to license with rel="license"
+func (l *License) HTML() template.HTML {
+	if !l.IsValid() {
+		return ""
+	}
+	return template.HTML(fmt.Sprintf(`<a rel="license" href="%s">%s</a>`,
+		template.HTMLEscapeString(l.URL), template.HTMLEscapeString(l.Title)))
+}
+
+// JSONLD return structured data of work with the license,
+// name and link are title and full url of the work
+func (l *License) JSONLD(name, link string) template.HTML {
+	if !l.IsValid() {
+		return ""
+	}
+	data := map[string]interface{}{
+		"@context": "https://schema.org",
+		"@type":    "CreativeWork",
+		"name":     name,
+		"url":      link,
+		"license":  l.URL,
+	}
+	if l.Holder != "" {
+		data["copyrightHolder"] = map[string]string{"@type": "Person", "name": l.Holder}
+	}
+	b, _ := json.Marshal(data)
+	return template.HTML(`<script type="application/ld+json">` + string(b) + `</script>`)
+}
+
+// ReadAttributions reads third-party assets in attributions file,
+// it return nil if file does not exist
+func ReadAttributions(file string) ([]*Attribution, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var v struct {
+		Assets []*Attribution `toml:"asset"`
+	}
+	if err = toml.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	for _, a := range v.Assets {
+		if a.Name == "" {
+			return nil, fmt.Errorf("asset need name in %s", file)
+		}
+		if a.LicenseName == "" {
+			continue
+		}
+		a.License = &License{Name: a.LicenseName, URL: a.LicenseURL}
+		if err = a.License.normalize(); err != nil {
+			return nil, fmt.Errorf("asset '%s': %s", a.Name, err.Error())
+		}
+	}
+	return v.Assets, nil
+}
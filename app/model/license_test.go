@@ -0,0 +1,57 @@
+package model
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLicense(t *testing.T) {
+	Convey("License", t, func() {
+		l, err := NewLicense("cc-by-sa-4.0")
+		So(err, ShouldBeNil)
+		So(l.Name, ShouldEqual, "CC-BY-SA-4.0")
+		So(l.URL, ShouldEqual, "https://creativecommons.org/licenses/by-sa/4.0/")
+		So(string(l.LinkTag()), ShouldEqual, `<link rel="license" href="https://creativecommons.org/licenses/by-sa/4.0/"/>`)
+		So(string(l.HTML()), ShouldContainSubstring, `>Creative Commons Attribution-ShareAlike 4.0</a>`)
+		So(string(l.JSONLD("Post", "http://example.com/post.html")), ShouldContainSubstring, `"license":"https://creativecommons.org/licenses/by-sa/4.0/"`)
+
+		l, err = NewLicense("CC0-1.0")
+		So(err, ShouldBeNil)
+		So(l.URL, ShouldEqual, "https://creativecommons.org/publicdomain/zero/1.0/")
+
+		_, err = NewLicense("MIT")
+		So(err, ShouldNotBeNil)
+
+		var empty *License
+		So(empty.IsValid(), ShouldBeFalse)
+		So(string(empty.HTML()), ShouldBeEmpty)
+	})
+
+	Convey("Attributions", t, func() {
+		dir, err := ioutil.TempDir("", "pugo-license")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		file := filepath.Join(dir, AttributionsFile)
+
+		assets, err := ReadAttributions(file)
+		So(err, ShouldBeNil)
+		So(assets, ShouldBeNil)
+
+		data := "[[asset]]\nname = \"Icons\"\nlicense = \"CC-BY-4.0\"\nfiles = [\"media/icons.svg\"]\n\n" +
+			"[[asset]]\nname = \"Font\"\nlicense = \"OFL-1.1\"\nlicense_url = \"https://scripts.sil.org/OFL\"\n"
+		So(ioutil.WriteFile(file, []byte(data), 0644), ShouldBeNil)
+		assets, err = ReadAttributions(file)
+		So(err, ShouldBeNil)
+		So(assets, ShouldHaveLength, 2)
+		So(assets[0].License.Title, ShouldEqual, "Creative Commons Attribution 4.0")
+		So(assets[1].License.URL, ShouldEqual, "https://scripts.sil.org/OFL")
+
+		So(ioutil.WriteFile(file, []byte("[[asset]]\nname = \"Font\"\nlicense = \"OFL-1.1\"\n"), 0644), ShouldBeNil)
+		_, err = ReadAttributions(file)
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -3,9 +3,11 @@ package model
 import (
 	"errors"
 	"fmt"
+	"net"
 	"net/url"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"gopkg.in/ini.v1"
@@ -22,16 +24,34 @@ type (
 		Root     string `toml:"root" ini:"root"`
 		Cover    string `toml:"cover" ini:"cover"`
 		Language string `toml:"lang" ini:"lang"`
-		Path     string `toml:"-" ini:"-"`
+		Timezone string `toml:"timezone" ini:"timezone"`
+		// HTTPS builds root as https://{domain}/ if root is empty
+		HTTPS bool `toml:"https" ini:"https"`
+		// CNAME writes domain to CNAME file in destination, for GitHub Pages
+		CNAME bool   `toml:"cname" ini:"cname"`
+		Path  string `toml:"-" ini:"-"`
+
+		location  *time.Location
+		host      string
+		scheme    string
+		domainErr error
 	}
 	// MetaAll is all data struct in meta file
 	MetaAll struct {
-		Meta        *Meta       `toml:"meta"`
-		NavGroup    NavGroup    `toml:"nav"`
-		AuthorGroup AuthorGroup `toml:"author"`
-		Comment     *Comment    `toml:"comment"`
-		Analytics   *Analytics  `toml:"analytics"`
-		Build       *Build      `toml:"build"`
+		Meta        *Meta            `toml:"meta"`
+		NavGroup    NavGroup         `toml:"nav"`
+		AuthorGroup AuthorGroup      `toml:"author"`
+		Comment     *Comment         `toml:"comment"`
+		Analytics   *Analytics       `toml:"analytics"`
+		Reaction    *Reaction        `toml:"reaction"`
+		FormGroup   FormGroup        `toml:"form"`
+		Mail        *Mail            `toml:"mail"`
+		Syndication SyndicationGroup `toml:"syndication"`
+		Build       *Build           `toml:"build"`
+		Verify      *Verify          `toml:"verify"`
+		License     *License         `toml:"license"`
+		// Env is values of environment variables in build settings
+		Env map[string]string `toml:"-"`
 	}
 )
 
@@ -40,23 +60,36 @@ var (
 	errMetaInvalid   = errors.New("meta title and (root or domain) cant be blank")
 )
 
-// NewMetaAll parse bytes with correct FormatType
+// NewMetaAll parse bytes with correct FormatType,
+// ${NAME} in secrets of mail and syndication are read from process environment
 func NewMetaAll(data []byte, format FormatType) (*MetaAll, error) {
-	var err error
+	return NewMetaAllEnv(data, format, nil)
+}
+
+// NewMetaAllEnv parse bytes like NewMetaAll,
+// ${NAME} in secrets are also read from fileEnv, such as variables in .env files
+func NewMetaAllEnv(data []byte, format FormatType, fileEnv map[string]string) (*MetaAll, error) {
+	var (
+		meta *MetaAll
+		err  error
+	)
 	switch format {
 	case FormatTOML:
-		meta := &MetaAll{}
-		if err = toml.Unmarshal(data, meta); err != nil {
-			return nil, err
-		}
-		if err = meta.Normalize(); err != nil {
-			return nil, err
-		}
-		return meta, nil
+		meta = new(MetaAll)
+		err = toml.Unmarshal(data, meta)
 	case FormatINI:
-		return newMetaAllFromINI(data)
+		meta, err = newMetaAllFromINI(data)
+	default:
+		return nil, errMetaUnsupport
+	}
+	if err != nil {
+		return nil, err
+	}
+	meta.expandSecrets(fileEnv)
+	if err = meta.Normalize(); err != nil {
+		return nil, err
 	}
-	return nil, errMetaUnsupport
+	return meta, nil
 }
 
 func newMetaAllFromINI(data []byte) (*MetaAll, error) {
@@ -109,26 +142,73 @@ func newMetaAllFromINI(data []byte) (*MetaAll, error) {
 	}
 	metaAll.AuthorGroup = authorGroup
 
-	// read comment and analytics and build settings
+	// read forms
+	var formGroup []*Form
+	formKeys := iniObj.Section("form").Keys()
+	for _, k := range formKeys {
+		sectionName = "form." + k.Value()
+		form := new(Form)
+		if err = iniObj.Section(sectionName).MapTo(form); err != nil {
+			return nil, err
+		}
+		if form.Name == "" {
+			continue
+		}
+		formGroup = append(formGroup, form)
+	}
+	metaAll.FormGroup = formGroup
+
+	// read syndication platforms
+	var syndication []*Syndication
+	for _, k := range iniObj.Section("syndication").Keys() {
+		sectionName = "syndication." + k.Value()
+		s := new(Syndication)
+		if err = iniObj.Section(sectionName).MapTo(s); err != nil {
+			return nil, err
+		}
+		if s.Name == "" {
+			continue
+		}
+		syndication = append(syndication, s)
+	}
+	metaAll.Syndication = syndication
+
+	// read comment, analytics, reaction, mail and build settings
 	cmt := new(Comment)
 	if err := iniObj.Section("comment").MapTo(cmt); err != nil {
 		return nil, err
 	}
 	any := new(Analytics)
-	if err := iniObj.Section("analytics").MapTo(cmt); err != nil {
+	if err := iniObj.Section("analytics").MapTo(any); err != nil {
+		return nil, err
+	}
+	mail := new(Mail)
+	if err := iniObj.Section("mail").MapTo(mail); err != nil {
+		return nil, err
+	}
+	reaction := new(Reaction)
+	if err := iniObj.Section("reaction").MapTo(reaction); err != nil {
 		return nil, err
 	}
 	build := new(Build)
 	if err := iniObj.Section("build").MapTo(build); err != nil {
 		return nil, err
 	}
+	verify := new(Verify)
+	if err := iniObj.Section("verify").MapTo(verify); err != nil {
+		return nil, err
+	}
+	license := new(License)
+	if err := iniObj.Section("license").MapTo(license); err != nil {
+		return nil, err
+	}
 	metaAll.Comment = cmt
 	metaAll.Analytics = any
+	metaAll.Reaction = reaction
+	metaAll.Mail = mail
 	metaAll.Build = build
-
-	if err = metaAll.Normalize(); err != nil {
-		return nil, err
-	}
+	metaAll.Verify = verify
+	metaAll.License = license
 	return metaAll, nil
 }
 
@@ -136,7 +216,26 @@ func newMetaAllFromINI(data []byte) (*MetaAll, error) {
 func (m *Meta) DomainURL(link string) string {
 	link = strings.TrimPrefix(link, m.Path)
 	link = strings.Trim(link, "/")
-	return fmt.Sprintf("http://%s/%s", m.Domain, path.Join(strings.Trim(m.Path, "/"), link))
+	scheme, host := m.scheme, m.host
+	if scheme == "" {
+		scheme = "http"
+	}
+	if host == "" {
+		host = m.Domain
+	}
+	return fmt.Sprintf("%s://%s/%s", scheme, host, path.Join(strings.Trim(m.Path, "/"), link))
+}
+
+// DomainMismatch return error if domain is different from host of root
+func (m *Meta) DomainMismatch() error {
+	return m.domainErr
+}
+
+// SetRoot changes root url of site, domain and path are updated by the root,
+// such as building site for another host
+func (m *Meta) SetRoot(root string) error {
+	m.Root, m.Domain = root, ""
+	return m.normalize()
 }
 
 func (m *Meta) normalize() error {
@@ -145,15 +244,31 @@ func (m *Meta) normalize() error {
 	}
 	if m.Root == "" && m.Domain != "" {
 		m.Root = "http://" + m.Domain + "/"
+		if m.HTTPS {
+			m.Root = "https://" + m.Domain + "/"
+		}
 	}
 	u, err := url.Parse(m.Root)
 	if err != nil {
 		return err
 	}
+	hostname := u.Host
+	if h, _, err := net.SplitHostPort(u.Host); err == nil {
+		hostname = h
+	}
+	m.domainErr = nil
 	if m.Domain == "" {
-		m.Domain = u.Host
+		m.Domain = hostname
+	} else if hostname != "" && !strings.EqualFold(m.Domain, hostname) {
+		// links use host of root, building decides whether it's an error
+		m.domainErr = fmt.Errorf("meta domain '%s' is different from host of root '%s'", m.Domain, m.Root)
+	}
+	if m.CNAME && m.Domain == "" {
+		return errors.New("meta cname needs domain")
 	}
 	m.Path = u.Path
+	m.host = u.Host
+	m.scheme = u.Scheme
 
 	if m.Desc == "" {
 		m.Desc = m.Title
@@ -161,9 +276,24 @@ func (m *Meta) normalize() error {
 	if m.Keyword == "" {
 		m.Keyword = m.Title
 	}
+	m.location = time.UTC
+	if m.Timezone != "" {
+		if m.location, err = time.LoadLocation(m.Timezone); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// Location return time zone of the site,
+// dates and permalinks of posts use this time zone
+func (m *Meta) Location() *time.Location {
+	if m.location == nil {
+		return time.UTC
+	}
+	return m.location
+}
+
 // Normalize make meta all data correct,
 // it fills blank fields to correct values
 func (ma *MetaAll) Normalize() error {
@@ -177,5 +307,27 @@ func (ma *MetaAll) Normalize() error {
 	if err = ma.AuthorGroup.normalize(); err != nil {
 		return err
 	}
+	if ma.Build != nil {
+		if err = ma.Build.normalize(); err != nil {
+			return err
+		}
+	}
+	if ma.Mail != nil {
+		ma.Mail.normalize()
+	}
+	if err = ma.FormGroup.normalize(ma.Mail); err != nil {
+		return err
+	}
+	if err = ma.Syndication.normalize(); err != nil {
+		return err
+	}
+	if ma.License != nil {
+		if err = ma.License.normalize(); err != nil {
+			return err
+		}
+		if ma.License.Holder == "" && len(ma.AuthorGroup) > 0 {
+			ma.License.Holder = ma.AuthorGroup[0].Nick
+		}
+	}
 	return nil
 }
@@ -84,6 +84,13 @@ func TestMetaRead(t *testing.T) {
 					So(meta.Comment.IsOK(), ShouldBeTrue)
 
 					So(meta.Meta.DomainURL("/abc.html"), ShouldEqual, "http://pugo.io/docs/abc.html")
+
+					Convey("SetRoot", func() {
+						So(meta.Meta.SetRoot("http://example.onion/blog/"), ShouldBeNil)
+						So(meta.Meta.Domain, ShouldEqual, "example.onion")
+						So(meta.Meta.Path, ShouldEqual, "/blog/")
+						So(meta.Meta.DomainURL("/blog/abc.html"), ShouldEqual, "http://example.onion/blog/abc.html")
+					})
 				})
 			}
 		}
@@ -103,5 +110,44 @@ func TestMetaRead(t *testing.T) {
 		}
 		err = meta2.normalize()
 		So(err, ShouldBeNil)
+
+		meta3 := &Meta{
+			Title:  "pugo",
+			Domain: "pugo.io",
+			Root:   "http://other.io/",
+		}
+		So(meta3.normalize(), ShouldBeNil)
+		So(meta3.DomainMismatch(), ShouldNotBeNil)
+	})
+
+	Convey("Meta Domain", t, func() {
+		meta := &Meta{
+			Title:  "pugo",
+			Domain: "pugo.io",
+			HTTPS:  true,
+		}
+		So(meta.normalize(), ShouldBeNil)
+		So(meta.Root, ShouldEqual, "https://pugo.io/")
+		So(meta.DomainURL("/abc.html"), ShouldEqual, "https://pugo.io/abc.html")
+
+		meta2 := &Meta{
+			Title:  "pugo",
+			Domain: "localhost",
+			Root:   "http://localhost:9899/blog/",
+		}
+		So(meta2.normalize(), ShouldBeNil)
+		So(meta2.DomainURL("/blog/abc.html"), ShouldEqual, "http://localhost:9899/blog/abc.html")
+		So(meta2.DomainMismatch(), ShouldBeNil)
+
+		meta3 := &Meta{
+			Title:  "pugo",
+			Domain: "pugo.io",
+			Root:   "http://example.com/",
+		}
+		So(meta3.normalize(), ShouldBeNil)
+		So(meta3.DomainMismatch(), ShouldNotBeNil)
+		So(meta3.DomainURL("/abc.html"), ShouldEqual, "http://example.com/abc.html")
+		So(meta3.SetRoot("http://pugo.io/"), ShouldBeNil)
+		So(meta3.DomainMismatch(), ShouldBeNil)
 	})
 }
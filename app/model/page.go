@@ -9,10 +9,8 @@ import (
 	"strings"
 	"time"
 
-	"github.com/BurntSushi/toml"
 	"github.com/Unknwon/com"
 	"github.com/go-xiaohei/pugo/app/helper"
-	"gopkg.in/ini.v1"
 )
 
 // Page contain all fields of a page content
@@ -33,6 +31,7 @@ type Page struct {
 	Draft      bool                   `toml:"draft" ini:"draft"`
 	Node       bool                   `toml:"node" ini:"node"`
 	JSONFile   string                 `toml:"json" ini:"json"`
+	NoIndex    bool                   `toml:"noindex" ini:"noindex"`
 	JSON       *JSON                  `toml:"-" ini:"-"`
 	Index      []*PostIndex           `toml:"-" ini:"-"`
 
@@ -40,6 +39,7 @@ type Page struct {
 	fileURL      string
 	destURL      string
 	contentBytes []byte
+	markdown     *helper.MarkdownCache
 	dateTime     time.Time
 	updateTime   time.Time
 }
@@ -84,6 +84,16 @@ func (p *Page) SetPlaceholder(htmlReplacer *strings.Replacer) {
 	p.contentBytes = []byte(htmlReplacer.Replace(string(p.contentBytes)))
 }
 
+// SetEditorial shows or strips editorial annotations in page content
+func (p *Page) SetEditorial(visible bool) {
+	p.contentBytes = helper.Editorial(p.contentBytes, visible)
+}
+
+// ReplaceContent replaces html content by fn
+func (p *Page) ReplaceContent(fn func([]byte) []byte) {
+	p.contentBytes = fn(p.contentBytes)
+}
+
 // Created get create time
 func (p *Page) Created() time.Time {
 	return p.dateTime
@@ -117,7 +127,7 @@ func (p *Page) normalize() error {
 			return err
 		}
 	}
-	p.contentBytes = helper.Markdown(p.Bytes)
+	p.contentBytes = p.markdown.Render(p.Bytes)
 	p.pageURL = "/" + p.Slug
 	if !p.Node && !strings.HasSuffix(p.pageURL, ".html") {
 		p.pageURL = fmt.Sprintf("/%s", p.Slug) + ".html"
@@ -136,47 +146,54 @@ func NewPageOfMarkdown(file, slug string, page *Page) (*Page, error) {
 	if err != nil {
 		return nil, err
 	}
+	if fileBytes, err = helper.DecodeText(fileBytes, ""); err != nil {
+		return nil, err
+	}
+	return NewPageOfBytes(file, slug, fileBytes, page)
+}
+
+// NewPageOfBytes parses page from UTF-8 content bytes of file
+func NewPageOfBytes(file, slug string, fileBytes []byte, page *Page) (*Page, error) {
+	return NewPageOfBytesWithCache(file, slug, fileBytes, page, nil)
+}
+
+// NewPageOfBytesWithCache parses page as NewPageOfBytes, markdown is rendered by mc
+func NewPageOfBytesWithCache(file, slug string, fileBytes []byte, page *Page, mc *helper.MarkdownCache) (*Page, error) {
+	// page-node need not content
+	if page != nil && page.Node == true {
+		return page, nil
+	}
 	if len(fileBytes) < 3 {
 		return nil, fmt.Errorf("page content is too less")
 	}
 	if page == nil {
-		dataSlice := bytes.SplitN(fileBytes, postBlockSeparator, 3)
-		if len(dataSlice) != 3 {
-			return nil, fmt.Errorf("page need front-matter block and markdown block")
-		}
-
-		idx := getFirstBreakByte(dataSlice[1])
-		if idx == 0 {
-			return nil, fmt.Errorf("page need front-matter block and markdown block")
+		fm, err := parseFrontMatter(file, fileBytes)
+		if err != nil {
+			return nil, err
 		}
-
-		formatType := detectFormat(string(dataSlice[1][:idx]))
-		if formatType == 0 {
-			return nil, fmt.Errorf("page front-matter block is unrecognized")
-		}
-
 		page = new(Page)
-		if formatType == FormatTOML {
-			if err = toml.Unmarshal(dataSlice[1][idx:], page); err != nil {
+		if fm.Format == FormatTOML {
+			if err = fm.DecodeTOML(page); err != nil {
 				return nil, err
 			}
 		}
-		if formatType == FormatINI {
-			iniObj, err := ini.Load(dataSlice[1][idx:])
+		if fm.Format == FormatINI {
+			iniObj, err := fm.DecodeINI()
 			if err != nil {
 				return nil, err
 			}
 			if err = newPageFromIniObject(iniObj, page, "DEFAULT", "meta"); err != nil {
-				return nil, err
+				return nil, fm.error(err)
 			}
 		}
 		if page.Node == false {
-			page.Bytes = bytes.Trim(dataSlice[2], "\n")
+			page.Bytes = bytes.Trim(fm.Body, "\n")
 		}
 	} else {
 		page.Bytes = bytes.Trim(fileBytes, "\n")
 	}
 	page.fileURL = file
+	page.markdown = mc
 	if page.Slug == "" {
 		page.Slug = slug
 	}
@@ -11,11 +11,9 @@ import (
 	"strings"
 	"time"
 
-	"github.com/BurntSushi/toml"
 	"github.com/Unknwon/com"
 	"github.com/go-xiaohei/pugo/app/helper"
 	"golang.org/x/net/html"
-	"gopkg.in/ini.v1"
 )
 
 var (
@@ -35,17 +33,41 @@ type Post struct {
 	AuthorName string       `toml:"author" ini:"author"`
 	Thumb      string       `toml:"thumb" ini:"thumb"`
 	Draft      bool         `toml:"draft" ini:"draft"`
+	UUID       string       `toml:"uuid,omitempty" ini:"uuid"`
 	TagString  []string     `toml:"tags" ini:"-"`
 	Tags       []*Tag       `toml:"-" ini:"-"`
 	Author     *Author      `toml:"-" ini:"-"`
 	Index      []*PostIndex `toml:"-" ini:"-"`
 
+	// LicenseName is content license of the post, site license by default
+	LicenseName string   `toml:"license,omitempty" ini:"license"`
+	License     *License `toml:"-" ini:"-"`
+
+	// NoIndex excludes the post from sitemap, feed and json api, and adds robots noindex meta
+	NoIndex bool `toml:"noindex,omitempty" ini:"noindex"`
+
+	// Syndicate is names of platforms to cross-post, such as "devto"
+	Syndicate []string `toml:"syndicate,omitempty" ini:"-"`
+
+	VariantMeta map[string]*PostVariant `toml:"variant,omitempty" ini:"-"`
+	Variant     string                  `toml:"-" ini:"-"`
+
+	// Views is page views count from analytics data
+	Views int64 `toml:"-" ini:"-"`
+	// Reactions is reactions count by kind, such as likes
+	Reactions map[string]int64 `toml:"-" ini:"-"`
+	// Syndicated is links of cross-posted articles by platform name
+	Syndicated map[string]string `toml:"-" ini:"-"`
+
 	dateTime   time.Time
 	updateTime time.Time
 
 	Bytes        []byte `toml:"-"`
 	contentBytes []byte
 	briefBytes   []byte
+	variants     []*Post
+	markdown     *helper.MarkdownCache
+	released     bool
 	postURL      string
 	fileURL      string
 	destURL      string
@@ -68,6 +90,18 @@ func (p *Post) SetPlaceholder(stringReplacer, htmlReplacer *strings.Replacer) {
 	p.briefBytes = []byte(htmlReplacer.Replace(string(p.briefBytes)))
 }
 
+// SetEditorial shows or strips editorial annotations in post content
+func (p *Post) SetEditorial(visible bool) {
+	p.contentBytes = helper.Editorial(p.contentBytes, visible)
+	p.briefBytes = helper.Editorial(p.briefBytes, visible)
+}
+
+// ReplaceContent replaces html content and brief by fn
+func (p *Post) ReplaceContent(fn func([]byte) []byte) {
+	p.contentBytes = fn(p.contentBytes)
+	p.briefBytes = fn(p.briefBytes)
+}
+
 // URL get url of the post
 func (p *Post) URL() string {
 	return p.postURL
@@ -83,16 +117,36 @@ func (p *Post) DestURL() string {
 	return filepath.ToSlash(p.destURL)
 }
 
+// GUID return global unique id of the post in feeds,
+// it's empty if post has no uuid
+func (p *Post) GUID() string {
+	if p.UUID == "" {
+		return ""
+	}
+	return "urn:uuid:" + strings.ToLower(p.UUID)
+}
+
 // ContentHTML get html content
 func (p *Post) ContentHTML() template.HTML {
-	return template.HTML(p.contentBytes)
+	return template.HTML(p.Content())
 }
 
-// Content get html content bytes
+// Content get html content bytes,
+// if content is released, use brief content
 func (p *Post) Content() []byte {
+	if p.released {
+		return p.briefBytes
+	}
 	return p.contentBytes
 }
 
+// ReleaseContent frees html content to save memory,
+// then Content returns brief content
+func (p *Post) ReleaseContent() {
+	p.contentBytes = nil
+	p.released = true
+}
+
 // BriefHTML get brief html content
 func (p *Post) BriefHTML() template.HTML {
 	return template.HTML(p.briefBytes)
@@ -149,13 +203,22 @@ func (p *Post) normalize() error {
 			return err
 		}
 	}
-	p.contentBytes = helper.Markdown(p.Bytes)
-	p.briefBytes = helper.Markdown(bytes.Split(p.Bytes, postBriefSeparator)[0])
-	permaURL := fmt.Sprintf("/%d/%d/%d/%s", p.dateTime.Year(), p.dateTime.Month(), p.dateTime.Day(), p.Slug)
-	p.postURL = permaURL + ".html"
+	raw := p.Bytes
+	if len(p.VariantNames()) > 0 {
+		p.Variant = VariantBase
+		raw = variantBytes(raw, VariantBase)
+	}
+	p.contentBytes = p.markdown.Render(raw)
+	p.briefBytes = p.markdown.Render(bytes.Split(raw, postBriefSeparator)[0])
+	p.postURL = postPermaURL(p.dateTime, p.Slug)
 	for _, t := range p.TagString {
 		p.Tags = append(p.Tags, NewTag(t))
 	}
+	if p.LicenseName != "" {
+		if p.License, err = NewLicense(p.LicenseName); err != nil {
+			return err
+		}
+	}
 	p.Index = newPostIndexs(bytes.NewReader(p.contentBytes))
 	return nil
 }
@@ -166,47 +229,54 @@ func NewPostOfMarkdown(file string, post *Post) (*Post, error) {
 	if err != nil {
 		return nil, err
 	}
+	if fileBytes, err = helper.DecodeText(fileBytes, ""); err != nil {
+		return nil, err
+	}
+	return NewPostOfBytes(file, fileBytes, post)
+}
+
+// NewPostOfBytes parses post from UTF-8 content bytes of file
+func NewPostOfBytes(file string, fileBytes []byte, post *Post) (*Post, error) {
+	return NewPostOfBytesWithCache(file, fileBytes, post, nil)
+}
+
+// NewPostOfBytesWithCache parses post as NewPostOfBytes,
+// markdown of post and its variants is rendered by mc
+func NewPostOfBytesWithCache(file string, fileBytes []byte, post *Post, mc *helper.MarkdownCache) (*Post, error) {
 	if len(fileBytes) < 3 {
 		return nil, fmt.Errorf("post content is too less")
 	}
 
 	if post == nil {
-		dataSlice := bytes.SplitN(fileBytes, postBlockSeparator, 3)
-		if len(dataSlice) != 3 {
-			return nil, fmt.Errorf("post need front-matter block and markdown block")
+		fm, err := parseFrontMatter(file, fileBytes)
+		if err != nil {
+			return nil, err
 		}
-
-		idx := getFirstBreakByte(dataSlice[1])
-		if idx == 0 {
-			return nil, fmt.Errorf("post need front-matter block and markdown block")
-		}
-
-		formatType := detectFormat(string(dataSlice[1][:idx]))
-		if formatType == 0 {
-			return nil, fmt.Errorf("post front-matter block is unrecognized")
-		}
-
 		post = new(Post)
-		if formatType == FormatTOML {
-			if err = toml.Unmarshal(dataSlice[1][idx:], post); err != nil {
+		if fm.Format == FormatTOML {
+			if err = fm.DecodeTOML(post); err != nil {
 				return nil, err
 			}
 		}
-		if formatType == FormatINI {
-			iniObj, err := ini.Load(dataSlice[1][idx:])
+		if fm.Format == FormatINI {
+			iniObj, err := fm.DecodeINI()
 			if err != nil {
 				return nil, err
 			}
 			section := iniObj.Section("DEFAULT")
 			if err = newPostFromIniSection(section, post); err != nil {
-				return nil, err
+				return nil, fm.error(err)
 			}
 		}
-		post.Bytes = bytes.Trim(dataSlice[2], "\n")
+		post.Bytes = bytes.Trim(fm.Body, "\n")
+		if err = post.checkVariantNames(); err != nil {
+			return nil, fm.error(err)
+		}
 	} else {
 		post.Bytes = bytes.Trim(fileBytes, "\n")
 	}
 	post.fileURL = file
+	post.markdown = mc
 	if post.Date == "" {
 		t, _ := com.FileMTime(file)
 		post.dateTime = time.Unix(t, 0)
@@ -214,19 +284,54 @@ func NewPostOfMarkdown(file string, post *Post) (*Post, error) {
 	return post, post.normalize()
 }
 
+// SetTimezone parses dates in time zone and updates permalink.
+// Dates without zone are in loc, file modified time and dates with zone are converted to loc
+func (p *Post) SetTimezone(loc *time.Location) error {
+	var err error
+	if p.Date != "" {
+		if p.dateTime, err = parseTimeStringIn(p.Date, loc); err != nil {
+			return err
+		}
+	} else {
+		p.dateTime = p.dateTime.In(loc)
+	}
+	if p.Update != "" {
+		if p.updateTime, err = parseTimeStringIn(p.Update, loc); err != nil {
+			return err
+		}
+	} else {
+		p.updateTime = p.updateTime.In(loc)
+	}
+	p.postURL = postPermaURL(p.dateTime, p.Slug)
+	return nil
+}
+
+// postPermaURL return permalink of post by date in its time zone
+func postPermaURL(t time.Time, slug string) string {
+	return fmt.Sprintf("/%d/%d/%d/%s.html", t.Year(), t.Month(), t.Day(), slug)
+}
+
 func parseTimeString(timeStr string) (time.Time, error) {
+	return parseTimeStringIn(timeStr, time.UTC)
+}
+
+// parseTimeStringIn parses time string without zone in loc
+func parseTimeStringIn(timeStr string, loc *time.Location) (time.Time, error) {
 	timeStr = strings.TrimSpace(timeStr)
 	if len(timeStr) == 0 {
 		return time.Time{}, errors.New("empty time string")
 	}
 	if len(timeStr) == len("2006-01-02") {
-		return time.Parse("2006-01-02", timeStr)
+		return time.ParseInLocation("2006-01-02", timeStr, loc)
 	}
 	if len(timeStr) == len("2006-01-02 15:04") {
-		return time.Parse("2006-01-02 15:04", timeStr)
+		return time.ParseInLocation("2006-01-02 15:04", timeStr, loc)
 	}
 	if len(timeStr) == len("2006-01-02 15:04:05") {
-		return time.Parse("2006-01-02 15:04:05", timeStr)
+		return time.ParseInLocation("2006-01-02 15:04:05", timeStr, loc)
+	}
+	if t, err := time.Parse(time.RFC3339, timeStr); err == nil {
+		return t.In(loc), nil
 	}
 	return time.Time{}, errors.New("unknown time string")
 }
@@ -68,10 +68,43 @@ func TestModelPostToml(t *testing.T) {
 			So(p.DestURL(), ShouldEqual, "dest/welcome.html")
 		})
 
+		Convey("PostGUID", func() {
+			So(p.GUID(), ShouldEqual, "")
+			p.UUID = "6BA7B810-9DAD-41D1-80B4-00C04FD430C8"
+			So(p.GUID(), ShouldEqual, "urn:uuid:6ba7b810-9dad-41d1-80b4-00c04fd430c8")
+		})
+
+		Convey("PostSetTimezone", func() {
+			loc := time.FixedZone("UTC+8", 8*3600)
+			So(p.SetTimezone(loc), ShouldBeNil)
+			So(p.URL(), ShouldEqual, "/2016/3/25/welcome.html")
+			So(p.Created().Location(), ShouldEqual, loc)
+
+			p.Date = "2016-03-25T20:20:20Z"
+			So(p.SetTimezone(loc), ShouldBeNil)
+			So(p.URL(), ShouldEqual, "/2016/3/26/welcome.html")
+		})
+
 		Convey("PostSetPlaceholder", func() {
 			p.SetPlaceholder(r, hr)
 			So(p.Thumb, ShouldEqual, "/media/golang.png")
 		})
+
+		Convey("PostReleaseContent", func() {
+			p.ReleaseContent()
+			So(string(p.Content()), ShouldEqual, string(p.BriefHTML()))
+		})
+	})
+}
+
+func TestModelPostCRLF(t *testing.T) {
+	Convey("ParseBOMAndCRLF", t, func() {
+		p, err := NewPostOfMarkdown("testdata/post/post_crlf.md", nil)
+		So(err, ShouldBeNil)
+		So(p.Title, ShouldEqual, "Windows")
+		So(p.Tags, ShouldHaveLength, 1)
+		So(string(p.Bytes), ShouldNotContainSubstring, "\r")
+		So(string(p.Brief()), ShouldContainSubstring, "edited on Windows")
 	})
 }
 
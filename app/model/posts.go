@@ -1,6 +1,10 @@
 package model
 
-import "github.com/go-xiaohei/pugo/app/helper"
+import (
+	"sort"
+
+	"github.com/go-xiaohei/pugo/app/helper"
+)
 
 // Posts are posts list
 type Posts []*Post
@@ -10,8 +14,17 @@ func (p Posts) Len() int {
 	return len(p)
 }
 
+// Less sorts posts by created time, newest first.
+// Posts created at the same second are sorted by title and file name,
+// so the order is stable in every building
 func (p Posts) Less(i, j int) bool {
-	return p[i].dateTime.Unix() > p[j].dateTime.Unix()
+	if ti, tj := p[i].dateTime.Unix(), p[j].dateTime.Unix(); ti != tj {
+		return ti > tj
+	}
+	if p[i].Title != p[j].Title {
+		return p[i].Title < p[j].Title
+	}
+	return p[i].fileURL < p[j].fileURL
 }
 func (p Posts) Swap(i, j int) {
 	p[i], p[j] = p[j], p[i]
@@ -33,6 +46,29 @@ func (p Posts) Range(i, j int) Posts {
 	return p[i : j+1]
 }
 
+// Popular get posts with page views, most viewed first
+func (p Posts) Popular() Posts {
+	var list Posts
+	for _, post := range p {
+		if post.Views > 0 {
+			list = append(list, post)
+		}
+	}
+	sort.Stable(postsByViews(list))
+	return list
+}
+
+type postsByViews Posts
+
+func (p postsByViews) Len() int { return len(p) }
+func (p postsByViews) Less(i, j int) bool {
+	if p[i].Views != p[j].Views {
+		return p[i].Views > p[j].Views
+	}
+	return Posts(p).Less(i, j)
+}
+func (p postsByViews) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+
 // TagPosts are list of posts belongs to a tag
 type TagPosts struct {
 	Posts
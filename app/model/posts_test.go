@@ -55,6 +55,21 @@ func TestPosts(t *testing.T) {
 		So(ps[1].Title, ShouldEqual, "abc")
 		So(ps[3].Slug, ShouldEqual, "uvw")
 
+		Convey("PostsSameTime", func() {
+			ps2 := Posts{
+				{Title: "b", Date: "2016-01-15 12:20", fileURL: "post/2.md"},
+				{Title: "b", Date: "2016-01-15 12:20", fileURL: "post/1.md"},
+				{Title: "a", Date: "2016-01-15 12:20", fileURL: "post/3.md"},
+			}
+			for _, p := range ps2 {
+				p.normalize()
+			}
+			sort.Sort(ps2)
+			So(ps2[0].fileURL, ShouldEqual, "post/3.md")
+			So(ps2[1].fileURL, ShouldEqual, "post/1.md")
+			So(ps2[2].fileURL, ShouldEqual, "post/2.md")
+		})
+
 		Convey("PostsTopN", func() {
 			ps2 := ps.TopN(2)
 			So(ps2, ShouldHaveLength, 2)
@@ -0,0 +1,112 @@
+package model
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	// ReactionDefaultFile is default data file of reactions in source directory
+	ReactionDefaultFile = "reactions.jsonl"
+	// ReactionEndpoint is url path to post reactions in serve mode
+	ReactionEndpoint = "/-/react"
+)
+
+type (
+	// Reaction is settings of post reactions, such as likes
+	Reaction struct {
+		// Kinds is allowed kinds of reactions, such as "like"
+		Kinds []string `toml:"kinds" ini:"kinds"`
+		// File is data file in source directory, reactions are appended to it as json lines
+		File string `toml:"file" ini:"file"`
+	}
+	// ReactionRecord is a reaction in data file
+	ReactionRecord struct {
+		Time time.Time `json:"time"`
+		URL  string    `json:"url"`
+		Kind string    `json:"kind"`
+	}
+	// ReactionManifest is static reactions data of a post,
+	// themes fetch it to show reactions without third-party services
+	ReactionManifest struct {
+		URL      string           `json:"url"`
+		Kinds    []string         `json:"kinds"`
+		Counts   map[string]int64 `json:"counts"`
+		Endpoint string           `json:"endpoint"`
+	}
+	// PostReactions is reactions count by url and kind
+	PostReactions map[string]map[string]int64
+)
+
+// IsOK return whether reactions are enabled
+func (r *Reaction) IsOK() bool {
+	return r != nil && len(r.Kinds) > 0
+}
+
+// IsKind return whether kind is allowed
+func (r *Reaction) IsKind(kind string) bool {
+	if r == nil {
+		return false
+	}
+	for _, k := range r.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// DataFile return data file name of reactions
+func (r *Reaction) DataFile() string {
+	if r == nil || r.File == "" {
+		return ReactionDefaultFile
+	}
+	return r.File
+}
+
+// NewPostReactions parse json lines of ReactionRecord,
+// invalid lines are skipped
+func NewPostReactions(r io.Reader) (PostReactions, error) {
+	reactions := make(PostReactions)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var record ReactionRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil || record.URL == "" || record.Kind == "" {
+			continue
+		}
+		reactions.Add(record.URL, record.Kind)
+	}
+	return reactions, scanner.Err()
+}
+
+// Add increases count of reaction kind of url
+func (pr PostReactions) Add(url, kind string) int64 {
+	key := ReactionKey(url)
+	if pr[key] == nil {
+		pr[key] = make(map[string]int64)
+	}
+	pr[key][kind]++
+	return pr[key][kind]
+}
+
+// Get return counts of kinds of url, kinds without reactions are zero
+func (pr PostReactions) Get(url string, kinds []string) map[string]int64 {
+	counts := make(map[string]int64, len(kinds))
+	for _, k := range kinds {
+		counts[k] = pr[ReactionKey(url)][k]
+	}
+	return counts
+}
+
+// ReactionKey return normalized url as key of PostReactions,
+// '/a/b.html', '/a/b/' and '/a/b' are same key
+func ReactionKey(url string) string {
+	return viewsKey(url)
+}
@@ -0,0 +1,37 @@
+package model
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPostReactions(t *testing.T) {
+	Convey("PostReactions", t, func() {
+		data := `{"time":"2016-03-25T10:00:00Z","url":"/2016/3/25/welcome.html","kind":"like"}
+{"time":"2016-03-25T10:01:00Z","url":"/2016/3/25/welcome","kind":"like"}
+not json
+{"time":"2016-03-25T10:02:00Z","url":"/2016/3/25/welcome.html","kind":"love"}
+{"time":"2016-03-25T10:03:00Z","url":"","kind":"like"}
+`
+		reactions, err := NewPostReactions(strings.NewReader(data))
+		So(err, ShouldBeNil)
+		counts := reactions.Get("/2016/3/25/welcome.html", []string{"like", "love", "wow"})
+		So(counts["like"], ShouldEqual, 2)
+		So(counts["love"], ShouldEqual, 1)
+		So(counts["wow"], ShouldEqual, 0)
+		So(reactions.Add("/2016/3/25/welcome/", "wow"), ShouldEqual, 1)
+	})
+
+	Convey("Reaction", t, func() {
+		var r *Reaction
+		So(r.IsOK(), ShouldBeFalse)
+		So(r.DataFile(), ShouldEqual, ReactionDefaultFile)
+		r = &Reaction{Kinds: []string{"like"}, File: "data/likes.jsonl"}
+		So(r.IsOK(), ShouldBeTrue)
+		So(r.IsKind("like"), ShouldBeTrue)
+		So(r.IsKind("love"), ShouldBeFalse)
+		So(r.DataFile(), ShouldEqual, "data/likes.jsonl")
+	})
+}
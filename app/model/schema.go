@@ -0,0 +1,199 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// JSONSchemaVersion is draft of generated JSON Schema
+const JSONSchemaVersion = "http://json-schema.org/draft-07/schema#"
+
+type (
+	// JSONSchema is JSON Schema of front-matter, for autocompletion and validation in editors
+	JSONSchema struct {
+		Schema      string                 `json:"$schema,omitempty"`
+		Title       string                 `json:"title,omitempty"`
+		Description string                 `json:"description,omitempty"`
+		Type        string                 `json:"type,omitempty"`
+		Pattern     string                 `json:"pattern,omitempty"`
+		Enum        []string               `json:"enum,omitempty"`
+		Examples    []string               `json:"examples,omitempty"`
+		Items       *JSONSchema            `json:"items,omitempty"`
+		Properties  map[string]*JSONSchema `json:"properties,omitempty"`
+		// AdditionalProperties is schema of other keys in object, or false to disallow them
+		AdditionalProperties interface{} `json:"additionalProperties,omitempty"`
+	}
+	// FrontMatterField is a custom field of front-matter declared by theme
+	FrontMatterField struct {
+		Name string `toml:"name"`
+		// Type is "string", "bool", "int", "float" or "array" of strings
+		Type string   `toml:"type"`
+		Desc string   `toml:"desc"`
+		Enum []string `toml:"enum"`
+	}
+)
+
+// frontMatterDesc are descriptions of front-matter fields by toml key
+var frontMatterDesc = map[string]string{
+	"title":       "title of the content",
+	"slug":        "link name in url",
+	"desc":        "description used in <meta name=\"description\">",
+	"date":        "creating date, such as \"2016-01-02 15:04\"",
+	"update_date": "updating date, creating date by default",
+	"author":      "name of author in meta file, site owner by default",
+	"thumb":       "thumbnail image, @media/ links to media directory",
+	"draft":       "skip the content when building",
+	"uuid":        "unique id used as guid in feed, keep it when changing slug",
+	"tags":        "tags of the post",
+	"license":     "content license, site license by default",
+	"syndicate":   "platforms to cross-post by 'pugo syndicate'",
+	"variant":     "A/B test variants by name",
+	"hover":       "navigation item to highlight",
+	"template":    "theme template to compile the page",
+	"lang":        "language of the page",
+	"meta":        "custom data for theme templates",
+	"sort":        "order in page lists",
+	"node":        "node page without content file",
+	"json":        "json data file for templates",
+	"noindex":     "exclude from sitemap, feed and json api, and add robots noindex meta",
+}
+
+// datePattern matches date formats of front-matter
+const datePattern = `^\d{4}-\d{2}-\d{2}([ T]\d{2}:\d{2}(:\d{2})?)?`
+
+// NewFrontMatterSchema return schema of front-matter by toml fields of v, such as *Post or *Page
+func NewFrontMatterSchema(v interface{}, title string) *JSONSchema {
+	s := structSchema(reflect.Indirect(reflect.ValueOf(v)).Type())
+	s.Schema = JSONSchemaVersion
+	s.Title = title
+	return s
+}
+
+func structSchema(t reflect.Type) *JSONSchema {
+	s := &JSONSchema{
+		Type:       "object",
+		Properties: make(map[string]*JSONSchema),
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("toml"), ",")[0]
+		if name == "" || name == "-" || f.PkgPath != "" {
+			continue
+		}
+		fs := typeSchema(f.Type)
+		if fs == nil {
+			continue
+		}
+		fs.Description = frontMatterDesc[name]
+		if name == "date" || name == "update_date" {
+			fs.Pattern = datePattern
+		}
+		s.Properties[name] = fs
+	}
+	return s
+}
+
+func typeSchema(t reflect.Type) *JSONSchema {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return &JSONSchema{Type: "string"}
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &JSONSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}
+	case reflect.Slice:
+		items := typeSchema(t.Elem())
+		if items == nil {
+			return nil
+		}
+		return &JSONSchema{Type: "array", Items: items}
+	case reflect.Map:
+		if t.Elem().Kind() == reflect.Interface {
+			return &JSONSchema{Type: "object", AdditionalProperties: true}
+		}
+		return &JSONSchema{Type: "object", AdditionalProperties: typeSchema(t.Elem())}
+	case reflect.Struct:
+		return structSchema(t)
+	}
+	return nil
+}
+
+// SetEnum sets values of string field or items of array field,
+// values are sorted and duplicates are removed
+func (s *JSONSchema) SetEnum(key string, values []string, strict bool) {
+	p := s.Properties[key]
+	if p == nil {
+		return
+	}
+	if p.Items != nil {
+		p = p.Items
+	}
+	values = uniqueSorted(values)
+	if len(values) == 0 {
+		return
+	}
+	if strict {
+		p.Enum = values
+		return
+	}
+	p.Examples = values
+}
+
+// AddFields adds custom fields in object of key, such as "meta" of page
+func (s *JSONSchema) AddFields(key string, fields []*FrontMatterField) {
+	p := s.Properties[key]
+	if p == nil || len(fields) == 0 {
+		return
+	}
+	if p.Properties == nil {
+		p.Properties = make(map[string]*JSONSchema)
+	}
+	for _, f := range fields {
+		fs := &JSONSchema{Type: "string", Description: f.Desc, Enum: f.Enum}
+		switch f.Type {
+		case "bool":
+			fs.Type = "boolean"
+		case "int":
+			fs.Type = "integer"
+		case "float":
+			fs.Type = "number"
+		case "array":
+			fs = &JSONSchema{Type: "array", Description: f.Desc, Items: &JSONSchema{Type: "string", Enum: f.Enum}}
+		}
+		p.Properties[f.Name] = fs
+	}
+}
+
+// JSON return indented json of schema, html characters in descriptions are not escaped
+func (s *JSONSchema) JSON() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(s); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func uniqueSorted(values []string) []string {
+	seen := make(map[string]bool)
+	var list []string
+	for _, v := range values {
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		list = append(list, v)
+	}
+	sort.Strings(list)
+	return list
+}
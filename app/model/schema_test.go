@@ -0,0 +1,50 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFrontMatterSchema(t *testing.T) {
+	Convey("FrontMatterSchema", t, func() {
+		s := NewFrontMatterSchema(new(Post), "post")
+		So(s.Schema, ShouldEqual, JSONSchemaVersion)
+		So(s.Properties["title"].Type, ShouldEqual, "string")
+		So(s.Properties["draft"].Type, ShouldEqual, "boolean")
+		So(s.Properties["tags"].Type, ShouldEqual, "array")
+		So(s.Properties["tags"].Items.Type, ShouldEqual, "string")
+		So(s.Properties["date"].Pattern, ShouldEqual, datePattern)
+		So(s.Properties["author"].Description, ShouldNotBeEmpty)
+		So(s.Properties, ShouldNotContainKey, "Tags")
+
+		s.SetEnum("tags", []string{"go", "", "blog", "go"}, false)
+		So(s.Properties["tags"].Items.Examples, ShouldResemble, []string{"blog", "go"})
+		s.SetEnum("author", []string{"pugo"}, true)
+		So(s.Properties["author"].Enum, ShouldResemble, []string{"pugo"})
+		s.SetEnum("none", []string{"a"}, true)
+
+		Convey("AddFields", func() {
+			s := NewFrontMatterSchema(new(Page), "page")
+			So(s.Properties["meta"].Type, ShouldEqual, "object")
+			So(s.Properties["sort"].Type, ShouldEqual, "integer")
+			s.AddFields("meta", []*FrontMatterField{
+				{Name: "banner", Desc: "banner image"},
+				{Name: "wide", Type: "bool"},
+				{Name: "colors", Type: "array", Enum: []string{"red", "blue"}},
+			})
+			meta := s.Properties["meta"].Properties
+			So(meta["banner"].Type, ShouldEqual, "string")
+			So(meta["banner"].Description, ShouldEqual, "banner image")
+			So(meta["wide"].Type, ShouldEqual, "boolean")
+			So(meta["colors"].Items.Enum, ShouldResemble, []string{"red", "blue"})
+
+			data, err := s.JSON()
+			So(err, ShouldBeNil)
+			var v map[string]interface{}
+			So(json.Unmarshal(data, &v), ShouldBeNil)
+			So(v["$schema"], ShouldEqual, JSONSchemaVersion)
+		})
+	})
+}
@@ -0,0 +1,78 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// platforms of syndication
+const (
+	SyndicationDevTo  = "devto"
+	SyndicationMedium = "medium"
+)
+
+// SyndicationDefaultFile is data file of syndicated post links in source directory
+const SyndicationDefaultFile = "syndication.json"
+
+type (
+	// Syndication is a platform to cross-post articles, such as dev.to or Medium
+	Syndication struct {
+		Name string `toml:"name" ini:"name"`
+		// Token is api key, it can be environment variable as "${DEVTO_TOKEN}"
+		Token string `toml:"token" ini:"token"`
+		// Publish publishes articles, or saves them as drafts
+		Publish bool `toml:"publish" ini:"publish"`
+	}
+	// SyndicationGroup is group of syndication platforms
+	SyndicationGroup []*Syndication
+	// PostSyndication is links of syndicated posts, by post url and platform name
+	PostSyndication map[string]map[string]string
+)
+
+// Get return syndication by name
+func (sg SyndicationGroup) Get(name string) *Syndication {
+	for _, s := range sg {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func (sg SyndicationGroup) normalize() error {
+	names := make(map[string]bool)
+	for _, s := range sg {
+		if s.Name != SyndicationDevTo && s.Name != SyndicationMedium {
+			return fmt.Errorf("syndication '%s' is unknown", s.Name)
+		}
+		if names[s.Name] {
+			return fmt.Errorf("syndication '%s' is duplicated", s.Name)
+		}
+		names[s.Name] = true
+	}
+	return nil
+}
+
+// NewPostSyndication parses links of syndicated posts from json
+func NewPostSyndication(r io.Reader) (PostSyndication, error) {
+	ps := make(PostSyndication)
+	if err := json.NewDecoder(r).Decode(&ps); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return ps, nil
+}
+
+// Get return links of post url by platform name
+func (ps PostSyndication) Get(url string) map[string]string {
+	return ps[viewsKey(url)]
+}
+
+// Set sets link of post url on platform
+func (ps PostSyndication) Set(url, name, link string) {
+	key := viewsKey(url)
+	if ps[key] == nil {
+		ps[key] = make(map[string]string)
+	}
+	ps[key][name] = link
+}
@@ -0,0 +1,116 @@
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// VariantBase is name of the base variant, the original page of a post
+	VariantBase = "a"
+)
+
+var (
+	variantBlock = regexp.MustCompile(`(?s){{%\s*variant\s+"([^"]+)"\s*%}}\n?(.*?){{%\s*/variant\s*%}}\n?`)
+	// variant names are used in file names and urls
+	variantName = regexp.MustCompile(`^[a-z0-9_-]+$`)
+)
+
+// PostVariant is alternative settings of a post for A/B testing
+type PostVariant struct {
+	Title    string `toml:"title" ini:"title"`
+	Desc     string `toml:"desc" ini:"desc"`
+	Template string `toml:"template" ini:"template"`
+}
+
+// VariantNames return names of all variants in the post, except base variant.
+// Names are declared in front-matter [variant.name] sections
+// or content {{% variant "name" %}} ... {{% /variant %}} blocks
+func (p *Post) VariantNames() []string {
+	names := make(map[string]bool)
+	for name := range p.VariantMeta {
+		names[name] = true
+	}
+	for _, m := range variantBlock.FindAllSubmatch(p.Bytes, -1) {
+		names[string(m[1])] = true
+	}
+	delete(names, VariantBase)
+	var list []string
+	for name := range names {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// checkVariantNames checks names of variants are safe in urls
+func (p *Post) checkVariantNames() error {
+	for _, name := range p.VariantNames() {
+		if !variantName.MatchString(name) {
+			return fmt.Errorf("invalid variant name '%s', only a-z, 0-9, '_' and '-' are allowed", name)
+		}
+	}
+	return nil
+}
+
+// NewVariant create a variant copy of the post by name.
+// It uses variant settings and content blocks, and appends name to post's url.
+func (p *Post) NewVariant(name string) *Post {
+	p2 := *p
+	p2.Variant = name
+	p2.variants = nil
+	if v := p.VariantMeta[name]; v != nil {
+		if v.Title != "" {
+			p2.Title = v.Title
+		}
+		if v.Desc != "" {
+			p2.Desc = v.Desc
+		}
+	}
+	raw := variantBytes(p.Bytes, name)
+	p2.contentBytes = p.markdown.Render(raw)
+	p2.briefBytes = p.markdown.Render(bytes.Split(raw, postBriefSeparator)[0])
+	p2.Index = newPostIndexs(bytes.NewReader(p2.contentBytes))
+	p2.postURL = variantURL(p.postURL, name)
+	p2.destURL = variantURL(p.destURL, name)
+	return &p2
+}
+
+// VariantTemplate return template name to compile the variant
+func (p *Post) VariantTemplate() string {
+	if v := p.VariantMeta[p.Variant]; v != nil && v.Template != "" {
+		return v.Template
+	}
+	return "post.html"
+}
+
+// AddVariant add a variant to the post
+func (p *Post) AddVariant(v *Post) {
+	p.variants = append(p.variants, v)
+}
+
+// Variants return all variants of the post, except itself
+func (p *Post) Variants() []*Post {
+	return p.variants
+}
+
+// variantBytes keeps content blocks of the variant and removes others
+func variantBytes(data []byte, name string) []byte {
+	return variantBlock.ReplaceAllFunc(data, func(raw []byte) []byte {
+		m := variantBlock.FindSubmatch(raw)
+		if string(m[1]) == name {
+			return m[2]
+		}
+		return nil
+	})
+}
+
+func variantURL(u, name string) string {
+	if u == "" {
+		return ""
+	}
+	return strings.TrimSuffix(u, ".html") + "." + name + ".html"
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPostVariant(t *testing.T) {
+	Convey("PostVariant", t, func() {
+		p, err := NewPostOfMarkdown("testdata/post/post_variant.md", nil)
+		So(err, ShouldBeNil)
+		So(p.Variant, ShouldEqual, VariantBase)
+		So(p.VariantNames(), ShouldResemble, []string{"b", "c"})
+		So(string(p.Content()), ShouldContainSubstring, "base paragraph")
+		So(string(p.Content()), ShouldNotContainSubstring, "paragraph b")
+		So(p.VariantTemplate(), ShouldEqual, "post.html")
+
+		Convey("NewVariant", func() {
+			p.SetDestURL("dest/2016/3/25/variant.html")
+			v := p.NewVariant("b")
+			So(v.Variant, ShouldEqual, "b")
+			So(v.Title, ShouldEqual, "Variant B")
+			So(v.URL(), ShouldEqual, "/2016/3/25/variant.b.html")
+			So(v.DestURL(), ShouldEqual, "dest/2016/3/25/variant.b.html")
+			So(v.VariantTemplate(), ShouldEqual, "post-b.html")
+			So(string(v.Content()), ShouldContainSubstring, "common paragraph")
+			So(string(v.Content()), ShouldContainSubstring, "paragraph b")
+			So(string(v.Content()), ShouldNotContainSubstring, "base paragraph")
+			So(string(v.Content()), ShouldNotContainSubstring, "paragraph c")
+
+			v2 := p.NewVariant("c")
+			So(v2.Title, ShouldEqual, "Variant")
+			So(v2.VariantTemplate(), ShouldEqual, "post.html")
+
+			p.AddVariant(v)
+			p.AddVariant(v2)
+			So(p.Variants(), ShouldHaveLength, 2)
+		})
+
+		Convey("Invalid Name", func() {
+			for _, data := range []string{
+				"```toml\ntitle = \"x\"\n[variant.\"../b\"]\ntitle = \"b\"\n```\n\ncontent",
+				"```toml\ntitle = \"x\"\n```\n\n{{% variant \"B C\" %}}\nb\n{{% /variant %}}\n",
+			} {
+				_, err := NewPostOfBytes("post.md", []byte(data), nil)
+				So(err, ShouldNotBeNil)
+				_, ok := err.(*FrontMatterError)
+				So(ok, ShouldBeTrue)
+				So(err.Error(), ShouldContainSubstring, "invalid variant name")
+			}
+		})
+	})
+}
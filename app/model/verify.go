@@ -0,0 +1,57 @@
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+)
+
+// Verify save site verification codes for search engine webmaster tools
+type Verify struct {
+	// codes for <meta> tags
+	Google string `toml:"google" ini:"google"`
+	Bing   string `toml:"bing" ini:"bing"`
+	Yandex string `toml:"yandex" ini:"yandex"`
+	Baidu  string `toml:"baidu" ini:"baidu"`
+
+	// codes for verification files
+	GoogleFile string `toml:"google_file" ini:"google_file"` // google{code}.html
+	BingFile   string `toml:"bing_file" ini:"bing_file"`     // BingSiteAuth.xml
+	YandexFile string `toml:"yandex_file" ini:"yandex_file"` // yandex_{code}.html
+}
+
+// MetaTags return <meta> tags of verification codes
+func (v *Verify) MetaTags() template.HTML {
+	var buf bytes.Buffer
+	tags := [][2]string{
+		{"google-site-verification", v.Google},
+		{"msvalidate.01", v.Bing},
+		{"yandex-verification", v.Yandex},
+		{"baidu-site-verification", v.Baidu},
+	}
+	for _, t := range tags {
+		if t[1] == "" {
+			continue
+		}
+		fmt.Fprintf(&buf, `<meta name="%s" content="%s"/>`, t[0], template.HTMLEscapeString(t[1]))
+	}
+	return template.HTML(buf.String())
+}
+
+// Files return verification files, filename to content
+func (v *Verify) Files() map[string][]byte {
+	files := make(map[string][]byte)
+	if v.GoogleFile != "" {
+		name := "google" + v.GoogleFile + ".html"
+		files[name] = []byte("google-site-verification: " + name)
+	}
+	if v.BingFile != "" {
+		files["BingSiteAuth.xml"] = []byte(`<?xml version="1.0"?>` + "\n" +
+			`<users><user>` + template.HTMLEscapeString(v.BingFile) + `</user></users>`)
+	}
+	if v.YandexFile != "" {
+		files["yandex_"+v.YandexFile+".html"] = []byte(`<html><head><meta http-equiv="Content-Type" content="text/html; charset=UTF-8"></head>` +
+			`<body>Verification: ` + template.HTMLEscapeString(v.YandexFile) + `</body></html>`)
+	}
+	return files
+}
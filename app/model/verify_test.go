@@ -0,0 +1,27 @@
+package model
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestVerify(t *testing.T) {
+	Convey("Verify", t, func() {
+		v := &Verify{
+			Google:     "abc",
+			Yandex:     "x\"y",
+			GoogleFile: "123",
+			BingFile:   "456",
+		}
+		tags := string(v.MetaTags())
+		So(tags, ShouldContainSubstring, `<meta name="google-site-verification" content="abc"/>`)
+		So(tags, ShouldContainSubstring, `<meta name="yandex-verification" content="x&#34;y"/>`)
+		So(tags, ShouldNotContainSubstring, "msvalidate.01")
+
+		files := v.Files()
+		So(files, ShouldHaveLength, 2)
+		So(string(files["google123.html"]), ShouldEqual, "google-site-verification: google123.html")
+		So(string(files["BingSiteAuth.xml"]), ShouldContainSubstring, "<user>456</user>")
+	})
+}
@@ -0,0 +1,348 @@
+package publish
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+const (
+	// PostPath is url path prefix to write new post, followed by site name
+	PostPath = "/post/"
+	// MicropubPath is url path prefix of micropub endpoint, followed by site name
+	MicropubPath = "/micropub/"
+
+	// maxPostSize is max size of post request, including photo
+	maxPostSize = 32 << 20
+)
+
+var (
+	errMicropubType   = errors.New("only h-entry is supported")
+	errMicropubAction = errors.New("action is not supported")
+	// errMicropubTokenTwice is returned if access token is in both header and body
+	errMicropubTokenTwice = errors.New("access token is in both header and body")
+)
+
+// bearerToken return token in bearer authorization header.
+// Tokens in query or body are not accepted for new posts and hooks, urls and bodies may be logged.
+func bearerToken(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	return ""
+}
+
+// micropubToken return access token of micropub request in bearer authorization header,
+// or access_token parameter in form encoded or multipart body as micropub allows.
+// Token in both places is an error, token in query is not accepted.
+func micropubToken(r *http.Request) (string, error) {
+	token := bearerToken(r)
+	if r.Method != "POST" || strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		return token, nil
+	}
+	if err := parsePostForm(r); err != nil {
+		return "", err
+	}
+	if body := r.PostForm.Get("access_token"); body != "" {
+		if token != "" {
+			return "", errMicropubTokenTwice
+		}
+		return body, nil
+	}
+	return token, nil
+}
+
+// isToken return whether token equals to want, empty tokens never match
+func isToken(token, want string) bool {
+	return token != "" && want != "" && subtle.ConstantTimeCompare([]byte(token), []byte(want)) == 1
+}
+
+// servePost accepts new post as json or form fields
+// title, slug, content, tags and photo, photo can be uploaded file or url
+func (s *Server) servePost(w http.ResponseWriter, r *http.Request, name string) {
+	site := s.sites[name]
+	if site == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != "POST" {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxPostSize)
+	if !isToken(bearerToken(r), site.APIToken) {
+		log15.Warn("Publish|%s|Post|invalid token from %s", name, r.RemoteAddr)
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	p := new(NewPost)
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var data struct {
+			Title   string   `json:"title"`
+			Slug    string   `json:"slug"`
+			Content string   `json:"content"`
+			Tags    []string `json:"tags"`
+			Photo   string   `json:"photo"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		p = &NewPost{Title: data.Title, Slug: data.Slug, Content: data.Content, Tags: data.Tags, Photo: data.Photo}
+	} else {
+		if err := parsePostForm(r); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		p.Title = r.FormValue("title")
+		p.Slug = r.FormValue("slug")
+		p.Content = r.FormValue("content")
+		p.Tags = formValues(r, "tags")
+		if err := formPhoto(r, "photo", p); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+	result, err := site.AddPost(p)
+	if err == errPostTitleContent {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log15.Error("Publish|%s|Post|%s", name, err.Error())
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	w.Header().Set("Location", result.URL)
+	writeJSON(w, http.StatusCreated, result)
+}
+
+// serveMicropub creates, updates and deletes posts by micropub requests.
+// Token is the api token of site, or IndieAuth token verified by token endpoint with scope of action.
+func (s *Server) serveMicropub(w http.ResponseWriter, r *http.Request, name string) {
+	site := s.sites[name]
+	if site == nil {
+		http.NotFound(w, r)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxPostSize)
+	token, err := micropubToken(r)
+	if err != nil {
+		micropubError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	if token == "" {
+		micropubError(w, http.StatusUnauthorized, "unauthorized", "access token is missing")
+		return
+	}
+	info, err := site.verifyToken(token)
+	if err != nil {
+		log15.Warn("Publish|%s|Micropub|%s|%s", name, r.RemoteAddr, err.Error())
+		micropubError(w, http.StatusForbidden, "forbidden", err.Error())
+		return
+	}
+	if r.Method == "GET" {
+		site.serveMicropubQuery(w, r)
+		return
+	}
+	if r.Method != "POST" {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	req, err := parseMicropub(r)
+	if err != nil {
+		micropubError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	scope := req.Action
+	if scope == "undelete" {
+		scope = "delete"
+	}
+	if !info.can(scope) {
+		micropubError(w, http.StatusForbidden, "insufficient_scope", "token need scope '"+scope+"'")
+		return
+	}
+	var result *PostResult
+	switch req.Action {
+	case "create":
+		result, err = site.AddPost(req.Post)
+	case "update":
+		result, err = site.UpdatePost(req.URL, req.Update)
+	case "delete", "undelete":
+		result, err = site.DeletePost(req.URL, req.Action == "delete")
+	default:
+		err = errMicropubAction
+	}
+	if err == errPostTitleContent || err == errPostNotFound || err == errMicropubAction {
+		micropubError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	if err != nil {
+		log15.Error("Publish|%s|Micropub|%s", name, err.Error())
+		micropubError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	log15.Info("Publish|%s|Micropub|%s|%s|%s", name, req.Action, info.ClientID, result.URL)
+	if req.Action == "create" {
+		w.Header().Set("Location", result.URL)
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serveMicropubQuery responds q=config and q=source queries
+func (s *Site) serveMicropubQuery(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Query().Get("q") {
+	case "config":
+		// no media endpoint and syndication targets
+		writeJSON(w, http.StatusOK, map[string]interface{}{})
+	case "source":
+		post, err := s.SourcePost(r.URL.Query().Get("url"))
+		if err != nil {
+			micropubError(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+		props := map[string]interface{}{
+			"name":      []string{post.Title},
+			"content":   []string{string(post.Bytes)},
+			"published": []string{post.Created().Format(time.RFC3339)},
+		}
+		if len(post.TagString) > 0 {
+			props["category"] = post.TagString
+		}
+		if post.Desc != "" {
+			props["summary"] = []string{post.Desc}
+		}
+		if post.Thumb != "" {
+			props["photo"] = []string{post.Thumb}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"type":       []string{"h-entry"},
+			"properties": props,
+		})
+	default:
+		micropubError(w, http.StatusBadRequest, "invalid_request", "query is not supported")
+	}
+}
+
+// micropubRequest is action of micropub request,
+// Post is new post to create, Update is properties to update post in URL
+type micropubRequest struct {
+	Action string
+	URL    string
+	Post   *NewPost
+	Update *PostUpdate
+}
+
+// parseMicropub parses micropub request in form encoded, multipart or json format
+func parseMicropub(r *http.Request) (*micropubRequest, error) {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var data struct {
+			PostUpdate
+			Type       []string                 `json:"type"`
+			Properties map[string][]interface{} `json:"properties"`
+			Action     string                   `json:"action"`
+			URL        string                   `json:"url"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			return nil, err
+		}
+		if data.Action != "" {
+			return &micropubRequest{Action: data.Action, URL: data.URL, Update: &data.PostUpdate}, nil
+		}
+		if len(data.Type) == 0 || data.Type[0] != "h-entry" {
+			return nil, errMicropubType
+		}
+		p := &NewPost{
+			Title:   propertyString(data.Properties["name"]),
+			Slug:    propertyString(data.Properties["mp-slug"]),
+			Content: propertyString(data.Properties["content"]),
+			Photo:   propertyString(data.Properties["photo"]),
+			Tags:    propertyStrings(data.Properties["category"]),
+		}
+		return &micropubRequest{Action: "create", Post: p}, nil
+	}
+	if err := parsePostForm(r); err != nil {
+		return nil, err
+	}
+	if action := r.FormValue("action"); action != "" {
+		if action == "update" {
+			// updating need json request
+			return nil, errMicropubAction
+		}
+		return &micropubRequest{Action: action, URL: r.FormValue("url")}, nil
+	}
+	if h := r.FormValue("h"); h != "" && h != "entry" {
+		return nil, errMicropubType
+	}
+	p := &NewPost{
+		Title:   r.FormValue("name"),
+		Slug:    r.FormValue("mp-slug"),
+		Content: r.FormValue("content"),
+		Tags:    formValues(r, "category"),
+	}
+	return &micropubRequest{Action: "create", Post: p}, formPhoto(r, "photo", p)
+}
+
+func micropubError(w http.ResponseWriter, status int, code, desc string) {
+	writeJSON(w, status, map[string]string{"error": code, "error_description": desc})
+}
+
+// parsePostForm parses form encoded or multipart body
+func parsePostForm(r *http.Request) error {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		return r.ParseMultipartForm(maxPostSize)
+	}
+	return r.ParseForm()
+}
+
+// formValues return values of field, such as "tags=a,b", "tags=a&tags=b" or "tags[]=a&tags[]=b"
+func formValues(r *http.Request, key string) []string {
+	var values []string
+	for _, v := range append(r.Form[key], r.Form[key+"[]"]...) {
+		for _, str := range strings.Split(v, ",") {
+			if str = strings.TrimSpace(str); str != "" {
+				values = append(values, str)
+			}
+		}
+	}
+	return values
+}
+
+// formPhoto reads uploaded photo file or photo url in form field
+func formPhoto(r *http.Request, key string, p *NewPost) error {
+	if r.MultipartForm != nil {
+		for _, k := range []string{key, key + "[]"} {
+			if fhs := r.MultipartForm.File[k]; len(fhs) > 0 {
+				return readPhoto(fhs[0], p)
+			}
+		}
+	}
+	if photo := r.FormValue(key); photo != "" {
+		p.Photo = photo
+		return nil
+	}
+	p.Photo = r.FormValue(key + "[]")
+	return nil
+}
+
+func readPhoto(fh *multipart.FileHeader, p *NewPost) error {
+	f, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if p.PhotoData, err = ioutil.ReadAll(f); err != nil {
+		return err
+	}
+	p.Photo = fh.Filename
+	return nil
+}
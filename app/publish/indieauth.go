@@ -0,0 +1,89 @@
+package publish
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	errTokenInvalid = errors.New("access token is invalid")
+	errTokenMe      = errors.New("access token is not issued for this site")
+
+	tokenClient = &http.Client{Timeout: 10 * time.Second}
+)
+
+// tokenInfo is verified IndieAuth token
+type tokenInfo struct {
+	Me       string `json:"me"`
+	ClientID string `json:"client_id"`
+	Scope    string `json:"scope"`
+}
+
+// can return whether token has scope,
+// "post" is legacy scope of "create"
+func (t *tokenInfo) can(scope string) bool {
+	for _, s := range strings.Fields(t.Scope) {
+		if s == scope || (scope == "create" && s == "post") {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyToken verifies token of micropub request.
+// The api token of site has all scopes, other tokens are verified by token endpoint of IndieAuth,
+// the token should be issued for me url of the site.
+func (s *Site) verifyToken(token string) (*tokenInfo, error) {
+	if isToken(token, s.APIToken) {
+		return &tokenInfo{Me: s.Me, Scope: "create update delete"}, nil
+	}
+	if s.TokenEndpoint == "" {
+		return nil, errTokenInvalid
+	}
+	req, err := http.NewRequest("GET", s.TokenEndpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	resp, err := tokenClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errTokenInvalid
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	info := new(tokenInfo)
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		if err = json.Unmarshal(data, info); err != nil {
+			return nil, err
+		}
+	} else {
+		// old endpoints respond form encoded values
+		values, err := url.ParseQuery(string(data))
+		if err != nil {
+			return nil, err
+		}
+		info.Me = values.Get("me")
+		info.ClientID = values.Get("client_id")
+		info.Scope = values.Get("scope")
+	}
+	if normalizeMe(info.Me) != normalizeMe(s.Me) {
+		return nil, errTokenMe
+	}
+	return info, nil
+}
+
+func normalizeMe(me string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(me)), "/")
+}
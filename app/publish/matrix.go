@@ -0,0 +1,224 @@
+package publish
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/go-xiaohei/pugo/app/builder"
+	"github.com/urfave/cli"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+type (
+	// Matrix is builds of site and targets to deploy them
+	Matrix struct {
+		Builds  []*MatrixBuild `toml:"build"`
+		Targets []*Target      `toml:"target"`
+	}
+	// MatrixBuild is a build variant of site
+	MatrixBuild struct {
+		Name   string `toml:"name"`
+		Source string `toml:"source"`
+		Theme  string `toml:"theme"`
+		Dest   string `toml:"dest"`
+		// Root overrides root url in meta, such as onion address
+		Root  string `toml:"root"`
+		Draft bool   `toml:"draft"`
+		// Legacy builds for old browsers
+		Legacy bool `toml:"legacy"`
+	}
+	// Target deploys output of a build
+	Target struct {
+		Name   string  `toml:"name"`
+		Build  string  `toml:"build"`
+		Deploy *Deploy `toml:"deploy"`
+		// After are shell commands run after deploying, such as invalidating CDN cache
+		After []string `toml:"after"`
+	}
+	// MatrixResult is result of a build or target in matrix
+	MatrixResult struct {
+		Kind     string
+		Name     string
+		Err      error
+		Skipped  bool
+		Duration time.Duration
+	}
+)
+
+// LoadMatrix loads builds and targets from toml file,
+// relative paths are in the directory of file
+func LoadMatrix(file string) (*Matrix, error) {
+	m := new(Matrix)
+	if _, err := toml.DecodeFile(file, m); err != nil {
+		return nil, err
+	}
+	if len(m.Builds) == 0 {
+		return nil, fmt.Errorf("no build in '%s'", file)
+	}
+	dir := filepath.Dir(file)
+	builds := make(map[string]bool)
+	for _, b := range m.Builds {
+		if !siteNameRegex.MatchString(b.Name) {
+			return nil, fmt.Errorf("build name '%s' is invalid", b.Name)
+		}
+		if builds[b.Name] {
+			return nil, fmt.Errorf("build '%s' is duplicated", b.Name)
+		}
+		builds[b.Name] = true
+		if b.Dest == "" {
+			return nil, fmt.Errorf("build '%s' need dest", b.Name)
+		}
+		if b.Source == "" {
+			b.Source = "source"
+		}
+		if b.Theme == "" {
+			b.Theme = "source/theme/default"
+		}
+		b.Source, b.Theme, b.Dest = inDir(dir, b.Source), inDir(dir, b.Theme), inDir(dir, b.Dest)
+	}
+	targets := make(map[string]bool)
+	for _, t := range m.Targets {
+		if !siteNameRegex.MatchString(t.Name) {
+			return nil, fmt.Errorf("target name '%s' is invalid", t.Name)
+		}
+		if targets[t.Name] {
+			return nil, fmt.Errorf("target '%s' is duplicated", t.Name)
+		}
+		targets[t.Name] = true
+		if !builds[t.Build] {
+			return nil, fmt.Errorf("target '%s' need build in matrix, but '%s' is not found", t.Name, t.Build)
+		}
+		if t.Deploy == nil || t.Deploy.Method == "" {
+			return nil, fmt.Errorf("target '%s' need deploy method", t.Name)
+		}
+		t.Deploy.AuditRules = inDir(dir, t.Deploy.AuditRules)
+	}
+	return m, nil
+}
+
+// Run builds and deploys targets of names, or all targets if names are empty.
+// Builds that no selected target uses are skipped, unless names are empty.
+// Targets of failed build are skipped, a failed target does not stop others
+func (m *Matrix) Run(names []string) ([]*MatrixResult, error) {
+	targets := m.Targets
+	if len(names) > 0 {
+		targets = nil
+		for _, name := range names {
+			t := m.target(name)
+			if t == nil {
+				return nil, fmt.Errorf("target '%s' is not found", name)
+			}
+			targets = append(targets, t)
+		}
+	}
+	used := make(map[string]bool)
+	for _, t := range targets {
+		used[t.Build] = true
+	}
+
+	var results []*MatrixResult
+	failed := make(map[string]bool)
+	for _, b := range m.Builds {
+		if len(names) > 0 && !used[b.Name] {
+			continue
+		}
+		t := time.Now()
+		log15.Info("Publish|Build|%s", b.Name)
+		err := b.run()
+		if err != nil {
+			failed[b.Name] = true
+			log15.Error("Publish|Build|%s|%s", b.Name, err.Error())
+		}
+		results = append(results, &MatrixResult{Kind: "build", Name: b.Name, Err: err, Duration: time.Since(t)})
+	}
+	for _, target := range targets {
+		if failed[target.Build] {
+			results = append(results, &MatrixResult{Kind: "target", Name: target.Name, Skipped: true})
+			continue
+		}
+		t := time.Now()
+		log15.Info("Publish|Deploy|%s|%s", target.Name, target.Deploy.Method)
+		err := target.run(m.build(target.Build))
+		if err != nil {
+			log15.Error("Publish|Deploy|%s|%s", target.Name, err.Error())
+		}
+		results = append(results, &MatrixResult{Kind: "target", Name: target.Name, Err: err, Duration: time.Since(t)})
+	}
+	return results, nil
+}
+
+func (m *Matrix) build(name string) *MatrixBuild {
+	for _, b := range m.Builds {
+		if b.Name == name {
+			return b
+		}
+	}
+	return nil
+}
+
+func (m *Matrix) target(name string) *Target {
+	for _, t := range m.Targets {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+func (b *MatrixBuild) run() (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("panic: %v", e)
+		}
+	}()
+	handlers := []builder.Handler{builder.ReadSource}
+	if b.Root != "" {
+		handlers = append(handlers, func(ctx *builder.Context) {
+			ctx.Err = ctx.Source.Meta.SetRoot(b.Root)
+		})
+	}
+	handlers = append(handlers, builder.ReadTheme, builder.AssembleSource, builder.Compile, builder.Sync)
+	ctx := builder.NewContext(&cli.Context{}, b.Source, b.Dest, b.Theme)
+	ctx.Draft = b.Draft
+	ctx.Legacy = b.Legacy
+	return builder.New(handlers...).Try(ctx)
+}
+
+func (t *Target) run(b *MatrixBuild) error {
+	if err := t.Deploy.Do(b.Dest, log15.Root()); err != nil {
+		return err
+	}
+	for _, command := range t.After {
+		log15.Info("Publish|Deploy|%s|After|%s", t.Name, command)
+		cmd := shellCommand(command)
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("after deploying, '%s': %s", command, err.Error())
+		}
+	}
+	return nil
+}
+
+func shellCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("sh", "-c", command)
+}
+
+// String return readable status of result
+func (r *MatrixResult) String() string {
+	switch {
+	case r.Skipped:
+		return fmt.Sprintf("%-6s %-20s skipped", r.Kind, r.Name)
+	case r.Err != nil:
+		return fmt.Sprintf("%-6s %-20s failed  %s", r.Kind, r.Name, strings.Replace(r.Err.Error(), "\n", " ", -1))
+	}
+	return fmt.Sprintf("%-6s %-20s ok      %.1fs", r.Kind, r.Name, r.Duration.Seconds())
+}
@@ -0,0 +1,91 @@
+package publish
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const testMatrix = `
+[[build]]
+name = "production"
+source = "{source}"
+theme = "{source}/theme/default"
+dest = "production"
+
+[[build]]
+name = "onion"
+source = "{source}"
+theme = "{source}/theme/default"
+dest = "onion"
+root = "http://example.onion/"
+
+[[build]]
+name = "broken"
+source = "{source}"
+theme = "not-exist"
+dest = "broken"
+
+[[target]]
+name = "unknown"
+build = "onion"
+[target.deploy]
+method = "not-exist"
+
+[[target]]
+name = "skipped"
+build = "broken"
+[target.deploy]
+method = "git"
+`
+
+func TestMatrix(t *testing.T) {
+	Convey("Publish Matrix", t, func() {
+		dir, err := ioutil.TempDir("", "pugo-matrix")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		file := filepath.Join(dir, "publish.toml")
+
+		So(ioutil.WriteFile(file, []byte("[[build]]\nname = \"a\"\n"), 0644), ShouldBeNil)
+		_, err = LoadMatrix(file)
+		So(err, ShouldNotBeNil)
+		So(ioutil.WriteFile(file, []byte("[[build]]\nname = \"a\"\ndest = \"a\"\n[[target]]\nname = \"t\"\nbuild = \"b\"\n"), 0644), ShouldBeNil)
+		_, err = LoadMatrix(file)
+		So(err, ShouldNotBeNil)
+
+		// source is out of the directory of file
+		abs, _ := filepath.Abs("../../source")
+		So(ioutil.WriteFile(file, []byte(strings.Replace(testMatrix, "{source}", filepath.ToSlash(abs), -1)), 0644), ShouldBeNil)
+		m, err := LoadMatrix(file)
+		So(err, ShouldBeNil)
+		So(m.Builds, ShouldHaveLength, 3)
+		So(m.Builds[0].Dest, ShouldEqual, filepath.Join(dir, "production"))
+		So(m.Builds[2].Theme, ShouldEqual, filepath.Join(dir, "not-exist"))
+
+		_, err = m.Run([]string{"not-exist"})
+		So(err, ShouldNotBeNil)
+
+		results, err := m.Run([]string{"unknown"})
+		So(err, ShouldBeNil)
+		So(results, ShouldHaveLength, 2)
+		So(results[0].Name, ShouldEqual, "onion")
+		So(results[0].Err, ShouldBeNil)
+		So(results[1].Err, ShouldNotBeNil)
+		data, err := ioutil.ReadFile(filepath.Join(dir, "onion", "index.html"))
+		So(err, ShouldBeNil)
+		So(string(data), ShouldContainSubstring, "http://example.onion/")
+
+		results, err = m.Run(nil)
+		So(err, ShouldBeNil)
+		So(results, ShouldHaveLength, 5)
+		So(results[2].Name, ShouldEqual, "broken")
+		So(results[2].Err, ShouldNotBeNil)
+		So(results[4].Name, ShouldEqual, "skipped")
+		So(results[4].Skipped, ShouldBeTrue)
+		So(results[4].String(), ShouldContainSubstring, "skipped")
+	})
+}
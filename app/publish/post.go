@@ -0,0 +1,483 @@
+package publish
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/Unknwon/com"
+	"github.com/go-xiaohei/pugo/app/builder"
+	"github.com/go-xiaohei/pugo/app/helper"
+	"github.com/go-xiaohei/pugo/app/model"
+	"github.com/urfave/cli"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+var (
+	postSlugRegex = regexp.MustCompile(`[^a-z0-9]+`)
+
+	errPostTitleContent = errors.New("post need title or content")
+	errPostNotFound     = errors.New("post is not found")
+)
+
+type (
+	// NewPost is a new post written by api
+	NewPost struct {
+		Title   string
+		Slug    string
+		Content string
+		Tags    []string
+		// Photo is url of photo, or file name of PhotoData
+		Photo     string
+		PhotoData []byte
+	}
+	// PostResult is result of new post
+	PostResult struct {
+		URL   string   `json:"url"`
+		Files []string `json:"files"`
+	}
+)
+
+// slug return slug of post by title, or time if title has no ascii letters
+func (p *NewPost) slug(t time.Time) string {
+	slug := strings.Trim(postSlugRegex.ReplaceAllString(strings.ToLower(p.Slug), "-"), "-")
+	if slug == "" {
+		slug = strings.Trim(postSlugRegex.ReplaceAllString(strings.ToLower(p.Title), "-"), "-")
+	}
+	if slug == "" {
+		slug = t.Format("01-02-15-04-05")
+	}
+	return slug
+}
+
+// AddPost writes new post to source directory of site,
+// commits it to git repository, and queues publishing.
+// Uploaded photo is saved in media directory.
+func (s *Site) AddPost(p *NewPost) (*PostResult, error) {
+	if strings.TrimSpace(p.Title) == "" && strings.TrimSpace(p.Content) == "" {
+		return nil, errPostTitleContent
+	}
+	s.gitLock.Lock()
+	defer s.gitLock.Unlock()
+
+	ctx, err := s.metaContext()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().In(ctx.Source.Meta.Location())
+	slug := p.slug(now)
+	year := fmt.Sprintf("%d", now.Year())
+	file := filepath.Join(ctx.SrcPostDir(), year, slug+".md")
+	if com.IsFile(file) {
+		slug += now.Format("-150405")
+		file = filepath.Join(ctx.SrcPostDir(), year, slug+".md")
+	}
+
+	uuid, err := helper.NewUUID()
+	if err != nil {
+		return nil, err
+	}
+	post := &model.Post{
+		UUID:      uuid,
+		Title:     p.Title,
+		Slug:      slug,
+		Date:      now.Format("2006-01-02 15:04:05"),
+		Update:    now.Format("2006-01-02 15:04:05"),
+		TagString: p.Tags,
+	}
+	if post.Title == "" {
+		post.Title = postBrief(p.Content)
+	}
+	content := p.Content
+	files := []string{file}
+	if p.Photo != "" {
+		photo := p.Photo
+		if len(p.PhotoData) > 0 {
+			name := path.Base(filepath.ToSlash(p.Photo))
+			photoFile := filepath.Join(ctx.SrcMediaDir(), year, slug+"-"+name)
+			os.MkdirAll(filepath.Dir(photoFile), os.ModePerm)
+			if err = ioutil.WriteFile(photoFile, p.PhotoData, os.ModePerm); err != nil {
+				return nil, err
+			}
+			files = append(files, photoFile)
+			photo = "@media/" + year + "/" + slug + "-" + name
+		}
+		post.Thumb = photo
+		content = fmt.Sprintf("![%s](%s)\n\n%s", post.Title, photo, content)
+	}
+
+	if err = writePost(file, post, content); err != nil {
+		return nil, err
+	}
+	log15.Info("Publish|%s|Post|%s", s.Name, file)
+
+	if post, err = model.NewPostOfMarkdown(file, nil); err != nil {
+		return nil, err
+	}
+	if err = post.SetTimezone(ctx.Source.Meta.Location()); err != nil {
+		return nil, err
+	}
+	if err = s.commit(files, "Add post "+post.Title); err != nil {
+		return nil, err
+	}
+	s.Trigger()
+	return &PostResult{
+		URL:   strings.TrimSuffix(ctx.Source.Meta.Root, "/") + post.URL(),
+		Files: files,
+	}, nil
+}
+
+// metaContext return context of site with meta only,
+// it's enough for directories, time zone and root url
+func (s *Site) metaContext() (*builder.Context, error) {
+	metaAll, err := builder.ReadSecondMeta(s.Source)
+	if err != nil {
+		return nil, err
+	}
+	ctx := builder.NewContext(&cli.Context{}, s.Source, s.Dest, s.Theme)
+	ctx.Source = builder.NewSource(metaAll)
+	return ctx, nil
+}
+
+// writePost writes post with toml front matter and markdown content to file
+func writePost(file string, post *model.Post, content string) error {
+	var buf bytes.Buffer
+	buf.WriteString("```toml\n")
+	if err := toml.NewEncoder(&buf).Encode(post); err != nil {
+		return err
+	}
+	buf.WriteString("```\n\n")
+	buf.WriteString(content)
+	buf.WriteString("\n")
+	os.MkdirAll(filepath.Dir(file), os.ModePerm)
+	return ioutil.WriteFile(file, buf.Bytes(), os.ModePerm)
+}
+
+// postBrief return first line of content as title, at most 50 characters
+func postBrief(content string) string {
+	line := strings.TrimSpace(strings.SplitN(strings.TrimSpace(content), "\n", 2)[0])
+	if r := []rune(line); len(r) > 50 {
+		line = string(r[:50]) + "..."
+	}
+	return line
+}
+
+// commit adds files to git repository of site and pushes,
+// it does nothing if site has no repository
+func (s *Site) commit(files []string, message string) error {
+	if s.Repo == "" || !com.IsDir(filepath.Join(s.Repo, ".git")) {
+		return nil
+	}
+	git := func(args ...string) (string, error) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = s.Repo
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(string(out)))
+		}
+		return strings.TrimSpace(string(out)), nil
+	}
+	if _, err := git(append([]string{"add", "--"}, files...)...); err != nil {
+		return err
+	}
+	if _, err := git("commit", "-m", message); err != nil {
+		return err
+	}
+	ref := "HEAD"
+	if s.Branch != "" {
+		ref = "HEAD:" + s.Branch
+	}
+	if out, err := git("push", "origin", ref); err != nil {
+		// the commit is kept, and pushed with next post
+		log15.Warn("Publish|%s|Push|%s", s.Name, err.Error())
+	} else {
+		log15.Debug("Publish|%s|Push|%s", s.Name, out)
+	}
+	return nil
+}
+
+// PostUpdate is micropub update of post properties,
+// Delete is list of property names, or values of properties to remove
+type PostUpdate struct {
+	Replace map[string][]interface{} `json:"replace"`
+	Add     map[string][]interface{} `json:"add"`
+	Delete  interface{}              `json:"delete"`
+}
+
+// UpdatePost updates properties of post in url, commits it and queues publishing
+func (s *Site) UpdatePost(rawURL string, u *PostUpdate) (*PostResult, error) {
+	s.gitLock.Lock()
+	defer s.gitLock.Unlock()
+	ctx, err := s.metaContext()
+	if err != nil {
+		return nil, err
+	}
+	post, err := findPost(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	old := copyPost(post)
+	content := string(post.Bytes)
+	for key, values := range u.Replace {
+		if err = setPostProperty(post, &content, key, values, false); err != nil {
+			return nil, err
+		}
+	}
+	for key, values := range u.Add {
+		if err = setPostProperty(post, &content, key, values, true); err != nil {
+			return nil, err
+		}
+	}
+	switch del := u.Delete.(type) {
+	case []interface{}:
+		for _, key := range del {
+			if name, ok := key.(string); ok {
+				if err = setPostProperty(post, &content, name, nil, false); err != nil {
+					return nil, err
+				}
+			}
+		}
+	case map[string]interface{}:
+		for key, values := range del {
+			list, _ := values.([]interface{})
+			if err = removePostProperty(post, key, list); err != nil {
+				return nil, err
+			}
+		}
+	}
+	post.Update = time.Now().In(ctx.Source.Meta.Location()).Format("2006-01-02 15:04:05")
+	return s.savePost(ctx, old, post, content, "Update post "+post.Title)
+}
+
+// DeletePost marks post in url as draft so it's not published, or publishes it again
+func (s *Site) DeletePost(rawURL string, deleted bool) (*PostResult, error) {
+	s.gitLock.Lock()
+	defer s.gitLock.Unlock()
+	ctx, err := s.metaContext()
+	if err != nil {
+		return nil, err
+	}
+	post, err := findPost(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	old := copyPost(post)
+	post.Draft = deleted
+	message := "Delete post " + post.Title
+	if !deleted {
+		message = "Undelete post " + post.Title
+	}
+	return s.savePost(ctx, old, post, string(post.Bytes), message)
+}
+
+// SourcePost return post in url
+func (s *Site) SourcePost(rawURL string) (*model.Post, error) {
+	ctx, err := s.metaContext()
+	if err != nil {
+		return nil, err
+	}
+	return findPost(ctx, rawURL)
+}
+
+// savePost writes changes from old to post into source file of post,
+// only changed keys of front-matter are edited so its format and other keys are kept
+func (s *Site) savePost(ctx *builder.Context, old, post *model.Post, content, message string) (*PostResult, error) {
+	file := post.SourceURL()
+	fi, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = model.EditFrontMatter(file, data, postChanges(old, post)); err != nil {
+		return nil, err
+	}
+	if content != string(old.Bytes) {
+		if data, err = model.ReplaceBody(file, data, []byte(content)); err != nil {
+			return nil, err
+		}
+	}
+	if err = ioutil.WriteFile(file, data, os.ModePerm); err != nil {
+		return nil, err
+	}
+	// permalink of post without date is from modified time of file, keep it
+	if post.Date == "" {
+		if err = os.Chtimes(file, fi.ModTime(), fi.ModTime()); err != nil {
+			return nil, err
+		}
+	}
+	log15.Info("Publish|%s|Post|%s", s.Name, message)
+	if err = s.commit([]string{file}, message); err != nil {
+		return nil, err
+	}
+	s.Trigger()
+	return &PostResult{
+		URL:   strings.TrimSuffix(ctx.Source.Meta.Root, "/") + post.URL(),
+		Files: []string{file},
+	}, nil
+}
+
+// copyPost return copy of post front-matter values to compare with changes
+func copyPost(post *model.Post) *model.Post {
+	old := *post
+	old.TagString = append([]string(nil), post.TagString...)
+	return &old
+}
+
+// postChanges return front-matter keys and values of post that are changed from old
+func postChanges(old, post *model.Post) map[string]interface{} {
+	values := make(map[string]interface{})
+	if post.Title != old.Title {
+		values["title"] = post.Title
+	}
+	if post.Desc != old.Desc {
+		values["desc"] = post.Desc
+	}
+	if post.Thumb != old.Thumb {
+		values["thumb"] = post.Thumb
+	}
+	if strings.Join(post.TagString, ",") != strings.Join(old.TagString, ",") {
+		values["tags"] = post.TagString
+	}
+	if post.Draft != old.Draft {
+		values["draft"] = post.Draft
+	}
+	if post.Update != old.Update {
+		values["update_date"] = post.Update
+	}
+	return values
+}
+
+// findPost return post of url in post directory, including drafts
+func findPost(ctx *builder.Context, rawURL string) (*model.Post, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	root, err := url.Parse(ctx.Source.Meta.Root)
+	if err != nil {
+		return nil, err
+	}
+	prefix := strings.TrimSuffix(root.Path, "/")
+	var found *model.Post
+	err = filepath.Walk(ctx.SrcPostDir(), func(p string, fi os.FileInfo, err error) error {
+		if err != nil || found != nil {
+			return err
+		}
+		if fi.IsDir() || filepath.Ext(p) != ".md" {
+			return nil
+		}
+		post, err := model.NewPostOfMarkdown(p, nil)
+		if err != nil {
+			log15.Warn("Publish|Post|%s|%s", p, err.Error())
+			return nil
+		}
+		if err = post.SetTimezone(ctx.Source.Meta.Location()); err != nil {
+			return nil
+		}
+		if prefix+post.URL() == u.Path {
+			found = post
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, errPostNotFound
+	}
+	return found, nil
+}
+
+// setPostProperty sets micropub property of post, nil values clear the property,
+// values of category are appended if add is true
+func setPostProperty(post *model.Post, content *string, key string, values []interface{}, add bool) error {
+	switch key {
+	case "name":
+		post.Title = propertyString(values)
+	case "content":
+		*content = propertyString(values)
+	case "summary":
+		post.Desc = propertyString(values)
+	case "photo":
+		post.Thumb = propertyString(values)
+	case "category":
+		if !add {
+			post.TagString = nil
+		}
+		for _, v := range propertyStrings(values) {
+			if !hasString(post.TagString, v) {
+				post.TagString = append(post.TagString, v)
+			}
+		}
+	default:
+		return fmt.Errorf("property '%s' is not supported", key)
+	}
+	return nil
+}
+
+// removePostProperty removes values of multiple value property
+func removePostProperty(post *model.Post, key string, values []interface{}) error {
+	if key != "category" {
+		return fmt.Errorf("property '%s' can't remove values", key)
+	}
+	remove := propertyStrings(values)
+	tags := post.TagString[:0]
+	for _, t := range post.TagString {
+		if !hasString(remove, t) {
+			tags = append(tags, t)
+		}
+	}
+	post.TagString = tags
+	return nil
+}
+
+// propertyString return string value of micropub property,
+// html content {"html": "..."} is used as markdown
+func propertyString(values []interface{}) string {
+	if len(values) == 0 {
+		return ""
+	}
+	switch v := values[0].(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		for _, key := range []string{"html", "value", "url"} {
+			if str, ok := v[key].(string); ok {
+				return str
+			}
+		}
+	}
+	return ""
+}
+
+func propertyStrings(values []interface{}) []string {
+	var list []string
+	for _, v := range values {
+		if str, ok := v.(string); ok {
+			list = append(list, str)
+		}
+	}
+	return list
+}
+
+func hasString(list []string, str string) bool {
+	for _, s := range list {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,360 @@
+package publish
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-xiaohei/pugo/app/model"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPublishPost(t *testing.T) {
+	Convey("Publish Post", t, func() {
+		dir, err := ioutil.TempDir("", "pugo-post")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		meta, err := ioutil.ReadFile("../../source/meta.toml")
+		So(err, ShouldBeNil)
+		src := filepath.Join(dir, "source")
+		So(os.MkdirAll(filepath.Join(src, "post"), os.ModePerm), ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join(src, "meta.toml"), meta, 0644), ShouldBeNil)
+		for _, args := range [][]string{
+			{"init"},
+			{"config", "user.name", "pugo"},
+			{"config", "user.email", "pugo@example.com"},
+		} {
+			cmd := exec.Command("git", args...)
+			cmd.Dir = dir
+			So(cmd.Run(), ShouldBeNil)
+		}
+
+		site := &Site{Name: "blog", Token: "hook", APIToken: "secret", Repo: dir, Source: src, Dest: filepath.Join(dir, "dest")}
+		srv := NewServer([]*Site{site})
+
+		Convey("Post Json", func() {
+			r := httptest.NewRequest("POST", PostPath+"blog", strings.NewReader(`{"title":"Hello World","content":"Hello *pugo*","tags":["a","b"]}`))
+			r.Header.Set("Content-Type", "application/json")
+			r.Header.Set("Authorization", "Bearer secret")
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, r)
+			So(w.Code, ShouldEqual, 201)
+			So(w.Header().Get("Location"), ShouldEndWith, "/hello-world.html")
+
+			files, _ := filepath.Glob(filepath.Join(src, "post", "*", "hello-world.md"))
+			So(files, ShouldHaveLength, 1)
+			data, _ := ioutil.ReadFile(files[0])
+			So(string(data), ShouldContainSubstring, `title = "Hello World"`)
+			So(string(data), ShouldContainSubstring, "Hello *pugo*")
+
+			cmd := exec.Command("git", "log", "--format=%s")
+			cmd.Dir = dir
+			out, _ := cmd.Output()
+			So(string(out), ShouldContainSubstring, "Add post Hello World")
+
+			Convey("Same Slug", func() {
+				r := httptest.NewRequest("POST", PostPath+"blog", strings.NewReader(`{"title":"Hello World"}`))
+				r.Header.Set("Content-Type", "application/json")
+				r.Header.Set("Authorization", "Bearer secret")
+				w := httptest.NewRecorder()
+				srv.ServeHTTP(w, r)
+				So(w.Code, ShouldEqual, 201)
+				So(w.Header().Get("Location"), ShouldNotEndWith, "/hello-world.html")
+			})
+		})
+
+		Convey("Post Photo", func() {
+			var buf bytes.Buffer
+			mw := multipart.NewWriter(&buf)
+			mw.WriteField("content", "A photo")
+			mw.WriteField("tags", "x, y")
+			fw, _ := mw.CreateFormFile("photo", "cat.jpg")
+			fw.Write([]byte("jpg"))
+			mw.Close()
+
+			r := httptest.NewRequest("POST", PostPath+"blog", &buf)
+			r.Header.Set("Content-Type", mw.FormDataContentType())
+			r.Header.Set("Authorization", "Bearer secret")
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, r)
+			So(w.Code, ShouldEqual, 201)
+
+			files, _ := filepath.Glob(filepath.Join(src, "media", "*", "*-cat.jpg"))
+			So(files, ShouldHaveLength, 1)
+			files, _ = filepath.Glob(filepath.Join(src, "post", "*", "*.md"))
+			So(files, ShouldHaveLength, 1)
+			data, _ := ioutil.ReadFile(files[0])
+			So(string(data), ShouldContainSubstring, `thumb = "@media/`)
+			So(string(data), ShouldContainSubstring, `title = "A photo"`)
+		})
+
+		Convey("Post Invalid", func() {
+			r := httptest.NewRequest("POST", PostPath+"blog", strings.NewReader(`{}`))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, r)
+			So(w.Code, ShouldEqual, 403)
+
+			// hook token and tokens out of authorization header are not accepted
+			for _, req := range []*http.Request{
+				httptest.NewRequest("POST", PostPath+"blog?token=secret", nil),
+				httptest.NewRequest("POST", PostPath+"blog", nil),
+			} {
+				req.Header.Set(TokenHeader, "secret")
+				w = httptest.NewRecorder()
+				srv.ServeHTTP(w, req)
+				So(w.Code, ShouldEqual, 403)
+			}
+			// access_token in body is only for micropub
+			body := url.Values{"access_token": {"secret"}, "title": {"t"}, "content": {"c"}}
+			r2 := httptest.NewRequest("POST", PostPath+"blog", strings.NewReader(body.Encode()))
+			r2.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w = httptest.NewRecorder()
+			srv.ServeHTTP(w, r2)
+			So(w.Code, ShouldEqual, 403)
+
+			r2 = httptest.NewRequest("POST", PostPath+"blog", nil)
+			r2.Header.Set("Authorization", "Bearer hook")
+			w = httptest.NewRecorder()
+			srv.ServeHTTP(w, r2)
+			So(w.Code, ShouldEqual, 403)
+
+			r.Header.Set("Authorization", "Bearer secret")
+			w = httptest.NewRecorder()
+			srv.ServeHTTP(w, r)
+			So(w.Code, ShouldEqual, 400)
+
+			w = httptest.NewRecorder()
+			srv.ServeHTTP(w, httptest.NewRequest("POST", PostPath+"unknown", nil))
+			So(w.Code, ShouldEqual, 404)
+		})
+
+		Convey("Micropub Form", func() {
+			form := url.Values{
+				"h":          {"entry"},
+				"content":    {"Posted by micropub"},
+				"category[]": {"indieweb", "pugo"},
+				"mp-slug":    {"micro"},
+			}
+			r := httptest.NewRequest("POST", MicropubPath+"blog", strings.NewReader(form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			r.Header.Set("Authorization", "Bearer secret")
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, r)
+			So(w.Code, ShouldEqual, 201)
+			So(w.Header().Get("Location"), ShouldEndWith, "/micro.html")
+
+			files, _ := filepath.Glob(filepath.Join(src, "post", "*", "micro.md"))
+			So(files, ShouldHaveLength, 1)
+			data, _ := ioutil.ReadFile(files[0])
+			So(string(data), ShouldContainSubstring, `"indieweb"`)
+		})
+
+		Convey("Micropub Body Token", func() {
+			form := url.Values{"h": {"entry"}, "content": {"Token in form"}, "mp-slug": {"form-token"}, "access_token": {"secret"}}
+			r := httptest.NewRequest("POST", MicropubPath+"blog", strings.NewReader(form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, r)
+			So(w.Code, ShouldEqual, 201)
+			So(w.Header().Get("Location"), ShouldEndWith, "/form-token.html")
+			files, _ := filepath.Glob(filepath.Join(src, "post", "*", "form-token.md"))
+			So(files, ShouldHaveLength, 1)
+			data, _ := ioutil.ReadFile(files[0])
+			So(string(data), ShouldNotContainSubstring, "secret")
+
+			var buf bytes.Buffer
+			mw := multipart.NewWriter(&buf)
+			mw.WriteField("h", "entry")
+			mw.WriteField("content", "Token in multipart")
+			mw.WriteField("mp-slug", "multipart-token")
+			mw.WriteField("access_token", "secret")
+			mw.Close()
+			r = httptest.NewRequest("POST", MicropubPath+"blog", &buf)
+			r.Header.Set("Content-Type", mw.FormDataContentType())
+			w = httptest.NewRecorder()
+			srv.ServeHTTP(w, r)
+			So(w.Code, ShouldEqual, 201)
+			So(w.Header().Get("Location"), ShouldEndWith, "/multipart-token.html")
+
+			// token in both header and body
+			r = httptest.NewRequest("POST", MicropubPath+"blog", strings.NewReader(form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			r.Header.Set("Authorization", "Bearer secret")
+			w = httptest.NewRecorder()
+			srv.ServeHTTP(w, r)
+			So(w.Code, ShouldEqual, 400)
+
+			// token in query is not accepted
+			form.Del("access_token")
+			r = httptest.NewRequest("POST", MicropubPath+"blog?access_token=secret", strings.NewReader(form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w = httptest.NewRecorder()
+			srv.ServeHTTP(w, r)
+			So(w.Code, ShouldEqual, 401)
+
+			form.Set("access_token", "wrong")
+			r = httptest.NewRequest("POST", MicropubPath+"blog", strings.NewReader(form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w = httptest.NewRecorder()
+			srv.ServeHTTP(w, r)
+			So(w.Code, ShouldEqual, 403)
+		})
+
+		Convey("Micropub Json", func() {
+			body := `{"type":["h-entry"],"properties":{"name":["Micro Json"],"content":[{"html":"<p>hi</p>"}],"category":["a"]}}`
+			r := httptest.NewRequest("POST", MicropubPath+"blog", strings.NewReader(body))
+			r.Header.Set("Content-Type", "application/json")
+			r.Header.Set("Authorization", "Bearer secret")
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, r)
+			So(w.Code, ShouldEqual, 201)
+			So(w.Header().Get("Location"), ShouldEndWith, "/micro-json.html")
+
+			r = httptest.NewRequest("POST", MicropubPath+"blog", strings.NewReader(`{"type":["h-event"]}`))
+			r.Header.Set("Content-Type", "application/json")
+			r.Header.Set("Authorization", "Bearer secret")
+			w = httptest.NewRecorder()
+			srv.ServeHTTP(w, r)
+			So(w.Code, ShouldEqual, 400)
+			So(w.Body.String(), ShouldContainSubstring, "invalid_request")
+		})
+
+		Convey("Micropub Update", func() {
+			body := `{"type":["h-entry"],"properties":{"name":["Update Me"],"content":["old"],"category":["a","b"]}}`
+			r := httptest.NewRequest("POST", MicropubPath+"blog", strings.NewReader(body))
+			r.Header.Set("Content-Type", "application/json")
+			r.Header.Set("Authorization", "Bearer secret")
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, r)
+			So(w.Code, ShouldEqual, 201)
+			location := w.Header().Get("Location")
+
+			body = `{"action":"update","url":"` + location + `","replace":{"content":["new"]},"add":{"category":["c"]},"delete":{"category":["a"]}}`
+			r = httptest.NewRequest("POST", MicropubPath+"blog", strings.NewReader(body))
+			r.Header.Set("Content-Type", "application/json")
+			r.Header.Set("Authorization", "Bearer secret")
+			w = httptest.NewRecorder()
+			srv.ServeHTTP(w, r)
+			So(w.Code, ShouldEqual, 204)
+
+			r = httptest.NewRequest("GET", MicropubPath+"blog?q=source&url="+url.QueryEscape(location), nil)
+			r.Header.Set("Authorization", "Bearer secret")
+			w = httptest.NewRecorder()
+			srv.ServeHTTP(w, r)
+			So(w.Code, ShouldEqual, 200)
+			So(w.Body.String(), ShouldContainSubstring, `"content":["new"]`)
+			So(w.Body.String(), ShouldContainSubstring, `"category":["b","c"]`)
+
+			form := url.Values{"action": {"delete"}, "url": {location}}
+			r = httptest.NewRequest("POST", MicropubPath+"blog", strings.NewReader(form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			r.Header.Set("Authorization", "Bearer secret")
+			w = httptest.NewRecorder()
+			srv.ServeHTTP(w, r)
+			So(w.Code, ShouldEqual, 204)
+			files, _ := filepath.Glob(filepath.Join(src, "post", "*", "update-me.md"))
+			So(files, ShouldHaveLength, 1)
+			data, _ := ioutil.ReadFile(files[0])
+			So(string(data), ShouldContainSubstring, "draft = true")
+
+			form.Set("url", location+"x")
+			r = httptest.NewRequest("POST", MicropubPath+"blog", strings.NewReader(form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			r.Header.Set("Authorization", "Bearer secret")
+			w = httptest.NewRecorder()
+			srv.ServeHTTP(w, r)
+			So(w.Code, ShouldEqual, 400)
+		})
+
+		Convey("Update Ini Post", func() {
+			file := filepath.Join(src, "post", "ini.md")
+			data := "```ini\n; ini post\ntitle = \"Ini\"\nslug = \"ini\"\nauthor = \"pugo\"\ntags = \"a,b\"\n```\n\nini body\n"
+			So(ioutil.WriteFile(file, []byte(data), 0644), ShouldBeNil)
+			// post without date has permalink of modified time
+			mtime := time.Now().Add(-48 * time.Hour)
+			So(os.Chtimes(file, mtime, mtime), ShouldBeNil)
+			ctx, err := site.metaContext()
+			So(err, ShouldBeNil)
+			post, err := model.NewPostOfMarkdown(file, nil)
+			So(err, ShouldBeNil)
+			So(post.SetTimezone(ctx.Source.Meta.Location()), ShouldBeNil)
+			location := strings.TrimSuffix(ctx.Source.Meta.Root, "/") + post.URL()
+
+			_, err = site.UpdatePost(location, &PostUpdate{
+				Replace: map[string][]interface{}{"name": {"Ini #1"}},
+				Add:     map[string][]interface{}{"category": {"c"}},
+			})
+			So(err, ShouldBeNil)
+			out, _ := ioutil.ReadFile(file)
+			So(string(out), ShouldStartWith, "```ini\n; ini post\ntitle = `Ini #1`\nslug = \"ini\"\nauthor = \"pugo\"\ntags = \"a,b,c\"\n")
+			So(string(out), ShouldNotContainSubstring, "date = \"\"")
+			So(string(out), ShouldEndWith, "```\n\nini body\n")
+
+			post2, err := site.SourcePost(location)
+			So(err, ShouldBeNil)
+			So(post2.Title, ShouldEqual, "Ini #1")
+			So(post2.TagString, ShouldResemble, []string{"a", "b", "c"})
+			So(post2.URL(), ShouldEqual, post.URL())
+
+			_, err = site.DeletePost(location, true)
+			So(err, ShouldBeNil)
+			out, _ = ioutil.ReadFile(file)
+			So(string(out), ShouldContainSubstring, "draft = true\n```")
+			So(string(out), ShouldStartWith, "```ini\n")
+		})
+
+		Convey("IndieAuth Token", func() {
+			endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.Header.Get("Authorization") {
+				case "Bearer creator":
+					w.Header().Set("Content-Type", "application/json")
+					w.Write([]byte(`{"me":"https://example.com/","client_id":"https://app.example.com/","scope":"create"}`))
+				case "Bearer other":
+					w.Header().Set("Content-Type", "application/x-www-form-urlencoded")
+					w.Write([]byte(`me=https%3A%2F%2Fother.com%2F&scope=create+update+delete`))
+				default:
+					w.WriteHeader(http.StatusUnauthorized)
+				}
+			}))
+			defer endpoint.Close()
+			site.Me = "https://example.com"
+			site.TokenEndpoint = endpoint.URL
+
+			post := func(token, body string) int {
+				r := httptest.NewRequest("POST", MicropubPath+"blog", strings.NewReader(body))
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				r.Header.Set("Authorization", "Bearer "+token)
+				w := httptest.NewRecorder()
+				srv.ServeHTTP(w, r)
+				return w.Code
+			}
+			So(post("creator", "h=entry&content=indieauth"), ShouldEqual, 201)
+			So(post("creator", "action=delete&url=https%3A%2F%2Fexample.com%2Fx.html"), ShouldEqual, 403)
+			So(post("other", "h=entry&content=indieauth"), ShouldEqual, 403)
+			So(post("unknown", "h=entry&content=indieauth"), ShouldEqual, 403)
+		})
+
+		Convey("Micropub Token", func() {
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, httptest.NewRequest("POST", MicropubPath+"blog", nil))
+			So(w.Code, ShouldEqual, 401)
+
+			r := httptest.NewRequest("POST", MicropubPath+"blog", nil)
+			r.Header.Set("Authorization", "Bearer wrong")
+			w = httptest.NewRecorder()
+			srv.ServeHTTP(w, r)
+			So(w.Code, ShouldEqual, 403)
+		})
+	})
+}
@@ -0,0 +1,107 @@
+package publish
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-xiaohei/pugo/app/metrics"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+const (
+	// HookPath is url path prefix to trigger publishing, followed by site name
+	HookPath = "/hook/"
+	// StatusPath is url path to show status of sites
+	StatusPath = "/-/status"
+	// MetricsPath is url path to show metrics
+	MetricsPath = "/-/metrics"
+	// TokenHeader is http header of site token
+	TokenHeader = "X-Pugo-Token"
+)
+
+// Server receives webhooks to publish sites
+type Server struct {
+	sites map[string]*Site
+	order []*Site
+}
+
+// NewServer create server of sites, the sites should be started
+func NewServer(sites []*Site) *Server {
+	s := &Server{
+		sites: make(map[string]*Site, len(sites)),
+		order: sites,
+	}
+	for _, site := range sites {
+		s.sites[site.Name] = site
+	}
+	return s
+}
+
+// ServeHTTP implement http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == StatusPath:
+		statuses := make([]SiteStatus, len(s.order))
+		for i, site := range s.order {
+			statuses[i] = site.Status()
+		}
+		writeJSON(w, http.StatusOK, statuses)
+	case r.URL.Path == MetricsPath:
+		metrics.Handler().ServeHTTP(w, r)
+	case strings.HasPrefix(r.URL.Path, HookPath):
+		s.serveHook(w, r, strings.TrimPrefix(r.URL.Path, HookPath))
+	case strings.HasPrefix(r.URL.Path, PostPath):
+		s.servePost(w, r, strings.TrimPrefix(r.URL.Path, PostPath))
+	case strings.HasPrefix(r.URL.Path, MicropubPath):
+		s.serveMicropub(w, r, strings.TrimPrefix(r.URL.Path, MicropubPath))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) serveHook(w http.ResponseWriter, r *http.Request, name string) {
+	site := s.sites[name]
+	if site == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != "POST" {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if !isToken(hookToken(r), site.Token) {
+		log15.Warn("Publish|%s|Hook|invalid token from %s", name, r.RemoteAddr)
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	queued := site.Trigger()
+	log15.Info("Publish|%s|Hook|%s|queued %v", name, r.RemoteAddr, queued)
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"site":   name,
+		"queued": queued,
+	})
+}
+
+// hookToken return token of hook request in TokenHeader or bearer authorization header
+func hookToken(r *http.Request) string {
+	if token := r.Header.Get(TokenHeader); token != "" {
+		return token
+	}
+	return bearerToken(r)
+}
+
+// Run run http server on addr
+func (s *Server) Run(addr string) {
+	log15.Info("Publish|Start|%s|%d sites", addr, len(s.order))
+	if err := http.ListenAndServe(addr, s); err != nil {
+		log15.Crit("Publish|Start|%s", err.Error())
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
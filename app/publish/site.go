@@ -0,0 +1,284 @@
+// Package publish builds and deploys many sites triggered by webhooks,
+// or builds and deploys a site to many targets by matrix.
+package publish
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/Unknwon/com"
+	"github.com/go-xiaohei/pugo/app/builder"
+	"github.com/go-xiaohei/pugo/app/extend/deploy"
+	"github.com/urfave/cli"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+var siteNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_\-]+$`)
+
+type (
+	// Site is a site to publish
+	Site struct {
+		Name string `toml:"name"`
+		// Token authorizes hooks to publish the site
+		Token string `toml:"token"`
+		// APIToken authorizes new posts and micropub requests,
+		// they are disabled if it's empty
+		APIToken string  `toml:"api_token"`
+		Repo     string  `toml:"repo"`
+		Branch   string  `toml:"branch"`
+		Source   string  `toml:"source"`
+		Theme    string  `toml:"theme"`
+		Dest     string  `toml:"dest"`
+		Deploy   *Deploy `toml:"deploy"`
+		// Me and TokenEndpoint verify IndieAuth tokens of micropub requests
+		Me            string `toml:"me"`
+		TokenEndpoint string `toml:"token_endpoint"`
+		// Logger receives logs of building and deploying the site, root logger by default
+		Logger log15.Logger `toml:"-"`
+
+		builder *builder.Builder
+		queue   chan struct{}
+		lock    sync.RWMutex
+		status  SiteStatus
+		ctx     *builder.Context
+		// gitLock serializes pulling and committing new posts in repo
+		gitLock sync.Mutex
+	}
+	// Deploy is deploy method of site
+	Deploy struct {
+		Method  string            `toml:"method"`
+		Options map[string]string `toml:"options"`
+		// AuditRules is file of extra patterns to audit before deploying
+		AuditRules string `toml:"audit_rules"`
+		// AllowSecrets deploys even if audit finds secrets
+		AllowSecrets bool `toml:"allow_secrets"`
+	}
+	// SiteStatus is status of last publishing of site
+	SiteStatus struct {
+		Name     string         `json:"name"`
+		Queued   bool           `json:"queued"`
+		Time     time.Time      `json:"time"`
+		Error    string         `json:"error,omitempty"`
+		Deployed time.Time      `json:"deployed"`
+		Build    builder.Status `json:"build"`
+	}
+)
+
+// LoadSites loads sites from toml file.
+// Relative source and theme are in repo directory,
+// relative repo and dest are in the directory of file.
+func LoadSites(file string) ([]*Site, error) {
+	var cfg struct {
+		Site []*Site `toml:"site"`
+	}
+	if _, err := toml.DecodeFile(file, &cfg); err != nil {
+		return nil, err
+	}
+	if len(cfg.Site) == 0 {
+		return nil, fmt.Errorf("no site in '%s'", file)
+	}
+	dir := filepath.Dir(file)
+	names := make(map[string]bool)
+	for _, s := range cfg.Site {
+		if err := s.normalize(dir); err != nil {
+			return nil, err
+		}
+		if names[s.Name] {
+			return nil, fmt.Errorf("site '%s' is duplicated", s.Name)
+		}
+		names[s.Name] = true
+	}
+	return cfg.Site, nil
+}
+
+func (s *Site) normalize(dir string) error {
+	if !siteNameRegex.MatchString(s.Name) {
+		return fmt.Errorf("site name '%s' is invalid", s.Name)
+	}
+	if s.Token == "" {
+		return fmt.Errorf("site '%s' need token", s.Name)
+	}
+	if s.APIToken == s.Token {
+		return fmt.Errorf("site '%s' need different token and api_token", s.Name)
+	}
+	if s.Dest == "" {
+		return fmt.Errorf("site '%s' need dest", s.Name)
+	}
+	if s.Source == "" {
+		s.Source = "source"
+	}
+	if s.Theme == "" {
+		s.Theme = "source/theme/default"
+	}
+	if s.TokenEndpoint != "" && s.Me == "" {
+		return fmt.Errorf("site '%s' need me url to verify tokens", s.Name)
+	}
+	if s.Deploy != nil && s.Deploy.Method == "" {
+		return fmt.Errorf("site '%s' need deploy method", s.Name)
+	}
+	if s.Deploy != nil {
+		s.Deploy.AuditRules = inDir(dir, s.Deploy.AuditRules)
+	}
+	s.Repo = inDir(dir, s.Repo)
+	s.Dest = inDir(dir, s.Dest)
+	base := s.Repo
+	if base == "" {
+		base = dir
+	}
+	s.Source = inDir(base, s.Source)
+	s.Theme = inDir(base, s.Theme)
+	return nil
+}
+
+func inDir(dir, file string) string {
+	if file == "" || filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(dir, file)
+}
+
+// Start starts publishing queued builds of site
+func (s *Site) Start() {
+	s.builder = builder.NewDefault()
+	s.queue = make(chan struct{}, 1)
+	s.status.Name = s.Name
+	go func() {
+		for range s.queue {
+			s.publish()
+		}
+	}()
+}
+
+// Trigger queues a publishing and cancels current building.
+// If a publishing is already queued, it returns false and the queued one covers the change.
+func (s *Site) Trigger() bool {
+	queued := true
+	select {
+	case s.queue <- struct{}{}:
+	default:
+		queued = false
+	}
+	s.lock.RLock()
+	if s.ctx != nil {
+		s.ctx.Cancel()
+	}
+	s.lock.RUnlock()
+	return queued
+}
+
+// Status return status of site
+func (s *Site) Status() SiteStatus {
+	s.lock.RLock()
+	status := s.status
+	s.lock.RUnlock()
+	status.Queued = len(s.queue) > 0
+	status.Build = s.builder.Status()
+	return status
+}
+
+// publish pulls, builds and deploys site,
+// the failure only affects this site
+func (s *Site) publish() {
+	err := s.run()
+	if err == builder.ErrCanceled {
+		log15.Info("Publish|%s|Cancel", s.Name)
+		return
+	}
+	s.lock.Lock()
+	s.status.Time = time.Now()
+	s.status.Error = ""
+	if err != nil {
+		s.status.Error = err.Error()
+	} else if s.Deploy != nil {
+		s.status.Deployed = s.status.Time
+	}
+	s.lock.Unlock()
+	if err != nil {
+		s.log().Error("Publish|%s|Fail|%s", s.Name, err.Error())
+		return
+	}
+	s.log().Info("Publish|%s|Done", s.Name)
+}
+
+func (s *Site) run() (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("panic: %v", e)
+		}
+	}()
+	if s.Repo != "" {
+		s.gitLock.Lock()
+		err = s.pull()
+		s.gitLock.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	ctx := builder.NewContext(&cli.Context{}, s.Source, s.Dest, s.Theme)
+	ctx.Logger = s.log()
+	s.lock.Lock()
+	s.ctx = ctx
+	s.lock.Unlock()
+	err = s.builder.Try(ctx)
+	s.lock.Lock()
+	s.ctx = nil
+	s.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	if s.Deploy == nil {
+		return nil
+	}
+	s.log().Info("Publish|%s|Deploy|%s", s.Name, s.Deploy.Method)
+	return s.Deploy.Do(s.Dest, s.log())
+}
+
+// log return logger of the site
+func (s *Site) log() log15.Logger {
+	if s.Logger == nil {
+		return log15.Root()
+	}
+	return s.Logger
+}
+
+// Do audits and deploys files in local directory, deploy method logs to logger
+func (d *Deploy) Do(local string, logger log15.Logger) error {
+	options := map[string]string{"local": local}
+	for k, v := range d.Options {
+		options[k] = v
+	}
+	m, err := deploy.Create(d.Method, options)
+	if err != nil {
+		return err
+	}
+	if err = deploy.CheckAudit(local, d.AuditRules, d.AllowSecrets); err != nil {
+		return err
+	}
+	return deploy.DoLog(m, logger)
+}
+
+// pull updates git repository of site
+func (s *Site) pull() error {
+	if !com.IsDir(filepath.Join(s.Repo, ".git")) {
+		return errors.New("directory '" + s.Repo + "' is not a git repository")
+	}
+	args := []string{"pull", "--ff-only"}
+	if s.Branch != "" {
+		args = append(args, "origin", s.Branch)
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Dir = s.Repo
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git pull: %s", strings.TrimSpace(string(out)))
+	}
+	log15.Debug("Publish|%s|Pull|%s", s.Name, strings.TrimSpace(string(out)))
+	return nil
+}
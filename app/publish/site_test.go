@@ -0,0 +1,122 @@
+package publish
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-xiaohei/pugo/app/helper"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const testSites = `
+[[site]]
+name = "good"
+token = "secret"
+source = "../../source"
+theme = "../../source/theme/default"
+dest = "good"
+
+[[site]]
+name = "broken"
+token = "secret"
+source = "../../source"
+theme = "not-exist"
+dest = "broken"
+`
+
+func TestPublish(t *testing.T) {
+	Convey("Publish Sites", t, func() {
+		dir, err := ioutil.TempDir("", "pugo-publish")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		abs, _ := filepath.Abs(".")
+		file := filepath.Join(abs, "sites.toml")
+		So(ioutil.WriteFile(file, []byte(testSites), 0644), ShouldBeNil)
+		defer os.Remove(file)
+
+		sites, err := LoadSites(file)
+		So(err, ShouldBeNil)
+		So(sites, ShouldHaveLength, 2)
+		So(sites[0].Dest, ShouldEqual, filepath.Join(abs, "good"))
+		capture := helper.NewLogCapture()
+		sites[0].Logger = helper.NewLogger(capture)
+		for _, s := range sites {
+			s.Dest = filepath.Join(dir, s.Name)
+			s.Start()
+		}
+		srv := NewServer(sites)
+
+		Convey("Hook", func() {
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, httptest.NewRequest("POST", "/hook/good?token=wrong", nil))
+			So(w.Code, ShouldEqual, 403)
+
+			w = httptest.NewRecorder()
+			srv.ServeHTTP(w, httptest.NewRequest("GET", "/hook/good?token=secret", nil))
+			So(w.Code, ShouldEqual, 405)
+
+			w = httptest.NewRecorder()
+			srv.ServeHTTP(w, httptest.NewRequest("POST", "/hook/unknown", nil))
+			So(w.Code, ShouldEqual, 404)
+
+			for _, name := range []string{"good", "broken"} {
+				r := httptest.NewRequest("POST", "/hook/"+name, nil)
+				r.Header.Set(TokenHeader, "secret")
+				w = httptest.NewRecorder()
+				srv.ServeHTTP(w, r)
+				So(w.Code, ShouldEqual, 202)
+			}
+
+			for i := 0; i < 100; i++ {
+				if sites[0].Status().Build.Count > 0 && sites[1].Status().Build.Count > 0 {
+					break
+				}
+				time.Sleep(50 * time.Millisecond)
+			}
+			So(sites[0].Status().Error, ShouldBeEmpty)
+			So(sites[0].Status().Build.Pages, ShouldBeGreaterThan, 0)
+			So(sites[1].Status().Error, ShouldNotBeEmpty)
+
+			// logs of building the site go to its logger
+			var read, done bool
+			for i := 0; i < 100 && !done; i++ {
+				time.Sleep(10 * time.Millisecond)
+				read, done = false, false
+				for _, r := range capture.Records() {
+					read = read || strings.HasPrefix(r.Msg, "Read|")
+					done = done || r.Msg == "Publish|good|Done"
+					So(r.Msg, ShouldNotContainSubstring, "broken")
+				}
+			}
+			So(read, ShouldBeTrue)
+			So(done, ShouldBeTrue)
+
+			w = httptest.NewRecorder()
+			srv.ServeHTTP(w, httptest.NewRequest("GET", StatusPath, nil))
+			So(w.Code, ShouldEqual, 200)
+			So(w.Body.String(), ShouldContainSubstring, `"name":"broken"`)
+		})
+	})
+
+	Convey("Invalid Sites", t, func() {
+		file := filepath.Join(os.TempDir(), "pugo-sites.toml")
+		defer os.Remove(file)
+		for _, data := range []string{
+			"",
+			"[[site]]\nname = \"a b\"\ntoken = \"t\"\ndest = \"d\"",
+			"[[site]]\nname = \"a\"\ndest = \"d\"",
+			"[[site]]\nname = \"a\"\ntoken = \"t\"\napi_token = \"t\"\ndest = \"d\"",
+			"[[site]]\nname = \"a\"\ntoken = \"t\"\ndest = \"d\"\n[[site]]\nname = \"a\"\ntoken = \"t\"\ndest = \"d\"",
+		} {
+			ioutil.WriteFile(file, []byte(data), 0644)
+			_, err := LoadSites(file)
+			So(err, ShouldNotBeNil)
+		}
+	})
+}
@@ -0,0 +1,202 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-xiaohei/pugo/app/model"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+const (
+	// FormPath is url path prefix to submit forms, followed by form name
+	FormPath = model.FormEndpoint
+
+	// FormRateLimit is max submissions from one ip in formRateWindow
+	FormRateLimit = 5
+
+	formMaxBytes   = 64 << 10
+	formRateWindow = 10 * time.Minute
+)
+
+// Forms handles submissions of forms with 'server' provider,
+// and forwards them to email by SMTP
+type Forms struct {
+	lock  sync.RWMutex
+	forms model.FormGroup
+	mail  *model.Mail
+
+	// window, hits and used tokens are guarded by limitLock
+	limitLock sync.Mutex
+	window    time.Time
+	hits      map[string]int
+	used      map[string]time.Time
+}
+
+// NewForms create forms handler
+func NewForms() *Forms {
+	return &Forms{
+		hits: make(map[string]int),
+		used: make(map[string]time.Time),
+	}
+}
+
+// Update sets forms and mail settings
+func (fs *Forms) Update(forms model.FormGroup, mail *model.Mail) {
+	fs.lock.Lock()
+	fs.forms = forms
+	fs.mail = mail
+	fs.lock.Unlock()
+}
+
+func (fs *Forms) get(name string) (*model.Form, *model.Mail) {
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+	f := fs.forms.Get(name)
+	if f == nil || f.Provider != model.FormServer {
+		return nil, nil
+	}
+	return f, fs.mail
+}
+
+// ServeHTTP implement http.Handler,
+// GET responds a new token of form, POST submits form with the token.
+// It responds 303 to success url of form, or referer if not set
+func (fs *Forms) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	f, mail := fs.get(r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:])
+	if f == nil {
+		http.NotFound(w, r)
+		return
+	}
+	now := time.Now()
+	if r.Method == http.MethodGet {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write([]byte(f.Token(mail.Secret, now)))
+		return
+	}
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	if !fs.allow(ip, now) {
+		log15.Warn("Server|Form|%s|too many submissions from %s", f.Name, ip)
+		http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, formMaxBytes)
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	token := r.PostFormValue(model.FormTokenField)
+	if !f.IsToken(token, mail.Secret, now) || !fs.use(token, now) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	// spam is responded as success, but not sent
+	if r.PostFormValue(model.FormHoneypot) != "" {
+		log15.Warn("Server|Form|%s|spam from %s", f.Name, r.RemoteAddr)
+		formRedirect(w, r, f)
+		return
+	}
+	for _, field := range f.Fields {
+		if field.Required && strings.TrimSpace(r.PostFormValue(field.Name)) == "" {
+			http.Error(w, fmt.Sprintf("'%s' is required", field.Label), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := sendForm(f, mail, r); err != nil {
+		log15.Error("Server|Form|%s|%s", f.Name, err.Error())
+		http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+		return
+	}
+	log15.Info("Server|Form|%s|Send|%s", f.Name, f.To)
+	formRedirect(w, r, f)
+}
+
+// allow counts submission of ip in current window,
+// it returns false if the ip reaches FormRateLimit
+func (fs *Forms) allow(ip string, now time.Time) bool {
+	fs.limitLock.Lock()
+	defer fs.limitLock.Unlock()
+	if now.Sub(fs.window) >= formRateWindow {
+		fs.window = now
+		fs.hits = make(map[string]int)
+		for token, t := range fs.used {
+			if now.Sub(t) > model.FormTokenTTL {
+				delete(fs.used, token)
+			}
+		}
+	}
+	if fs.hits[ip] >= FormRateLimit {
+		return false
+	}
+	fs.hits[ip]++
+	return true
+}
+
+// use marks token as used, it returns false if the token is used before,
+// tokens are kept until they expire
+func (fs *Forms) use(token string, now time.Time) bool {
+	fs.limitLock.Lock()
+	defer fs.limitLock.Unlock()
+	if _, ok := fs.used[token]; ok {
+		return false
+	}
+	fs.used[token] = now
+	return true
+}
+
+func formRedirect(w http.ResponseWriter, r *http.Request, f *model.Form) {
+	to := f.Success
+	if to == "" {
+		to = r.Referer()
+	}
+	if to == "" {
+		to = "/"
+	}
+	http.Redirect(w, r, to, http.StatusSeeOther)
+}
+
+// sendForm sends values of form fields as plain text email,
+// email field is used as reply-to address
+func sendForm(f *model.Form, mail *model.Mail, r *http.Request) error {
+	subject := f.Subject
+	if subject == "" {
+		subject = "New submission of form " + f.Name
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\nTo: %s\r\nSubject: %s\r\n", mail.From, f.To, headerValue(subject))
+	if email := headerValue(r.PostFormValue("email")); strings.Contains(email, "@") {
+		fmt.Fprintf(&buf, "Reply-To: %s\r\n", email)
+	}
+	fmt.Fprintf(&buf, "Date: %s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n", time.Now().Format(time.RFC1123Z))
+	for _, field := range f.Fields {
+		fmt.Fprintf(&buf, "%s:\r\n%s\r\n\r\n", field.Label, strings.Replace(r.PostFormValue(field.Name), "\n", "\r\n", -1))
+	}
+	fmt.Fprintf(&buf, "--\r\nfrom %s at %s\r\n", r.RemoteAddr, r.Referer())
+
+	var auth smtp.Auth
+	if mail.User != "" {
+		auth = smtp.PlainAuth("", mail.User, mail.Password, mail.Host)
+	}
+	return smtp.SendMail(net.JoinHostPort(mail.Host, strconv.Itoa(mail.Port)), auth, mail.From, []string{f.To}, buf.Bytes())
+}
+
+// headerValue removes line breaks to prevent header injection
+func headerValue(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(strings.TrimSpace(s))
+}
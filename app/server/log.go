@@ -1,15 +1,18 @@
 package server
 
 import (
-	"time"
-
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"sync"
+	"time"
 
 	"gopkg.in/inconshreveable/log15.v2"
 )
 
-var (
-	logFormat = "Http|%s|%d|%s|%s|%.1fms" //
+const (
+	logFormat = "Http|%s|%d|%s|%s|%.1fms"
 )
 
 // log middleware handler
@@ -42,3 +45,59 @@ func logger(w *responseWriter, r *http.Request) {
 	log15.Info(logFormat+"|%v", r.Method, statusCode, p, r.RemoteAddr, time.Since(w.startTime).Seconds()*1000, w.error)
 
 }
+
+// accessRecord is a request log in json
+type accessRecord struct {
+	Time      time.Time `json:"time"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Status    int       `json:"status"`
+	Size      int64     `json:"size"`
+	Duration  float64   `json:"duration_ms"`
+	Remote    string    `json:"remote"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Referer   string    `json:"referer,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// accessWriter writes json lines of concurrent requests one by one
+type accessWriter struct {
+	lock sync.Mutex
+	w    io.Writer
+}
+
+func (a *accessWriter) Write(p []byte) (int, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.w.Write(p)
+}
+
+// jsonLogger writes request log as json line to w
+func jsonLogger(out io.Writer, w *responseWriter, r *http.Request) {
+	if w.status == 0 {
+		http.NotFound(w, r)
+	}
+	p := r.URL.Path
+	if len(r.URL.RawQuery) > 0 {
+		p = p + "?" + r.URL.RawQuery
+	}
+	record := accessRecord{
+		Time:      w.startTime,
+		Method:    r.Method,
+		Path:      p,
+		Status:    w.status,
+		Size:      w.size,
+		Duration:  time.Since(w.startTime).Seconds() * 1000,
+		Remote:    r.RemoteAddr,
+		UserAgent: r.UserAgent(),
+		Referer:   r.Referer(),
+	}
+	if w.error != nil {
+		record.Error = fmt.Sprint(w.error)
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+	out.Write(append(data, '\n'))
+}
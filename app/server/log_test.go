@@ -0,0 +1,46 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAccessLog(t *testing.T) {
+	Convey("Access Log", t, func() {
+		dir, err := ioutil.TempDir("", "pugo-access")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		So(ioutil.WriteFile(filepath.Join(dir, "post.html"), []byte("post"), 0644), ShouldBeNil)
+
+		var buf bytes.Buffer
+		s := New(dir)
+		s.SetAccessLog(&buf)
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/post.html", nil))
+			}()
+		}
+		wg.Wait()
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		So(lines, ShouldHaveLength, 50)
+		for _, line := range lines {
+			var record accessRecord
+			So(json.Unmarshal([]byte(line), &record), ShouldBeNil)
+			So(record.Path, ShouldEqual, "/post.html")
+			So(record.Status, ShouldEqual, 200)
+		}
+	})
+}
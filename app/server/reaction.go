@@ -0,0 +1,212 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-xiaohei/pugo/app/model"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+const (
+	// ReactionPath is url path to post reactions
+	ReactionPath = model.ReactionEndpoint
+	// ReactionRateLimit is max reactions from one ip in a minute
+	ReactionRateLimit = 10
+
+	reactionMaxBytes  = 1 << 10
+	reactionDedupeTTL = 24 * time.Hour
+	// reactionDedupeMax is max remembered reactions, the oldest one is forgotten over it
+	reactionDedupeMax = 100000
+)
+
+var reactionBotAgents = []string{"bot", "spider", "crawl", "slurp"}
+
+// Reactions handles reactions posted to ReactionPath,
+// it appends reactions to data file and updates manifests in destination
+type Reactions struct {
+	lock      sync.Mutex
+	file      string
+	reaction  *model.Reaction
+	counts    model.PostReactions
+	manifests map[string]string
+
+	window time.Time
+	hits   map[string]int
+	seen   map[string]time.Time
+	// seenKeys are keys of seen in order of time
+	seenKeys []string
+}
+
+// NewReactions create reactions handler
+func NewReactions() *Reactions {
+	return &Reactions{
+		hits: make(map[string]int),
+		seen: make(map[string]time.Time),
+	}
+}
+
+// Update sets data file, settings, current counts and manifest files by post url,
+// only urls in manifests accept reactions
+func (rs *Reactions) Update(file string, reaction *model.Reaction, counts model.PostReactions, manifests map[string]string) {
+	if counts == nil {
+		counts = make(model.PostReactions)
+	}
+	rs.lock.Lock()
+	rs.file = file
+	rs.reaction = reaction
+	rs.counts = counts
+	rs.manifests = make(map[string]string, len(manifests))
+	for u, f := range manifests {
+		rs.manifests[model.ReactionKey(u)] = f
+	}
+	rs.lock.Unlock()
+}
+
+// ServeHTTP implement http.Handler,
+// it accepts form values 'url' and 'kind' and responds counts of url as json
+func (rs *Reactions) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, reactionMaxBytes)
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	if isReactionSpam(r) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	link, kind := r.PostFormValue("url"), r.PostFormValue("kind")
+
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+	manifest, ok := rs.manifests[model.ReactionKey(link)]
+	if !ok || !rs.reaction.IsKind(kind) {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	now := time.Now()
+	if !rs.allow(ip, now) {
+		http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		return
+	}
+	// repeated reaction is accepted but not counted
+	key := ip + "|" + model.ReactionKey(link) + "|" + kind
+	if _, ok := rs.seen[key]; !ok {
+		rs.remember(key, now)
+		if err = rs.add(now, link, kind, manifest); err != nil {
+			log15.Error("Server|Reaction|%s", err.Error())
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"url":    link,
+		"kind":   kind,
+		"counts": rs.counts.Get(link, rs.reaction.Kinds),
+	})
+}
+
+// allow counts reaction of ip in current minute,
+// it returns false if the ip reaches ReactionRateLimit
+func (rs *Reactions) allow(ip string, now time.Time) bool {
+	if now.Sub(rs.window) >= time.Minute {
+		rs.window = now
+		rs.hits = make(map[string]int)
+		for len(rs.seenKeys) > 0 && now.Sub(rs.seen[rs.seenKeys[0]]) >= reactionDedupeTTL {
+			rs.forgetOldest()
+		}
+	}
+	if rs.hits[ip] >= ReactionRateLimit {
+		return false
+	}
+	rs.hits[ip]++
+	return true
+}
+
+// remember adds reaction key as seen, the oldest keys are forgotten over reactionDedupeMax
+func (rs *Reactions) remember(key string, now time.Time) {
+	rs.seen[key] = now
+	rs.seenKeys = append(rs.seenKeys, key)
+	for len(rs.seenKeys) > reactionDedupeMax {
+		rs.forgetOldest()
+	}
+}
+
+func (rs *Reactions) forgetOldest() {
+	delete(rs.seen, rs.seenKeys[0])
+	rs.seenKeys[0] = ""
+	rs.seenKeys = rs.seenKeys[1:]
+}
+
+// add appends reaction to data file, then updates counts in manifest file
+func (rs *Reactions) add(now time.Time, link, kind, manifestFile string) error {
+	data, err := json.Marshal(model.ReactionRecord{Time: now.UTC(), URL: link, Kind: kind})
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(rs.file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	f.Close()
+	if err != nil {
+		return err
+	}
+	rs.counts.Add(link, kind)
+	log15.Info("Server|Reaction|%s|%s", link, kind)
+
+	var manifest model.ReactionManifest
+	if data, err = ioutil.ReadFile(manifestFile); err != nil {
+		return err
+	}
+	if err = json.Unmarshal(data, &manifest); err != nil {
+		return err
+	}
+	manifest.Counts = rs.counts.Get(link, rs.reaction.Kinds)
+	if data, err = json.Marshal(manifest); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(manifestFile, data, os.ModePerm)
+}
+
+// isReactionSpam checks honeypot field 'website', user agent and origin of request
+func isReactionSpam(r *http.Request) bool {
+	if r.PostFormValue("website") != "" {
+		return true
+	}
+	ua := strings.ToLower(r.UserAgent())
+	if ua == "" {
+		return true
+	}
+	for _, bot := range reactionBotAgents {
+		if strings.Contains(ua, bot) {
+			return true
+		}
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		u, err := url.Parse(origin)
+		if err != nil || u.Host != r.Host {
+			return true
+		}
+	}
+	return false
+}
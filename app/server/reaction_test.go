@@ -0,0 +1,30 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReactionsSeen(t *testing.T) {
+	Convey("Reactions Seen", t, func() {
+		rs := NewReactions()
+		now := time.Now()
+		for i := 0; i < reactionDedupeMax+10; i++ {
+			rs.remember(fmt.Sprintf("ip%d|/post|like", i), now)
+		}
+		So(rs.seen, ShouldHaveLength, reactionDedupeMax)
+		So(rs.seenKeys, ShouldHaveLength, reactionDedupeMax)
+		So(rs.seen, ShouldNotContainKey, "ip0|/post|like")
+		So(rs.seen, ShouldContainKey, fmt.Sprintf("ip%d|/post|like", reactionDedupeMax+9))
+
+		Convey("Expire", func() {
+			rs.remember("new|/post|like", now.Add(time.Hour))
+			So(rs.allow("ip", now.Add(reactionDedupeTTL)), ShouldBeTrue)
+			So(rs.seen, ShouldHaveLength, 1)
+			So(rs.seen, ShouldContainKey, "new|/post|like")
+		})
+	})
+}
@@ -0,0 +1,276 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-xiaohei/pugo/app/helper"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+const (
+	// ReloadPath is url path of server-sent events to reload pages after building
+	ReloadPath = "/-/reload"
+	// ReloadScriptPath is url path of script to reload pages, it's injected in html pages
+	ReloadScriptPath = "/-/reload.js"
+
+	// ReloadPatch is event to patch pages in urls
+	ReloadPatch = "patch"
+	// ReloadFull is event to reload pages fully
+	ReloadFull = "reload"
+)
+
+var (
+	// files with these extensions change with content,
+	// they do not change appearance of pages
+	reloadContentExts = map[string]bool{".html": true, ".xml": true, ".json": true, ".txt": true}
+
+	reloadScriptTag = []byte(`<script src="` + ReloadScriptPath + `"></script>`)
+)
+
+type (
+	// Reload pushes events to browsers after building by server-sent events.
+	// If only html pages change, browsers patch DOM of the page,
+	// so scroll position and form state are kept. Otherwise browsers reload pages.
+	Reload struct {
+		lock    sync.Mutex
+		clients map[chan []byte]bool
+		hashes  map[string]string
+	}
+	// ReloadEvent is event sent to browsers
+	ReloadEvent struct {
+		Type string   `json:"type"`
+		URLs []string `json:"urls,omitempty"`
+	}
+)
+
+// NewReload create reload handler
+func NewReload() *Reload {
+	return &Reload{clients: make(map[chan []byte]bool)}
+}
+
+// Notify compares files in dstDir with last building and sends event to browsers,
+// it only saves hashes of files in first building
+func (rl *Reload) Notify(dstDir string) {
+	hashes := make(map[string]string)
+	err := filepath.Walk(dstDir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			return nil
+		}
+		rel, _ := filepath.Rel(dstDir, p)
+		hashes["/"+filepath.ToSlash(rel)], err = helper.Md5File(p)
+		return err
+	})
+	if err != nil {
+		log15.Warn("Server|Reload|%s", err.Error())
+		return
+	}
+	rl.lock.Lock()
+	old := rl.hashes
+	rl.hashes = hashes
+	rl.lock.Unlock()
+	if old == nil {
+		return
+	}
+	if event := reloadEvent(old, hashes); event != nil {
+		log15.Debug("Server|Reload|%s|%d urls", event.Type, len(event.URLs))
+		rl.send(event)
+	}
+}
+
+// reloadEvent return event by changed files, nil if nothing changes
+func reloadEvent(old, hashes map[string]string) *ReloadEvent {
+	var changed []string
+	for f, h := range hashes {
+		if old[f] != h {
+			changed = append(changed, f)
+		}
+	}
+	for f := range old {
+		if _, ok := hashes[f]; !ok {
+			changed = append(changed, f)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	event := &ReloadEvent{Type: ReloadPatch}
+	for _, f := range changed {
+		ext := path.Ext(f)
+		if !reloadContentExts[ext] {
+			return &ReloadEvent{Type: ReloadFull}
+		}
+		if ext == ".html" {
+			event.URLs = append(event.URLs, f)
+		}
+	}
+	if len(event.URLs) == 0 {
+		return nil
+	}
+	return event
+}
+
+func (rl *Reload) send(event *ReloadEvent) {
+	data, _ := json.Marshal(event)
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+	for ch := range rl.clients {
+		select {
+		case ch <- data:
+		default:
+			// client is slow, skip the event
+		}
+	}
+}
+
+// ServeHTTP serves reload script in ReloadScriptPath and events in ReloadPath
+func (rl *Reload) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == ReloadScriptPath {
+		w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Write([]byte(reloadScript))
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is unsupported", http.StatusInternalServerError)
+		return
+	}
+	ch := make(chan []byte, 4)
+	rl.lock.Lock()
+	rl.clients[ch] = true
+	rl.lock.Unlock()
+	defer func() {
+		rl.lock.Lock()
+		delete(rl.clients, ch)
+		rl.lock.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case data := <-ch:
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+		}
+	}
+}
+
+// injectReload adds reload script tag before </body> of html data
+func injectReload(data []byte) []byte {
+	i := bytes.LastIndex(bytes.ToLower(data), []byte("</body>"))
+	if i < 0 {
+		return append(data, reloadScriptTag...)
+	}
+	html := make([]byte, 0, len(data)+len(reloadScriptTag))
+	html = append(html, data[:i]...)
+	html = append(html, reloadScriptTag...)
+	return append(html, data[i:]...)
+}
+
+// reloadScript listens events in ReloadPath,
+// it patches DOM of current page if the page changes, or reloads page fully
+var reloadScript = strings.TrimSpace(`
+(function () {
+    if (!window.EventSource || !window.DOMParser) {
+        return;
+    }
+    // urls of current page in destination, such as "/post.html" and "/post/index.html"
+    function pageURLs() {
+        var p = decodeURI(location.pathname), list = [p];
+        if (p.slice(-1) === "/") {
+            list.push(p + "index.html", p.slice(0, -1) + ".html");
+        } else if (!/\.html$/.test(p)) {
+            list.push(p + ".html", p + "/index.html");
+        }
+        return list;
+    }
+    // morph updates from node to be same as to node, and keeps unchanged nodes,
+    // values typed in form fields are not changed
+    function morph(from, to) {
+        if (from.nodeType !== to.nodeType || from.nodeName !== to.nodeName) {
+            from.parentNode.replaceChild(document.importNode(to, true), from);
+            return;
+        }
+        if (from.nodeType === 3 || from.nodeType === 8) {
+            if (from.nodeValue !== to.nodeValue) {
+                from.nodeValue = to.nodeValue;
+            }
+            return;
+        }
+        if (from.nodeType !== 1) {
+            return;
+        }
+        var i, attr;
+        for (i = from.attributes.length - 1; i >= 0; i--) {
+            if (!to.hasAttribute(from.attributes[i].name)) {
+                from.removeAttribute(from.attributes[i].name);
+            }
+        }
+        for (i = 0; i < to.attributes.length; i++) {
+            attr = to.attributes[i];
+            if (from.getAttribute(attr.name) !== attr.value) {
+                from.setAttribute(attr.name, attr.value);
+            }
+        }
+        if (from.nodeName === "TEXTAREA" || from.nodeName === "SELECT") {
+            return;
+        }
+        var fromNodes = from.childNodes, toNodes = to.childNodes;
+        for (i = 0; i < toNodes.length; i++) {
+            if (i < fromNodes.length) {
+                morph(fromNodes[i], toNodes[i]);
+            } else {
+                from.appendChild(document.importNode(toNodes[i], true));
+            }
+        }
+        while (fromNodes.length > toNodes.length) {
+            from.removeChild(from.lastChild);
+        }
+    }
+    function patch() {
+        fetch(location.href, {cache: "no-store"}).then(function (res) {
+            if (!res.ok) {
+                throw new Error(res.statusText);
+            }
+            return res.text();
+        }).then(function (html) {
+            var doc = new DOMParser().parseFromString(html, "text/html");
+            document.title = doc.title;
+            morph(document.body, doc.body);
+        }).catch(function () {
+            location.reload();
+        });
+    }
+    new EventSource("` + ReloadPath + `").onmessage = function (e) {
+        var event = JSON.parse(e.data);
+        if (event.type === "` + ReloadFull + `") {
+            location.reload();
+            return;
+        }
+        var urls = pageURLs();
+        for (var i = 0; i < event.urls.length; i++) {
+            if (urls.indexOf(event.urls[i]) >= 0) {
+                patch();
+                return;
+            }
+        }
+    };
+})();
+`)
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReload(t *testing.T) {
+	Convey("Reload Event", t, func() {
+		old := map[string]string{
+			"/index.html":   "a",
+			"/post/1.html":  "b",
+			"/css/site.css": "c",
+			"/feed.xml":     "d",
+		}
+		copyHashes := func() map[string]string {
+			hashes := make(map[string]string)
+			for k, v := range old {
+				hashes[k] = v
+			}
+			return hashes
+		}
+
+		So(reloadEvent(old, copyHashes()), ShouldBeNil)
+
+		hashes := copyHashes()
+		hashes["/post/1.html"] = "b2"
+		hashes["/feed.xml"] = "d2"
+		event := reloadEvent(old, hashes)
+		So(event, ShouldNotBeNil)
+		So(event.Type, ShouldEqual, ReloadPatch)
+		So(event.URLs, ShouldResemble, []string{"/post/1.html"})
+
+		// removed page is patched too
+		hashes = copyHashes()
+		delete(hashes, "/post/1.html")
+		event = reloadEvent(old, hashes)
+		So(event.Type, ShouldEqual, ReloadPatch)
+		So(event.URLs, ShouldResemble, []string{"/post/1.html"})
+
+		// content without html pages sends nothing
+		hashes = copyHashes()
+		hashes["/feed.xml"] = "d2"
+		So(reloadEvent(old, hashes), ShouldBeNil)
+
+		hashes = copyHashes()
+		hashes["/index.html"] = "a2"
+		hashes["/css/site.css"] = "c2"
+		event = reloadEvent(old, hashes)
+		So(event.Type, ShouldEqual, ReloadFull)
+		So(event.URLs, ShouldBeEmpty)
+	})
+
+	Convey("Inject Reload", t, func() {
+		tag := string(reloadScriptTag)
+		So(string(injectReload([]byte("<html><BODY><p>x</p></BODY></html>"))), ShouldEqual,
+			"<html><BODY><p>x</p>"+tag+"</BODY></html>")
+		So(string(injectReload([]byte("<p>x</p>"))), ShouldEqual, "<p>x</p>"+tag)
+	})
+
+	Convey("Reload Serve Traversal", t, func() {
+		dir, err := ioutil.TempDir("", "pugo-reload")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		dstDir := filepath.Join(dir, "dest")
+		So(os.MkdirAll(dstDir, os.ModePerm), ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join(dstDir, "index.html"), []byte("<body>index</body>"), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join(dir, "secret.html"), []byte("<body>secret</body>"), 0644), ShouldBeNil)
+
+		s := New(dstDir)
+		s.SetReload(NewReload())
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/index.html", nil))
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldContainSubstring, "index")
+		So(w.Body.String(), ShouldContainSubstring, string(reloadScriptTag))
+
+		for _, p := range []string{"/../secret.html", "/post/../../secret.html", "/..\\secret.html"} {
+			r := httptest.NewRequest("GET", "/index.html", nil)
+			r.URL.Path = p
+			w = httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+			So(w.Body.String(), ShouldNotContainSubstring, "secret")
+		}
+	})
+}
@@ -1,19 +1,38 @@
 package server
 
 import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"path"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Unknwon/com"
 	"gopkg.in/inconshreveable/log15.v2"
 )
 
+const (
+	// StatusPath is url path to show building status
+	StatusPath = "/-/status"
+	// MetricsPath is url path to show metrics
+	MetricsPath = "/-/metrics"
+)
+
 // Server is built-in http server address
 type Server struct {
-	dstDir string
-	prefix string
+	dstDir    string
+	prefix    string
+	status    func() interface{}
+	metrics   http.Handler
+	reactions http.Handler
+	forms     http.Handler
+	reload    *Reload
+	gone      map[string]bool
+	accessLog io.Writer
+	lock      sync.RWMutex
 }
 
 // New create new server on dstDir
@@ -30,43 +49,181 @@ func (s *Server) SetPrefix(prefix string) {
 	if prefix == "" {
 		prefix = "/"
 	}
+	s.lock.Lock()
 	s.prefix = prefix
+	s.lock.Unlock()
 }
 
 // GetPrefix get prefix
 func (s *Server) GetPrefix() string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
 	return s.prefix
 }
 
+// SetStatus set function to get status value,
+// the value is responded as json in StatusPath
+func (s *Server) SetStatus(fn func() interface{}) {
+	s.lock.Lock()
+	s.status = fn
+	s.lock.Unlock()
+}
+
+// SetMetrics set handler to show metrics in MetricsPath
+func (s *Server) SetMetrics(h http.Handler) {
+	s.lock.Lock()
+	s.metrics = h
+	s.lock.Unlock()
+}
+
+// SetReactions set handler to accept reactions in ReactionPath under prefix
+func (s *Server) SetReactions(h http.Handler) {
+	s.lock.Lock()
+	s.reactions = h
+	s.lock.Unlock()
+}
+
+// SetForms set handler to accept form submissions in FormPath under prefix
+func (s *Server) SetForms(h http.Handler) {
+	s.lock.Lock()
+	s.forms = h
+	s.lock.Unlock()
+}
+
+// SetReload set reload handler to serve ReloadPath and ReloadScriptPath,
+// reload script is injected in html pages
+func (s *Server) SetReload(rl *Reload) {
+	s.lock.Lock()
+	s.reload = rl
+	s.lock.Unlock()
+}
+
+// SetGone set url paths that are removed, they are responded as 410 Gone
+func (s *Server) SetGone(paths []string) {
+	gone := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		gone[p] = true
+	}
+	s.lock.Lock()
+	s.gone = gone
+	s.lock.Unlock()
+}
+
+// SetAccessLog set writer to write request logs as json lines,
+// if nil, request logs are printed by text logger
+func (s *Server) SetAccessLog(w io.Writer) {
+	if w != nil {
+		w = &accessWriter{w: w}
+	}
+	s.lock.Lock()
+	s.accessLog = w
+	s.lock.Unlock()
+}
+
+func (s *Server) getStatus() func() interface{} {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.status
+}
+
+func (s *Server) getMetrics() http.Handler {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.metrics
+}
+
+func (s *Server) getReactions() http.Handler {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.reactions
+}
+
+func (s *Server) getForms() http.Handler {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.forms
+}
+
+func (s *Server) getReload() *Reload {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.reload
+}
+
+func (s *Server) isGone(param string) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	trimmed := strings.TrimSuffix(param, "/")
+	return s.gone[param] || s.gone[trimmed+".html"] || s.gone[trimmed+"/index.html"]
+}
+
+func (s *Server) getAccessLog() io.Writer {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.accessLog
+}
+
+func (s *Server) serveStatus(w http.ResponseWriter, r *http.Request) bool {
+	fn := s.getStatus()
+	if fn == nil {
+		return false
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(fn())
+	return true
+}
+
 func (s *Server) serveFile(w http.ResponseWriter, r *http.Request, file string) bool {
 	if com.IsFile(file) {
 		log15.Debug("Server|Dest|%s", file)
+		if s.getReload() != nil && path.Ext(file) == ".html" {
+			s.serveHTML(w, r, file)
+			return true
+		}
 		http.ServeFile(w, r, file)
 		return true
 	}
 	return false
 }
 
+// serveHTML serves html file with reload script
+func (s *Server) serveHTML(w http.ResponseWriter, r *http.Request, file string) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		w.Write(injectReload(data))
+	}
+}
+
 func (s *Server) serveFiles(w http.ResponseWriter, r *http.Request, param string) bool {
+	prefix := s.GetPrefix()
 	ext := path.Ext(param)
 	if ext == "" || ext == "." {
 		// /xyz -> /xyz.html
 		if !strings.HasSuffix(param, "/") {
-			if s.serveFile(w, r, path.Join(s.dstDir, s.prefix, param+".html")) {
+			if s.serveFile(w, r, path.Join(s.dstDir, prefix, param+".html")) {
 				return true
 			}
 		}
 		// /xyz/ -> /xyz/index.html
-		if s.serveFile(w, r, path.Join(s.dstDir, s.prefix, param, "index.html")) {
+		if s.serveFile(w, r, path.Join(s.dstDir, prefix, param, "index.html")) {
 			return true
 		}
 		// /xyz/ -> /xzy.html
 		param = strings.TrimSuffix(param, "/")
-		if s.serveFile(w, r, path.Join(s.dstDir, s.prefix, param+".html")) {
+		if s.serveFile(w, r, path.Join(s.dstDir, prefix, param+".html")) {
 			return true
 		}
 	}
-	if s.serveFile(w, r, path.Join(s.dstDir, s.prefix, param)) {
+	if s.serveFile(w, r, path.Join(s.dstDir, prefix, param)) {
 		return true
 	}
 	return false
@@ -86,22 +243,74 @@ func (s *Server) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 				http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
 			}
 		}
+		if accessLog := s.getAccessLog(); accessLog != nil {
+			jsonLogger(accessLog, w, r)
+			return
+		}
 		logger(w, r)
 	}()
 
 	param := r.URL.Path
+	// files are read by joined path without http.ServeFile when reloading, reject ".." as it does
+	if containsDotDot(param) {
+		http.Error(w, "invalid URL path", http.StatusBadRequest)
+		return
+	}
+	if param == StatusPath && s.serveStatus(w, r) {
+		return
+	}
+	if param == ReloadPath || param == ReloadScriptPath {
+		if rl := s.getReload(); rl != nil {
+			rl.ServeHTTP(w, r)
+			return
+		}
+	}
+	if param == MetricsPath {
+		if h := s.getMetrics(); h != nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+	}
+	prefix := s.GetPrefix()
+	local := strings.TrimPrefix(param, strings.TrimSuffix(prefix, "/"))
+	if local == ReactionPath {
+		if h := s.getReactions(); h != nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+	}
+	if strings.HasPrefix(local, FormPath) {
+		if h := s.getForms(); h != nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+	}
 	if param == "favicon.ico" || param == "robots.txt" {
 		if !s.serveFiles(w, r, param) {
 			http.NotFound(w, r)
 		}
 		return
 	}
-	if !strings.HasPrefix(param, s.prefix) {
-		http.Redirect(w, r, s.prefix, 302)
+	if !strings.HasPrefix(param, prefix) {
+		http.Redirect(w, r, prefix, 302)
 		return
 	}
-	param = strings.TrimPrefix(param, s.prefix)
-	s.serveFiles(w, r, param)
+	if !s.serveFiles(w, r, strings.TrimPrefix(param, prefix)) && s.isGone(param) {
+		http.Error(w, http.StatusText(http.StatusGone), http.StatusGone)
+	}
+}
+
+// containsDotDot return whether path has ".." element
+func containsDotDot(v string) bool {
+	if !strings.Contains(v, "..") {
+		return false
+	}
+	for _, ent := range strings.FieldsFunc(v, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if ent == ".." {
+			return true
+		}
+	}
+	return false
 }
 
 // Run run http server on addr
@@ -115,6 +324,7 @@ func (s *Server) Run(addr string) {
 type responseWriter struct {
 	http.ResponseWriter
 	status    int
+	size      int64
 	startTime time.Time
 	error     interface{}
 }
@@ -123,3 +333,19 @@ func (r *responseWriter) WriteHeader(status int) {
 	r.status = status
 	r.ResponseWriter.WriteHeader(status)
 }
+
+func (r *responseWriter) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(data)
+	r.size += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher to stream responses
+func (r *responseWriter) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,396 @@
+// Package service installs pugo server as a system service,
+// a systemd unit on Linux or a task that starts at boot on Windows.
+package service
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf16"
+)
+
+// restart policies
+const (
+	RestartAlways    = "always"
+	RestartOnFailure = "on-failure"
+)
+
+var (
+	nameRegex = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+	// systemdDir and systemdUserDir are directories of systemd units,
+	// systemdUserDir is relative to home directory
+	systemdDir     = "/etc/systemd/system"
+	systemdUserDir = ".config/systemd/user"
+
+	// runCommand runs service manager commands, such as systemctl and schtasks
+	runCommand = func(name string, args ...string) error {
+		cmd := exec.Command(name, args...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s %s: %s", name, strings.Join(args, " "), err.Error())
+		}
+		return nil
+	}
+)
+
+// Windows accounts that need no password
+var windowsAccounts = map[string]string{
+	"system":         "S-1-5-18",
+	"localservice":   "S-1-5-19",
+	"networkservice": "S-1-5-20",
+}
+
+// Config is settings of service that runs pugo
+type Config struct {
+	// Name is name of systemd unit or Windows task, "pugo" by default
+	Name string
+	Desc string
+	// Exec is absolute path of pugo executable, current executable by default
+	Exec string
+	// Args are arguments of pugo, such as "server --sites=sites.toml"
+	Args []string
+	// User runs the service. It's the user who runs sudo or current user on Linux,
+	// and "NetworkService" on Windows by default
+	User string
+	// Dir is working directory, relative paths in Args are in it, current directory by default
+	Dir string
+	// Restart is "always" or "on-failure", Windows task only supports "on-failure"
+	Restart      string
+	RestartDelay time.Duration
+	// UserUnit installs systemd unit of current user, it does not need root
+	UserUnit bool
+}
+
+func (c *Config) normalize() error {
+	if c.Name == "" {
+		c.Name = "pugo"
+	}
+	if !nameRegex.MatchString(c.Name) {
+		return fmt.Errorf("service name '%s' is invalid", c.Name)
+	}
+	if c.Desc == "" {
+		c.Desc = "PuGo server"
+	}
+	// unit lines and task xml can't keep control characters, such as new lines
+	for _, v := range append([]string{c.Desc, c.Exec, c.Dir, c.User}, c.Args...) {
+		if hasControl(v) {
+			return fmt.Errorf("service setting %q has control characters", v)
+		}
+	}
+	var err error
+	if c.Exec == "" {
+		if c.Exec, err = os.Executable(); err != nil {
+			return err
+		}
+	}
+	if c.Dir == "" {
+		c.Dir = "."
+	}
+	if c.Exec, err = filepath.Abs(c.Exec); err != nil {
+		return err
+	}
+	if c.Dir, err = filepath.Abs(c.Dir); err != nil {
+		return err
+	}
+	switch c.Restart {
+	case "":
+		c.Restart = RestartOnFailure
+	case RestartAlways, RestartOnFailure:
+	default:
+		return fmt.Errorf("restart policy '%s' is not 'always' or 'on-failure'", c.Restart)
+	}
+	if c.RestartDelay <= 0 {
+		c.RestartDelay = 5 * time.Second
+	}
+	if c.User == "" && !c.UserUnit {
+		c.User = defaultUser()
+	}
+	return nil
+}
+
+// hasControl return whether s has control characters
+func hasControl(s string) bool {
+	for _, r := range s {
+		if unicode.IsControl(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func defaultUser() string {
+	if runtime.GOOS == "windows" {
+		return "NetworkService"
+	}
+	if name := os.Getenv("SUDO_USER"); name != "" {
+		return name
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
+// Systemd return systemd unit of service
+func Systemd(c *Config) ([]byte, error) {
+	if err := c.normalize(); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "[Unit]\nDescription=%s\n", strings.Replace(c.Desc, "%", "%%", -1))
+	buf.WriteString("Wants=network-online.target\nAfter=network-online.target\n\n")
+	buf.WriteString("[Service]\nType=simple\n")
+	if c.User != "" && !c.UserUnit {
+		fmt.Fprintf(&buf, "User=%s\n", c.User)
+	}
+	fmt.Fprintf(&buf, "WorkingDirectory=%s\n", strings.Replace(c.Dir, "%", "%%", -1))
+	args := []string{systemdQuote(c.Exec)}
+	for _, a := range c.Args {
+		args = append(args, systemdQuote(a))
+	}
+	fmt.Fprintf(&buf, "ExecStart=%s\n", strings.Join(args, " "))
+	fmt.Fprintf(&buf, "Restart=%s\nRestartSec=%d\n", c.Restart, int(c.RestartDelay.Seconds()))
+	buf.WriteString("NoNewPrivileges=true\n\n")
+	buf.WriteString("[Install]\n")
+	if c.UserUnit {
+		buf.WriteString("WantedBy=default.target\n")
+	} else {
+		buf.WriteString("WantedBy=multi-user.target\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// systemdQuote quotes argument with spaces or special characters,
+// % and $ are escaped as systemd expands them
+func systemdQuote(s string) string {
+	s = strings.NewReplacer("%", "%%", "$", "$$").Replace(s)
+	if s != "" && !strings.ContainsAny(s, " \t\"'\\;") {
+		return s
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// SystemdFile return path of systemd unit file
+func SystemdFile(c *Config) (string, error) {
+	if err := c.normalize(); err != nil {
+		return "", err
+	}
+	if c.UserUnit {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, systemdUserDir, c.Name+".service"), nil
+	}
+	return filepath.Join(systemdDir, c.Name+".service"), nil
+}
+
+type (
+	windowsTask struct {
+		XMLName     xml.Name `xml:"Task"`
+		Version     string   `xml:"version,attr"`
+		Xmlns       string   `xml:"xmlns,attr"`
+		Description string   `xml:"RegistrationInfo>Description"`
+		Boot        struct {
+			Enabled bool
+		} `xml:"Triggers>BootTrigger"`
+		Principal struct {
+			ID        string `xml:"id,attr"`
+			UserID    string `xml:"UserId"`
+			LogonType string `xml:"LogonType"`
+			RunLevel  string `xml:"RunLevel"`
+		} `xml:"Principals>Principal"`
+		Settings struct {
+			MultipleInstancesPolicy    string
+			DisallowStartIfOnBatteries bool
+			StopIfGoingOnBatteries     bool
+			ExecutionTimeLimit         string
+			StartWhenAvailable         bool
+			RestartOnFailure           struct {
+				Interval string
+				Count    int
+			}
+		}
+		Actions struct {
+			Context string `xml:",attr"`
+			Exec    struct {
+				Command          string
+				Arguments        string `xml:",omitempty"`
+				WorkingDirectory string
+			}
+		}
+	}
+)
+
+// WindowsTask return definition of Windows task that starts service at boot,
+// and restarts it after failure. Task scheduler can't restart a task that exits normally,
+// so restart policy "always" is an error.
+func WindowsTask(c *Config) ([]byte, error) {
+	if err := c.normalize(); err != nil {
+		return nil, err
+	}
+	if c.Restart == RestartAlways {
+		return nil, fmt.Errorf("restart policy '%s' is not supported by Windows task, use '%s'", RestartAlways, RestartOnFailure)
+	}
+	t := &windowsTask{
+		Version:     "1.2",
+		Xmlns:       "http://schemas.microsoft.com/windows/2004/02/mit/task",
+		Description: c.Desc,
+	}
+	t.Boot.Enabled = true
+	t.Principal.ID = "Author"
+	t.Principal.RunLevel = "LeastPrivilege"
+	if sid, ok := windowsAccounts[strings.ToLower(c.User)]; ok {
+		t.Principal.UserID, t.Principal.LogonType = sid, "ServiceAccount"
+	} else {
+		t.Principal.UserID, t.Principal.LogonType = c.User, "Password"
+	}
+	t.Settings.MultipleInstancesPolicy = "IgnoreNew"
+	t.Settings.ExecutionTimeLimit = "PT0S"
+	t.Settings.StartWhenAvailable = true
+	// task scheduler restarts after one minute at least
+	minutes := int((c.RestartDelay + time.Minute - 1) / time.Minute)
+	t.Settings.RestartOnFailure.Interval = fmt.Sprintf("PT%dM", minutes)
+	t.Settings.RestartOnFailure.Count = 999
+	t.Actions.Context = "Author"
+	t.Actions.Exec.Command = c.Exec
+	t.Actions.Exec.Arguments = windowsArgs(c.Args)
+	t.Actions.Exec.WorkingDirectory = c.Dir
+
+	data, err := xml.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(`<?xml version="1.0" encoding="UTF-16"?>`+"\n"), data...), nil
+}
+
+// windowsArgs joins arguments to command line, arguments with spaces or quotes are quoted
+func windowsArgs(args []string) string {
+	var list []string
+	for _, a := range args {
+		if a != "" && !strings.ContainsAny(a, " \t\"") {
+			list = append(list, a)
+			continue
+		}
+		list = append(list, `"`+strings.Replace(a, `"`, `\"`, -1)+`"`)
+	}
+	return strings.Join(list, " ")
+}
+
+// utf16File return text as UTF-16 with BOM, schtasks reads task definition in it
+func utf16File(data []byte) []byte {
+	codes := utf16.Encode([]rune(string(data)))
+	buf := make([]byte, 2, 2+len(codes)*2)
+	buf[0], buf[1] = 0xff, 0xfe
+	for _, c := range codes {
+		buf = append(buf, byte(c), byte(c>>8))
+	}
+	return buf
+}
+
+// Install writes service definition, then enables and starts service
+func Install(c *Config) error {
+	switch runtime.GOOS {
+	case "linux":
+		return installSystemd(c)
+	case "windows":
+		return installWindows(c)
+	}
+	return fmt.Errorf("service is not supported on %s", runtime.GOOS)
+}
+
+// Uninstall stops service and removes its definition
+func Uninstall(c *Config) error {
+	switch runtime.GOOS {
+	case "linux":
+		return uninstallSystemd(c)
+	case "windows":
+		if err := c.normalize(); err != nil {
+			return err
+		}
+		runCommand("schtasks", "/End", "/TN", c.Name)
+		return runCommand("schtasks", "/Delete", "/TN", c.Name, "/F")
+	}
+	return fmt.Errorf("service is not supported on %s", runtime.GOOS)
+}
+
+func systemctl(c *Config, args ...string) error {
+	if c.UserUnit {
+		args = append([]string{"--user"}, args...)
+	}
+	return runCommand("systemctl", args...)
+}
+
+func installSystemd(c *Config) error {
+	data, err := Systemd(c)
+	if err != nil {
+		return err
+	}
+	file, err := SystemdFile(c)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(file, data, 0644); err != nil {
+		return err
+	}
+	if err = systemctl(c, "daemon-reload"); err != nil {
+		return err
+	}
+	return systemctl(c, "enable", "--now", c.Name+".service")
+}
+
+func uninstallSystemd(c *Config) error {
+	file, err := SystemdFile(c)
+	if err != nil {
+		return err
+	}
+	if err = systemctl(c, "disable", "--now", c.Name+".service"); err != nil {
+		return err
+	}
+	if err = os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return systemctl(c, "daemon-reload")
+}
+
+func installWindows(c *Config) error {
+	data, err := WindowsTask(c)
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile("", c.Name+"-*.xml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(utf16File(data))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	args := []string{"/Create", "/TN", c.Name, "/XML", f.Name(), "/F"}
+	if _, ok := windowsAccounts[strings.ToLower(c.User)]; !ok {
+		// schtasks prompts password of the account
+		args = append(args, "/RU", c.User, "/RP", "*")
+	}
+	if err = runCommand("schtasks", args...); err != nil {
+		return err
+	}
+	return runCommand("schtasks", "/Run", "/TN", c.Name)
+}
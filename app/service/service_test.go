@@ -0,0 +1,121 @@
+package service
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSystemd(t *testing.T) {
+	Convey("Systemd unit", t, func() {
+		c := &Config{
+			Exec: "/usr/local/bin/pugo",
+			Args: []string{"server", "--sites=my sites.toml", "--addr=0.0.0.0:9899"},
+			User: "www",
+			Dir:  "/srv/pugo",
+		}
+		data, err := Systemd(c)
+		So(err, ShouldBeNil)
+		unit := string(data)
+		So(unit, ShouldContainSubstring, "User=www\n")
+		So(unit, ShouldContainSubstring, "WorkingDirectory=/srv/pugo\n")
+		So(unit, ShouldContainSubstring, `ExecStart=/usr/local/bin/pugo server "--sites=my sites.toml" --addr=0.0.0.0:9899`+"\n")
+		So(unit, ShouldContainSubstring, "Restart=on-failure\nRestartSec=5\n")
+		So(unit, ShouldContainSubstring, "WantedBy=multi-user.target")
+
+		c.UserUnit = true
+		c.User = ""
+		data, err = Systemd(c)
+		So(err, ShouldBeNil)
+		So(string(data), ShouldNotContainSubstring, "User=")
+		So(string(data), ShouldContainSubstring, "WantedBy=default.target")
+
+		So(systemdQuote("100%"), ShouldEqual, "100%%")
+		So(systemdQuote(`a"b c`), ShouldEqual, `"a\"b c"`)
+
+		_, err = Systemd(&Config{Name: "pugo blog", Exec: "/bin/pugo"})
+		So(err, ShouldNotBeNil)
+		_, err = Systemd(&Config{Exec: "/bin/pugo", Restart: "never"})
+		So(err, ShouldNotBeNil)
+
+		// control characters can't be in unit lines
+		for _, c := range []*Config{
+			{Exec: "/bin/pugo", Desc: "PuGo\nExecStartPre=/bin/sh"},
+			{Exec: "/bin/pugo", Dir: "/srv\r/pugo"},
+			{Exec: "/bin/pugo", Args: []string{"server", "--addr=:80\nUser=root"}},
+			{Exec: "/bin/pugo", Name: "pugo\n"},
+		} {
+			_, err = Systemd(c)
+			So(err, ShouldNotBeNil)
+		}
+	})
+
+	Convey("Install systemd unit", t, func() {
+		dir, _ := ioutil.TempDir("", "pugo-service")
+		defer os.RemoveAll(dir)
+		oldDir, oldRun := systemdDir, runCommand
+		defer func() { systemdDir, runCommand = oldDir, oldRun }()
+		systemdDir = dir
+		var commands []string
+		runCommand = func(name string, args ...string) error {
+			commands = append(commands, name+" "+strings.Join(args, " "))
+			return nil
+		}
+
+		c := &Config{Name: "blog", Exec: "/bin/pugo", Args: []string{"server"}, User: "www"}
+		So(installSystemd(c), ShouldBeNil)
+		data, err := ioutil.ReadFile(filepath.Join(dir, "blog.service"))
+		So(err, ShouldBeNil)
+		So(string(data), ShouldContainSubstring, "ExecStart=/bin/pugo server")
+		So(commands, ShouldResemble, []string{"systemctl daemon-reload", "systemctl enable --now blog.service"})
+
+		commands = nil
+		So(uninstallSystemd(c), ShouldBeNil)
+		_, err = os.Stat(filepath.Join(dir, "blog.service"))
+		So(os.IsNotExist(err), ShouldBeTrue)
+		So(commands, ShouldResemble, []string{"systemctl disable --now blog.service", "systemctl daemon-reload"})
+	})
+}
+
+func TestWindowsTask(t *testing.T) {
+	Convey("Windows task", t, func() {
+		c := &Config{
+			Exec:         `C:\pugo\pugo.exe`,
+			Args:         []string{"server", `--sites=C:\my sites\sites.toml`},
+			User:         "NetworkService",
+			Dir:          `C:\pugo`,
+			RestartDelay: 90 * time.Second,
+		}
+		data, err := WindowsTask(c)
+		So(err, ShouldBeNil)
+		So(string(data), ShouldStartWith, `<?xml version="1.0" encoding="UTF-16"?>`)
+
+		task := new(windowsTask)
+		So(xml.Unmarshal(data[strings.Index(string(data), "\n"):], task), ShouldBeNil)
+		So(task.Principal.UserID, ShouldEqual, "S-1-5-20")
+		So(task.Principal.LogonType, ShouldEqual, "ServiceAccount")
+		So(task.Actions.Exec.Arguments, ShouldEqual, `server "--sites=C:\my sites\sites.toml"`)
+		So(task.Settings.RestartOnFailure.Interval, ShouldEqual, "PT2M")
+		So(task.Settings.ExecutionTimeLimit, ShouldEqual, "PT0S")
+
+		c.User = `DOMAIN\pugo`
+		data, err = WindowsTask(c)
+		So(err, ShouldBeNil)
+		So(string(data), ShouldContainSubstring, "<LogonType>Password</LogonType>")
+
+		c.Restart = RestartAlways
+		_, err = WindowsTask(c)
+		So(err, ShouldNotBeNil)
+		_, err = WindowsTask(&Config{Exec: `C:\pugo.exe`, Desc: "a\tb"})
+		So(err, ShouldNotBeNil)
+
+		b := utf16File([]byte("<a/>"))
+		So(b[:4], ShouldResemble, []byte{0xff, 0xfe, '<', 0})
+	})
+}
@@ -13,16 +13,23 @@ import (
 
 // Syncer manage sync file
 type Syncer struct {
-	dir         string
-	syncLock    sync.Mutex
-	syncedFiles map[string]bool
+	dir      string
+	syncLock sync.Mutex
+	// syncedFiles saves source file of synced file,
+	// source is empty if the file is compiled
+	syncedFiles map[string]string
+	// hashes saves md5 hash of copied files by relative path
+	hashes map[string]string
+	// Logger receives logs of syncing, root logger by default
+	Logger log15.Logger
 }
 
 // NewSyncer create a sync object to sync file to dir
 func NewSyncer(dir string) *Syncer {
 	return &Syncer{
 		dir:         dir,
-		syncedFiles: make(map[string]bool),
+		syncedFiles: make(map[string]string),
+		hashes:      make(map[string]string),
 	}
 }
 
@@ -41,18 +48,24 @@ type DirOption struct {
 	Filter func(string) bool
 	Prefix string
 	Ignore []string
+	// Keep keeps files that are synced already,
+	// and logs conflicts of them
+	Keep bool
+	// Transform return new relative file and copy function of file,
+	// the file is skipped if new relative file is empty,
+	// the file is copied as it is if copy function is nil
+	Transform func(relFile, src string) (string, func(src, dst string) error)
 }
 
-// SyncDir sync directory files to syncer's directory
+// SyncDir sync directory files to syncer's directory.
+// Files are copied in a worker pool, md5 hash of each file is computed during copying,
+// and copying is skipped if destination file has same hash.
 func (s *Syncer) SyncDir(dir string, opt *DirOption) error {
 	if !com.IsDir(dir) {
 		return nil
 	}
-	var (
-		relFile string
-		dstFile string
-	)
-	return filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+	w := helper.NewWorker(0)
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -64,7 +77,7 @@ func (s *Syncer) SyncDir(dir string, opt *DirOption) error {
 				return nil
 			}
 		}
-		relFile, _ = filepath.Rel(dir, p)
+		relFile, _ := filepath.Rel(dir, p)
 		if opt != nil {
 			if len(opt.Ignore) > 0 {
 				for _, ignore := range opt.Ignore {
@@ -77,34 +90,148 @@ func (s *Syncer) SyncDir(dir string, opt *DirOption) error {
 				relFile = filepath.Join(opt.Prefix, relFile)
 			}
 		}
-		dstFile = filepath.Join(s.dir, relFile)
-		if com.IsFile(dstFile) {
-			hash1, _ := helper.Md5File(p)
-			hash2, _ := helper.Md5File(dstFile)
-			if hash1 == hash2 {
-				log15.Debug("Sync|Keep|%s", dstFile)
-				s.SetSynced(dstFile)
+		var copyFn func(src, dst string) error
+		if opt != nil && opt.Transform != nil {
+			if relFile, copyFn = opt.Transform(relFile, p); relFile == "" {
+				s.log().Debug("Sync|Skip|%s", p)
 				return nil
 			}
 		}
-		os.MkdirAll(filepath.Dir(dstFile), os.ModePerm)
-		if err := com.Copy(p, dstFile); err != nil {
-			return err
+		dstFile := filepath.Join(s.dir, relFile)
+		if opt != nil && opt.Keep {
+			if from, ok := s.SyncedFrom(dstFile); ok {
+				if from == "" {
+					from = "compiled"
+				}
+				s.log().Warn("Sync|Conflict|%s|use %s, skip %s", filepath.ToSlash(relFile), from, filepath.ToSlash(p))
+				return nil
+			}
 		}
-		log15.Debug("Sync|Write|%s", dstFile)
-		s.SetSynced(dstFile)
+		// set synced before copying, so conflicts are found in walking
+		s.setSynced(dstFile, p)
+		w.AddFunc(func() error {
+			return s.syncFile(p, dstFile, copyFn)
+		})
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+	w.RunOnce()
+	if errs := w.Errors(); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// syncFile copies src to dst file and saves hash of dst file,
+// it writes dst file by copyFn if it's not nil
+func (s *Syncer) syncFile(src, dst string, copyFn func(src, dst string) error) error {
+	os.MkdirAll(filepath.Dir(dst), os.ModePerm)
+	if copyFn != nil {
+		if err := copyFn(src, dst); err != nil {
+			return err
+		}
+		s.log().Debug("Sync|Write|%s", dst)
+		hash, err := helper.Md5File(dst)
+		if err != nil {
+			return err
+		}
+		s.setHash(dst, hash)
+		return nil
+	}
+	if si, di := fileSize(src), fileSize(dst); si >= 0 && si == di {
+		hash1, _ := helper.Md5File(src)
+		hash2, _ := helper.Md5File(dst)
+		if hash1 != "" && hash1 == hash2 {
+			s.log().Debug("Sync|Keep|%s", dst)
+			s.setHash(dst, hash1)
+			return nil
+		}
+	}
+	hash, err := helper.CopyFileHash(src, dst)
+	if err != nil {
+		return err
+	}
+	s.log().Debug("Sync|Write|%s", dst)
+	s.setHash(dst, hash)
+	return nil
+}
+
+// log return logger of syncer
+func (s *Syncer) log() log15.Logger {
+	if s.Logger == nil {
+		return log15.Root()
+	}
+	return s.Logger
+}
+
+func fileSize(file string) int64 {
+	fi, err := os.Stat(file)
+	if err != nil {
+		return -1
+	}
+	return fi.Size()
 }
 
-// SetSynced set file as synced file
+func (s *Syncer) setHash(file, hash string) {
+	rel, _ := filepath.Rel(s.dir, file)
+	s.syncLock.Lock()
+	s.hashes[filepath.ToSlash(rel)] = hash
+	s.syncLock.Unlock()
+}
+
+// Hashes return md5 hashes of copied files by relative path in s.dir,
+// compiled files are not included
+func (s *Syncer) Hashes() map[string]string {
+	s.syncLock.Lock()
+	defer s.syncLock.Unlock()
+	hashes := make(map[string]string, len(s.hashes))
+	for f, h := range s.hashes {
+		hashes[f] = h
+	}
+	return hashes
+}
+
+// Rehash updates md5 hash of copied file after it's changed,
+// compiled files are skipped
+func (s *Syncer) Rehash(file string) error {
+	rel, _ := filepath.Rel(s.dir, file)
+	s.syncLock.Lock()
+	_, ok := s.hashes[filepath.ToSlash(rel)]
+	s.syncLock.Unlock()
+	if !ok {
+		return nil
+	}
+	hash, err := helper.Md5File(file)
+	if err != nil {
+		return err
+	}
+	s.setHash(file, hash)
+	return nil
+}
+
+// SetSynced set file as synced file that is compiled
 func (s *Syncer) SetSynced(file string) {
+	s.setSynced(file, "")
+}
+
+func (s *Syncer) setSynced(file, from string) {
 	file = filepath.ToSlash(file)
 	s.syncLock.Lock()
-	s.syncedFiles[file] = true
+	s.syncedFiles[file] = filepath.ToSlash(from)
 	s.syncLock.Unlock()
 }
 
+// SyncedFrom return source file of the synced file,
+// source is empty if the file is compiled
+func (s *Syncer) SyncedFrom(file string) (string, bool) {
+	s.syncLock.Lock()
+	defer s.syncLock.Unlock()
+	from, ok := s.syncedFiles[filepath.ToSlash(file)]
+	return from, ok
+}
+
 // Clear clean all non-synced file in s.dir
 func (s *Syncer) Clear(opt *DirOption) error {
 	return filepath.Walk(s.dir, func(p string, info os.FileInfo, err error) error {
@@ -119,11 +246,11 @@ func (s *Syncer) Clear(opt *DirOption) error {
 				}
 			}
 		}
-		p = filepath.ToSlash(p)
-		if s.syncedFiles[p] {
+		if _, ok := s.SyncedFrom(p); ok {
 			return nil
 		}
-		log15.Debug("Sync|Del|%s", p)
+		p = filepath.ToSlash(p)
+		s.log().Debug("Sync|Del|%s", p)
 		return os.Remove(p)
 	})
 }
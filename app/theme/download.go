@@ -0,0 +1,116 @@
+package theme
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+const downloadMaxSize = 64 << 20
+
+// Install downloads theme to dir,
+// it extracts zip archive of download url, or clones git repository
+func (item *IndexItem) Install(dir string) error {
+	if item.Download != "" {
+		log15.Info("Theme|Download|%s", item.Download)
+		resp, err := indexHTTPClient.Get(item.Download)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("download theme '%s': %s", item.Name, resp.Status)
+		}
+		data, err := ioutil.ReadAll(io.LimitReader(resp.Body, downloadMaxSize))
+		if err != nil {
+			return err
+		}
+		return unzip(data, dir)
+	}
+	if err := checkRepo(item.Repo); err != nil {
+		return fmt.Errorf("clone theme '%s': %s", item.Name, err.Error())
+	}
+	log15.Info("Theme|Clone|%s", item.Repo)
+	cmd := exec.Command("git", "clone", "--depth", "1", "--", item.Repo, dir)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("clone theme '%s': %s", item.Name, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// checkRepo allows https and git urls of repository only,
+// repository from index is not trusted as an argument of git
+func checkRepo(repo string) error {
+	u, err := url.Parse(repo)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "git") || u.Host == "" || strings.HasPrefix(repo, "-") {
+		return fmt.Errorf("repository '%s' is not https or git url", repo)
+	}
+	return nil
+}
+
+// unzip extracts zip data to dir,
+// the only top directory in archive is trimmed, as archives of GitHub
+func unzip(data []byte, dir string) error {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+	var top string
+	for i, f := range r.File {
+		name := strings.SplitN(f.Name, "/", 2)[0]
+		if i == 0 {
+			top = name
+		}
+		if name != top || name == ".." || !strings.Contains(f.Name, "/") {
+			top = ""
+			break
+		}
+	}
+	for _, f := range r.File {
+		name := f.Name
+		if top != "" {
+			name = strings.TrimPrefix(name, top+"/")
+		}
+		if name == "" {
+			continue
+		}
+		dst := filepath.Join(dir, filepath.FromSlash(name))
+		if !strings.HasPrefix(dst, filepath.Clean(dir)+string(filepath.Separator)) {
+			return fmt.Errorf("illegal file path '%s' in archive", f.Name)
+		}
+		if f.FileInfo().IsDir() {
+			os.MkdirAll(dst, os.ModePerm)
+			continue
+		}
+		os.MkdirAll(filepath.Dir(dst), os.ModePerm)
+		if err = unzipFile(f, dst); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func unzipFile(f *zip.File, dst string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	w, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	_, err = io.Copy(w, rc)
+	return err
+}
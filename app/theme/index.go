@@ -0,0 +1,116 @@
+package theme
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Unknwon/com"
+	"github.com/go-xiaohei/pugo/app/model"
+	"github.com/go-xiaohei/pugo/app/vars"
+	"github.com/mcuadros/go-version"
+)
+
+// DefaultIndexURL is community index of themes
+const DefaultIndexURL = "https://pugo.io/themes.json"
+
+var indexHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// IndexItem is a theme in community index
+type IndexItem struct {
+	Name       string   `json:"name"`
+	Desc       string   `json:"desc"`
+	Tags       []string `json:"tags"`
+	Repo       string   `json:"repo"`
+	Download   string   `json:"download"`
+	Demo       string   `json:"demo"`
+	Version    string   `json:"version"`
+	MinVersion string   `json:"min_version"`
+}
+
+// IsCompatible return whether current PuGo version is enough for the theme
+func (item *IndexItem) IsCompatible() bool {
+	return item.MinVersion == "" || !version.Compare(vars.Version, item.MinVersion, "<")
+}
+
+// Index is list of themes in community index
+type Index []*IndexItem
+
+// FetchIndex fetches themes index as json from url
+func FetchIndex(url string) (Index, error) {
+	resp, err := indexHTTPClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch themes index: %s", resp.Status)
+	}
+	return NewIndex(resp.Body)
+}
+
+// NewIndex parses themes index from json,
+// themes without name or repo and download url are skipped
+func NewIndex(r io.Reader) (Index, error) {
+	var items Index
+	if err := json.NewDecoder(r).Decode(&items); err != nil {
+		return nil, err
+	}
+	index := make(Index, 0, len(items))
+	for _, item := range items {
+		if item == nil || item.Name == "" || (item.Repo == "" && item.Download == "") {
+			continue
+		}
+		index = append(index, item)
+	}
+	sort.Sort(index)
+	return index, nil
+}
+
+func (idx Index) Len() int           { return len(idx) }
+func (idx Index) Less(i, j int) bool { return idx[i].Name < idx[j].Name }
+func (idx Index) Swap(i, j int)      { idx[i], idx[j] = idx[j], idx[i] }
+
+// Get return theme by name, name is case-insensitive
+func (idx Index) Get(name string) *IndexItem {
+	for _, item := range idx {
+		if strings.EqualFold(item.Name, name) {
+			return item
+		}
+	}
+	return nil
+}
+
+// Local is theme in local directory
+type Local struct {
+	Dir  string
+	Meta *Meta
+}
+
+// ListLocal return themes in sub directories of dir, the directory with theme meta file is a theme
+func ListLocal(dir string) ([]*Local, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var themes []*Local
+	for _, fi := range files {
+		if !fi.IsDir() {
+			continue
+		}
+		sub := filepath.Join(dir, fi.Name())
+		for _, f := range model.ShouldThemeMetaFiles() {
+			if com.IsFile(filepath.Join(sub, f)) {
+				themes = append(themes, &Local{Dir: sub, Meta: New(sub).Meta})
+				break
+			}
+		}
+	}
+	return themes, nil
+}
@@ -0,0 +1,98 @@
+package theme
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Unknwon/com"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestIndex(t *testing.T) {
+	Convey("NewIndex", t, func() {
+		data := `[
+			{"name":"uno","desc":"uno theme","repo":"https://github.com/example/uno","min_version":"0.10.0"},
+			{"name":"future","download":"https://example.com/future.zip","min_version":"99.0.0"},
+			{"name":"broken"},
+			{"desc":"no name","repo":"https://github.com/example/x"}
+		]`
+		index, err := NewIndex(strings.NewReader(data))
+		So(err, ShouldBeNil)
+		So(index, ShouldHaveLength, 2)
+		So(index[0].Name, ShouldEqual, "future")
+		So(index.Get("UNO"), ShouldEqual, index[1])
+		So(index.Get("broken"), ShouldBeNil)
+		So(index.Get("uno").IsCompatible(), ShouldBeTrue)
+		So(index.Get("future").IsCompatible(), ShouldBeFalse)
+	})
+
+	Convey("Install", t, func() {
+		var buf bytes.Buffer
+		w := zip.NewWriter(&buf)
+		for name, content := range map[string]string{
+			"theme-master/theme.toml":         `name = "zipped"`,
+			"theme-master/post.html":          "post",
+			"theme-master/static/css/app.css": "body{}",
+		} {
+			f, _ := w.Create(name)
+			f.Write([]byte(content))
+		}
+		w.Close()
+		ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.Write(buf.Bytes())
+		}))
+		defer ts.Close()
+
+		dir, _ := ioutil.TempDir("", "pugo-theme-")
+		defer os.RemoveAll(dir)
+		item := &IndexItem{Name: "zipped", Download: ts.URL}
+		So(item.Install(dir), ShouldBeNil)
+		So(com.IsFile(filepath.Join(dir, "theme.toml")), ShouldBeTrue)
+		So(com.IsFile(filepath.Join(dir, "static/css/app.css")), ShouldBeTrue)
+
+		themes, err := ListLocal(filepath.Dir(dir))
+		So(err, ShouldBeNil)
+		var found bool
+		for _, th := range themes {
+			if th.Dir == dir {
+				found = th.Meta != nil && th.Meta.Name == "zipped"
+			}
+		}
+		So(found, ShouldBeTrue)
+	})
+
+	Convey("UnzipIllegalPath", t, func() {
+		var buf bytes.Buffer
+		w := zip.NewWriter(&buf)
+		f, _ := w.Create("theme/post.html")
+		f.Write([]byte("post"))
+		f, _ = w.Create("theme/../../evil.txt")
+		f.Write([]byte("evil"))
+		w.Close()
+		dir, _ := ioutil.TempDir("", "pugo-unzip-")
+		defer os.RemoveAll(dir)
+		So(unzip(buf.Bytes(), filepath.Join(dir, "theme")), ShouldNotBeNil)
+		So(com.IsFile(filepath.Join(dir, "evil.txt")), ShouldBeFalse)
+	})
+	Convey("CheckRepo", t, func() {
+		So(checkRepo("https://github.com/go-xiaohei/pugo-theme.git"), ShouldBeNil)
+		So(checkRepo("git://example.com/theme.git"), ShouldBeNil)
+		So(checkRepo("--upload-pack=touch /tmp/x"), ShouldNotBeNil)
+		So(checkRepo("ext::sh -c touch% /tmp/x"), ShouldNotBeNil)
+		So(checkRepo("file:///etc"), ShouldNotBeNil)
+		So(checkRepo("/tmp/theme"), ShouldNotBeNil)
+
+		dir, _ := ioutil.TempDir("", "pugo-clone-")
+		defer os.RemoveAll(dir)
+		item := &IndexItem{Name: "evil", Repo: "--upload-pack=touch " + filepath.Join(dir, "x")}
+		So(item.Install(filepath.Join(dir, "theme")), ShouldNotBeNil)
+		So(com.IsFile(filepath.Join(dir, "x")), ShouldBeFalse)
+	})
+}
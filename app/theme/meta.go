@@ -8,12 +8,13 @@ import (
 
 // Meta is description of theme
 type Meta struct {
-	Name string   `toml:"name" ini:"name"`
-	Repo string   `toml:"repo" ini:"repo"`
-	URL  string   `toml:"url" ini:"url"`
-	Date string   `toml:"date" ini:"date"`
-	Desc string   `toml:"desc" ini:"desc"`
-	Tags []string `toml:"tags" ini:"-"`
+	Name    string   `toml:"name" ini:"name"`
+	Version string   `toml:"version" ini:"version"`
+	Repo    string   `toml:"repo" ini:"repo"`
+	URL     string   `toml:"url" ini:"url"`
+	Date    string   `toml:"date" ini:"date"`
+	Desc    string   `toml:"desc" ini:"desc"`
+	Tags    []string `toml:"tags" ini:"-"`
 
 	MinVersion string `toml:"min_version" ini:"min_version"`
 
@@ -22,6 +23,9 @@ type Meta struct {
 
 	License    string `toml:"license" ini:"license"`
 	LicenseURL string `toml:"license_url" ini:"license_url"`
+
+	// Fields are custom fields of page front-matter in meta table, used by templates
+	Fields []*model.FrontMatterField `toml:"field" ini:"-"`
 }
 
 type metaRef struct {
@@ -0,0 +1,70 @@
+package theme
+
+import (
+	"fmt"
+	"html/template"
+	"sort"
+)
+
+// PlaceholderClass is css class of placeholders rendered for missing templates
+const PlaceholderClass = "pugo-placeholder"
+
+// missingTemplate handles template that is referenced but missing when loading,
+// it's error in strict mode, or defined as placeholder in placeholder mode
+func (th *Theme) missingTemplate(name string) error {
+	if !th.missing[name] {
+		th.missing[name] = true
+		th.log().Warn("Theme|Template|%s|file is missing", name)
+	}
+	if th.Strict {
+		return fmt.Errorf("template '%s' is missing", name)
+	}
+	if !th.Placeholder {
+		return nil
+	}
+	for _, nt := range th.cache {
+		if nt.Name == name {
+			return nil
+		}
+	}
+	th.cache = append(th.cache, &namedTemplate{
+		Name: name,
+		Src:  string(placeholderHTML(name)),
+	})
+	return nil
+}
+
+// missingInclude return content of missing template in Include func
+func (th *Theme) missingInclude(name string) (template.HTML, error) {
+	th.lock.Lock()
+	first := !th.missing[name]
+	th.missing[name] = true
+	th.lock.Unlock()
+	if first {
+		th.log().Warn("Theme|Template|%s|file is missing", name)
+	}
+	if th.Strict {
+		return "", fmt.Errorf("template '%s' is missing", name)
+	}
+	if th.Placeholder {
+		return placeholderHTML(name), nil
+	}
+	return template.HTML("<!-- template " + name + " is missing -->"), nil
+}
+
+// Missing return names of missing templates that are referenced by templates
+func (th *Theme) Missing() []string {
+	th.lock.Lock()
+	defer th.lock.Unlock()
+	var names []string
+	for name := range th.missing {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func placeholderHTML(name string) template.HTML {
+	return template.HTML(fmt.Sprintf(`<div class="%s" style="border:2px dashed #c00;color:#c00;padding:8px">missing template %s</div>`,
+		PlaceholderClass, template.HTMLEscapeString(name)))
+}
@@ -0,0 +1,49 @@
+package theme
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMissing(t *testing.T) {
+	Convey("Missing", t, func() {
+		dir, err := ioutil.TempDir("", "pugo-missing-")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		So(ioutil.WriteFile(filepath.Join(dir, "index.html"), []byte(`a{{template "header.html" .}}b`), os.ModePerm), ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join(dir, "page.html"), []byte(`c{{Include "embed/none.html" .}}d`), os.ModePerm), ShouldBeNil)
+
+		th := New(dir)
+		So(th.Load(), ShouldBeNil)
+		So(th.Missing(), ShouldResemble, []string{"header.html"})
+		var buf bytes.Buffer
+		So(th.Execute(&buf, "index.html", nil), ShouldNotBeNil)
+		buf.Reset()
+		So(th.Execute(&buf, "page.html", nil), ShouldBeNil)
+		So(buf.String(), ShouldEqual, "c<!-- template embed/none.html is missing -->d")
+		So(th.Missing(), ShouldResemble, []string{"embed/none.html", "header.html"})
+
+		th = New(dir)
+		th.Placeholder = true
+		So(th.Load(), ShouldBeNil)
+		buf.Reset()
+		So(th.Execute(&buf, "index.html", nil), ShouldBeNil)
+		So(buf.String(), ShouldContainSubstring, PlaceholderClass)
+		So(buf.String(), ShouldContainSubstring, "missing template header.html")
+		buf.Reset()
+		So(th.Execute(&buf, "page.html", nil), ShouldBeNil)
+		So(buf.String(), ShouldContainSubstring, "missing template embed/none.html")
+
+		th = New(dir)
+		th.Strict = true
+		So(th.Load(), ShouldNotBeNil)
+		So(os.Remove(filepath.Join(dir, "index.html")), ShouldBeNil)
+		So(th.Load(), ShouldBeNil)
+		So(th.Execute(&buf, "page.html", nil), ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,28 @@
+package theme
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// IsInside return whether file is in theme directory after resolving symlinks
+func (th *Theme) IsInside(file string) bool {
+	dir, err := filepath.EvalSymlinks(th.dir)
+	if err != nil {
+		return false
+	}
+	if file, err = filepath.EvalSymlinks(file); err != nil {
+		return false
+	}
+	if dir, err = filepath.Abs(dir); err != nil {
+		return false
+	}
+	if file, err = filepath.Abs(file); err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(dir, file)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
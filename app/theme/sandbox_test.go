@@ -0,0 +1,48 @@
+package theme
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSandbox(t *testing.T) {
+	Convey("Sandbox", t, func() {
+		tmpDir, err := ioutil.TempDir("", "pugo-sandbox-")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(tmpDir)
+		dir := filepath.Join(tmpDir, "theme")
+		So(os.MkdirAll(dir, os.ModePerm), ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join(tmpDir, "secret.html"), []byte("secret"), os.ModePerm), ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join(dir, "index.html"), []byte(`index`), os.ModePerm), ShouldBeNil)
+		So(os.Symlink(filepath.Join(tmpDir, "secret.html"), filepath.Join(dir, "link.html")), ShouldBeNil)
+
+		newTheme := func(sandbox bool) *Theme {
+			th := New(dir)
+			th.Sandbox = sandbox
+			return th
+		}
+
+		th := newTheme(false)
+		So(th.Load(), ShouldBeNil)
+		So(th.Template("link.html"), ShouldNotBeNil)
+		var buf bytes.Buffer
+		So(th.Execute(&buf, "index.html", nil), ShouldBeNil)
+		So(buf.String(), ShouldEqual, "index")
+
+		th = newTheme(true)
+		So(th.Load(), ShouldBeNil)
+		So(th.Template("link.html"), ShouldBeNil)
+		So(th.IsInside(filepath.Join(dir, "index.html")), ShouldBeTrue)
+		So(th.IsInside(filepath.Join(dir, "link.html")), ShouldBeFalse)
+
+		// template out of theme directory
+		So(ioutil.WriteFile(filepath.Join(dir, "index.html"), []byte(`{{template "../secret.html"}}`), os.ModePerm), ShouldBeNil)
+		So(newTheme(true).Load(), ShouldNotBeNil)
+		So(newTheme(false).Load(), ShouldBeNil)
+	})
+}
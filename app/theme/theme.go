@@ -11,6 +11,7 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 
@@ -43,6 +44,18 @@ type (
 
 		cache               []*namedTemplate
 		regularTemplateDefs []string
+
+		// Sandbox is restricted mode for untrusted themes,
+		// templates and static files out of theme directory are not read
+		Sandbox bool
+		// Strict fails loading or executing templates that reference missing templates
+		Strict bool
+		// Placeholder renders visible placeholders of missing templates, such as in draft mode
+		Placeholder bool
+		// missing are names of missing templates that are referenced
+		missing map[string]bool
+		// Logger receives logs of theme, root logger by default
+		Logger log15.Logger
 	}
 	namedTemplate struct {
 		Name string
@@ -56,6 +69,7 @@ func New(dir string) *Theme {
 		dir:        dir,
 		funcMap:    make(template.FuncMap),
 		extensions: []string{".html"},
+		missing:    make(map[string]bool),
 	}
 	theme.funcMap["HTML"] = func(v interface{}) template.HTML {
 		if str, ok := v.(string); ok {
@@ -66,26 +80,29 @@ func New(dir string) *Theme {
 		}
 		return template.HTML(fmt.Sprintf("%v", v))
 	}
-	theme.funcMap["Include"] = func(values ...interface{}) template.HTML {
+	theme.funcMap["Include"] = func(values ...interface{}) (template.HTML, error) {
 		var buf bytes.Buffer
 		if len(values) < 2 {
-			return template.HTML("<!-- include template without path or data -->")
+			return template.HTML("<!-- include template without path or data -->"), nil
 		}
 		var pathData []string
 		for i, v := range values {
 			if i < len(values)-1 {
 				str, ok := v.(string)
 				if !ok {
-					return template.HTML("<!-- include template with non-string path -->")
+					return template.HTML("<!-- include template with non-string path -->"), nil
 				}
 				pathData = append(pathData, str)
 			}
 		}
 		tpl := path.Join(pathData...)
+		if theme.Template(tpl) == nil {
+			return theme.missingInclude(tpl)
+		}
 		if err := theme.Execute(&buf, tpl, values[len(values)-1]); err != nil {
-			return template.HTML("<!-- template " + tpl + " error:" + err.Error() + "-->")
+			return template.HTML("<!-- template " + tpl + " error:" + err.Error() + "-->"), nil
 		}
-		return template.HTML(string(buf.Bytes()))
+		return template.HTML(string(buf.Bytes())), nil
 	}
 	theme.parseMeta()
 	return theme
@@ -105,12 +122,20 @@ func (th *Theme) parseMeta() {
 		th.Meta, th.metaError = NewMeta(data, t)
 		if th.Meta != nil {
 			th.metaFile = file
-			log15.Debug("Theme|%s", file)
+			th.log().Debug("Theme|%s", file)
 		}
 		return
 	}
 }
 
+// log return logger of theme
+func (th *Theme) log() log15.Logger {
+	if th.Logger == nil {
+		return log15.Root()
+	}
+	return th.Logger
+}
+
 // Func add template func to theme
 func (th *Theme) Func(key string, fn interface{}) {
 	th.funcMap[key] = fn
@@ -133,6 +158,7 @@ func (th *Theme) loadTemplates() error {
 	defer th.lock.Unlock()
 
 	templates := make(map[string]*template.Template)
+	th.missing = make(map[string]bool)
 
 	err := filepath.Walk(th.dir, func(p string, fi os.FileInfo, err error) error {
 		r, err := filepath.Rel(th.dir, p) // get relative path
@@ -142,6 +168,10 @@ func (th *Theme) loadTemplates() error {
 		ext := getExt(r)
 		for _, extension := range th.extensions {
 			if ext == extension {
+				if th.Sandbox && !th.IsInside(p) {
+					th.log().Warn("Theme|Sandbox|%s|out of theme directory, skip", p)
+					break
+				}
 				if err := th.add(p); err != nil {
 					return err
 				}
@@ -240,7 +270,17 @@ func (th *Theme) add(path string) error {
 		}
 
 		// Add this template and continue looking for more template blocks
-		th.add(filepath.Join(th.dir, templatePath))
+		file := filepath.Join(th.dir, templatePath)
+		if th.Sandbox && com.IsFile(file) && !th.IsInside(file) {
+			return fmt.Errorf("template '%s' is out of theme directory in sandbox mode", templatePath)
+		}
+		if !com.IsFile(file) {
+			if err := th.missingTemplate(templatePath); err != nil {
+				return err
+			}
+			continue
+		}
+		th.add(file)
 	}
 	return nil
 }
@@ -275,6 +315,27 @@ func (th *Theme) Template(name string) *template.Template {
 	return th.templates[name]
 }
 
+// TemplateNames return names of all loaded templates
+func (th *Theme) TemplateNames() []string {
+	var names []string
+	for name := range th.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Variant return name of variant template, such as "post.legacy.html" of "post.html",
+// it return name if the variant template is missing
+func (th *Theme) Variant(name, variant string) string {
+	ext := path.Ext(name)
+	v := strings.TrimSuffix(name, ext) + "." + variant + ext
+	if th.Template(v) != nil {
+		return v
+	}
+	return name
+}
+
 // Validate check theme meta is valid or not
 func (th *Theme) Validate() error {
 	if th.metaFile == "" {
@@ -25,6 +25,13 @@ func TestTheme(t *testing.T) {
 			So(funcs, ShouldContainKey, "HTML")
 			So(funcs, ShouldContainKey, "Include")
 		})
+
+		Convey("Variant", func() {
+			So(theme.Variant("post.html", "legacy"), ShouldEqual, "post.html")
+			theme.templates["post.legacy.html"] = theme.Template("post.html")
+			defer delete(theme.templates, "post.legacy.html")
+			So(theme.Variant("post.html", "legacy"), ShouldEqual, "post.legacy.html")
+		})
 	})
 
 }
@@ -27,8 +27,21 @@ func main() {
 		command.Build,
 		command.Server,
 		command.New,
+		command.Fmt,
+		command.Test,
+		command.Dev,
+		command.Theme,
+		command.Migrate,
+		command.Syndicate,
 		command.Doc,
 		command.Deploy,
+		command.Rollback,
+		command.Graph,
+		command.Tag,
+		command.Cache,
+		command.Publish,
+		command.Schema,
+		command.Service,
 		command.Version,
 	}
 	app.HideVersion = true